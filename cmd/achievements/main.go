@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/mustaphalimar/prepilot/internal/achievements"
+)
+
+// Awards every badge that users' history has already earned, at the time it
+// was earned. Run it after adding badges or to catch up on missed events; it
+// never awards a badge twice. Badges in ACHIEVEMENTS_DIR are included as the
+// API does.
+func main() {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+
+	badges, err := achievements.LoadWithDir(os.Getenv("ACHIEVEMENTS_DIR"))
+	if err != nil {
+		log.Fatalf("Failed to load badges: %v", err)
+	}
+
+	sqlDB, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	awarded, err := achievements.NewEngine(sqlDB, badges).Backfill(context.Background())
+	if err != nil {
+		log.Fatalf("Failed to backfill achievements after %d awards: %v", awarded, err)
+	}
+
+	fmt.Printf("Awarded %d achievements\n", awarded)
+}
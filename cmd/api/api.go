@@ -18,22 +18,25 @@ func serve(app *app.Application) error {
 	// middlewares
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*", "http://*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Last-Event-ID", "If-Match", "If-None-Match", "X-Impersonation-Session"},
+		ExposedHeaders:   []string{"Link", "ETag", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
 	// Middlewares
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Logger)
+	r.Use(app.RequestLogger)
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(app.RealIP)
+	r.Use(app.RateLimitClients)
+	r.Use(app.TimeoutMiddleware(60 * time.Second))
 
 	// Register all routes
-	app.RegisterRoutes(r)
+	if err := app.RegisterRoutes(r); err != nil {
+		return err
+	}
 
 	srv := &http.Server{
 		Addr:         app.Config.Addr,
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"errors"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/mustaphalimar/prepilot/internal/achievements"
 	"github.com/mustaphalimar/prepilot/internal/app"
+	"github.com/mustaphalimar/prepilot/internal/clerk"
 	"github.com/mustaphalimar/prepilot/internal/db"
 	"github.com/mustaphalimar/prepilot/internal/env"
 )
@@ -49,16 +54,72 @@ func main() {
 	}
 	log.Println("Database migrations completed successfully.")
 
+	// Badges shipped with the API, plus any defined in ACHIEVEMENTS_DIR
+	badges, err := achievements.LoadWithDir(env.GetString("ACHIEVEMENTS_DIR", ""))
+	if err != nil {
+		log.Fatalf("Failed to load badges: %v", err)
+	}
+
+	// Session tokens are verified against the keys of the Clerk instance
+	sessions, err := clerk.NewVerifier(clerk.Options{
+		Issuer:            env.GetString("CLERK_ISSUER", ""),
+		AuthorizedParties: strings.Fields(strings.ReplaceAll(env.GetString("CLERK_AUTHORIZED_PARTIES", ""), ",", " ")),
+		PublicKey:         env.GetString("CLERK_JWT_KEY", ""),
+	})
+	if errors.Is(err, clerk.ErrNoIssuer) {
+		log.Println("CLERK_ISSUER is not set, Clerk session tokens will be refused.")
+	} else if err != nil {
+		log.Fatalf("Failed to set up Clerk session verification: %v", err)
+	}
+
+	// Forwarded client IPs are only believed from these proxies
+	trustedProxies, err := app.ParseTrustedProxies(env.GetString("TRUSTED_PROXIES", ""))
+	if err != nil {
+		log.Fatalf("Failed to parse TRUSTED_PROXIES: %v", err)
+	}
+
 	// Application configuration
 	appConfig := app.Config{
 		Addr:               env.GetString("ADDR", ":8080"),
 		Env:                env.GetString("ENV", "development"),
+		DatabaseURL:        dbCfg.addr,
 		ClerkWebhookSecret: env.GetString("CLERK_WEBHOOK_SECRET", ""),
+		AdminClerkIDs:      strings.Fields(strings.ReplaceAll(env.GetString("ADMIN_CLERK_IDS", ""), ",", " ")),
+		TrashRetention:     time.Duration(env.GetInt("TRASH_RETENTION_DAYS", 30)) * 24 * time.Hour,
+		RateLimitStore:     env.GetString("RATE_LIMIT_STORE", "memory"),
+		Badges:             badges,
+		TrustedProxies:     trustedProxies,
+		ClerkSessions:      sessions,
 	}
 
 	// Create application instance
 	application := app.NewApplication(appConfig, sqlDB)
 
+	// Start the real-time event broker
+	if err := application.Events.Start(); err != nil {
+		log.Fatalf("Failed to start event broker: %v", err)
+	}
+	defer application.Events.Close()
+
+	// Start delivering outbound webhooks
+	application.Webhooks.Start()
+	defer application.Webhooks.Close()
+
+	// Start building requested data exports
+	application.Exports.Start()
+	defer application.Exports.Close()
+
+	// Start purging expired trash
+	application.Trash.Start()
+	defer application.Trash.Close()
+
+	// Start recording the outcome of ended goal periods
+	application.Goals.Start()
+	defer application.Goals.Close()
+
+	// Let checks started by requests finish before the workers stop
+	defer application.Wait()
+
 	// Start the HTTP server (defined in api.go)
 	if err := serve(application); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"
+	"github.com/mustaphalimar/prepilot/internal/templates"
+)
+
+// Seeds the official study plan templates. Directories given as arguments
+// are imported as well, so more templates can be added without a release.
+func main() {
+	databaseURL := os.Getenv("DATABASE_URL")
+	if databaseURL == "" {
+		log.Fatal("DATABASE_URL environment variable is required")
+	}
+
+	official, err := templates.LoadOfficial()
+	if err != nil {
+		log.Fatalf("Failed to load official templates: %v", err)
+	}
+	all := official
+	for _, dir := range os.Args[1:] {
+		extra, err := templates.Load(os.DirFS(dir))
+		if err != nil {
+			log.Fatalf("Failed to load templates from %s: %v", dir, err)
+		}
+		all = append(all, extra...)
+	}
+
+	sqlDB, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	if err := templates.Seed(context.Background(), sqlDB, all); err != nil {
+		log.Fatalf("Failed to seed templates: %v", err)
+	}
+
+	fmt.Printf("Seeded %d study plan templates\n", len(all))
+}
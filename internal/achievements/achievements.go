@@ -0,0 +1,190 @@
+// Package achievements awards badges for what users accomplish.
+//
+// Badges are declared in YAML files rather than in code. A badge names a
+// metric measured from the user's history and the threshold that earns it:
+//
+//	slug: early-bird
+//	name: Early bird
+//	description: Complete 10 tasks before 8am.
+//	icon: sunrise
+//	metric: tasks_completed
+//	threshold: 10
+//	before_hour: 8    # only count what happened before 08:00 UTC
+//
+// With streak: true the threshold is a number of consecutive days with at
+// least one occurrence rather than a total. Each metric is re-evaluated when
+// one of the domain events that can move it happens, and a badge is awarded
+// once, at the time its threshold was reached, so evaluating the whole
+// history again awards nothing twice.
+package achievements
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Domain events that badges are evaluated on
+const (
+	EventTaskCompleted = "task.completed"
+	EventExamScored    = "exam.scored"
+	EventGoalMet       = "goal.met"
+)
+
+// Metrics a badge can be earned on
+const (
+	// MetricTasksCompleted counts completed tasks
+	MetricTasksCompleted = "tasks_completed"
+	// MetricStudyMinutes sums the minutes spent on completed tasks
+	MetricStudyMinutes = "study_minutes"
+	// MetricStudyDays counts the days with at least one completed task
+	MetricStudyDays = "study_days"
+	// MetricPlansFinishedEarly counts plans whose tasks were all completed
+	// before the day of the first exam they prepare for
+	MetricPlansFinishedEarly = "plans_finished_before_exam"
+	// MetricGoalsMet counts goal periods that met their target
+	MetricGoalsMet = "goals_met"
+	// MetricExamTargetsMet counts exams scored at or above their target
+	MetricExamTargetsMet = "exam_targets_met"
+)
+
+// Builtin holds the badges shipped with the API
+//
+//go:embed badges/*.yaml
+var Builtin embed.FS
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Badge is the definition of an achievement
+type Badge struct {
+	Slug        string `yaml:"slug"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Icon        string `yaml:"icon"`
+	Metric      string `yaml:"metric"`
+	Threshold   int64  `yaml:"threshold"`
+	// Streak counts consecutive days instead of the total
+	Streak bool `yaml:"streak"`
+	// BeforeHour, when set, only counts occurrences before that hour, UTC
+	BeforeHour *int64 `yaml:"before_hour"`
+}
+
+// Parse reads a badge from YAML. Unknown fields are an error, so a typo
+// is not silently ignored.
+func Parse(data []byte) (Badge, error) {
+	var b Badge
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&b); err != nil && !errors.Is(err, io.EOF) {
+		return Badge{}, err
+	}
+
+	return b, b.Validate()
+}
+
+// Validate checks a badge definition
+func (b Badge) Validate() error {
+	m, ok := metrics[b.Metric]
+	switch {
+	case !slugPattern.MatchString(b.Slug):
+		return fmt.Errorf("slug must be lowercase letters, digits and dashes")
+	case strings.TrimSpace(b.Name) == "":
+		return fmt.Errorf("name is required")
+	case strings.TrimSpace(b.Description) == "":
+		return fmt.Errorf("description is required")
+	case !ok:
+		return fmt.Errorf("unknown metric %q", b.Metric)
+	case b.Threshold < 1:
+		return fmt.Errorf("threshold must be at least 1")
+	case b.BeforeHour != nil && !m.timed:
+		return fmt.Errorf("before_hour is not supported by the %s metric", b.Metric)
+	case b.BeforeHour != nil && (*b.BeforeHour < 1 || *b.BeforeHour > 23):
+		return fmt.Errorf("before_hour must be between 1 and 23")
+	}
+	return nil
+}
+
+// Event returns the domain event that can move the badge's metric
+func (b Badge) Event() string {
+	return metrics[b.Metric].event
+}
+
+// Load reads every .yaml and .yml file in the root of fsys, sorted by name
+func Load(fsys fs.FS) ([]Badge, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	badges := make([]Badge, 0, len(names))
+	slugs := make(map[string]string, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		b, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if other, ok := slugs[b.Slug]; ok {
+			return nil, fmt.Errorf("%s: slug %q is already used by %s", name, b.Slug, other)
+		}
+		slugs[b.Slug] = name
+		badges = append(badges, b)
+	}
+	return badges, nil
+}
+
+// LoadBuiltin reads the badges shipped with the API
+func LoadBuiltin() ([]Badge, error) {
+	fsys, err := fs.Sub(Builtin, "badges")
+	if err != nil {
+		return nil, err
+	}
+	return Load(fsys)
+}
+
+// LoadWithDir reads the builtin badges and, when dir is not empty, the ones
+// in that directory. A badge in dir replaces the builtin badge with the same
+// slug, so thresholds can be tuned without a release.
+func LoadWithDir(dir string) ([]Badge, error) {
+	badges, err := LoadBuiltin()
+	if err != nil || dir == "" {
+		return badges, err
+	}
+
+	extra, err := Load(os.DirFS(dir))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dir, err)
+	}
+	for _, badge := range extra {
+		i := slices.IndexFunc(badges, func(b Badge) bool { return b.Slug == badge.Slug })
+		if i >= 0 {
+			badges[i] = badge
+		} else {
+			badges = append(badges, badge)
+		}
+	}
+	return badges, nil
+}
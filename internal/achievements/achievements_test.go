@@ -0,0 +1,76 @@
+package achievements
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		want    Badge
+		wantErr string
+	}{
+		{
+			name: "total",
+			yaml: "slug: first-task\nname: First task\ndescription: Complete a task.\nmetric: tasks_completed\nthreshold: 1\n",
+			want: Badge{Slug: "first-task", Name: "First task", Description: "Complete a task.", Metric: MetricTasksCompleted, Threshold: 1},
+		},
+		{
+			name: "streak before an hour",
+			yaml: "slug: early-week\nname: Early week\ndescription: \"Study early: 7 days in a row.\"\nicon: sunrise\nmetric: study_minutes\nthreshold: 7\nstreak: true\nbefore_hour: 8\n",
+			want: Badge{Slug: "early-week", Name: "Early week", Description: "Study early: 7 days in a row.", Icon: "sunrise", Metric: MetricStudyMinutes, Threshold: 7, Streak: true, BeforeHour: hour(8)},
+		},
+		{name: "empty", yaml: "", wantErr: "slug"},
+		{name: "not a mapping", yaml: "- slug\n", wantErr: "cannot unmarshal"},
+		{name: "unknown field", yaml: "slug: a\ntreshold: 1\n", wantErr: "field treshold not found"},
+		{name: "threshold not a number", yaml: "slug: a\nthreshold: ten\n", wantErr: "cannot unmarshal"},
+		{name: "bad slug", yaml: "slug: First Task\nname: a\ndescription: a\nmetric: tasks_completed\nthreshold: 1\n", wantErr: "slug"},
+		{name: "no name", yaml: "slug: a\ndescription: a\nmetric: tasks_completed\nthreshold: 1\n", wantErr: "name is required"},
+		{name: "unknown metric", yaml: "slug: a\nname: a\ndescription: a\nmetric: pages_read\nthreshold: 1\n", wantErr: "unknown metric"},
+		{name: "zero threshold", yaml: "slug: a\nname: a\ndescription: a\nmetric: tasks_completed\nthreshold: 0\n", wantErr: "threshold"},
+		{name: "before_hour on days", yaml: "slug: a\nname: a\ndescription: a\nmetric: study_days\nthreshold: 1\nbefore_hour: 8\n", wantErr: "not supported"},
+		{name: "before_hour out of range", yaml: "slug: a\nname: a\ndescription: a\nmetric: tasks_completed\nthreshold: 1\nbefore_hour: 24\n", wantErr: "between 1 and 23"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.yaml))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Parse = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if got.Slug != tt.want.Slug || got.Name != tt.want.Name || got.Description != tt.want.Description ||
+				got.Icon != tt.want.Icon || got.Metric != tt.want.Metric || got.Threshold != tt.want.Threshold ||
+				got.Streak != tt.want.Streak || !sameHour(got.BeforeHour, tt.want.BeforeHour) {
+				t.Errorf("Parse = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadBuiltin(t *testing.T) {
+	badges, err := LoadBuiltin()
+	if err != nil {
+		t.Fatalf("LoadBuiltin: %v", err)
+	}
+	if len(badges) == 0 {
+		t.Error("no builtin badges")
+	}
+}
+
+func hour(h int64) *int64 {
+	return &h
+}
+
+func sameHour(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,261 @@
+package achievements
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// occurrence is something a metric counts, such as a completed task, and
+// when it happened
+type occurrence struct {
+	At     time.Time
+	Amount int64
+}
+
+// history is the part of a user's history the metrics are measured from.
+// Queries that several metrics share are only run once.
+type history struct {
+	queries *store.Queries
+	userID  string
+
+	completions []store.ListAchievementCompletionsRow
+	loaded      bool
+}
+
+// taskCompletions lists the user's completed tasks
+func (h *history) taskCompletions(ctx context.Context) ([]store.ListAchievementCompletionsRow, error) {
+	if !h.loaded {
+		rows, err := h.queries.ListAchievementCompletions(ctx, h.userID)
+		if err != nil {
+			return nil, err
+		}
+		h.completions, h.loaded = rows, true
+	}
+	return h.completions, nil
+}
+
+// metric measures one thing from a user's history
+type metric struct {
+	// event is the domain event that can move the metric
+	event string
+	// timed is set when occurrences carry a time of day
+	timed bool
+	list  func(ctx context.Context, h *history) ([]occurrence, error)
+}
+
+var metrics = map[string]metric{
+	MetricTasksCompleted: {event: EventTaskCompleted, timed: true, list: func(ctx context.Context, h *history) ([]occurrence, error) {
+		rows, err := h.taskCompletions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]occurrence, len(rows))
+		for i, row := range rows {
+			list[i] = occurrence{At: row.CompletedAt, Amount: 1}
+		}
+		return list, nil
+	}},
+	MetricStudyMinutes: {event: EventTaskCompleted, timed: true, list: func(ctx context.Context, h *history) ([]occurrence, error) {
+		rows, err := h.taskCompletions(ctx)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]occurrence, len(rows))
+		for i, row := range rows {
+			list[i] = occurrence{At: row.CompletedAt, Amount: row.MinutesSpent}
+		}
+		return list, nil
+	}},
+	MetricStudyDays: {event: EventTaskCompleted, list: func(ctx context.Context, h *history) ([]occurrence, error) {
+		days, err := h.queries.ListAchievementStudyDays(ctx, h.userID)
+		return occurrences(days), err
+	}},
+	MetricPlansFinishedEarly: {event: EventTaskCompleted, list: func(ctx context.Context, h *history) ([]occurrence, error) {
+		rows, err := h.queries.ListAchievementFinishedPlans(ctx, h.userID)
+		if err != nil {
+			return nil, err
+		}
+		var list []occurrence
+		for _, row := range rows {
+			if date(row.FinishedAt).Before(date(row.ExamStartsAt)) {
+				list = append(list, occurrence{At: row.FinishedAt, Amount: 1})
+			}
+		}
+		return list, nil
+	}},
+	MetricGoalsMet: {event: EventGoalMet, list: func(ctx context.Context, h *history) ([]occurrence, error) {
+		times, err := h.queries.ListAchievementGoalsMet(ctx, h.userID)
+		return occurrences(times), err
+	}},
+	MetricExamTargetsMet: {event: EventExamScored, list: func(ctx context.Context, h *history) ([]occurrence, error) {
+		times, err := h.queries.ListAchievementExamTargetsMet(ctx, h.userID)
+		return occurrences(times), err
+	}},
+}
+
+func occurrences(times []time.Time) []occurrence {
+	list := make([]occurrence, len(times))
+	for i, t := range times {
+		list[i] = occurrence{At: t, Amount: 1}
+	}
+	return list
+}
+
+// date returns the UTC day of t at midnight
+func date(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// measure returns the badge's progress over the occurrences, sorted by
+// time, and when its threshold was first reached
+func (b Badge) measure(list []occurrence) (value int64, reachedAt time.Time, reached bool) {
+	var run int64
+	var lastDay time.Time
+	for _, o := range list {
+		if b.BeforeHour != nil && int64(o.At.UTC().Hour()) >= *b.BeforeHour {
+			continue
+		}
+
+		if b.Streak {
+			day := date(o.At)
+			switch {
+			case day.Equal(lastDay):
+				continue
+			case day.Equal(lastDay.AddDate(0, 0, 1)):
+				run++
+			default:
+				run = 1
+			}
+			lastDay = day
+			value = max(value, run)
+		} else {
+			value += o.Amount
+		}
+
+		if !reached && value >= b.Threshold {
+			reachedAt, reached = o.At, true
+		}
+	}
+	return value, reachedAt, reached
+}
+
+// Progress is where a user stands on a badge
+type Progress struct {
+	Badge     Badge
+	Value     int64
+	Earned    bool
+	AwardedAt time.Time
+	// New is set when the badge was awarded by this evaluation
+	New bool
+}
+
+// Engine evaluates badges against users' history and awards them
+type Engine struct {
+	queries *store.Queries
+	badges  []Badge
+}
+
+// NewEngine creates a new Engine for the badges
+func NewEngine(db *sql.DB, badges []Badge) *Engine {
+	return &Engine{
+		queries: store.New(db),
+		badges:  badges,
+	}
+}
+
+// Badges returns the badges the engine awards
+func (e *Engine) Badges() []Badge {
+	return e.badges
+}
+
+// Measure returns the user's progress on every badge without awarding
+// anything. A badge whose threshold has been reached is only earned once
+// it has been awarded.
+func (e *Engine) Measure(ctx context.Context, userID string) ([]Progress, error) {
+	return e.evaluate(ctx, userID, "", false)
+}
+
+// Evaluate measures the user's badges and awards those whose threshold has
+// been reached. With an event, only the badges it can move and the user has
+// not earned yet are evaluated and returned; with an empty event every badge
+// is. Awarding is idempotent, so this is safe to repeat.
+func (e *Engine) Evaluate(ctx context.Context, userID, event string) ([]Progress, error) {
+	return e.evaluate(ctx, userID, event, true)
+}
+
+func (e *Engine) evaluate(ctx context.Context, userID, event string, award bool) ([]Progress, error) {
+	awards, err := e.queries.ListUserAchievements(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	awardedAt := make(map[string]time.Time, len(awards))
+	for _, award := range awards {
+		awardedAt[award.Badge] = award.AwardedAt
+	}
+
+	h := &history{queries: e.queries, userID: userID}
+	measured := make(map[string][]occurrence)
+	var result []Progress
+	for _, badge := range e.badges {
+		at, earned := awardedAt[badge.Slug]
+		if event != "" && (earned || badge.Event() != event) {
+			continue
+		}
+
+		list, ok := measured[badge.Metric]
+		if !ok {
+			list, err = metrics[badge.Metric].list(ctx, h)
+			if err != nil {
+				return nil, fmt.Errorf("failed to measure %s: %w", badge.Metric, err)
+			}
+			sort.SliceStable(list, func(i, j int) bool { return list[i].At.Before(list[j].At) })
+			measured[badge.Metric] = list
+		}
+
+		progress := Progress{Badge: badge, Earned: earned, AwardedAt: at}
+		var reachedAt time.Time
+		progress.Value, reachedAt, ok = badge.measure(list)
+		if ok && !earned && award {
+			added, err := e.queries.AwardAchievement(ctx, store.AwardAchievementParams{
+				UserID:    userID,
+				Badge:     badge.Slug,
+				AwardedAt: reachedAt,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to award %s: %w", badge.Slug, err)
+			}
+			progress.Earned, progress.AwardedAt, progress.New = true, reachedAt, added > 0
+		}
+		result = append(result, progress)
+	}
+	return result, nil
+}
+
+// Backfill evaluates every badge for every user, awarding what their
+// history has already earned. It returns the number of new awards.
+func (e *Engine) Backfill(ctx context.Context) (int, error) {
+	userIDs, err := e.queries.ListAchievementUserIDs(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	awarded := 0
+	for _, userID := range userIDs {
+		list, err := e.Evaluate(ctx, userID, "")
+		if err != nil {
+			return awarded, fmt.Errorf("user %s: %w", userID, err)
+		}
+		for _, progress := range list {
+			if progress.New {
+				awarded++
+			}
+		}
+	}
+	return awarded, nil
+}
@@ -0,0 +1,95 @@
+package achievements
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBadgeMeasure(t *testing.T) {
+	day := time.Date(2026, time.March, 30, 10, 0, 0, 0, time.UTC)
+	at := func(days, hours int) time.Time { return day.AddDate(0, 0, days).Add(time.Duration(hours) * time.Hour) }
+
+	tests := []struct {
+		name        string
+		badge       Badge
+		list        []occurrence
+		wantValue   int64
+		wantReached time.Time
+	}{
+		{
+			name:  "nothing yet",
+			badge: Badge{Threshold: 1},
+		},
+		{
+			name:        "total reached",
+			badge:       Badge{Threshold: 3},
+			list:        []occurrence{{At: at(0, 0), Amount: 1}, {At: at(1, 0), Amount: 1}, {At: at(2, 0), Amount: 1}, {At: at(3, 0), Amount: 1}},
+			wantValue:   4,
+			wantReached: at(2, 0),
+		},
+		{
+			name:        "amounts add up",
+			badge:       Badge{Threshold: 60},
+			list:        []occurrence{{At: at(0, 0), Amount: 45}, {At: at(0, 1), Amount: 30}},
+			wantValue:   75,
+			wantReached: at(0, 1),
+		},
+		{
+			name:      "total short of the threshold",
+			badge:     Badge{Threshold: 10},
+			list:      []occurrence{{At: at(0, 0), Amount: 9}},
+			wantValue: 9,
+		},
+		{
+			name:        "before an hour",
+			badge:       Badge{Threshold: 2, BeforeHour: hour(8)},
+			list:        []occurrence{{At: at(0, -3), Amount: 1}, {At: at(0, -2), Amount: 1}, {At: at(1, -3), Amount: 1}},
+			wantValue:   2,
+			wantReached: at(1, -3),
+		},
+		{
+			name:        "streak across a month end",
+			badge:       Badge{Threshold: 3, Streak: true},
+			list:        []occurrence{{At: at(0, 0)}, {At: at(1, 0)}, {At: at(2, 0)}},
+			wantValue:   3,
+			wantReached: at(2, 0),
+		},
+		{
+			name:        "same day counts once",
+			badge:       Badge{Threshold: 2, Streak: true},
+			list:        []occurrence{{At: at(0, 0)}, {At: at(0, 5)}, {At: at(1, 0)}},
+			wantValue:   2,
+			wantReached: at(1, 0),
+		},
+		{
+			name:      "streak resets after a missed day",
+			badge:     Badge{Threshold: 3, Streak: true},
+			list:      []occurrence{{At: at(0, 0)}, {At: at(1, 0)}, {At: at(3, 0)}, {At: at(4, 0)}},
+			wantValue: 2,
+		},
+		{
+			name:        "longest streak is kept",
+			badge:       Badge{Threshold: 3, Streak: true},
+			list:        []occurrence{{At: at(0, 0)}, {At: at(1, 0)}, {At: at(2, 0)}, {At: at(5, 0)}},
+			wantValue:   3,
+			wantReached: at(2, 0),
+		},
+		{
+			name:      "streak before an hour skips late days",
+			badge:     Badge{Threshold: 2, Streak: true, BeforeHour: hour(8)},
+			list:      []occurrence{{At: at(0, -3)}, {At: at(1, 0)}, {At: at(2, -3)}},
+			wantValue: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, reachedAt, reached := tt.badge.measure(tt.list)
+			if value != tt.wantValue {
+				t.Errorf("value = %d, want %d", value, tt.wantValue)
+			}
+			if reached != !tt.wantReached.IsZero() || !reachedAt.Equal(tt.wantReached) {
+				t.Errorf("reached = %v at %v, want %v", reached, reachedAt, tt.wantReached)
+			}
+		})
+	}
+}
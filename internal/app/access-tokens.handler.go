@@ -0,0 +1,279 @@
+package app
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Scopes a personal access token can be granted
+const (
+	ScopePlansRead     = "plans:read"
+	ScopePlansWrite    = "plans:write"
+	ScopeTasksRead     = "tasks:read"
+	ScopeTasksWrite    = "tasks:write"
+	ScopeWebhooksRead  = "webhooks:read"
+	ScopeWebhooksWrite = "webhooks:write"
+	ScopeActivityRead  = "activity:read"
+	ScopeProfileRead   = "profile:read"
+
+	ScopeClassroomsRead  = "classrooms:read"
+	ScopeClassroomsWrite = "classrooms:write"
+	ScopeTemplatesRead   = "templates:read"
+	ScopeTemplatesWrite  = "templates:write"
+	ScopeSubjectsRead    = "subjects:read"
+	ScopeSubjectsWrite   = "subjects:write"
+	ScopeExamsRead       = "exams:read"
+	ScopeExamsWrite      = "exams:write"
+	ScopeGoalsRead       = "goals:read"
+	ScopeGoalsWrite      = "goals:write"
+
+	ScopeAchievementsRead = "achievements:read"
+)
+
+// accessTokenScopes lists every scope, in the order they are documented
+var accessTokenScopes = []string{
+	ScopePlansRead, ScopePlansWrite,
+	ScopeTasksRead, ScopeTasksWrite,
+	ScopeWebhooksRead, ScopeWebhooksWrite,
+	ScopeActivityRead, ScopeProfileRead,
+	ScopeClassroomsRead, ScopeClassroomsWrite,
+	ScopeTemplatesRead, ScopeTemplatesWrite,
+	ScopeSubjectsRead, ScopeSubjectsWrite,
+	ScopeExamsRead, ScopeExamsWrite,
+	ScopeGoalsRead, ScopeGoalsWrite,
+	ScopeAchievementsRead,
+}
+
+// accessTokenPrefix starts every personal access token so the auth
+// middleware can tell them apart from Clerk JWTs
+const accessTokenPrefix = "pp_pat_"
+
+// accessTokenDisplayLength is how much of a token is kept in clear so the
+// user can recognise it in a list
+const accessTokenDisplayLength = len(accessTokenPrefix) + 6
+
+// Activity log entity type and actions for access tokens
+const (
+	activityAccessToken  = "access_token"
+	activityTokenCreated = "token.created"
+	activityTokenRevoked = "token.revoked"
+)
+
+var (
+	errAccessTokenRevoked = errors.New("access token revoked")
+	errAccessTokenExpired = errors.New("access token expired")
+)
+
+// CreateAccessTokenRequest represents the request to mint a personal access token
+type CreateAccessTokenRequest struct {
+	Name      string     `json:"name" validate:"required,notblank,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,scope"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// AccessTokenResponse represents a personal access token in API responses.
+// Token is only set when the token is created.
+type AccessTokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Token      string     `json:"token,omitempty"`
+}
+
+func convertAccessTokenToResponse(token store.PersonalAccessToken) AccessTokenResponse {
+	return AccessTokenResponse{
+		ID:         token.ID,
+		Name:       token.Name,
+		Prefix:     token.TokenPrefix,
+		Scopes:     token.Scopes,
+		ExpiresAt:  nullTimeToPointer(token.ExpiresAt),
+		LastUsedAt: nullTimeToPointer(token.LastUsedAt),
+		RevokedAt:  nullTimeToPointer(token.RevokedAt),
+		CreatedAt:  token.CreatedAt,
+	}
+}
+
+// CreateAccessTokenHandler mints a personal access token. The token is only
+// returned in this response; afterwards only its hash is stored.
+func (app *Application) CreateAccessTokenHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req CreateAccessTokenRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		app.badRequestError(w, r, &FieldError{Field: "expires_at", Code: "future", Message: "must be in the future"})
+		return
+	}
+
+	secret, err := newSecretToken(accessTokenPrefix)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	token, err := app.Queries.CreatePersonalAccessToken(r.Context(), store.CreatePersonalAccessTokenParams{
+		UserID:      user.ClerkID,
+		Name:        strings.TrimSpace(req.Name),
+		TokenPrefix: secret[:accessTokenDisplayLength],
+		TokenHash:   hashSecretToken(secret),
+		Scopes:      dedupeScopes(req.Scopes),
+		ExpiresAt:   timeToNullTime(req.ExpiresAt),
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityTokenCreated,
+		EntityType: activityAccessToken,
+		EntityID:   token.ID.String(),
+		After:      convertAccessTokenToResponse(token),
+	})
+
+	response := convertAccessTokenToResponse(token)
+	response.Token = secret
+
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// GetAccessTokensHandler lists the user's personal access tokens, including
+// revoked and expired ones
+func (app *Application) GetAccessTokensHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	tokens, err := app.Queries.GetPersonalAccessTokensByUser(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]AccessTokenResponse, len(tokens))
+	for i, token := range tokens {
+		response[i] = convertAccessTokenToResponse(token)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RevokeAccessTokenHandler revokes a personal access token. Revoked tokens
+// stay listed so the user can see when they were last used.
+func (app *Application) RevokeAccessTokenHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	tokenID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	token, err := app.Queries.RevokePersonalAccessToken(r.Context(), store.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: user.ClerkID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Access token not found or already revoked")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityTokenRevoked,
+		EntityType: activityAccessToken,
+		EntityID:   token.ID.String(),
+		Before:     map[string]any{"revoked_at": nil},
+		After:      map[string]any{"revoked_at": token.RevokedAt.Time},
+	})
+
+	app.jsonResponse(w, http.StatusOK, convertAccessTokenToResponse(token))
+}
+
+// authenticateAccessToken resolves a personal access token to the claims of
+// its owner. It fails for unknown, revoked and expired tokens and for banned
+// users.
+func (app *Application) authenticateAccessToken(r *http.Request, secret string) (*UserClaims, error) {
+	token, err := app.Queries.GetPersonalAccessTokenByHash(r.Context(), hashSecretToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token.RevokedAt.Valid {
+		return nil, errAccessTokenRevoked
+	}
+	if token.ExpiresAt.Valid && !token.ExpiresAt.Time.After(time.Now()) {
+		return nil, errAccessTokenExpired
+	}
+	if token.Banned {
+		return nil, errUserBanned
+	}
+
+	// Last use is informational, so a failure does not block the request
+	if err := app.Queries.TouchPersonalAccessToken(r.Context(), token.ID); err != nil {
+		log.Printf("access tokens: failed to record use of %s: %v", token.ID, err)
+	}
+
+	return &UserClaims{
+		ClerkID: token.UserID,
+		Email:   token.Email,
+		Role:    app.userRole(token.UserID, token.Role),
+		TokenID: token.ID,
+		Scopes:  token.Scopes,
+	}, nil
+}
+
+// newSecretToken generates a token with 256 bits of randomness after prefix
+func newSecretToken(prefix string) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// hashSecretToken is the value stored for a token. The tokens are random
+// enough that a plain SHA-256 needs no salt.
+func hashSecretToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// dedupeScopes drops repeated scopes while keeping their order
+func dedupeScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	result := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			result = append(result, scope)
+		}
+	}
+	return result
+}
@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/achievements"
+	"github.com/mustaphalimar/prepilot/internal/events"
+)
+
+// AchievementResponse is a badge with the user's progress towards it
+type AchievementResponse struct {
+	Slug        string     `json:"slug"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Icon        string     `json:"icon,omitempty"`
+	Metric      string     `json:"metric"`
+	Streak      bool       `json:"streak"`
+	Threshold   int64      `json:"threshold"`
+	Progress    int64      `json:"progress"`
+	Earned      bool       `json:"earned"`
+	AwardedAt   *time.Time `json:"awarded_at"`
+}
+
+func convertProgressToAchievement(progress achievements.Progress) AchievementResponse {
+	response := AchievementResponse{
+		Slug:        progress.Badge.Slug,
+		Name:        progress.Badge.Name,
+		Description: progress.Badge.Description,
+		Icon:        progress.Badge.Icon,
+		Metric:      progress.Badge.Metric,
+		Streak:      progress.Badge.Streak,
+		Threshold:   progress.Badge.Threshold,
+		Progress:    progress.Value,
+		Earned:      progress.Earned,
+	}
+	if progress.Earned {
+		awardedAt := progress.AwardedAt
+		response.AwardedAt = &awardedAt
+	}
+	return response
+}
+
+// checkAchievements evaluates the badges an event can move and tells the
+// user about the ones they earned
+func (app *Application) checkAchievements(ctx context.Context, userID, event string) {
+	list, err := app.Achievements.Evaluate(ctx, userID, event)
+	if err != nil {
+		log.Printf("achievements: failed to evaluate %s for %s: %v", event, userID, err)
+		return
+	}
+	for _, progress := range list {
+		if progress.New {
+			app.publishEvent(ctx, userID, events.AchievementAwarded, convertProgressToAchievement(progress))
+		}
+	}
+}
+
+// GetAchievementsHandler lists every badge with the user's progress.
+// Badges are only awarded as events happen and by the backfill command.
+func (app *Application) GetAchievementsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	list, err := app.Achievements.Measure(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]AchievementResponse, len(list))
+	for i, progress := range list {
+		response[i] = convertProgressToAchievement(progress)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
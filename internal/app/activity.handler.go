@@ -0,0 +1,270 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Activity log entity types
+const (
+	activityStudyPlan = "study_plan"
+	activityStudyTask = "study_task"
+	activityUser      = "user"
+	activityClassroom = "classroom"
+
+	activityPlanTemplate = "plan_template"
+)
+
+// Activity log actions that have no matching real-time event. Other
+// actions use the event type names from the events package.
+const (
+	activityPlanPurged  = "plan.purged"
+	activityTaskPurged  = "task.purged"
+	activityUserCreated = "user.created"
+	activityUserUpdated = "user.updated"
+
+	activityUserBanned       = "user.banned"
+	activityUserUnbanned     = "user.unbanned"
+	activityUserRoleChanged  = "user.role_changed"
+	activityUserImpersonated = "user.impersonated"
+
+	activityPlanMemberAdded       = "plan.member_added"
+	activityPlanMemberRoleChanged = "plan.member_role_changed"
+	activityPlanMemberRemoved     = "plan.member_removed"
+	activityPlanInviteCreated     = "plan.invite_created"
+	activityPlanInviteRevoked     = "plan.invite_revoked"
+	activityPlanShared            = "plan.share_link_created"
+	activityPlanShareRevoked      = "plan.share_link_revoked"
+
+	activityClassroomCreated        = "classroom.created"
+	activityClassroomUpdated        = "classroom.updated"
+	activityClassroomDeleted        = "classroom.deleted"
+	activityClassroomPublished      = "classroom.published"
+	activityClassroomJoined         = "classroom.joined"
+	activityClassroomStudentRemoved = "classroom.student_removed"
+
+	activityTemplateCreated     = "template.created"
+	activityTemplateUpdated     = "template.updated"
+	activityTemplateDeleted     = "template.deleted"
+	activityTemplatePublished   = "template.published"
+	activityTemplateUnpublished = "template.unpublished"
+)
+
+// activityActorClerk is the actor recorded for changes made by Clerk webhooks
+const activityActorClerk = "clerk"
+
+// ActivityResponse is an activity log entry in API responses
+type ActivityResponse struct {
+	ID         int64           `json:"id"`
+	ActorID    string          `json:"actor_id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// GetActivityHandler lists the user's activity, newest first. It can be
+// filtered by entity_type, entity_id, action and a since/until time range.
+func (app *Application) GetActivityHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	limit, offset, err := parsePagination(r, 50, 200)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	params := store.ListActivityParams{
+		UserID:     user.ClerkID,
+		EntityType: stringValueToNullString(query.Get("entity_type")),
+		EntityID:   stringValueToNullString(query.Get("entity_id")),
+		Action:     stringValueToNullString(query.Get("action")),
+		RowLimit:   limit,
+		RowOffset:  offset,
+	}
+
+	if params.Since, err = parseTimeParam(query, "since"); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if params.Until, err = parseTimeParam(query, "until"); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	entries, err := app.Queries.ListActivity(r.Context(), params)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]ActivityResponse, len(entries))
+	for i, entry := range entries {
+		response[i] = ActivityResponse{
+			ID:         entry.ID,
+			ActorID:    entry.ActorID,
+			Action:     entry.Action,
+			EntityType: entry.EntityType,
+			EntityID:   entry.EntityID,
+			Before:     entry.Before,
+			After:      entry.After,
+			CreatedAt:  entry.CreatedAt,
+		}
+	}
+
+	if err := app.pageResponse(w, r, response, len(response), limit, offset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// parseTimeParam parses the optional RFC 3339 timestamp or date in the
+// query parameter name
+func parseTimeParam(query url.Values, name string) (sql.NullTime, error) {
+	value := query.Get(name)
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+		}
+	}
+	return sql.NullTime{}, invalidParam(name, value)
+}
+
+// activityEntry describes one change to record. Before is nil for creations
+// and After is nil for deletions.
+type activityEntry struct {
+	UserID     string
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	Before     any
+	After      any
+}
+
+// recordActivity adds an entry to the activity log. Like publishEvent,
+// failures are logged rather than failing the request that made the change.
+func (app *Application) recordActivity(ctx context.Context, entry activityEntry) {
+	if err := logActivity(ctx, app.Queries, entry); err != nil {
+		log.Printf("activity: failed to record %s for %s: %v", entry.Action, entry.EntityID, err)
+	}
+}
+
+// logActivity writes an entry using the given queries, so it can be part of
+// a transaction. When both sides are given only the fields that changed are
+// stored, and nothing is stored if no field changed.
+func logActivity(ctx context.Context, queries *store.Queries, entry activityEntry) error {
+	before, err := activitySnapshot(entry.Before)
+	if err != nil {
+		return err
+	}
+	after, err := activitySnapshot(entry.After)
+	if err != nil {
+		return err
+	}
+
+	if before != nil && after != nil {
+		diffSnapshots(before, after)
+		if len(before) == 0 && len(after) == 0 {
+			return nil
+		}
+	}
+
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+
+	return queries.CreateActivityLogEntry(ctx, store.CreateActivityLogEntryParams{
+		UserID:     entry.UserID,
+		ActorID:    entry.ActorID,
+		Action:     entry.Action,
+		EntityType: entry.EntityType,
+		EntityID:   entry.EntityID,
+		Before:     beforeJSON,
+		After:      afterJSON,
+	})
+}
+
+// activitySnapshot turns a value into a map of its JSON fields
+func activitySnapshot(v any) (map[string]json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode activity snapshot: %w", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode activity snapshot: %w", err)
+	}
+	return fields, nil
+}
+
+// diffSnapshots removes the fields that are the same on both sides.
+// Timestamps that change on every write are not worth recording.
+func diffSnapshots(before, after map[string]json.RawMessage) {
+	for key, value := range before {
+		if key == "updated_at" || bytes.Equal(value, after[key]) {
+			delete(before, key)
+			delete(after, key)
+		}
+	}
+	delete(after, "updated_at")
+}
+
+// planSnapshot is the view of a study plan kept in the activity log
+func planSnapshot(plan store.StudyPlan) map[string]any {
+	snapshot := map[string]any{
+		"id":          plan.ID,
+		"title":       plan.Title,
+		"subject":     plan.Subject,
+		"description": nil,
+		"exam_date":   plan.ExamDate.Format(time.DateOnly),
+		"start_date":  plan.StartDate.Format(time.DateOnly),
+		"end_date":    plan.EndDate.Format(time.DateOnly),
+	}
+	if plan.Description.Valid {
+		snapshot["description"] = plan.Description.String
+	}
+	return snapshot
+}
+
+// userSnapshot is the view of a user kept in the activity log
+func userSnapshot(user store.User) map[string]any {
+	snapshot := map[string]any{
+		"email":          user.Email,
+		"first_name":     nil,
+		"last_name":      nil,
+		"image_url":      nil,
+		"email_verified": user.EmailVerified.Valid && user.EmailVerified.Bool,
+		"banned":         user.Banned.Valid && user.Banned.Bool,
+	}
+	if user.FirstName.Valid {
+		snapshot["first_name"] = user.FirstName.String
+	}
+	if user.LastName.Valid {
+		snapshot["last_name"] = user.LastName.String
+	}
+	if user.ImageUrl.Valid {
+		snapshot["image_url"] = user.ImageUrl.String
+	}
+	return snapshot
+}
@@ -0,0 +1,507 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// User roles. Support staff can look at accounts; admins can also change them.
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+// impersonationHeader names the impersonation session an admin request acts under
+const impersonationHeader = "X-Impersonation-Session"
+
+// Impersonation sessions last an hour unless asked otherwise
+const defaultImpersonationMinutes = 60
+
+var (
+	errUserBanned            = errors.New("user is banned")
+	errImpersonationInvalid  = errors.New("impersonation session not found, ended or expired")
+	errImpersonationNotAdmin = errors.New("only admins can impersonate users")
+)
+
+// AdminUserResponse is a user as seen by support staff and admins
+type AdminUserResponse struct {
+	UserResponse
+	Banned bool `json:"banned"`
+}
+
+func convertUserToAdminResponse(user store.User) AdminUserResponse {
+	return AdminUserResponse{
+		UserResponse: convertUserToResponse(user),
+		Banned:       user.Banned.Valid && user.Banned.Bool,
+	}
+}
+
+// SetUserRoleRequest represents the request to change a user's role
+type SetUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=user support admin"`
+}
+
+// StartImpersonationRequest represents the request to act as a user. The
+// reason is kept in the audit trail.
+type StartImpersonationRequest struct {
+	Reason          string `json:"reason" validate:"required,notblank,max=500"`
+	DurationMinutes int    `json:"duration_minutes" validate:"omitempty,min=1,max=240"`
+}
+
+// ImpersonationResponse represents an impersonation session in API responses
+type ImpersonationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	AdminID   string     `json:"admin_id"`
+	UserID    string     `json:"user_id"`
+	Reason    string     `json:"reason"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func convertImpersonationToResponse(session store.ImpersonationSession) ImpersonationResponse {
+	return ImpersonationResponse{
+		ID:        session.ID,
+		AdminID:   session.AdminID,
+		UserID:    session.UserID,
+		Reason:    session.Reason,
+		ExpiresAt: session.ExpiresAt,
+		EndedAt:   nullTimeToPointer(session.EndedAt),
+		CreatedAt: session.CreatedAt,
+	}
+}
+
+// userRole is the role of a user. Users listed in ADMIN_CLERK_IDS are
+// always admins, so the first admin can be set up without the API.
+func (app *Application) userRole(clerkID, role string) string {
+	if app.isBootstrapAdmin(clerkID) {
+		return RoleAdmin
+	}
+	return role
+}
+
+// isBootstrapAdmin reports whether the user is listed in ADMIN_CLERK_IDS
+func (app *Application) isBootstrapAdmin(clerkID string) bool {
+	return slices.Contains(app.Config.AdminClerkIDs, clerkID)
+}
+
+// canActOnAdmin reports whether actor may ban or demote target. Admins can
+// only be acted on by the admins in ADMIN_CLERK_IDS, who cannot be acted on
+// at all, so one admin cannot lock the others out.
+func (app *Application) canActOnAdmin(actor *UserClaims, target store.User) bool {
+	if app.userRole(target.ClerkID, target.Role) != RoleAdmin {
+		return true
+	}
+	return app.isBootstrapAdmin(actor.ClerkID) && !app.isBootstrapAdmin(target.ClerkID)
+}
+
+// impersonate resolves the impersonation session an admin sent in the
+// impersonation header to the claims of the impersonated user
+func (app *Application) impersonate(ctx context.Context, admin *UserClaims, sessionID string) (*UserClaims, error) {
+	if admin.Role != RoleAdmin {
+		return nil, errImpersonationNotAdmin
+	}
+
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return nil, errImpersonationInvalid
+	}
+
+	session, err := app.Queries.GetImpersonationSession(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errImpersonationInvalid
+		}
+		return nil, err
+	}
+	if session.AdminID != admin.ClerkID || session.EndedAt.Valid || !session.ExpiresAt.After(time.Now()) {
+		return nil, errImpersonationInvalid
+	}
+
+	user, err := app.Queries.GetUserByClerkID(ctx, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+	// Banned after the session was opened
+	if user.Banned.Valid && user.Banned.Bool {
+		return nil, errUserBanned
+	}
+
+	return &UserClaims{
+		ClerkID:        user.ClerkID,
+		Email:          user.Email,
+		Role:           app.userRole(user.ClerkID, user.Role),
+		ImpersonatorID: admin.ClerkID,
+	}, nil
+}
+
+// SearchUsersHandler lists users, newest first. q matches the email, name
+// or Clerk ID; role and banned filter the list.
+func (app *Application) SearchUsersHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	limit, offset, err := parsePagination(r, 50, 200)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	params := store.SearchUsersParams{
+		Query:     stringValueToNullString(strings.TrimSpace(query.Get("q"))),
+		RowLimit:  limit,
+		RowOffset: offset,
+	}
+
+	if value := query.Get("role"); value != "" {
+		switch value {
+		case RoleUser, RoleSupport, RoleAdmin:
+			params.Role = sql.NullString{String: value, Valid: true}
+		default:
+			app.badRequestError(w, r, invalidParam("role", value))
+			return
+		}
+	}
+	if value := query.Get("banned"); value != "" {
+		banned, err := strconv.ParseBool(value)
+		if err != nil {
+			app.badRequestError(w, r, invalidParam("banned", value))
+			return
+		}
+		params.Banned = sql.NullBool{Bool: banned, Valid: true}
+	}
+
+	users, err := app.Queries.SearchUsers(r.Context(), params)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]AdminUserResponse, len(users))
+	for i, u := range users {
+		response[i] = convertUserToAdminResponse(u)
+	}
+
+	if err := app.pageResponse(w, r, response, len(response), limit, offset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetUserHandler retrieves a user by Clerk ID
+func (app *Application) GetUserHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	target, ok := app.getTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, convertUserToAdminResponse(target))
+}
+
+// GetUserStudyPlansHandler lists a user's study plans, read-only
+func (app *Application) GetUserStudyPlansHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	target, ok := app.getTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	plans, err := app.Queries.GetStudyPlansByUserId(r.Context(), target.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]StudyPlanResponse, len(plans))
+	for i, plan := range plans {
+		response[i] = convertStudyPlanToResponse(plan)
+	}
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+// GetUserStudyPlanTasksHandler lists the tasks of one of a user's study
+// plans, read-only
+func (app *Application) GetUserStudyPlanTasksHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	target, ok := app.getTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	planID, err := uuid.Parse(chi.URLParam(r, "planID"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	plan, err := app.Queries.GetStudyPlanByID(r.Context(), planID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Study plan not found")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+	if plan.UserID != target.ClerkID {
+		app.writeJSONError(w, r, http.StatusNotFound, "Study plan not found")
+		return
+	}
+
+	tasks, err := app.Queries.GetTasksByPlan(r.Context(), uuid.NullUUID{UUID: plan.ID, Valid: true})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]StudyTaskResponse, len(tasks))
+	for i, task := range tasks {
+		response[i] = convertStudyTaskToResponse(task)
+	}
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+// SetUserRoleHandler changes a user's role. Admins cannot change their own
+// role, so there is always an admin left to undo a mistake, and only the
+// admins in ADMIN_CLERK_IDS can demote other admins.
+func (app *Application) SetUserRoleHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	target, ok := app.getTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req SetUserRoleRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if target.ClerkID == user.ClerkID {
+		app.conflictError(w, r, errors.New("admins cannot change their own role"))
+		return
+	}
+	if app.isBootstrapAdmin(target.ClerkID) {
+		app.writeJSONError(w, r, http.StatusForbidden, "Users in ADMIN_CLERK_IDS are always admins")
+		return
+	}
+	if !app.canActOnAdmin(user, target) {
+		app.writeJSONError(w, r, http.StatusForbidden, "Only the admins in ADMIN_CLERK_IDS can change the role of another admin")
+		return
+	}
+
+	updated, err := app.Queries.SetUserRole(r.Context(), store.SetUserRoleParams{
+		ClerkID: target.ClerkID,
+		Role:    req.Role,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     target.ClerkID,
+		ActorID:    user.ClerkID,
+		Action:     activityUserRoleChanged,
+		EntityType: activityUser,
+		EntityID:   target.ClerkID,
+		Before:     map[string]any{"role": target.Role},
+		After:      map[string]any{"role": updated.Role},
+	})
+
+	app.jsonResponse(w, http.StatusOK, convertUserToAdminResponse(updated))
+}
+
+// BanUserHandler bans a user. Banned users are refused by AuthMiddleware,
+// including their access tokens. Admins are protected like for roles.
+func (app *Application) BanUserHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	app.setUserBanned(w, r, user, true)
+}
+
+// UnbanUserHandler lifts a ban
+func (app *Application) UnbanUserHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	app.setUserBanned(w, r, user, false)
+}
+
+func (app *Application) setUserBanned(w http.ResponseWriter, r *http.Request, user *UserClaims, banned bool) {
+	target, ok := app.getTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	if target.ClerkID == user.ClerkID {
+		app.conflictError(w, r, errors.New("admins cannot ban themselves"))
+		return
+	}
+	if banned && !app.canActOnAdmin(user, target) {
+		app.writeJSONError(w, r, http.StatusForbidden, "Only the admins in ADMIN_CLERK_IDS can ban another admin")
+		return
+	}
+
+	action := activityUserBanned
+	ban := app.Queries.BanUser
+	if !banned {
+		action = activityUserUnbanned
+		ban = app.Queries.UnbanUser
+	}
+	if err := ban(r.Context(), target.ClerkID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	updated, err := app.Queries.GetUserByClerkID(r.Context(), target.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     target.ClerkID,
+		ActorID:    user.ClerkID,
+		Action:     action,
+		EntityType: activityUser,
+		EntityID:   target.ClerkID,
+		Before:     map[string]any{"banned": target.Banned.Valid && target.Banned.Bool},
+		After:      map[string]any{"banned": banned},
+	})
+
+	app.jsonResponse(w, http.StatusOK, convertUserToAdminResponse(updated))
+}
+
+// StartImpersonationHandler opens an impersonation session. The admin then
+// sends its ID in the X-Impersonation-Session header along with their own
+// token to act as the user until it expires or is ended.
+func (app *Application) StartImpersonationHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	target, ok := app.getTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	var req StartImpersonationRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	// Acting as staff would hand out their permissions
+	if app.userRole(target.ClerkID, target.Role) != RoleUser {
+		app.writeJSONError(w, r, http.StatusForbidden, "Only regular users can be impersonated")
+		return
+	}
+	if target.Banned.Valid && target.Banned.Bool {
+		app.writeJSONError(w, r, http.StatusForbidden, "Banned users cannot be impersonated")
+		return
+	}
+
+	minutes := req.DurationMinutes
+	if minutes == 0 {
+		minutes = defaultImpersonationMinutes
+	}
+
+	session, err := app.Queries.CreateImpersonationSession(r.Context(), store.CreateImpersonationSessionParams{
+		AdminID:   user.ClerkID,
+		UserID:    target.ClerkID,
+		Reason:    strings.TrimSpace(req.Reason),
+		ExpiresAt: time.Now().Add(time.Duration(minutes) * time.Minute),
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// Shown in the user's own activity, so impersonation is never silent
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     target.ClerkID,
+		ActorID:    user.ClerkID,
+		Action:     activityUserImpersonated,
+		EntityType: activityUser,
+		EntityID:   target.ClerkID,
+		After:      convertImpersonationToResponse(session),
+	})
+
+	app.jsonResponse(w, http.StatusCreated, convertImpersonationToResponse(session))
+}
+
+// GetImpersonationsHandler lists impersonation sessions, newest first,
+// optionally for one user
+func (app *Application) GetImpersonationsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	limit, offset, err := parsePagination(r, 50, 200)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	sessions, err := app.Queries.ListImpersonationSessions(r.Context(), store.ListImpersonationSessionsParams{
+		UserID:    stringValueToNullString(r.URL.Query().Get("user_id")),
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]ImpersonationResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = convertImpersonationToResponse(session)
+	}
+
+	if err := app.pageResponse(w, r, response, len(response), limit, offset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// EndImpersonationHandler ends one of the admin's impersonation sessions
+func (app *Application) EndImpersonationHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	sessionID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	session, err := app.Queries.EndImpersonationSession(r.Context(), store.EndImpersonationSessionParams{
+		ID:      sessionID,
+		AdminID: user.ClerkID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Impersonation session not found or already ended")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, convertImpersonationToResponse(session))
+}
+
+// getTargetUser loads the user named by the Clerk ID in the URL
+func (app *Application) getTargetUser(w http.ResponseWriter, r *http.Request) (store.User, bool) {
+	target, err := app.Queries.GetUserByClerkID(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "User not found")
+			return store.User{}, false
+		}
+		app.internalServerError(w, r, err)
+		return store.User{}, false
+	}
+	return target, true
+}
@@ -1,32 +1,105 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"net/netip"
+	"sync"
+	"time"
 
+	"github.com/mustaphalimar/prepilot/internal/achievements"
+	"github.com/mustaphalimar/prepilot/internal/clerk"
+	"github.com/mustaphalimar/prepilot/internal/events"
+	"github.com/mustaphalimar/prepilot/internal/exports"
+	"github.com/mustaphalimar/prepilot/internal/goals"
+	"github.com/mustaphalimar/prepilot/internal/ratelimit"
 	dbsqlc "github.com/mustaphalimar/prepilot/internal/store"
+	"github.com/mustaphalimar/prepilot/internal/trash"
+	"github.com/mustaphalimar/prepilot/internal/webhooks"
 )
 
 // Config holds application configuration
 type Config struct {
-	Addr              string
-	Env               string
+	Addr               string
+	Env                string
+	DatabaseURL        string
 	ClerkWebhookSecret string
+	AdminClerkIDs      []string
+	TrashRetention     time.Duration
+	RateLimitStore     string // "memory" or "postgres"
+	Badges             []achievements.Badge
+
+	// TrustedProxies are the proxies whose X-Forwarded-For and X-Real-IP
+	// headers are believed. Requests from anywhere else are identified by
+	// their connection address.
+	TrustedProxies []netip.Prefix
+
+	// ClerkSessions verifies Clerk session tokens. Without it only personal
+	// access tokens are accepted.
+	ClerkSessions *clerk.Verifier
 }
 
 // Application holds dependencies for the application
 type Application struct {
-	Config  Config
-	DB      *sql.DB
-	Queries *dbsqlc.Queries
-	Version string
+	Config   Config
+	DB       *sql.DB
+	Queries  *dbsqlc.Queries
+	Events   *events.Broker
+	Webhooks *webhooks.Dispatcher
+	Exports  *exports.Exporter
+	Trash    *trash.Purger
+	Goals    *goals.Tracker
+	Version  string
+
+	// Achievements awards badges as users make progress
+	Achievements *achievements.Engine
+
+	// RateLimits holds the token buckets of the RateLimit middleware
+	RateLimits ratelimit.Store
+
+	// openAPIDocument is built from the routes by RegisterRoutes
+	openAPIDocument []byte
+
+	// background tracks the work requests leave running after they answer
+	background sync.WaitGroup
 }
 
 // NewApplication creates a new Application instance
 func NewApplication(config Config, db *sql.DB) *Application {
-	return &Application{
-		Config:  config,
-		DB:      db,
-		Queries: dbsqlc.New(db),
-		Version: "0.0.1",
+	version := "0.0.1"
+	app := &Application{
+		Config:   config,
+		DB:       db,
+		Queries:  dbsqlc.New(db),
+		Events:   events.NewBroker(db, config.DatabaseURL),
+		Webhooks: webhooks.NewDispatcher(db, version),
+		Exports:  exports.NewExporter(db),
+		Trash:    trash.NewPurger(db, config.TrashRetention),
+		Goals:    goals.NewTracker(db),
+		Version:  version,
+
+		Achievements: achievements.NewEngine(db, config.Badges),
+
+		RateLimits: newRateLimitStore(db, config.RateLimitStore),
+	}
+	app.Goals.OnPeriodMet = func(ctx context.Context, goal dbsqlc.Goal) {
+		app.checkAchievements(ctx, goal.UserID, achievements.EventGoalMet)
 	}
+	return app
+}
+
+// runInBackground runs fn once the request no longer needs to wait for it,
+// with a context that is not cancelled when the request ends
+func (app *Application) runInBackground(ctx context.Context, fn func(ctx context.Context)) {
+	ctx = context.WithoutCancel(ctx)
+	app.background.Add(1)
+	go func() {
+		defer app.background.Done()
+		fn(ctx)
+	}()
+}
+
+// Wait waits for the work left running by requests to finish
+func (app *Application) Wait() {
+	app.background.Wait()
 }
@@ -25,5 +25,5 @@ func (app *Application) RegisterHandler(w http.ResponseWriter, r *http.Request)
 
 	// This handler is now deprecated as user creation is handled by Clerk webhooks
 	// Return an error indicating that registration should be done through Clerk
-	app.writeJSONError(w, http.StatusBadRequest, "User registration is handled through Clerk authentication")
+	app.writeJSONError(w, r, http.StatusBadRequest, "User registration is handled through Clerk authentication")
 }
@@ -0,0 +1,756 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Roles of a user in a classroom
+const (
+	ClassroomTeacher = "teacher"
+	ClassroomStudent = "student"
+)
+
+// joinCodeAlphabet leaves out characters that are easy to misread
+const joinCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// joinCodeLength gives 40 bits per code, plenty for codes that are shared
+// by hand and can be rotated
+const joinCodeLength = 8
+
+var errAlreadyEnrolled = errors.New("already enrolled in this classroom")
+
+// CreateClassroomRequest represents the request body for creating a
+// classroom around one of the teacher's plans
+type CreateClassroomRequest struct {
+	Name           string    `json:"name" validate:"title"`
+	Description    *string   `json:"description" validate:"omitempty,notes"`
+	PlanID         uuid.UUID `json:"plan_id" validate:"required"`
+	OrganizationID *string   `json:"organization_id" validate:"omitempty,max=100"`
+}
+
+// UpdateClassroomRequest represents the request body for updating a classroom
+type UpdateClassroomRequest struct {
+	Name        string  `json:"name" validate:"title"`
+	Description *string `json:"description" validate:"omitempty,notes"`
+}
+
+// JoinClassroomRequest represents the request to join a classroom with its code
+type JoinClassroomRequest struct {
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+// ClassroomResponse represents a classroom in API responses. The join code
+// is only shown to the teacher.
+type ClassroomResponse struct {
+	ID             uuid.UUID  `json:"id"`
+	TeacherID      string     `json:"teacher_id"`
+	OrganizationID *string    `json:"organization_id"`
+	TemplatePlanID uuid.UUID  `json:"template_plan_id"`
+	Name           string     `json:"name"`
+	Description    *string    `json:"description"`
+	JoinCode       string     `json:"join_code,omitempty"`
+	Role           string     `json:"role"`
+	StudentCount   *int64     `json:"student_count,omitempty"`
+	PublishedAt    *time.Time `json:"published_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func convertClassroomToResponse(classroom store.Classroom, role string) ClassroomResponse {
+	response := ClassroomResponse{
+		ID:             classroom.ID,
+		TeacherID:      classroom.TeacherID,
+		OrganizationID: nullStringToPointer(classroom.OrganizationID),
+		TemplatePlanID: classroom.TemplatePlanID,
+		Name:           classroom.Name,
+		Description:    nullStringToPointer(classroom.Description),
+		Role:           role,
+		PublishedAt:    nullTimeToPointer(classroom.PublishedAt),
+		CreatedAt:      classroom.CreatedAt,
+		UpdatedAt:      classroom.UpdatedAt,
+	}
+	if role == ClassroomTeacher {
+		response.JoinCode = classroom.JoinCode
+	}
+	return response
+}
+
+// ClassroomStudentResponse represents an enrolled student in API responses
+type ClassroomStudentResponse struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName *string   `json:"first_name"`
+	LastName  *string   `json:"last_name"`
+	ImageURL  *string   `json:"image_url"`
+	JoinedAt  time.Time `json:"joined_at"`
+}
+
+// PublishClassroomResponse counts what a publish changed in students' plans
+type PublishClassroomResponse struct {
+	PlansCreated int64     `json:"plans_created"`
+	PlansUpdated int64     `json:"plans_updated"`
+	TasksCreated int64     `json:"tasks_created"`
+	TasksUpdated int64     `json:"tasks_updated"`
+	PublishedAt  time.Time `json:"published_at"`
+}
+
+// StudentProgressResponse is one student's progress on their copy of the plan
+type StudentProgressResponse struct {
+	UserID         string     `json:"user_id"`
+	Email          string     `json:"email"`
+	FirstName      *string    `json:"first_name"`
+	LastName       *string    `json:"last_name"`
+	PlanID         *uuid.UUID `json:"plan_id"`
+	TotalTasks     int64      `json:"total_tasks"`
+	CompletedTasks int64      `json:"completed_tasks"`
+	OverdueTasks   int64      `json:"overdue_tasks"`
+	CompletionRate float64    `json:"completion_rate"`
+	LastActiveOn   *string    `json:"last_active_on"`
+}
+
+// ClassroomProgressResponse is the teacher's dashboard across students
+type ClassroomProgressResponse struct {
+	StudentCount          int                       `json:"student_count"`
+	AverageCompletionRate float64                   `json:"average_completion_rate"`
+	StudentsWithOverdue   int                       `json:"students_with_overdue"`
+	Students              []StudentProgressResponse `json:"students"`
+}
+
+// newJoinCode generates a random classroom code
+func newJoinCode() (string, error) {
+	buf := make([]byte, joinCodeLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	code := make([]byte, joinCodeLength)
+	for i, b := range buf {
+		code[i] = joinCodeAlphabet[int(b)%len(joinCodeAlphabet)]
+	}
+	return string(code), nil
+}
+
+// normalizeJoinCode accepts codes typed in lower case or with separators
+func normalizeJoinCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// getClassroom loads a classroom and the user's role in it. Anyone other
+// than the teacher and enrolled students is denied. On failure an error
+// response has been written and ok is false.
+func (app *Application) getClassroom(w http.ResponseWriter, r *http.Request, user *UserClaims) (classroom store.Classroom, role string, ok bool) {
+	classroomID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return classroom, "", false
+	}
+
+	classroom, err = app.Queries.GetClassroom(r.Context(), classroomID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Classroom not found")
+			return classroom, "", false
+		}
+		app.internalServerError(w, r, err)
+		return classroom, "", false
+	}
+
+	if classroom.TeacherID == user.ClerkID {
+		return classroom, ClassroomTeacher, true
+	}
+
+	enrolled, err := app.Queries.IsClassroomStudent(r.Context(), store.IsClassroomStudentParams{
+		ClassroomID: classroom.ID,
+		UserID:      user.ClerkID,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return classroom, "", false
+	}
+	if !enrolled {
+		app.writeJSONError(w, r, http.StatusForbidden, "Access denied")
+		return classroom, "", false
+	}
+	return classroom, ClassroomStudent, true
+}
+
+// getClassroomAsTeacher is getClassroom for actions only the teacher can take
+func (app *Application) getClassroomAsTeacher(w http.ResponseWriter, r *http.Request, user *UserClaims) (store.Classroom, bool) {
+	classroom, role, ok := app.getClassroom(w, r, user)
+	if !ok {
+		return classroom, false
+	}
+	if role != ClassroomTeacher {
+		app.writeJSONError(w, r, http.StatusForbidden, "Only the teacher can do this")
+		return classroom, false
+	}
+	return classroom, true
+}
+
+// CreateClassroomHandler creates a classroom whose template is one of the
+// teacher's plans
+func (app *Application) CreateClassroomHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req CreateClassroomRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, _, ok := app.getPlanForMember(w, r, user, req.PlanID, PlanRoleOwner); !ok {
+		return
+	}
+
+	if req.OrganizationID != nil {
+		member, err := app.Queries.IsOrganizationMember(r.Context(), store.IsOrganizationMemberParams{
+			OrganizationClerkID: *req.OrganizationID,
+			UserClerkID:         user.ClerkID,
+		})
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		if !member {
+			app.writeJSONError(w, r, http.StatusForbidden, "You are not a member of this organization")
+			return
+		}
+	}
+
+	code, err := newJoinCode()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	classroom, err := app.Queries.CreateClassroom(r.Context(), store.CreateClassroomParams{
+		TeacherID:      user.ClerkID,
+		OrganizationID: stringToNullString(req.OrganizationID),
+		TemplatePlanID: req.PlanID,
+		Name:           strings.TrimSpace(req.Name),
+		Description:    stringToNullString(req.Description),
+		JoinCode:       code,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertClassroomToResponse(classroom, ClassroomTeacher)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityClassroomCreated,
+		EntityType: activityClassroom,
+		EntityID:   classroom.ID.String(),
+		After:      response,
+	})
+
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// GetClassroomsHandler lists the classrooms the user teaches or attends
+func (app *Application) GetClassroomsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classrooms, err := app.Queries.ListClassroomsForUser(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]ClassroomResponse, len(classrooms))
+	for i, row := range classrooms {
+		role := ClassroomStudent
+		if row.TeacherID == user.ClerkID {
+			role = ClassroomTeacher
+		}
+		response[i] = convertClassroomToResponse(store.Classroom{
+			ID:             row.ID,
+			TeacherID:      row.TeacherID,
+			OrganizationID: row.OrganizationID,
+			TemplatePlanID: row.TemplatePlanID,
+			Name:           row.Name,
+			Description:    row.Description,
+			JoinCode:       row.JoinCode,
+			PublishedAt:    row.PublishedAt,
+			CreatedAt:      row.CreatedAt,
+			UpdatedAt:      row.UpdatedAt,
+		}, role)
+		if role == ClassroomTeacher {
+			count := row.StudentCount
+			response[i].StudentCount = &count
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetClassroomHandler retrieves a classroom
+func (app *Application) GetClassroomHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classroom, role, ok := app.getClassroom(w, r, user)
+	if !ok {
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, convertClassroomToResponse(classroom, role))
+}
+
+// UpdateClassroomHandler renames a classroom or changes its description
+func (app *Application) UpdateClassroomHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req UpdateClassroomRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	previous, ok := app.getClassroomAsTeacher(w, r, user)
+	if !ok {
+		return
+	}
+
+	classroom, err := app.Queries.UpdateClassroom(r.Context(), store.UpdateClassroomParams{
+		ID:          previous.ID,
+		Name:        strings.TrimSpace(req.Name),
+		Description: stringToNullString(req.Description),
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertClassroomToResponse(classroom, ClassroomTeacher)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityClassroomUpdated,
+		EntityType: activityClassroom,
+		EntityID:   classroom.ID.String(),
+		Before:     convertClassroomToResponse(previous, ClassroomTeacher),
+		After:      response,
+	})
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+// DeleteClassroomHandler deletes a classroom. Students keep their copies of
+// the plan, which stop receiving updates.
+func (app *Application) DeleteClassroomHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classroom, ok := app.getClassroomAsTeacher(w, r, user)
+	if !ok {
+		return
+	}
+
+	if _, err := app.Queries.DeleteClassroom(r.Context(), classroom.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityClassroomDeleted,
+		EntityType: activityClassroom,
+		EntityID:   classroom.ID.String(),
+		Before:     convertClassroomToResponse(classroom, ClassroomTeacher),
+	})
+
+	app.respondDeleted(w, r, "Classroom deleted")
+}
+
+// RotateClassroomJoinCodeHandler replaces the join code, so the old one
+// stops working. Enrolled students are not affected.
+func (app *Application) RotateClassroomJoinCodeHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classroom, ok := app.getClassroomAsTeacher(w, r, user)
+	if !ok {
+		return
+	}
+
+	code, err := newJoinCode()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	classroom, err = app.Queries.RotateClassroomJoinCode(r.Context(), store.RotateClassroomJoinCodeParams{
+		ID:       classroom.ID,
+		JoinCode: code,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, convertClassroomToResponse(classroom, ClassroomTeacher))
+}
+
+// JoinClassroomHandler enrolls the user with a join code
+func (app *Application) JoinClassroomHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req JoinClassroomRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	classroom, err := app.Queries.GetClassroomByJoinCode(r.Context(), normalizeJoinCode(req.Code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "No classroom has this code")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.joinClassroom(w, r, user, classroom)
+}
+
+// JoinOrganizationClassroomHandler enrolls the user without a code in a
+// classroom tied to a Clerk organization they belong to
+func (app *Application) JoinOrganizationClassroomHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classroomID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	classroom, err := app.Queries.GetClassroom(r.Context(), classroomID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Classroom not found")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	member := false
+	if classroom.OrganizationID.Valid {
+		member, err = app.Queries.IsOrganizationMember(r.Context(), store.IsOrganizationMemberParams{
+			OrganizationClerkID: classroom.OrganizationID.String,
+			UserClerkID:         user.ClerkID,
+		})
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+	if !member {
+		app.writeJSONError(w, r, http.StatusForbidden, "Joining this classroom requires its code")
+		return
+	}
+
+	app.joinClassroom(w, r, user, classroom)
+}
+
+// joinClassroom enrolls the user and responds with the classroom. Once the
+// template has been published the student gets their copy straight away.
+func (app *Application) joinClassroom(w http.ResponseWriter, r *http.Request, user *UserClaims, classroom store.Classroom) {
+	if classroom.TeacherID == user.ClerkID {
+		app.writeJSONError(w, r, http.StatusConflict, "Teachers cannot join their own classroom")
+		return
+	}
+
+	if err := app.enrollStudent(r.Context(), classroom, user.ClerkID); err != nil {
+		if errors.Is(err, errAlreadyEnrolled) {
+			app.writeJSONError(w, r, http.StatusConflict, "Already enrolled in this classroom")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityClassroomJoined,
+		EntityType: activityClassroom,
+		EntityID:   classroom.ID.String(),
+	})
+
+	app.jsonResponse(w, http.StatusOK, convertClassroomToResponse(classroom, ClassroomStudent))
+}
+
+// enrollStudent adds the student and, for published classrooms, copies the
+// template into their account in one transaction
+func (app *Application) enrollStudent(ctx context.Context, classroom store.Classroom, userID string) error {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	added, err := queries.AddClassroomStudent(ctx, store.AddClassroomStudentParams{
+		ClassroomID: classroom.ID,
+		UserID:      userID,
+	})
+	if err != nil {
+		return err
+	}
+	if added == 0 {
+		return errAlreadyEnrolled
+	}
+
+	if classroom.PublishedAt.Valid {
+		student := sql.NullString{String: userID, Valid: true}
+		if _, err := queries.CopyClassroomPlans(ctx, store.CopyClassroomPlansParams{ClassroomID: classroom.ID, UserID: student}); err != nil {
+			return err
+		}
+		if _, err := queries.CopyClassroomTasks(ctx, store.CopyClassroomTasksParams{ClassroomID: classroom.ID, UserID: student}); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit enrollment: %w", err)
+	}
+	return nil
+}
+
+// PublishClassroomHandler sends the template to every student. Students
+// without a copy get one. Existing copies get new tasks, and changes to the
+// plan and its tasks wherever the student has not edited them; nothing the
+// student deleted comes back and their progress is kept.
+func (app *Application) PublishClassroomHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classroom, ok := app.getClassroomAsTeacher(w, r, user)
+	if !ok {
+		return
+	}
+
+	response, err := app.publishClassroom(r.Context(), classroom.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityClassroomPublished,
+		EntityType: activityClassroom,
+		EntityID:   classroom.ID.String(),
+		After:      response,
+	})
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+func (app *Application) publishClassroom(ctx context.Context, classroomID uuid.UUID) (PublishClassroomResponse, error) {
+	var response PublishClassroomResponse
+
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return response, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	if response.PlansCreated, err = queries.CopyClassroomPlans(ctx, store.CopyClassroomPlansParams{ClassroomID: classroomID}); err != nil {
+		return response, err
+	}
+	if response.PlansUpdated, err = queries.SyncClassroomPlans(ctx, classroomID); err != nil {
+		return response, err
+	}
+	if response.TasksUpdated, err = queries.SyncClassroomTasks(ctx, classroomID); err != nil {
+		return response, err
+	}
+	if response.TasksCreated, err = queries.CopyClassroomTasks(ctx, store.CopyClassroomTasksParams{ClassroomID: classroomID}); err != nil {
+		return response, err
+	}
+
+	classroom, err := queries.MarkClassroomPublished(ctx, classroomID)
+	if err != nil {
+		return response, err
+	}
+	response.PublishedAt = classroom.PublishedAt.Time
+
+	if err := tx.Commit(); err != nil {
+		return response, fmt.Errorf("failed to commit publish: %w", err)
+	}
+	return response, nil
+}
+
+// GetClassroomStudentsHandler lists the students of a classroom
+func (app *Application) GetClassroomStudentsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classroom, ok := app.getClassroomAsTeacher(w, r, user)
+	if !ok {
+		return
+	}
+
+	students, err := app.Queries.ListClassroomStudents(r.Context(), classroom.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]ClassroomStudentResponse, len(students))
+	for i, student := range students {
+		response[i] = ClassroomStudentResponse{
+			UserID:    student.UserID,
+			Email:     student.Email,
+			FirstName: nullStringToPointer(student.FirstName),
+			LastName:  nullStringToPointer(student.LastName),
+			ImageURL:  nullStringToPointer(student.ImageUrl),
+			JoinedAt:  student.JoinedAt,
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RemoveClassroomStudentHandler removes a student, or lets a student leave.
+// The student keeps their copy of the plan, which stops receiving updates.
+func (app *Application) RemoveClassroomStudentHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classroom, role, ok := app.getClassroom(w, r, user)
+	if !ok {
+		return
+	}
+
+	studentID := chi.URLParam(r, "userID")
+	if role != ClassroomTeacher && studentID != user.ClerkID {
+		app.writeJSONError(w, r, http.StatusForbidden, "Only the teacher can do this")
+		return
+	}
+
+	removed, err := app.removeStudent(r.Context(), classroom.ID, studentID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if !removed {
+		app.writeJSONError(w, r, http.StatusNotFound, "Student not found")
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityClassroomStudentRemoved,
+		EntityType: activityClassroom,
+		EntityID:   classroom.ID.String(),
+		Before:     map[string]string{"user_id": studentID},
+	})
+
+	app.respondDeleted(w, r, "Student removed")
+}
+
+func (app *Application) removeStudent(ctx context.Context, classroomID uuid.UUID, userID string) (bool, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	removed, err := queries.RemoveClassroomStudent(ctx, store.RemoveClassroomStudentParams{
+		ClassroomID: classroomID,
+		UserID:      userID,
+	})
+	if err != nil || removed == 0 {
+		return false, err
+	}
+
+	if err := queries.UnlinkClassroomPlan(ctx, store.UnlinkClassroomPlanParams{
+		ClassroomID: classroomID,
+		UserID:      userID,
+	}); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit removal: %w", err)
+	}
+	return true, nil
+}
+
+// GetClassroomProgressHandler is the teacher's dashboard: each student's
+// progress on their copy of the plan, with class-wide figures
+func (app *Application) GetClassroomProgressHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	classroom, ok := app.getClassroomAsTeacher(w, r, user)
+	if !ok {
+		return
+	}
+
+	rows, err := app.Queries.GetClassroomProgress(r.Context(), classroom.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := ClassroomProgressResponse{
+		StudentCount: len(rows),
+		Students:     make([]StudentProgressResponse, len(rows)),
+	}
+
+	var totalRate float64
+	for i, row := range rows {
+		student := StudentProgressResponse{
+			UserID:         row.UserID,
+			Email:          row.Email,
+			FirstName:      nullStringToPointer(row.FirstName),
+			LastName:       nullStringToPointer(row.LastName),
+			TotalTasks:     row.TotalTasks,
+			CompletedTasks: row.CompletedTasks,
+			OverdueTasks:   row.OverdueTasks,
+		}
+		if row.PlanID.Valid {
+			planID := row.PlanID.UUID
+			student.PlanID = &planID
+		}
+		if row.TotalTasks > 0 {
+			student.CompletionRate = float64(row.CompletedTasks) / float64(row.TotalTasks)
+		}
+		if row.LastActiveOn.Valid {
+			day := row.LastActiveOn.Time.Format(time.DateOnly)
+			student.LastActiveOn = &day
+		}
+		if row.OverdueTasks > 0 {
+			response.StudentsWithOverdue++
+		}
+
+		totalRate += student.CompletionRate
+		response.Students[i] = student
+	}
+
+	if len(rows) > 0 {
+		response.AverageCompletionRate = totalRate / float64(len(rows))
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
@@ -1,8 +1,19 @@
 package app
 
 import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-playground/validator/v10"
 )
 
 const (
@@ -10,26 +21,325 @@ const (
 	ColorReset = "\033[0m"
 )
 
+// problemTypePrefix namespaces the type URI of every problem. The part
+// after it is the same as Problem.Code.
+const problemTypePrefix = "urn:prepilot:problem:"
+
+// Problem codes that are not derived from the status code
+const (
+	problemValidationFailed = "validation_failed"
+	problemInvalidJSON      = "invalid_json"
+	problemBodyTooLarge     = "body_too_large"
+	problemInvalidParameter = "invalid_parameter"
+)
+
+// Problem is an RFC 7807 problem details response
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Code      string       `json:"code"`
+	Instance  string       `json:"instance,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is a validation failure for one field of the request body,
+// named by its JSON path so forms can show it next to the input
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FieldError is also returned as an error by hand-written validation
+func (fe *FieldError) Error() string {
+	return fe.Field + " " + fe.Message
+}
+
+// paramError is an invalid path or query parameter. Unlike other errors,
+// its message is shown to the client.
+type paramError struct {
+	Name  string
+	Value string
+}
+
+func (pe *paramError) Error() string {
+	return fmt.Sprintf("invalid %s: %q", pe.Name, pe.Value)
+}
+
+// invalidParam reports that the parameter has a value that is not accepted
+func invalidParam(name, value string) error {
+	return &paramError{Name: name, Value: value}
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// statusCode is the default problem code for a status, e.g. "not_found"
+func statusCode(status int) string {
+	if status == http.StatusInternalServerError {
+		return "internal_error"
+	}
+	text := strings.ToLower(http.StatusText(status))
+	return strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+}
+
+// writeProblem fills in the fields every problem shares and writes it
+func (app *Application) writeProblem(w http.ResponseWriter, r *http.Request, problem Problem) error {
+	if problem.Code == "" {
+		problem.Code = statusCode(problem.Status)
+	}
+	problem.Type = problemTypePrefix + problem.Code
+	problem.Title = http.StatusText(problem.Status)
+	problem.Instance = r.URL.Path
+	problem.RequestID = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	return json.NewEncoder(w).Encode(problem)
+}
+
+// writeJSONError writes a problem with the default code for its status
+func (app *Application) writeJSONError(w http.ResponseWriter, r *http.Request, status int, detail string) error {
+	return app.writeProblem(w, r, Problem{Status: status, Detail: detail})
+}
+
 func (app *Application) internalServerError(w http.ResponseWriter, r *http.Request, err error) {
 	log.Printf("%sINTERNAL_SERVER_ERROR_OCCURED: %s path: %s error: %s%s",
 		ColorRed, r.Method, r.URL.Path, err.Error(), ColorReset)
-	app.writeJSONError(w, http.StatusInternalServerError, "The server encountered a problem while procession your request.")
+	app.writeJSONError(w, r, http.StatusInternalServerError, "The server encountered a problem while procession your request.")
 }
 
 func (app *Application) conflictError(w http.ResponseWriter, r *http.Request, err error) {
 	log.Printf("%sCONFLICT_ERROR_OCCURED: %s path: %s error: %s%s",
 		ColorRed, r.Method, r.URL.Path, err.Error(), ColorReset)
-	app.writeJSONError(w, http.StatusConflict, err.Error())
+	app.writeJSONError(w, r, http.StatusConflict, err.Error())
 }
 
+// badRequestError turns decoding and validation errors into problems that
+// name the offending fields instead of passing Go's messages through
 func (app *Application) badRequestError(w http.ResponseWriter, r *http.Request, err error) {
 	log.Printf("%sBAD_REQUEST_ERROR: %s path: %s error: %s%s",
 		ColorRed, r.Method, r.URL.Path, err.Error(), ColorReset)
-	app.writeJSONError(w, http.StatusBadRequest, err.Error())
+	app.writeProblem(w, r, requestProblem(err))
+}
+
+// routeNotFoundHandler answers requests that match no route
+func (app *Application) routeNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeJSONError(w, r, http.StatusNotFound, "No route matches "+r.URL.Path+".")
+}
+
+// methodNotAllowedHandler answers requests whose route has no handler for the method
+func (app *Application) methodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	app.writeJSONError(w, r, http.StatusMethodNotAllowed, r.Method+" is not supported for "+r.URL.Path+".")
 }
 
 func (app *Application) notFoundError(w http.ResponseWriter, r *http.Request, err error) {
 	log.Printf("%sNOT_FOUND_ERROR: %s path: %s error: %s%s",
 		ColorRed, r.Method, r.URL.Path, err.Error(), ColorReset)
-	app.writeJSONError(w, http.StatusNotFound, "Resource not found.")
+	app.writeJSONError(w, r, http.StatusNotFound, "Resource not found.")
+}
+
+// requestProblem describes an error caused by the request itself
+func requestProblem(err error) Problem {
+	var validationErrors validator.ValidationErrors
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var timeError *time.ParseError
+	var maxBytesError *http.MaxBytesError
+	var fieldError *FieldError
+	var paramErr *paramError
+
+	switch {
+	case errors.As(err, &validationErrors):
+		fields := make([]FieldError, len(validationErrors))
+		for i, fe := range validationErrors {
+			fields[i] = FieldError{
+				Field:   fieldPath(fe),
+				Code:    fe.ActualTag(),
+				Message: validationMessage(fe),
+			}
+		}
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemValidationFailed,
+			Detail: "The request body has invalid fields.",
+			Errors: fields,
+		}
+
+	case errors.As(err, &fieldError):
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemValidationFailed,
+			Detail: "The request body has invalid fields.",
+			Errors: []FieldError{*fieldError},
+		}
+
+	case errors.As(err, &syntaxError):
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemInvalidJSON,
+			Detail: fmt.Sprintf("The request body is not valid JSON (at byte %d).", syntaxError.Offset),
+		}
+
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemInvalidJSON,
+			Detail: "The request body is not valid JSON.",
+		}
+
+	case errors.Is(err, io.EOF):
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemInvalidJSON,
+			Detail: "The request body must not be empty.",
+		}
+
+	case errors.As(err, &typeError):
+		field := typeError.Field
+		if field == "" {
+			return Problem{
+				Status: http.StatusBadRequest,
+				Code:   problemInvalidJSON,
+				Detail: fmt.Sprintf("The request body must be a JSON object, not %s.", typeError.Value),
+			}
+		}
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemValidationFailed,
+			Detail: "The request body has invalid fields.",
+			Errors: []FieldError{{
+				Field:   field,
+				Code:    "type",
+				Message: "must be " + jsonTypeName(typeError.Type),
+			}},
+		}
+
+	case errors.As(err, &timeError):
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemValidationFailed,
+			Detail: fmt.Sprintf("Dates and times must be RFC 3339, e.g. 2006-01-02T15:04:05Z; got %s.", timeError.Value),
+		}
+
+	case errors.As(err, &maxBytesError):
+		return Problem{
+			Status: http.StatusRequestEntityTooLarge,
+			Code:   problemBodyTooLarge,
+			Detail: fmt.Sprintf("The request body must not be larger than %d bytes.", maxBytesError.Limit),
+		}
+
+	case errors.As(err, &paramErr):
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemInvalidParameter,
+			Detail: fmt.Sprintf("The %s parameter has an invalid value: %q.", paramErr.Name, paramErr.Value),
+		}
+
+	// Errors of uuid.Parse have no type to match
+	case strings.HasPrefix(err.Error(), "invalid UUID"):
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemInvalidParameter,
+			Detail: "An ID in the request is not a valid UUID.",
+		}
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return Problem{
+			Status: http.StatusBadRequest,
+			Code:   problemValidationFailed,
+			Detail: "The request body has invalid fields.",
+			Errors: []FieldError{{Field: field, Code: "unknown", Message: "is not a known field"}},
+		}
+	}
+
+	// Anything else may carry internal details; badRequestError has logged it
+	return Problem{Status: http.StatusBadRequest, Detail: "The request is invalid."}
+}
+
+// fieldPath is the JSON path of a field, without the request struct name
+func fieldPath(fe validator.FieldError) string {
+	namespace := fe.Namespace()
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+// validationMessage is a readable message for a failed validation tag
+func validationMessage(fe validator.FieldError) string {
+	switch fe.ActualTag() {
+	case "required":
+		return "is required"
+	case "notblank":
+		return "must not be blank"
+	case "timezone":
+		return "must be an IANA time zone such as Europe/Paris"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "oneof":
+		return "must be one of: " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "min", "gte":
+		if isLengthKind(fe) {
+			return fmt.Sprintf("must be at least %s characters long", fe.Param())
+		}
+		return "must be at least " + fe.Param()
+	case "max", "lte":
+		if isLengthKind(fe) {
+			return fmt.Sprintf("must be at most %s characters long", fe.Param())
+		}
+		return "must be at most " + fe.Param()
+	case "len":
+		return fmt.Sprintf("must be exactly %s characters long", fe.Param())
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "gtefield":
+		return "must not be before " + jsonFieldName(fe.Param())
+	case "gtfield":
+		return "must be after " + jsonFieldName(fe.Param())
+	case "ltefield":
+		return "must not be after " + jsonFieldName(fe.Param())
+	case "ltfield":
+		return "must be before " + jsonFieldName(fe.Param())
+	}
+	return "is invalid"
+}
+
+// isLengthKind reports whether min/max limits apply to a length
+func isLengthKind(fe validator.FieldError) bool {
+	switch fe.Kind().String() {
+	case "string", "slice", "map", "array":
+		return true
+	}
+	return false
+}
+
+// jsonTypeName is the kind of JSON value a client should send for a type
+func jsonTypeName(t reflect.Type) string {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return "a string"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	}
+	return "an object"
 }
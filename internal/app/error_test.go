@@ -0,0 +1,126 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestRequestProblem(t *testing.T) {
+	_, uuidErr := uuid.Parse("not-a-uuid")
+	_, timeErr := time.Parse(time.RFC3339, "tomorrow")
+	var typeErr error = json.Unmarshal([]byte(`{"priority": "high"}`), &struct {
+		Priority int `json:"priority"`
+	}{})
+	var arrayErr error = json.Unmarshal([]byte(`[]`), &struct{}{})
+	var syntaxErr error = json.Unmarshal([]byte(`{"a" 1}`), &struct{}{})
+
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+		wantFields []string
+	}{
+		{name: "field error", err: &FieldError{Field: "subject_id", Code: "exists", Message: "does not exist"}, wantStatus: http.StatusBadRequest, wantCode: problemValidationFailed, wantFields: []string{"subject_id"}},
+		{name: "wrapped field error", err: fmt.Errorf("subject: %w", &FieldError{Field: "subject"}), wantStatus: http.StatusBadRequest, wantCode: problemValidationFailed, wantFields: []string{"subject"}},
+		{name: "syntax", err: syntaxErr, wantStatus: http.StatusBadRequest, wantCode: problemInvalidJSON},
+		{name: "truncated", err: io.ErrUnexpectedEOF, wantStatus: http.StatusBadRequest, wantCode: problemInvalidJSON},
+		{name: "empty body", err: io.EOF, wantStatus: http.StatusBadRequest, wantCode: problemInvalidJSON},
+		{name: "field type", err: typeErr, wantStatus: http.StatusBadRequest, wantCode: problemValidationFailed, wantFields: []string{"priority"}},
+		{name: "body type", err: arrayErr, wantStatus: http.StatusBadRequest, wantCode: problemInvalidJSON},
+		{name: "time", err: timeErr, wantStatus: http.StatusBadRequest, wantCode: problemValidationFailed},
+		{name: "too large", err: &http.MaxBytesError{Limit: 10}, wantStatus: http.StatusRequestEntityTooLarge, wantCode: problemBodyTooLarge},
+		{name: "parameter", err: invalidParam("limit", "-1"), wantStatus: http.StatusBadRequest, wantCode: problemInvalidParameter},
+		{name: "uuid", err: uuidErr, wantStatus: http.StatusBadRequest, wantCode: problemInvalidParameter},
+		{name: "unknown field", err: errors.New(`json: unknown field "titel"`), wantStatus: http.StatusBadRequest, wantCode: problemValidationFailed, wantFields: []string{"titel"}},
+		{name: "other", err: errors.New("pq: connection refused"), wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requestProblem(tt.err)
+			if got.Status != tt.wantStatus || got.Code != tt.wantCode {
+				t.Errorf("requestProblem = %d %q, want %d %q", got.Status, got.Code, tt.wantStatus, tt.wantCode)
+			}
+			if len(got.Errors) != len(tt.wantFields) {
+				t.Fatalf("errors = %+v, want fields %v", got.Errors, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if got.Errors[i].Field != field {
+					t.Errorf("errors[%d].field = %q, want %q", i, got.Errors[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestValidationMessage(t *testing.T) {
+	type item struct {
+		Title string `json:"title" validate:"title"`
+	}
+	type request struct {
+		Title     string    `json:"title" validate:"title"`
+		Priority  int       `json:"priority" validate:"priority"`
+		Kind      string    `json:"kind" validate:"omitempty,oneof=exam quiz"`
+		StartDate time.Time `json:"start_date"`
+		EndDate   time.Time `json:"end_date" validate:"gtefield=StartDate"`
+		Items     []item    `json:"items" validate:"dive"`
+	}
+	valid := request{Title: "Algebra", EndDate: time.Now()}
+
+	tests := []struct {
+		name        string
+		edit        func(*request)
+		wantField   string
+		wantCode    string
+		wantMessage string
+	}{
+		{name: "blank", edit: func(r *request) { r.Title = "  " }, wantField: "title", wantCode: "notblank", wantMessage: "must not be blank"},
+		{name: "too long", edit: func(r *request) { r.Title = string(make([]byte, maxTitleLength+1)) }, wantField: "title", wantCode: "max", wantMessage: "must be at most 200 characters long"},
+		{name: "number", edit: func(r *request) { r.Priority = 3 }, wantField: "priority", wantCode: "max", wantMessage: "must be at most 2"},
+		{name: "oneof", edit: func(r *request) { r.Kind = "essay" }, wantField: "kind", wantCode: "oneof", wantMessage: "must be one of: exam, quiz"},
+		{name: "cross field", edit: func(r *request) { r.StartDate = r.EndDate.Add(time.Hour) }, wantField: "end_date", wantCode: "gtefield", wantMessage: "must not be before start_date"},
+		{name: "nested", edit: func(r *request) { r.Items = []item{{Title: "a"}, {}} }, wantField: "items[1].title", wantCode: "notblank", wantMessage: "must not be blank"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := valid
+			tt.edit(&req)
+
+			got := requestProblem(Validate.Struct(req))
+			if got.Code != problemValidationFailed || len(got.Errors) != 1 {
+				t.Fatalf("requestProblem = %+v, want one field error", got)
+			}
+			fe := got.Errors[0]
+			if fe.Field != tt.wantField || fe.Code != tt.wantCode || fe.Message != tt.wantMessage {
+				t.Errorf("field error = %+v, want %s %s %q", fe, tt.wantField, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: http.StatusNotFound, want: "not_found"},
+		{status: http.StatusInternalServerError, want: "internal_error"},
+		{status: http.StatusPreconditionFailed, want: "precondition_failed"},
+		{status: http.StatusRequestEntityTooLarge, want: "request_entity_too_large"},
+		{status: http.StatusTeapot, want: "im_a_teapot"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := statusCode(tt.status); got != tt.want {
+				t.Errorf("statusCode(%d) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// versionETag is the strong ETag for a row at a given version
+func versionETag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// planETag is the strong ETag of a plan as a member sees it. The body
+// carries the member's role, so a role change must change the tag too;
+// If-Match only compares the version part.
+func planETag(version int32, role string) string {
+	return fmt.Sprintf(`"%d-%s"`, version, role)
+}
+
+// listETag is a weak ETag for a collection, derived from the ID and version
+// of every row in it and the caller's role on it
+func listETag(ids []uuid.UUID, versions []int32, roles []string) string {
+	h := sha256.New()
+	for i, id := range ids {
+		h.Write(id[:])
+		binary.Write(h, binary.BigEndian, versions[i])
+		h.Write([]byte(roles[i]))
+		h.Write([]byte{0})
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// studyTasksETag is the list ETag for a set of tasks. Completion is part of
+// it because plan members' own progress does not change task versions.
+func studyTasksETag(tasks []store.StudyTask) string {
+	h := sha256.New()
+	for _, task := range tasks {
+		h.Write(task.ID[:])
+		binary.Write(h, binary.BigEndian, task.Version)
+		binary.Write(h, binary.BigEndian, task.IsCompleted.Bool)
+	}
+	return `W/"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag header and reports whether the client's
+// If-None-Match already covers it, in which case a 304 has been written
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	// If-None-Match uses weak comparison
+	for _, candidate := range splitETags(header) {
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch compares the If-Match header with the current version of a
+// row, given as a version or plan ETag. It returns the version the write must still find, so a change made
+// between the read and the write is also caught. On mismatch a 412 has
+// been written and ok is false.
+func (app *Application) checkIfMatch(w http.ResponseWriter, r *http.Request, version int32) (expected sql.NullInt32, ok bool) {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return sql.NullInt32{}, true
+	}
+
+	// If-Match uses strong comparison, so weak tags never match
+	etag := versionETag(version)
+	planPrefix := strings.TrimSuffix(etag, `"`) + "-"
+	for _, candidate := range splitETags(header) {
+		if candidate == "*" {
+			// Only requires the row to exist, which the caller has checked
+			return sql.NullInt32{}, true
+		}
+		if candidate == etag || strings.HasPrefix(candidate, planPrefix) {
+			return sql.NullInt32{Int32: version, Valid: true}, true
+		}
+	}
+
+	app.preconditionFailed(w, r)
+	return sql.NullInt32{}, false
+}
+
+func (app *Application) preconditionFailed(w http.ResponseWriter, r *http.Request) {
+	app.writeJSONError(w, r, http.StatusPreconditionFailed, "Resource has been modified; fetch it again and retry")
+}
+
+func splitETags(header string) []string {
+	parts := strings.Split(header, ",")
+	tags := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if tag := strings.TrimSpace(part); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,119 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		etag        string
+		want        bool
+	}{
+		{name: "no header", etag: `"3"`},
+		{name: "same tag", ifNoneMatch: `"3"`, etag: `"3"`, want: true},
+		{name: "other tag", ifNoneMatch: `"2"`, etag: `"3"`},
+		{name: "one of several", ifNoneMatch: `"1", "3" ,"4"`, etag: `"3"`, want: true},
+		{name: "any", ifNoneMatch: `*`, etag: `"3"`, want: true},
+		{name: "weak against strong", ifNoneMatch: `W/"3"`, etag: `"3"`, want: true},
+		{name: "strong against weak", ifNoneMatch: `"abc"`, etag: `W/"abc"`, want: true},
+		{name: "role changed", ifNoneMatch: planETag(3, PlanRoleEditor), etag: planETag(3, PlanRoleViewer)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.ifNoneMatch != "" {
+				r.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			w := httptest.NewRecorder()
+
+			if got := notModified(w, r, tt.etag); got != tt.want {
+				t.Fatalf("notModified = %v, want %v", got, tt.want)
+			}
+			if got := w.Header().Get("ETag"); got != tt.etag {
+				t.Errorf("ETag = %s, want %s", got, tt.etag)
+			}
+			if tt.want && w.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusNotModified)
+			}
+		})
+	}
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	app := &Application{Config: Config{Env: "development"}}
+
+	tests := []struct {
+		name     string
+		ifMatch  string
+		want     sql.NullInt32
+		wantPass bool
+	}{
+		{name: "no header", wantPass: true},
+		{name: "any", ifMatch: "*", wantPass: true},
+		{name: "version tag", ifMatch: `"7"`, want: sql.NullInt32{Int32: 7, Valid: true}, wantPass: true},
+		{name: "plan tag", ifMatch: planETag(7, PlanRoleEditor), want: sql.NullInt32{Int32: 7, Valid: true}, wantPass: true},
+		{name: "one of several", ifMatch: `"6", "7"`, want: sql.NullInt32{Int32: 7, Valid: true}, wantPass: true},
+		{name: "stale version", ifMatch: `"6"`},
+		{name: "stale plan tag", ifMatch: planETag(6, PlanRoleOwner)},
+		{name: "longer version", ifMatch: `"70"`},
+		{name: "weak tag", ifMatch: `W/"7"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPut, "/", nil)
+			if tt.ifMatch != "" {
+				r.Header.Set("If-Match", tt.ifMatch)
+			}
+			w := httptest.NewRecorder()
+
+			got, ok := app.checkIfMatch(w, r, 7)
+			if ok != tt.wantPass || got != tt.want {
+				t.Fatalf("checkIfMatch = %v, %v, want %v, %v", got, ok, tt.want, tt.wantPass)
+			}
+			if !ok && w.Code != http.StatusPreconditionFailed {
+				t.Errorf("status = %d, want %d", w.Code, http.StatusPreconditionFailed)
+			}
+		})
+	}
+}
+
+func TestListETag(t *testing.T) {
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	base := listETag(ids, []int32{1, 1}, []string{PlanRoleOwner, PlanRoleViewer})
+
+	tests := []struct {
+		name     string
+		versions []int32
+		roles    []string
+		same     bool
+	}{
+		{name: "unchanged", versions: []int32{1, 1}, roles: []string{PlanRoleOwner, PlanRoleViewer}, same: true},
+		{name: "new version", versions: []int32{1, 2}, roles: []string{PlanRoleOwner, PlanRoleViewer}},
+		{name: "new role", versions: []int32{1, 1}, roles: []string{PlanRoleOwner, PlanRoleEditor}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := listETag(ids, tt.versions, tt.roles); (got == base) != tt.same {
+				t.Errorf("listETag = %s, base %s, want same = %v", got, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestStudyTasksETag(t *testing.T) {
+	task := store.StudyTask{ID: uuid.New(), Version: 1}
+	completed := task
+	completed.IsCompleted = sql.NullBool{Bool: true, Valid: true}
+
+	if studyTasksETag([]store.StudyTask{task}) == studyTasksETag([]store.StudyTask{completed}) {
+		t.Error("completing a task did not change the ETag")
+	}
+}
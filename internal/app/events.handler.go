@@ -0,0 +1,160 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/events"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+const (
+	// streamHeartbeat keeps proxies from closing idle streams
+	streamHeartbeat = 15 * time.Second
+
+	// streamReplayLimit caps how many missed events are replayed on resume.
+	// Clients further behind receive a "reset" event and should refetch.
+	streamReplayLimit = 500
+)
+
+// TimerTickRequest represents the request body for broadcasting timer state
+type TimerTickRequest struct {
+	State          string  `json:"state" validate:"required,oneof=running paused stopped"`
+	ElapsedSeconds int64   `json:"elapsed_seconds" validate:"gte=0"`
+	TaskID         *string `json:"task_id"`
+}
+
+// EventsStreamHandler streams the user's events as Server-Sent Events
+func (app *Application) EventsStreamHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		app.writeJSONError(w, r, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("events: failed to clear write deadline: %v", err)
+	}
+
+	lastID, err := parseLastEventID(r)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	// Subscribe before replaying so nothing published in between is lost
+	sub := app.Events.Subscribe(user.ClerkID)
+	defer app.Events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+
+	if lastID > 0 {
+		missed, err := app.Events.Replay(r.Context(), user.ClerkID, lastID, streamReplayLimit+1)
+		if err != nil {
+			log.Printf("events: replay failed for %s: %v", user.ClerkID, err)
+			return
+		}
+		if len(missed) > streamReplayLimit {
+			writeSSE(w, store.Event{Type: "reset", Data: json.RawMessage(`{}`)})
+			missed = nil
+		}
+		for _, event := range missed {
+			writeSSE(w, event)
+			lastID = event.ID
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case event, ok := <-sub.C:
+			if !ok {
+				// Disconnected by the broker; the client reconnects and resumes
+				return
+			}
+			// Skip anything already sent during replay
+			if event.ID != 0 && event.ID <= lastID {
+				continue
+			}
+			writeSSE(w, event)
+			flusher.Flush()
+			if event.ID != 0 {
+				lastID = event.ID
+			}
+		}
+	}
+}
+
+// TimerTickHandler broadcasts the study timer state to the user's other devices
+func (app *Application) TimerTickHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req TimerTickRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := app.Events.PublishEphemeral(r.Context(), user.ClerkID, events.TimerTick, req); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// publishEvent pushes an event to the user's open streams. Failures are
+// logged rather than returned so they never fail the originating request.
+func (app *Application) publishEvent(ctx context.Context, userID, eventType string, data any) {
+	if err := app.Events.Publish(ctx, userID, eventType, data); err != nil {
+		log.Printf("events: failed to publish %s for %s: %v", eventType, userID, err)
+	}
+}
+
+// parseLastEventID reads the resume position from the Last-Event-ID header,
+// falling back to a query parameter for clients that cannot set headers
+func parseLastEventID(r *http.Request) (int64, error) {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("last_event_id")
+	}
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id < 0 {
+		return 0, invalidParam("Last-Event-ID", value)
+	}
+	return id, nil
+}
+
+// writeSSE writes a single event in text/event-stream format
+func writeSSE(w http.ResponseWriter, event store.Event) {
+	if event.ID != 0 {
+		fmt.Fprintf(w, "id: %d\n", event.ID)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, event.Data)
+}
@@ -0,0 +1,637 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/achievements"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Exam formats
+const (
+	ExamFormatWritten   = "written"
+	ExamFormatOral      = "oral"
+	ExamFormatPractical = "practical"
+	ExamFormatOnline    = "online"
+	ExamFormatOther     = "other"
+)
+
+// defaultExamMaxScore is what scores are out of unless an exam says otherwise
+const defaultExamMaxScore = 100
+
+// minExamsForCorrelation is how many scored exams it takes before effort and
+// outcome are correlated; with fewer the coefficients are noise
+const minExamsForCorrelation = 3
+
+// ExamRequest represents the request body for creating or replacing an
+// exam. Scores are out of max_score, 100 by default, and the actual score
+// can only be recorded once the exam has started. PlanIDs are the plans
+// preparing for it, which the user must be a member of.
+type ExamRequest struct {
+	Title           string      `json:"title" validate:"title"`
+	SubjectID       *uuid.UUID  `json:"subject_id"`
+	StartsAt        time.Time   `json:"starts_at" validate:"required"`
+	DurationMinutes *int32      `json:"duration_minutes" validate:"omitempty,gt=0,minutes"`
+	Location        *string     `json:"location" validate:"omitempty,max=200"`
+	Format          string      `json:"format" validate:"omitempty,oneof=written oral practical online other"`
+	Weight          *float64    `json:"weight" validate:"omitempty,gt=0,max=100"`
+	MaxScore        *float64    `json:"max_score" validate:"omitempty,gt=0"`
+	TargetScore     *float64    `json:"target_score" validate:"omitempty,min=0"`
+	ActualScore     *float64    `json:"actual_score" validate:"omitempty,min=0"`
+	Notes           *string     `json:"notes" validate:"omitempty,notes"`
+	PlanIDs         []uuid.UUID `json:"plan_ids" validate:"max=20"`
+}
+
+// validate checks the scores against the maximum and the time of the exam
+func (req ExamRequest) validate(now time.Time) error {
+	maxScore := float64(defaultExamMaxScore)
+	if req.MaxScore != nil {
+		maxScore = *req.MaxScore
+	}
+	if req.TargetScore != nil && *req.TargetScore > maxScore {
+		return &FieldError{Field: "target_score", Code: "lte", Message: "must not be more than max_score"}
+	}
+	if req.ActualScore != nil && *req.ActualScore > maxScore {
+		return &FieldError{Field: "actual_score", Code: "lte", Message: "must not be more than max_score"}
+	}
+	if req.ActualScore != nil && req.StartsAt.After(now) {
+		return &FieldError{Field: "actual_score", Code: "future", Message: "can only be recorded once the exam has started"}
+	}
+	return nil
+}
+
+// ExamResponse represents an exam in API responses
+type ExamResponse struct {
+	ID              uuid.UUID       `json:"id"`
+	SubjectID       *uuid.UUID      `json:"subject_id"`
+	Title           string          `json:"title"`
+	StartsAt        time.Time       `json:"starts_at"`
+	DurationMinutes *int32          `json:"duration_minutes"`
+	Location        *string         `json:"location"`
+	Format          string          `json:"format"`
+	Weight          *float64        `json:"weight"`
+	MaxScore        float64         `json:"max_score"`
+	TargetScore     *float64        `json:"target_score"`
+	ActualScore     *float64        `json:"actual_score"`
+	Notes           *string         `json:"notes"`
+	PlanIDs         []uuid.UUID     `json:"plan_ids"`
+	Preparation     ExamPreparation `json:"preparation"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// ExamPreparation sums up the study done for an exam: the tasks of its
+// plans due up to the exam, with the user's own progress on shared plans
+type ExamPreparation struct {
+	PlanCount      int64   `json:"plan_count"`
+	TotalTasks     int64   `json:"total_tasks"`
+	CompletedTasks int64   `json:"completed_tasks"`
+	CompletionRate float64 `json:"completion_rate"`
+	MinutesStudied int64   `json:"minutes_studied"`
+}
+
+// ExamCountdownResponse is an upcoming exam with the time left before it
+type ExamCountdownResponse struct {
+	ExamResponse
+	SecondsRemaining int64 `json:"seconds_remaining"`
+	DaysRemaining    int64 `json:"days_remaining"`
+}
+
+// ExamOutcome sets the result of a past exam against the study done for it.
+// Scores are percentages of the maximum.
+type ExamOutcome struct {
+	ExamID        uuid.UUID       `json:"exam_id"`
+	Title         string          `json:"title"`
+	StartsAt      time.Time       `json:"starts_at"`
+	ScorePercent  float64         `json:"score_percent"`
+	TargetPercent *float64        `json:"target_percent"`
+	MetTarget     *bool           `json:"met_target"`
+	Preparation   ExamPreparation `json:"preparation"`
+}
+
+// ExamCorrelations are the Pearson coefficients, from -1 to 1, between a
+// measure of effort and the score percent. They are null until enough
+// exams are scored or when the measure never varies.
+type ExamCorrelations struct {
+	MinutesStudied *float64 `json:"minutes_studied"`
+	CompletedTasks *float64 `json:"completed_tasks"`
+	CompletionRate *float64 `json:"completion_rate"`
+}
+
+// ExamInsightsResponse relates study effort to exam outcomes
+type ExamInsightsResponse struct {
+	Exams               []ExamOutcome    `json:"exams"`
+	AverageScorePercent *float64         `json:"average_score_percent"`
+	TargetsSet          int              `json:"targets_set"`
+	TargetsMet          int              `json:"targets_met"`
+	Correlations        ExamCorrelations `json:"correlations"`
+}
+
+func convertExamToResponse(exam store.Exam) ExamResponse {
+	response := ExamResponse{
+		ID:          exam.ID,
+		Title:       exam.Title,
+		StartsAt:    exam.StartsAt,
+		Location:    nullStringToPointer(exam.Location),
+		Format:      exam.Format,
+		Weight:      nullFloat64ToPointer(exam.Weight),
+		MaxScore:    exam.MaxScore,
+		TargetScore: nullFloat64ToPointer(exam.TargetScore),
+		ActualScore: nullFloat64ToPointer(exam.ActualScore),
+		Notes:       nullStringToPointer(exam.Notes),
+		PlanIDs:     []uuid.UUID{},
+		CreatedAt:   exam.CreatedAt,
+		UpdatedAt:   exam.UpdatedAt,
+	}
+	if exam.SubjectID.Valid {
+		subjectID := exam.SubjectID.UUID
+		response.SubjectID = &subjectID
+	}
+	if exam.DurationMinutes.Valid {
+		duration := exam.DurationMinutes.Int32
+		response.DurationMinutes = &duration
+	}
+	return response
+}
+
+// examDetails holds what exam responses show besides the exam itself
+type examDetails struct {
+	planIDs     map[uuid.UUID][]uuid.UUID
+	preparation map[uuid.UUID]ExamPreparation
+}
+
+// loadExamDetails loads the linked plans and preparation of the given exams
+// of userID
+func (app *Application) loadExamDetails(ctx context.Context, userID string, exams ...store.Exam) (examDetails, error) {
+	examIDs := make([]uuid.UUID, len(exams))
+	for i, exam := range exams {
+		examIDs[i] = exam.ID
+	}
+
+	links, err := app.Queries.ListExamPlans(ctx, store.ListExamPlansParams{UserID: userID, ExamIds: examIDs})
+	if err != nil {
+		return examDetails{}, err
+	}
+	rows, err := app.Queries.GetExamPreparation(ctx, store.GetExamPreparationParams{UserID: userID, ExamIds: examIDs})
+	if err != nil {
+		return examDetails{}, err
+	}
+
+	details := examDetails{
+		planIDs:     make(map[uuid.UUID][]uuid.UUID),
+		preparation: make(map[uuid.UUID]ExamPreparation, len(rows)),
+	}
+	for _, link := range links {
+		details.planIDs[link.ExamID] = append(details.planIDs[link.ExamID], link.PlanID)
+	}
+	for _, row := range rows {
+		preparation := ExamPreparation{
+			PlanCount:      row.PlanCount,
+			TotalTasks:     row.TotalTasks,
+			CompletedTasks: row.CompletedTasks,
+			MinutesStudied: row.MinutesStudied,
+		}
+		if row.TotalTasks > 0 {
+			preparation.CompletionRate = float64(row.CompletedTasks) / float64(row.TotalTasks)
+		}
+		details.preparation[row.ExamID] = preparation
+	}
+	return details, nil
+}
+
+// response converts an exam along with its plans and preparation
+func (details examDetails) response(exam store.Exam) ExamResponse {
+	response := convertExamToResponse(exam)
+	if planIDs, ok := details.planIDs[exam.ID]; ok {
+		response.PlanIDs = planIDs
+	}
+	response.Preparation = details.preparation[exam.ID]
+	return response
+}
+
+// getExam loads the exam in the URL if it belongs to the user
+func (app *Application) getExam(w http.ResponseWriter, r *http.Request, user *UserClaims) (store.Exam, bool) {
+	examID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return store.Exam{}, false
+	}
+
+	exam, err := app.Queries.GetExam(r.Context(), examID)
+	if err == nil && exam.UserID != user.ClerkID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Exam not found")
+			return exam, false
+		}
+		app.internalServerError(w, r, err)
+		return exam, false
+	}
+	return exam, true
+}
+
+// readExamRequest decodes and validates an exam request. On failure an
+// error response has been written and ok is false.
+func (app *Application) readExamRequest(w http.ResponseWriter, r *http.Request, user *UserClaims) (req ExamRequest, ok bool) {
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return req, false
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return req, false
+	}
+
+	if err := req.validate(time.Now()); err != nil {
+		app.badRequestError(w, r, err)
+		return req, false
+	}
+
+	if req.SubjectID != nil {
+		if _, err := app.ownedSubject(r.Context(), app.Queries, "subject_id", user.ClerkID, *req.SubjectID); err != nil {
+			app.writeSubjectError(w, r, err)
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// saveExam creates the exam, or replaces the one with existingID, and links
+// it to the plans of the request
+func (app *Application) saveExam(ctx context.Context, user *UserClaims, existingID uuid.UUID, req ExamRequest) (store.Exam, error) {
+	params := store.CreateExamParams{
+		UserID:      user.ClerkID,
+		Title:       req.Title,
+		StartsAt:    req.StartsAt.UTC(),
+		Location:    stringToNullString(req.Location),
+		Format:      req.Format,
+		Weight:      float64ToNullFloat64(req.Weight),
+		MaxScore:    defaultExamMaxScore,
+		TargetScore: float64ToNullFloat64(req.TargetScore),
+		ActualScore: float64ToNullFloat64(req.ActualScore),
+		Notes:       stringToNullString(req.Notes),
+	}
+	if req.SubjectID != nil {
+		params.SubjectID = uuid.NullUUID{UUID: *req.SubjectID, Valid: true}
+	}
+	if req.DurationMinutes != nil {
+		params.DurationMinutes = sql.NullInt32{Int32: *req.DurationMinutes, Valid: true}
+	}
+	if params.Format == "" {
+		params.Format = ExamFormatWritten
+	}
+	if req.MaxScore != nil {
+		params.MaxScore = *req.MaxScore
+	}
+
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return store.Exam{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	var exam store.Exam
+	if existingID == uuid.Nil {
+		exam, err = queries.CreateExam(ctx, params)
+	} else {
+		exam, err = queries.UpdateExam(ctx, store.UpdateExamParams{
+			ID:              existingID,
+			SubjectID:       params.SubjectID,
+			Title:           params.Title,
+			StartsAt:        params.StartsAt,
+			DurationMinutes: params.DurationMinutes,
+			Location:        params.Location,
+			Format:          params.Format,
+			Weight:          params.Weight,
+			MaxScore:        params.MaxScore,
+			TargetScore:     params.TargetScore,
+			ActualScore:     params.ActualScore,
+			Notes:           params.Notes,
+		})
+		if err == nil {
+			err = queries.DeleteExamPlans(ctx, existingID)
+		}
+	}
+	if err != nil {
+		return store.Exam{}, err
+	}
+
+	planIDs := uniqueUUIDs(req.PlanIDs)
+	if len(planIDs) > 0 {
+		linked, err := queries.AddExamPlans(ctx, store.AddExamPlansParams{
+			ExamID:  exam.ID,
+			UserID:  user.ClerkID,
+			PlanIds: planIDs,
+		})
+		if err != nil {
+			return store.Exam{}, err
+		}
+		if linked != int64(len(planIDs)) {
+			return store.Exam{}, &FieldError{Field: "plan_ids", Code: "not_found", Message: "must be study plans you are a member of"}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.Exam{}, fmt.Errorf("failed to commit exam: %w", err)
+	}
+	return exam, nil
+}
+
+// uniqueUUIDs drops repeated IDs, keeping the first of each
+func uniqueUUIDs(ids []uuid.UUID) []uuid.UUID {
+	unique := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// GetExamsHandler lists the user's exams by date. They can be narrowed down
+// to a subject, to those a plan prepares for, or to upcoming or past ones.
+func (app *Application) GetExamsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	query := r.URL.Query()
+	params := store.ListExamsParams{UserID: user.ClerkID}
+
+	if value := query.Get("subject_id"); value != "" {
+		subjectID, err := uuid.Parse(value)
+		if err != nil {
+			app.badRequestError(w, r, invalidParam("subject_id", value))
+			return
+		}
+		params.SubjectID = uuid.NullUUID{UUID: subjectID, Valid: true}
+	}
+
+	if value := query.Get("plan_id"); value != "" {
+		planID, err := uuid.Parse(value)
+		if err != nil {
+			app.badRequestError(w, r, invalidParam("plan_id", value))
+			return
+		}
+		params.PlanID = uuid.NullUUID{UUID: planID, Valid: true}
+	}
+
+	if value := query.Get("upcoming"); value != "" {
+		upcoming, err := strconv.ParseBool(value)
+		if err != nil {
+			app.badRequestError(w, r, invalidParam("upcoming", value))
+			return
+		}
+		params.Upcoming = sql.NullBool{Bool: upcoming, Valid: true}
+	}
+
+	exams, err := app.Queries.ListExams(r.Context(), params)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	details, err := app.loadExamDetails(r.Context(), user.ClerkID, exams...)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]ExamResponse, len(exams))
+	for i, exam := range exams {
+		response[i] = details.response(exam)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreateExamHandler creates an exam linked to the plans preparing for it
+func (app *Application) CreateExamHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	req, ok := app.readExamRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	exam, err := app.saveExam(r.Context(), user, uuid.Nil, req)
+	if err != nil {
+		app.writeSubjectError(w, r, err)
+		return
+	}
+	if exam.ActualScore.Valid {
+		app.checkAchievements(r.Context(), user.ClerkID, achievements.EventExamScored)
+	}
+
+	details, err := app.loadExamDetails(r.Context(), user.ClerkID, exam)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, details.response(exam))
+}
+
+// GetExamHandler returns an exam with its plans and preparation
+func (app *Application) GetExamHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	exam, ok := app.getExam(w, r, user)
+	if !ok {
+		return
+	}
+
+	details, err := app.loadExamDetails(r.Context(), user.ClerkID, exam)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, details.response(exam)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateExamHandler replaces an exam and the plans linked to it. Recording
+// the actual score is an update like any other.
+func (app *Application) UpdateExamHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	existing, ok := app.getExam(w, r, user)
+	if !ok {
+		return
+	}
+
+	req, ok := app.readExamRequest(w, r, user)
+	if !ok {
+		return
+	}
+
+	exam, err := app.saveExam(r.Context(), user, existing.ID, req)
+	if err != nil {
+		app.writeSubjectError(w, r, err)
+		return
+	}
+	if exam.ActualScore.Valid {
+		app.checkAchievements(r.Context(), user.ClerkID, achievements.EventExamScored)
+	}
+
+	details, err := app.loadExamDetails(r.Context(), user.ClerkID, exam)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, details.response(exam))
+}
+
+// DeleteExamHandler deletes an exam. The plans preparing for it are kept.
+func (app *Application) DeleteExamHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	exam, ok := app.getExam(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.Queries.DeleteExam(r.Context(), exam.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.respondDeleted(w, r, "Exam deleted successfully")
+}
+
+// GetExamCountdownHandler lists the upcoming exams, soonest first, with the
+// time left before each
+func (app *Application) GetExamCountdownHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	exams, err := app.Queries.ListExams(r.Context(), store.ListExamsParams{
+		UserID:   user.ClerkID,
+		Upcoming: sql.NullBool{Bool: true, Valid: true},
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	details, err := app.loadExamDetails(r.Context(), user.ClerkID, exams...)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	response := make([]ExamCountdownResponse, len(exams))
+	for i, exam := range exams {
+		remaining := max(exam.StartsAt.Sub(now), 0)
+		response[i] = ExamCountdownResponse{
+			ExamResponse:     details.response(exam),
+			SecondsRemaining: int64(remaining / time.Second),
+			DaysRemaining:    int64(remaining / (24 * time.Hour)),
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetExamInsightsHandler relates the study done for past exams to their
+// results, for the exams with an actual score
+func (app *Application) GetExamInsightsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	exams, err := app.Queries.ListExams(r.Context(), store.ListExamsParams{UserID: user.ClerkID})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	details, err := app.loadExamDetails(r.Context(), user.ClerkID, exams...)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := ExamInsightsResponse{Exams: []ExamOutcome{}}
+	var scores, minutes, completed, rates []float64
+	for _, exam := range exams {
+		if !exam.ActualScore.Valid {
+			continue
+		}
+
+		outcome := ExamOutcome{
+			ExamID:       exam.ID,
+			Title:        exam.Title,
+			StartsAt:     exam.StartsAt,
+			ScorePercent: 100 * exam.ActualScore.Float64 / exam.MaxScore,
+			Preparation:  details.preparation[exam.ID],
+		}
+		if exam.TargetScore.Valid {
+			target := 100 * exam.TargetScore.Float64 / exam.MaxScore
+			met := exam.ActualScore.Float64 >= exam.TargetScore.Float64
+			outcome.TargetPercent = &target
+			outcome.MetTarget = &met
+			response.TargetsSet++
+			if met {
+				response.TargetsMet++
+			}
+		}
+		response.Exams = append(response.Exams, outcome)
+
+		scores = append(scores, outcome.ScorePercent)
+		minutes = append(minutes, float64(outcome.Preparation.MinutesStudied))
+		completed = append(completed, float64(outcome.Preparation.CompletedTasks))
+		rates = append(rates, outcome.Preparation.CompletionRate)
+	}
+
+	if len(scores) > 0 {
+		var total float64
+		for _, score := range scores {
+			total += score
+		}
+		average := total / float64(len(scores))
+		response.AverageScorePercent = &average
+	}
+
+	if len(scores) >= minExamsForCorrelation {
+		response.Correlations = ExamCorrelations{
+			MinutesStudied: pearson(minutes, scores),
+			CompletedTasks: pearson(completed, scores),
+			CompletionRate: pearson(rates, scores),
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// pearson returns the Pearson correlation coefficient of xs and ys, or nil
+// when either does not vary
+func pearson(xs, ys []float64) *float64 {
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(len(xs))
+	meanY /= float64(len(ys))
+
+	var covariance, varianceX, varianceY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		covariance += dx * dy
+		varianceX += dx * dx
+		varianceY += dy * dy
+	}
+	if varianceX == 0 || varianceY == 0 {
+		return nil
+	}
+
+	coefficient := covariance / math.Sqrt(varianceX*varianceY)
+	return &coefficient
+}
@@ -0,0 +1,523 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/events"
+	"github.com/mustaphalimar/prepilot/internal/goals"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// GoalRequest represents the request body for creating or updating a goal.
+// Dates are days; starts_on defaults to today. plan_completion goals need
+// a plan and an end date and their target is a percent. The kind, period
+// and start of a goal cannot be changed, since its history depends on them.
+type GoalRequest struct {
+	Title     string     `json:"title" validate:"title"`
+	Kind      string     `json:"kind" validate:"required,oneof=study_minutes tasks_completed plan_completion"`
+	Target    int32      `json:"target" validate:"required,gt=0,max=100000"`
+	Period    string     `json:"period" validate:"required,oneof=daily weekly monthly once"`
+	PlanID    *uuid.UUID `json:"plan_id"`
+	SubjectID *uuid.UUID `json:"subject_id"`
+	StartsOn  *time.Time `json:"starts_on"`
+	EndsOn    *time.Time `json:"ends_on"`
+}
+
+// validate checks the rules that depend on the kind and period of the goal
+func (req GoalRequest) validate(startsOn time.Time) error {
+	if req.Kind == goals.KindPlanCompletion {
+		switch {
+		case req.PlanID == nil:
+			return &FieldError{Field: "plan_id", Code: "required", Message: "is required for plan_completion goals"}
+		case req.SubjectID != nil:
+			return &FieldError{Field: "subject_id", Code: "excluded", Message: "cannot be set for plan_completion goals"}
+		case req.Period != goals.PeriodOnce:
+			return &FieldError{Field: "period", Code: "oneof", Message: "must be once for plan_completion goals"}
+		case req.Target > 100:
+			return &FieldError{Field: "target", Code: "max", Message: "is a percent for plan_completion goals"}
+		}
+	}
+	if req.Period == goals.PeriodOnce && req.EndsOn == nil {
+		return &FieldError{Field: "ends_on", Code: "required", Message: "is required for once goals"}
+	}
+	if req.EndsOn != nil && goals.Date(*req.EndsOn).Before(startsOn) {
+		return &FieldError{Field: "ends_on", Code: "gtefield", Message: "must be on or after starts_on"}
+	}
+	return nil
+}
+
+// GoalResponse represents a goal in API responses. Current is the period
+// under way, or the last one of a goal that has ended, and is null before
+// the goal starts.
+type GoalResponse struct {
+	ID        uuid.UUID             `json:"id"`
+	Title     string                `json:"title"`
+	Kind      string                `json:"kind"`
+	Target    int32                 `json:"target"`
+	Period    string                `json:"period"`
+	PlanID    *uuid.UUID            `json:"plan_id"`
+	SubjectID *uuid.UUID            `json:"subject_id"`
+	StartsOn  time.Time             `json:"starts_on"`
+	EndsOn    *time.Time            `json:"ends_on"`
+	Current   *GoalProgressResponse `json:"current"`
+	CreatedAt time.Time             `json:"created_at"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// GoalProgressResponse is the progress of a goal over one period, with the
+// milestones, in percent of the target, reached during it
+type GoalProgressResponse struct {
+	StartsOn   time.Time `json:"starts_on"`
+	EndsOn     time.Time `json:"ends_on"`
+	Progress   int64     `json:"progress"`
+	Target     int32     `json:"target"`
+	Percent    int32     `json:"percent"`
+	Met        bool      `json:"met"`
+	Milestones []int32   `json:"milestones"`
+}
+
+// GoalMilestoneEvent is pushed to the user when a goal reaches a milestone
+type GoalMilestoneEvent struct {
+	GoalID   uuid.UUID `json:"goal_id"`
+	Title    string    `json:"title"`
+	StartsOn time.Time `json:"starts_on"`
+	Percent  int32     `json:"percent"`
+}
+
+func convertGoalToResponse(goal store.Goal) GoalResponse {
+	response := GoalResponse{
+		ID:        goal.ID,
+		Title:     goal.Title,
+		Kind:      goal.Kind,
+		Target:    goal.Target,
+		Period:    goal.Period,
+		StartsOn:  goal.StartsOn,
+		EndsOn:    nullTimeToPointer(goal.EndsOn),
+		CreatedAt: goal.CreatedAt,
+		UpdatedAt: goal.UpdatedAt,
+	}
+	if goal.PlanID.Valid {
+		planID := goal.PlanID.UUID
+		response.PlanID = &planID
+	}
+	if goal.SubjectID.Valid {
+		subjectID := goal.SubjectID.UUID
+		response.SubjectID = &subjectID
+	}
+	return response
+}
+
+// currentWindow is the period of the goal that its progress is shown for:
+// the one running today, or its last one once the goal has ended
+func currentWindow(goal store.Goal, today time.Time) (goals.Window, bool) {
+	window, ok := goals.WindowAt(goal, today)
+	if !ok && goal.EndsOn.Valid && today.After(goal.EndsOn.Time) {
+		window, ok = goals.WindowAt(goal, goal.EndsOn.Time)
+	}
+	return window, ok
+}
+
+// currentGoalProgress measures the goal over its current period, along
+// with the milestones recorded for it. It returns nil before the goal
+// starts.
+func (app *Application) currentGoalProgress(ctx context.Context, goal store.Goal, today time.Time) (*GoalProgressResponse, error) {
+	window, ok := currentWindow(goal, today)
+	if !ok {
+		return nil, nil
+	}
+
+	progress, err := goals.Measure(ctx, app.Queries, goal, window)
+	if err != nil {
+		return nil, err
+	}
+
+	milestones, err := app.Queries.ListGoalMilestones(ctx, goal.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GoalProgressResponse{
+		StartsOn:   progress.StartsOn,
+		EndsOn:     progress.EndsOn,
+		Progress:   progress.Value,
+		Target:     progress.Target,
+		Percent:    progress.Percent,
+		Met:        progress.Met,
+		Milestones: []int32{},
+	}
+	for _, milestone := range milestones {
+		if milestone.PeriodStartsOn.Equal(progress.StartsOn) {
+			response.Milestones = append(response.Milestones, milestone.Percent)
+		}
+	}
+	return response, nil
+}
+
+// goalResponse converts a goal along with its progress in the period
+// running on the user's today
+func (app *Application) goalResponse(ctx context.Context, goal store.Goal, today time.Time) (GoalResponse, error) {
+	response := convertGoalToResponse(goal)
+	current, err := app.currentGoalProgress(ctx, goal, today)
+	if err != nil {
+		return response, err
+	}
+	response.Current = current
+	return response, nil
+}
+
+// checkGoalMilestones measures the user's goals after they completed work
+// and records the milestones reached, so they are announced as they are
+// reached
+func (app *Application) checkGoalMilestones(ctx context.Context, userID string) {
+	list, err := app.Queries.ListGoals(ctx, userID)
+	if err != nil {
+		log.Printf("goals: failed to list goals of %s: %v", userID, err)
+		return
+	}
+	today, err := app.userToday(ctx, userID)
+	if err != nil {
+		log.Printf("goals: failed to load the time zone of %s: %v", userID, err)
+		return
+	}
+
+	for _, goal := range list {
+		window, ok := currentWindow(goal, today)
+		if !ok {
+			continue
+		}
+
+		progress, err := goals.Measure(ctx, app.Queries, goal, window)
+		if err != nil {
+			log.Printf("goals: failed to measure goal %s: %v", goal.ID, err)
+			continue
+		}
+		reached, err := goals.RecordMilestones(ctx, app.Queries, goal, progress)
+		if err != nil {
+			log.Printf("goals: failed to record the milestones of goal %s: %v", goal.ID, err)
+			continue
+		}
+		for _, percent := range reached {
+			app.publishEvent(ctx, goal.UserID, events.GoalMilestone, GoalMilestoneEvent{
+				GoalID:   goal.ID,
+				Title:    goal.Title,
+				StartsOn: progress.StartsOn,
+				Percent:  percent,
+			})
+		}
+	}
+}
+
+// userToday is the day it is for the user, in their time zone
+func (app *Application) userToday(ctx context.Context, userID string) (time.Time, error) {
+	user, err := app.Queries.GetUserByClerkID(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return goals.Today(time.Now(), goals.Location(user.TimeZone)), nil
+}
+
+// getGoal loads the goal in the URL if it belongs to the user
+func (app *Application) getGoal(w http.ResponseWriter, r *http.Request, user *UserClaims) (store.Goal, bool) {
+	goalID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return store.Goal{}, false
+	}
+
+	goal, err := app.Queries.GetGoal(r.Context(), goalID)
+	if err == nil && goal.UserID != user.ClerkID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Goal not found")
+			return goal, false
+		}
+		app.internalServerError(w, r, err)
+		return goal, false
+	}
+	return goal, true
+}
+
+// readGoalRequest decodes and validates a goal request, including that its
+// plan and subject are the user's. On failure an error response has been
+// written and ok is false.
+func (app *Application) readGoalRequest(w http.ResponseWriter, r *http.Request, user *UserClaims, startsOn time.Time) (req GoalRequest, ok bool) {
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return req, false
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return req, false
+	}
+
+	if req.StartsOn != nil {
+		startsOn = goals.Date(*req.StartsOn)
+	}
+	if err := req.validate(startsOn); err != nil {
+		app.badRequestError(w, r, err)
+		return req, false
+	}
+
+	if req.PlanID != nil {
+		_, err := app.Queries.GetStudyPlanByID(r.Context(), *req.PlanID)
+		if err == nil {
+			_, err = app.Queries.GetPlanMember(r.Context(), store.GetPlanMemberParams{PlanID: *req.PlanID, UserID: user.ClerkID})
+		}
+		if err == sql.ErrNoRows {
+			app.badRequestError(w, r, &FieldError{Field: "plan_id", Code: "not_found", Message: "is not a study plan you are a member of"})
+			return req, false
+		}
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return req, false
+		}
+	}
+
+	if req.SubjectID != nil {
+		if _, err := app.ownedSubject(r.Context(), app.Queries, "subject_id", user.ClerkID, *req.SubjectID); err != nil {
+			app.writeSubjectError(w, r, err)
+			return req, false
+		}
+	}
+	return req, true
+}
+
+// GetGoalsHandler lists the user's goals with their current progress
+func (app *Application) GetGoalsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	list, err := app.Queries.ListGoals(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	today, err := app.userToday(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]GoalResponse, len(list))
+	for i, goal := range list {
+		response[i], err = app.goalResponse(r.Context(), goal, today)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreateGoalHandler creates a goal
+func (app *Application) CreateGoalHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	today, err := app.userToday(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	req, ok := app.readGoalRequest(w, r, user, today)
+	if !ok {
+		return
+	}
+
+	params := store.CreateGoalParams{
+		UserID:   user.ClerkID,
+		Title:    req.Title,
+		Kind:     req.Kind,
+		Target:   req.Target,
+		Period:   req.Period,
+		StartsOn: today,
+	}
+	if req.PlanID != nil {
+		params.PlanID = uuid.NullUUID{UUID: *req.PlanID, Valid: true}
+	}
+	if req.SubjectID != nil {
+		params.SubjectID = uuid.NullUUID{UUID: *req.SubjectID, Valid: true}
+	}
+	if req.StartsOn != nil {
+		params.StartsOn = goals.Date(*req.StartsOn)
+	}
+	if req.EndsOn != nil {
+		params.EndsOn = sql.NullTime{Time: goals.Date(*req.EndsOn), Valid: true}
+	}
+
+	goal, err := app.Queries.CreateGoal(r.Context(), params)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response, err := app.goalResponse(r.Context(), goal, today)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// GetGoalHandler returns a goal with its current progress
+func (app *Application) GetGoalHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	goal, ok := app.getGoal(w, r, user)
+	if !ok {
+		return
+	}
+
+	today, err := app.userToday(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	response, err := app.goalResponse(r.Context(), goal, today)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateGoalHandler changes the title, target, links or end date of a goal.
+// Periods already recorded keep the target they had.
+func (app *Application) UpdateGoalHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	existing, ok := app.getGoal(w, r, user)
+	if !ok {
+		return
+	}
+
+	req, ok := app.readGoalRequest(w, r, user, existing.StartsOn)
+	if !ok {
+		return
+	}
+
+	var fieldError *FieldError
+	switch {
+	case req.Kind != existing.Kind:
+		fieldError = &FieldError{Field: "kind", Code: "immutable", Message: "cannot be changed"}
+	case req.Period != existing.Period:
+		fieldError = &FieldError{Field: "period", Code: "immutable", Message: "cannot be changed"}
+	case req.StartsOn != nil && !goals.Date(*req.StartsOn).Equal(existing.StartsOn):
+		fieldError = &FieldError{Field: "starts_on", Code: "immutable", Message: "cannot be changed"}
+	}
+	if fieldError != nil {
+		app.badRequestError(w, r, fieldError)
+		return
+	}
+
+	params := store.UpdateGoalParams{
+		ID:     existing.ID,
+		Title:  req.Title,
+		Target: req.Target,
+	}
+	if req.PlanID != nil {
+		params.PlanID = uuid.NullUUID{UUID: *req.PlanID, Valid: true}
+	}
+	if req.SubjectID != nil {
+		params.SubjectID = uuid.NullUUID{UUID: *req.SubjectID, Valid: true}
+	}
+	if req.EndsOn != nil {
+		params.EndsOn = sql.NullTime{Time: goals.Date(*req.EndsOn), Valid: true}
+	}
+
+	goal, err := app.Queries.UpdateGoal(r.Context(), params)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	today, err := app.userToday(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	response, err := app.goalResponse(r.Context(), goal, today)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+// DeleteGoalHandler deletes a goal with its history
+func (app *Application) DeleteGoalHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	goal, ok := app.getGoal(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.Queries.DeleteGoal(r.Context(), goal.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.respondDeleted(w, r, "Goal deleted successfully")
+}
+
+// GetGoalHistoryHandler lists the ended periods of a goal, latest first,
+// with whether the goal was met and the milestones reached in each
+func (app *Application) GetGoalHistoryHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	goal, ok := app.getGoal(w, r, user)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r, 30, 366)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	periods, err := app.Queries.ListGoalPeriods(r.Context(), store.ListGoalPeriodsParams{
+		GoalID:    goal.ID,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	milestones, err := app.Queries.ListGoalMilestones(r.Context(), goal.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	reached := make(map[string][]int32)
+	for _, milestone := range milestones {
+		day := milestone.PeriodStartsOn.Format(time.DateOnly)
+		reached[day] = append(reached[day], milestone.Percent)
+	}
+
+	response := make([]GoalProgressResponse, len(periods))
+	for i, period := range periods {
+		response[i] = GoalProgressResponse{
+			StartsOn:   period.StartsOn,
+			EndsOn:     period.EndsOn,
+			Progress:   period.Progress,
+			Target:     period.Target,
+			Percent:    int32(100 * period.Progress / int64(period.Target)),
+			Met:        period.Met,
+			Milestones: []int32{},
+		}
+		if percents, ok := reached[period.StartsOn.Format(time.DateOnly)]; ok {
+			response[i].Milestones = percents
+		}
+	}
+
+	if err := app.pageResponse(w, r, response, len(response), limit, offset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
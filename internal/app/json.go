@@ -3,6 +3,8 @@ package app
 import (
 	"encoding/json"
 	"net/http"
+	"reflect"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -11,6 +13,20 @@ var Validate *validator.Validate
 
 func init() {
 	Validate = validator.New(validator.WithRequiredStructEnabled())
+
+	// Report fields by their JSON names so clients can match them to inputs
+	Validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+
+	registerValidators(Validate)
 }
 
 func (app *Application) writeJSON(w http.ResponseWriter, status int, data any) error {
@@ -28,22 +44,13 @@ func (app *Application) readJSON(w http.ResponseWriter, r *http.Request, data an
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields() // as its name suggest
 
-	return decoder.Decode(data)
-}
-
-func (app *Application) writeJSONError(w http.ResponseWriter, status int, message string) error {
-	type envelope struct {
-		Error string `json:"error"`
+	if err := decoder.Decode(data); err != nil {
+		return err
 	}
-
-	return app.writeJSON(w, status, &envelope{
-		Error: message,
-	})
+	normalizeTitles(reflect.ValueOf(data))
+	return nil
 }
 
 func (app *Application) jsonResponse(w http.ResponseWriter, status int, data any) error {
-	type envelope struct {
-		Data any `json:"data"`
-	}
-	return app.writeJSON(w, status, &envelope{Data: data})
+	return app.writeJSON(w, status, Envelope{Data: data})
 }
@@ -3,19 +3,50 @@ package app
 import (
 	"context"
 	"database/sql"
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
 	"github.com/mustaphalimar/prepilot/internal/store"
 )
 
-// UserClaims represents the user information extracted from JWT
+// UserClaims represents the user information extracted from JWT or a
+// personal access token. TokenID and Scopes are only set for tokens, and
+// ImpersonatorID only when an admin is acting as the user.
 type UserClaims struct {
-	ClerkID string
-	Email   string
+	ClerkID        string
+	Email          string
+	Role           string
+	TokenID        uuid.UUID
+	Scopes         []string
+	ImpersonatorID string
+}
+
+// ActorID is who is behind the request, for the activity log: the admin
+// when impersonating, the user otherwise
+func (u *UserClaims) ActorID() string {
+	if u.ImpersonatorID != "" {
+		return u.ImpersonatorID
+	}
+	return u.ClerkID
+}
+
+// IsAccessToken reports whether the request used a personal access token
+func (u *UserClaims) IsAccessToken() bool {
+	return u.TokenID != uuid.Nil
+}
+
+// HasScope reports whether the request may use scope. Clerk sessions have
+// every scope.
+func (u *UserClaims) HasScope(scope string) bool {
+	return !u.IsAccessToken() || slices.Contains(u.Scopes, scope)
 }
 
 // contextKey is a custom type for context keys to avoid collisions
@@ -30,11 +61,17 @@ func (app *Application) AuthMiddleware(next http.Handler) http.Handler {
 		
 		// Get the Authorization header
 		authHeader := r.Header.Get("Authorization")
-		fmt.Printf("🔑 Authorization header: %s\n", authHeader)
+
+		// EventSource cannot set headers, so the event stream may pass the token in the query
+		if authHeader == "" && isEventStream(r) {
+			if token := r.URL.Query().Get("access_token"); token != "" {
+				authHeader = "Bearer " + token
+			}
+		}
 
 		if authHeader == "" {
 			fmt.Printf("❌ No authorization header provided\n")
-			app.writeJSONError(w, http.StatusUnauthorized, "Authorization header required")
+			app.writeJSONError(w, r, http.StatusUnauthorized, "Authorization header required")
 			return
 		}
 
@@ -42,44 +79,91 @@ func (app *Application) AuthMiddleware(next http.Handler) http.Handler {
 		tokenParts := strings.Split(authHeader, " ")
 		if len(tokenParts) != 2 || strings.ToLower(tokenParts[0]) != "bearer" {
 			fmt.Printf("❌ Invalid authorization header format. Parts: %d, First part: %s\n", len(tokenParts), tokenParts[0])
-			app.writeJSONError(w, http.StatusUnauthorized, "Invalid authorization header format")
+			app.writeJSONError(w, r, http.StatusUnauthorized, "Invalid authorization header format")
 			return
 		}
 
 		token := tokenParts[1]
-		fmt.Printf("🎫 Token extracted (first 20 chars): %s...\n", token[:min(20, len(token))])
 
-		// Extract user ID from JWT payload (for development)
-		clerkID, email, err := app.extractUserFromJWT(token)
-		if err != nil {
-			fmt.Printf("❌ Failed to extract user from JWT: %v\n", err)
-			app.writeJSONError(w, http.StatusUnauthorized, "Invalid token format")
+		// Personal access tokens belong to existing users and carry their own scopes
+		if strings.HasPrefix(token, accessTokenPrefix) {
+			userClaims, err := app.authenticateAccessToken(r, token)
+			if err == errUserBanned {
+				fmt.Printf("❌ Rejected access token of banned user\n")
+				app.writeJSONError(w, r, http.StatusForbidden, "Account suspended")
+				return
+			}
+			if err != nil {
+				fmt.Printf("❌ Rejected personal access token: %v\n", err)
+				app.writeJSONError(w, r, http.StatusUnauthorized, "Invalid, expired or revoked access token")
+				return
+			}
+
+			fmt.Printf("✅ Authenticated with access token %s for Clerk ID: %s\n", userClaims.TokenID, userClaims.ClerkID)
+			ctx := context.WithValue(r.Context(), userContextKey, userClaims)
+			next.ServeHTTP(w, r.WithContext(ctx))
 			return
 		}
 
-		if clerkID == "" {
-			fmt.Printf("❌ Extracted clerk ID is empty\n")
-			app.writeJSONError(w, http.StatusUnauthorized, "Invalid user ID in token")
+		// Anything else must be a Clerk session token signed by our instance
+		if app.Config.ClerkSessions == nil {
+			fmt.Printf("❌ Rejected session token: CLERK_ISSUER is not configured\n")
+			app.writeJSONError(w, r, http.StatusUnauthorized, "Invalid or expired session token")
+			return
+		}
+		claims, err := app.Config.ClerkSessions.Verify(r.Context(), token)
+		if err != nil {
+			fmt.Printf("❌ Rejected session token: %v\n", err)
+			app.writeJSONError(w, r, http.StatusUnauthorized, "Invalid or expired session token")
 			return
 		}
 
-		fmt.Printf("✅ Successfully extracted user - Clerk ID: %s, Email: %s\n", clerkID, email)
+		fmt.Printf("✅ Verified session token for Clerk ID: %s\n", claims.Subject)
 
 		// Create user claims object
 		userClaims := &UserClaims{
-			ClerkID: clerkID,
-			Email:   email,
+			ClerkID: claims.Subject,
+			Email:   claims.Email,
 		}
 
 		// Ensure user exists in database (for development compatibility)
 		fmt.Printf("🔄 Ensuring user exists in database...\n")
-		if err := app.ensureUserExists(r.Context(), userClaims); err != nil {
+		dbUser, err := app.ensureUserExists(r.Context(), userClaims)
+		if err != nil {
 			fmt.Printf("❌ Failed to ensure user exists: %v\n", err)
-			app.writeJSONError(w, http.StatusInternalServerError, "Failed to process user authentication")
+			app.writeJSONError(w, r, http.StatusInternalServerError, "Failed to process user authentication")
 			return
 		}
 		fmt.Printf("✅ User existence confirmed\n")
 
+		if dbUser.Banned.Valid && dbUser.Banned.Bool {
+			fmt.Printf("❌ User is banned\n")
+			app.writeJSONError(w, r, http.StatusForbidden, "Account suspended")
+			return
+		}
+		userClaims.Role = app.userRole(dbUser.ClerkID, dbUser.Role)
+
+		// Admins may act as a user within an impersonation session
+		if sessionID := r.Header.Get(impersonationHeader); sessionID != "" {
+			impersonated, err := app.impersonate(r.Context(), userClaims, sessionID)
+			switch {
+			case err == errImpersonationNotAdmin:
+				app.writeJSONError(w, r, http.StatusForbidden, "Only admins can impersonate users")
+				return
+			case err == errImpersonationInvalid:
+				app.writeJSONError(w, r, http.StatusUnauthorized, "Impersonation session not found, ended or expired")
+				return
+			case err == errUserBanned:
+				app.writeJSONError(w, r, http.StatusForbidden, "Banned users cannot be impersonated")
+				return
+			case err != nil:
+				app.internalServerError(w, r, err)
+				return
+			}
+			fmt.Printf("🎭 %s is impersonating %s\n", userClaims.ClerkID, impersonated.ClerkID)
+			userClaims = impersonated
+		}
+
 		// Add user claims to request context
 		ctx := context.WithValue(r.Context(), userContextKey, userClaims)
 		r = r.WithContext(ctx)
@@ -90,6 +174,56 @@ func (app *Application) AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// TimeoutMiddleware cancels requests after the given duration. The event
+// stream is long-lived by design and is left untouched.
+func (app *Application) TimeoutMiddleware(timeout time.Duration) func(http.Handler) http.Handler {
+	withTimeout := middleware.Timeout(timeout)
+	return func(next http.Handler) http.Handler {
+		timed := withTimeout(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isEventStream(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timed.ServeHTTP(w, r)
+		})
+	}
+}
+
+// eventStreamPath matches the Server-Sent Events route of every API version
+var eventStreamPath = regexp.MustCompile(`^/v[0-9]+/events/stream$`)
+
+// isEventStream reports whether the request is for the GET /events/stream
+// route. It is decided by the route, not by headers the client controls.
+func isEventStream(r *http.Request) bool {
+	return r.Method == http.MethodGet && eventStreamPath.MatchString(r.URL.Path)
+}
+
+// RequestLogger logs requests like chi's middleware.Logger, with tokens
+// passed in the query string redacted
+func (app *Application) RequestLogger(next http.Handler) http.Handler {
+	return requestLogger(next)
+}
+
+var requestLogger = middleware.RequestLogger(redactingLogFormatter{
+	LogFormatter: &middleware.DefaultLogFormatter{Logger: log.New(os.Stdout, "", log.LstdFlags), NoColor: true},
+})
+
+// redactingLogFormatter hides the access_token query parameter from access logs
+type redactingLogFormatter struct {
+	middleware.LogFormatter
+}
+
+func (f redactingLogFormatter) NewLogEntry(r *http.Request) middleware.LogEntry {
+	if query := r.URL.Query(); query.Has("access_token") {
+		query.Set("access_token", "REDACTED")
+		redacted := *r
+		redacted.RequestURI = r.URL.Path + "?" + query.Encode()
+		r = &redacted
+	}
+	return f.LogFormatter.NewLogEntry(r)
+}
+
 // GetUserFromContext extracts user claims from the request context
 func GetUserFromContext(ctx context.Context) (*UserClaims, bool) {
 	user, ok := ctx.Value(userContextKey).(*UserClaims)
@@ -100,7 +234,7 @@ func GetUserFromContext(ctx context.Context) (*UserClaims, bool) {
 func (app *Application) RequireAuth(w http.ResponseWriter, r *http.Request) (*UserClaims, bool) {
 	user, ok := GetUserFromContext(r.Context())
 	if !ok {
-		app.writeJSONError(w, http.StatusUnauthorized, "Authentication required")
+		app.writeJSONError(w, r, http.StatusUnauthorized, "Authentication required")
 		return nil, false
 	}
 	return user, true
@@ -120,19 +254,75 @@ func (app *Application) WithAuth(handler AuthenticatedHandler) http.HandlerFunc
 	}
 }
 
+// RequireRole only lets through users with one of the given roles. Staff
+// impersonating a user have that user's role. It must run after
+// AuthMiddleware.
+func (app *Application) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := app.RequireAuth(w, r)
+			if !ok {
+				return
+			}
+			if !slices.Contains(roles, user.Role) {
+				app.writeJSONError(w, r, http.StatusForbidden, fmt.Sprintf("Requires the %s role", strings.Join(roles, " or ")))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireScope only lets through requests whose access token was granted
+// every given scope. Clerk sessions always pass. It must run after
+// AuthMiddleware.
+func (app *Application) RequireScope(scopes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := app.RequireAuth(w, r)
+			if !ok {
+				return
+			}
+			for _, scope := range scopes {
+				if !user.HasScope(scope) {
+					app.writeJSONError(w, r, http.StatusForbidden, fmt.Sprintf("Access token is missing the %s scope", scope))
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession rejects personal access tokens and impersonation, for
+// routes that manage the account itself. It must run after AuthMiddleware.
+func (app *Application) RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := app.RequireAuth(w, r)
+		if !ok {
+			return
+		}
+		if user.IsAccessToken() || user.ImpersonatorID != "" {
+			app.writeJSONError(w, r, http.StatusForbidden, "This endpoint requires the user's own signed-in session")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 // ensureUserExists checks if user exists in database and creates them if not
 // This is particularly useful for development where webhooks might not work
-func (app *Application) ensureUserExists(ctx context.Context, userClaims *UserClaims) error {
+func (app *Application) ensureUserExists(ctx context.Context, userClaims *UserClaims) (store.User, error) {
 	// Check if user already exists
-	_, err := app.Queries.GetUserByClerkID(ctx, userClaims.ClerkID)
+	user, err := app.Queries.GetUserByClerkID(ctx, userClaims.ClerkID)
 	if err == nil {
 		// User exists, nothing to do
-		return nil
+		return user, nil
 	}
 	
 	if err != sql.ErrNoRows {
 		// Some other error occurred
-		return fmt.Errorf("failed to check user existence: %w", err)
+		return store.User{}, fmt.Errorf("failed to check user existence: %w", err)
 	}
 
 	// User doesn't exist, create them
@@ -156,72 +346,11 @@ func (app *Application) ensureUserExists(ctx context.Context, userClaims *UserCl
 		LastSignInAt:  sql.NullTime{Valid: false},
 	}
 
-	_, err = app.Queries.UpsertUserByClerkID(ctx, params)
+	user, err = app.Queries.UpsertUserByClerkID(ctx, params)
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		return store.User{}, fmt.Errorf("failed to create user: %w", err)
 	}
 
 	fmt.Printf("✅ Successfully created user for Clerk ID: %s\n", userClaims.ClerkID)
-	return nil
-}
-
-// JWTPayload represents the JWT payload structure
-type JWTPayload struct {
-	Sub   string `json:"sub"`   // User ID
-	Email string `json:"email"` // Email address
-	Exp   int64  `json:"exp"`   // Expiration time
-	Iat   int64  `json:"iat"`   // Issued at time
-}
-
-// extractUserFromJWT extracts user information from JWT payload without full verification
-// This is a simplified approach for development environments
-func (app *Application) extractUserFromJWT(token string) (string, string, error) {
-	fmt.Printf("🔍 Extracting user info from JWT token\n")
-	
-	// Split the JWT token into parts
-	parts := strings.Split(token, ".")
-	if len(parts) != 3 {
-		return "", "", fmt.Errorf("invalid JWT format: expected 3 parts, got %d", len(parts))
-	}
-
-	// Decode the payload (second part)
-	payload := parts[1]
-	fmt.Printf("📦 JWT payload part (first 50 chars): %s...\n", payload[:min(50, len(payload))])
-	
-	// Add padding if needed for base64 decoding
-	switch len(payload) % 4 {
-	case 2:
-		payload += "=="
-	case 3:
-		payload += "="
-	}
-
-	// Decode the base64 payload
-	payloadBytes, err := base64.URLEncoding.DecodeString(payload)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to decode JWT payload: %w", err)
-	}
-
-	fmt.Printf("📄 Decoded payload: %s\n", string(payloadBytes))
-
-	// Parse the JSON payload
-	var jwtPayload JWTPayload
-	if err := json.Unmarshal(payloadBytes, &jwtPayload); err != nil {
-		return "", "", fmt.Errorf("failed to parse JWT payload: %w", err)
-	}
-
-	fmt.Printf("📋 Parsed JWT - Sub: %s, Email: %s\n", jwtPayload.Sub, jwtPayload.Email)
-
-	// Basic validation - check if token is not expired (optional for development)
-	// For now, we'll skip expiration check for simplicity
-
-	return jwtPayload.Sub, jwtPayload.Email, nil
-}
-
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
-	}
-	return b
+	return user, nil
 }
@@ -0,0 +1,425 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// openAPIOperation describes one route for the OpenAPI document. Routes are
+// looked up by method and pattern without the version prefix, e.g.
+// "GET /study-plans/{id}", so one entry covers /v1 and /v2.
+type openAPIOperation struct {
+	Summary string
+	Tag     string
+	// Public routes do not need a bearer token
+	Public bool
+	Query  []openAPIParam
+	// Request is a zero value of the JSON request body, if any
+	Request any
+	// MergePatch marks a request body sent as JSON merge patch
+	MergePatch bool
+	// Response is a zero value of the data returned in the envelope
+	Response any
+	// Legacy is a zero value of the /v1 body when it is not the envelope
+	Legacy any
+	// Status is the success status, 200 when zero
+	Status int
+	// Paginated responses take limit and offset and return meta
+	Paginated bool
+	// Deleted responses come from respondDeleted
+	Deleted bool
+	// MediaType is set for responses that are not JSON
+	MediaType string
+}
+
+type openAPIParam struct {
+	Name        string
+	Type        string
+	Description string
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	uuidType       = reflect.TypeFor[uuid.UUID]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	pathParamRe    = regexp.MustCompile(`\{([^}:]+)(:[^}]*)?\}`)
+	versionPrefix  = regexp.MustCompile(`^/v(\d+)`)
+)
+
+// buildOpenAPIDocument describes every route registered on routes. A route
+// without an entry in openAPIOperations is an error, so the document
+// cannot silently fall behind the router.
+func buildOpenAPIDocument(routes chi.Routes, version string) ([]byte, error) {
+	schemas := newSchemaGenerator()
+	problem := schemas.schema(reflect.TypeFor[Problem]())
+	paths := map[string]map[string]any{}
+	var missing []string
+
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/")
+		match := versionPrefix.FindStringSubmatch(route)
+		if match == nil {
+			return nil
+		}
+		apiVersion, _ := strconv.Atoi(match[1])
+		pattern := strings.TrimPrefix(route, match[0])
+
+		op, ok := openAPIOperations[method+" "+pattern]
+		if !ok {
+			missing = append(missing, method+" "+route)
+			return nil
+		}
+
+		path := pathParamRe.ReplaceAllString(route, "{$1}")
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(method)] = op.describe(schemas, route, apiVersion)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return nil, fmt.Errorf("openapi: no operation described for %s", strings.Join(missing, ", "))
+	}
+
+	return json.MarshalIndent(map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "Prepilot API",
+			"version":     version,
+			"description": "Errors are returned as application/problem+json. /v2 wraps every response in the data/meta/links envelope. Responses carry RateLimit-* headers, and 429 responses a Retry-After header.",
+		},
+		"paths":    paths,
+		"security": []any{map[string]any{"bearerAuth": []string{}}},
+		"components": map[string]any{
+			"schemas": schemas.schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type": "http", "scheme": "bearer",
+					"description": "A Clerk session JWT, or a personal access token (pp_pat_...) limited to the scopes it was granted",
+				},
+			},
+			"responses": map[string]any{
+				"Problem": map[string]any{
+					"description": "Problem details",
+					"content":     map[string]any{"application/problem+json": map[string]any{"schema": problem}},
+				},
+			},
+		},
+	}, "", "  ")
+}
+
+// describe builds the OpenAPI operation object for a route of one version
+func (op openAPIOperation) describe(schemas *schemaGenerator, route string, apiVersion int) map[string]any {
+	operation := map[string]any{
+		"summary": op.Summary,
+		"tags":    []string{op.Tag},
+		"responses": map[string]any{
+			"default": map[string]any{"$ref": "#/components/responses/Problem"},
+		},
+	}
+	if op.Public {
+		operation["security"] = []any{}
+	}
+
+	var params []any
+	for _, match := range pathParamRe.FindAllStringSubmatch(route, -1) {
+		params = append(params, map[string]any{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]any{"type": "string"},
+		})
+	}
+	query := op.Query
+	if op.Paginated {
+		query = append(slices.Clone(query),
+			openAPIParam{Name: "limit", Type: "integer", Description: "Maximum number of items to return"},
+			openAPIParam{Name: "offset", Type: "integer", Description: "Number of items to skip"},
+		)
+	}
+	for _, param := range query {
+		params = append(params, map[string]any{
+			"name": param.Name, "in": "query", "description": param.Description, "schema": map[string]any{"type": param.Type},
+		})
+	}
+	if len(params) > 0 {
+		operation["parameters"] = params
+	}
+
+	if op.Request != nil {
+		mediaType := "application/json"
+		if op.MergePatch {
+			mediaType = mergePatchContentType
+		}
+		operation["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{mediaType: map[string]any{"schema": schemas.schema(reflect.TypeOf(op.Request))}},
+		}
+	}
+
+	status, response := op.successResponse(schemas, apiVersion)
+	operation["responses"].(map[string]any)[strconv.Itoa(status)] = response
+	return operation
+}
+
+// successResponse is the status and response object of a successful call
+func (op openAPIOperation) successResponse(schemas *schemaGenerator, apiVersion int) (int, map[string]any) {
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	response := map[string]any{"description": http.StatusText(status)}
+
+	var schema map[string]any
+	switch {
+	case op.Deleted && apiVersion == 1:
+		schema = schemas.schema(reflect.TypeFor[map[string]string]())
+	case op.Deleted:
+		return http.StatusNoContent, map[string]any{"description": http.StatusText(http.StatusNoContent)}
+	case op.MediaType != "":
+		response["content"] = map[string]any{op.MediaType: map[string]any{
+			"schema": map[string]any{"type": "string"},
+		}}
+		return status, response
+	case op.Response == nil:
+		return status, response
+	case op.Legacy != nil && apiVersion == 1:
+		schema = schemas.schema(reflect.TypeOf(op.Legacy))
+	default:
+		properties := map[string]any{
+			"data":  schemas.schema(reflect.TypeOf(op.Response)),
+			"links": schemas.schema(reflect.TypeFor[Links]()),
+		}
+		if op.Paginated {
+			properties["meta"] = schemas.schema(reflect.TypeFor[PageMeta]())
+		}
+		schema = map[string]any{"type": "object", "required": []string{"data"}, "properties": properties}
+	}
+
+	response["content"] = map[string]any{"application/json": map[string]any{"schema": schema}}
+	return status, response
+}
+
+// schemaGenerator turns Go types into JSON Schemas, collecting named
+// structs as components
+type schemaGenerator struct {
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+func newSchemaGenerator() *schemaGenerator {
+	return &schemaGenerator{schemas: map[string]any{}, names: map[reflect.Type]string{}}
+}
+
+// schema returns the schema for t, or a reference to it for named structs
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t == uuidType:
+		return map[string]any{"type": "string", "format": "uuid"}
+	case t == rawMessageType:
+		return map[string]any{}
+	case isOptional(t):
+		value, _ := t.FieldByName("Value")
+		return nullable(g.schema(value.Type))
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return nullable(g.schema(t.Elem()))
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int32, reflect.Uint32:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		return g.ref(t)
+	}
+	return map[string]any{}
+}
+
+// ref registers a named struct as a component and returns a reference
+func (g *schemaGenerator) ref(t reflect.Type) map[string]any {
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		if _, taken := g.schemas[name]; taken {
+			// Same name in another package, e.g. store.StudyPlan
+			name = strings.ReplaceAll(t.String(), ".", "_")
+		}
+		g.names[t] = name
+		// Reserve the name before generating so recursive types terminate
+		g.schemas[name] = map[string]any{}
+		g.schemas[name] = g.object(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// object builds the schema of a struct from its JSON and validate tags.
+// Embedded structs without a JSON name are flattened like encoding/json does.
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+
+	var addFields func(t reflect.Type)
+	addFields = func(t reflect.Type) {
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+			if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+				addFields(field.Type)
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+
+			schema := g.schema(field.Type)
+			if applyValidation(schema, field.Tag.Get("validate"), field.Type) {
+				required = append(required, name)
+			}
+			properties[name] = schema
+		}
+	}
+	addFields(t)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// applyValidation adds the constraints of a validate tag to a schema and
+// reports whether the field is required. Tags after dive apply to items.
+func applyValidation(schema map[string]any, tag string, t reflect.Type) (required bool) {
+	if tag == "" {
+		return false
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	rules := strings.Split(tag, ",")
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		if tags, ok := validationAliases[name]; ok {
+			required = applyValidation(schema, tags, t) || required
+			continue
+		}
+		switch name {
+		case "required":
+			required = true
+		case "notblank":
+			schema["pattern"] = `\S`
+		case "dive":
+			if items, ok := schema["items"].(map[string]any); ok {
+				applyValidation(items, strings.Join(rules[i+1:], ","), t.Elem())
+			}
+			return required
+		case "email":
+			schema["format"] = "email"
+		case "url", "http_url":
+			schema["format"] = "uri"
+		case "uuid", "uuid4":
+			schema["format"] = "uuid"
+		case "startswith":
+			schema["pattern"] = "^" + regexp.QuoteMeta(param)
+		case "oneof":
+			values := strings.Fields(param)
+			enum := make([]any, len(values))
+			for j, value := range values {
+				enum[j] = value
+				if number, err := strconv.ParseFloat(value, 64); err == nil && t.Kind() != reflect.String {
+					enum[j] = number
+				}
+			}
+			schema["enum"] = enum
+		case "min", "gte":
+			setLimit(schema, t, "min", param)
+		case "max", "lte":
+			setLimit(schema, t, "max", param)
+		case "gt":
+			setLimit(schema, t, "exclusiveMin", param)
+		case "lt":
+			setLimit(schema, t, "exclusiveMax", param)
+		case "len":
+			setLimit(schema, t, "min", param)
+			setLimit(schema, t, "max", param)
+		}
+	}
+	return required
+}
+
+// setLimit sets a length, size or value bound depending on the field kind
+func setLimit(schema map[string]any, t reflect.Type, bound, param string) {
+	number, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	var key string
+	switch t.Kind() {
+	case reflect.String:
+		key = map[string]string{"min": "minLength", "max": "maxLength"}[bound]
+	case reflect.Slice, reflect.Array:
+		key = map[string]string{"min": "minItems", "max": "maxItems"}[bound]
+	case reflect.Map:
+		key = map[string]string{"min": "minProperties", "max": "maxProperties"}[bound]
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		key = map[string]string{
+			"min": "minimum", "max": "maximum", "exclusiveMin": "exclusiveMinimum", "exclusiveMax": "exclusiveMaximum",
+		}[bound]
+	}
+	if key != "" {
+		schema[key] = number
+	}
+}
+
+// nullable allows null in addition to the given schema
+func nullable(schema map[string]any) map[string]any {
+	if typ, ok := schema["type"].(string); ok {
+		schema["type"] = []string{typ, "null"}
+		return schema
+	}
+	return map[string]any{"anyOf": []any{schema, map[string]any{"type": "null"}}}
+}
+
+// isOptional reports whether t is an instantiation of Optional
+func isOptional(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct &&
+		t.PkgPath() == reflect.TypeFor[Optional[string]]().PkgPath() &&
+		strings.HasPrefix(t.Name(), "Optional[")
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"net/http"
+)
+
+// apiDocsPage renders the OpenAPI document with Redoc
+const apiDocsPage = `<!DOCTYPE html>
+<html>
+<head>
+  <title>Prepilot API</title>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+</head>
+<body>
+  <redoc spec-url="/v1/openapi.json"></redoc>
+  <script src="https://cdn.redoc.ly/redoc/latest/bundles/redoc.standalone.js"></script>
+</body>
+</html>
+`
+
+// OpenAPIHandler serves the OpenAPI document built from the routes
+func (app *Application) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(app.openAPIDocument)
+}
+
+// APIDocsHandler serves an interactive reference for the OpenAPI document.
+// It is only routed in development.
+func (app *Application) APIDocsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(apiDocsPage))
+}
@@ -0,0 +1,537 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// openAPIOperations describes every route in RegisterRoutes. Adding a route
+// without an entry here fails at startup.
+var openAPIOperations = map[string]openAPIOperation{
+	// Meta
+	"GET /health": {
+		Summary: "Check that the API is up", Tag: "meta", Public: true,
+		Response: map[string]string{},
+	},
+	"GET /openapi.json": {
+		Summary: "This OpenAPI document", Tag: "meta", Public: true,
+		MediaType: "application/json",
+	},
+	"GET /docs": {
+		Summary: "Interactive API reference (development only)", Tag: "meta", Public: true,
+		MediaType: "text/html",
+	},
+
+	// Auth and Clerk
+	"POST /webhooks/clerk": {
+		Summary: "Receive a Clerk webhook event signed by Svix", Tag: "clerk", Public: true,
+		Request: ClerkWebhookEvent{}, Response: map[string]string{}, Legacy: map[string]string{},
+	},
+	"POST /auth/register": {
+		Summary: "Deprecated: registration is handled by Clerk", Tag: "auth", Public: true,
+		Request: RegisterRequest{},
+	},
+
+	// User
+	"POST /user/initialize": {
+		Summary: "Create the user on first sign-in and return the profile", Tag: "user",
+		Response: UserResponse{}, Legacy: map[string]any{},
+	},
+	"GET /user/profile": {
+		Summary: "Get the signed-in user's profile", Tag: "user",
+		Response: UserResponse{}, Legacy: map[string]any{},
+	},
+	"PATCH /user/profile": {
+		Summary: "Update the signed-in user's settings", Tag: "user",
+		Request: UpdateUserProfileRequest{}, Response: UserResponse{}, Legacy: UserResponse{},
+	},
+	"POST /user/data-export": {
+		Summary: "Start an export of all the user's data", Tag: "user",
+		Response: DataExportResponse{}, Status: http.StatusAccepted,
+	},
+	"GET /user/data-exports": {
+		Summary: "List the user's data exports", Tag: "user",
+		Response: []DataExportResponse{},
+	},
+	"GET /user/data-exports/{id}": {
+		Summary: "Get a data export", Tag: "user",
+		Response: DataExportResponse{},
+	},
+	"GET /user/data-exports/{id}/download": {
+		Summary: "Download a completed data export", Tag: "user",
+		MediaType: "application/zip",
+	},
+	"POST /user/tokens": {
+		Summary: "Create a personal access token; the token is only shown once", Tag: "user",
+		Request: CreateAccessTokenRequest{}, Response: AccessTokenResponse{}, Status: http.StatusCreated,
+	},
+	"GET /user/tokens": {
+		Summary: "List the user's personal access tokens", Tag: "user",
+		Response: []AccessTokenResponse{},
+	},
+	"DELETE /user/tokens/{id}": {
+		Summary: "Revoke a personal access token", Tag: "user",
+		Response: AccessTokenResponse{},
+	},
+	"GET /user/plan-invites": {
+		Summary: "List pending study plan invites sent to the user's email", Tag: "user",
+		Response: []PlanInviteResponse{},
+	},
+	"POST /user/plan-invites/accept": {
+		Summary: "Join a study plan with an invite link token", Tag: "user",
+		Request: AcceptPlanInviteRequest{}, Response: StudyPlanResponse{},
+	},
+	"POST /user/plan-invites/{id}/accept": {
+		Summary: "Join a study plan with an invite sent to the user's email", Tag: "user",
+		Response: StudyPlanResponse{},
+	},
+
+	// Study plans
+	"POST /study-plans": {
+		Summary: "Create a study plan", Tag: "study-plans",
+		Request: CreateStudyPlanRequest{}, Response: StudyPlanResponse{}, Legacy: store.StudyPlan{},
+		Status: http.StatusCreated,
+	},
+	"GET /study-plans": {
+		Summary: "List the study plans the user created or was invited to", Tag: "study-plans",
+		Response: []StudyPlanResponse{},
+		Legacy: struct {
+			Data []store.StudyPlan `json:"data"`
+		}{},
+	},
+	"GET /study-plans/{id}": {
+		Summary: "Get a study plan", Tag: "study-plans",
+		Response: StudyPlanResponse{}, Legacy: store.StudyPlan{},
+	},
+	"PUT /study-plans/{id}": {
+		Summary: "Replace a study plan", Tag: "study-plans",
+		Request: UpdateStudyPlanRequest{}, Response: StudyPlanResponse{}, Legacy: StudyPlanResponse{},
+	},
+	"PATCH /study-plans/{id}": {
+		Summary: "Update some fields of a study plan", Tag: "study-plans",
+		Request: PatchStudyPlanRequest{}, MergePatch: true, Response: StudyPlanResponse{}, Legacy: StudyPlanResponse{},
+	},
+	"DELETE /study-plans/{id}": {
+		Summary: "Move a study plan and its tasks to the trash", Tag: "study-plans",
+		Deleted: true,
+	},
+	"GET /study-plans/{id}/tasks": {
+		Summary: "List the tasks of a study plan", Tag: "study-plans",
+		Response: []StudyTaskResponse{}, Legacy: []StudyTaskResponse{},
+	},
+	"GET /study-plans/{id}/members": {
+		Summary: "List the members of a study plan", Tag: "study-plans",
+		Response: []PlanMemberResponse{},
+	},
+	"PUT /study-plans/{id}/members/{userID}": {
+		Summary: "Change a member's role on a study plan; owners only", Tag: "study-plans",
+		Request: SetPlanMemberRoleRequest{}, Response: map[string]string{},
+	},
+	"DELETE /study-plans/{id}/members/{userID}": {
+		Summary: "Remove a member from a study plan, or leave it", Tag: "study-plans",
+		Deleted: true,
+	},
+	"POST /study-plans/{id}/invites": {
+		Summary: "Invite someone to a study plan by email or link; a link token is only shown once", Tag: "study-plans",
+		Request: CreatePlanInviteRequest{}, Response: PlanInviteResponse{}, Status: http.StatusCreated,
+	},
+	"GET /study-plans/{id}/invites": {
+		Summary: "List the pending invites of a study plan; owners only", Tag: "study-plans",
+		Response: []PlanInviteResponse{},
+	},
+	"DELETE /study-plans/{id}/invites/{inviteID}": {
+		Summary: "Revoke a study plan invite", Tag: "study-plans",
+		Deleted: true,
+	},
+	"POST /study-plans/{id}/share": {
+		Summary: "Create a public read-only link to a study plan; the token is only shown once", Tag: "study-plans",
+		Request: CreatePlanShareLinkRequest{}, Response: PlanShareLinkResponse{}, Status: http.StatusCreated,
+	},
+	"GET /study-plans/{id}/share": {
+		Summary: "List the share links of a study plan with their view counts; owners only", Tag: "study-plans",
+		Response: []PlanShareLinkResponse{},
+	},
+	"DELETE /study-plans/{id}/share/{linkID}": {
+		Summary: "Revoke a study plan share link", Tag: "study-plans",
+		Deleted: true,
+	},
+
+	// Public plans
+	"GET /public/plans/{token}": {
+		Summary: "View a shared study plan without signing in", Tag: "public", Public: true,
+		Response: PublicPlanResponse{},
+	},
+	"POST /public/plans/{token}/copy": {
+		Summary: "Copy a shared study plan and its tasks into your account", Tag: "public",
+		Response: StudyPlanResponse{}, Status: http.StatusCreated,
+	},
+
+	// Study tasks
+	"POST /study-tasks": {
+		Summary: "Create a study task", Tag: "study-tasks",
+		Request: CreateStudyTaskRequest{}, Response: StudyTaskResponse{}, Legacy: StudyTaskResponse{},
+		Status: http.StatusCreated,
+	},
+	"GET /study-tasks": {
+		Summary: "List the tasks of every study plan the user is a member of", Tag: "study-tasks",
+		Query: []openAPIParam{
+			{Name: "plan_id", Type: "string", Description: "Only tasks of this plan"},
+			{Name: "priority", Type: "integer", Description: "Only tasks with this priority; requires plan_id"},
+			{Name: "status", Type: "boolean", Description: "Only completed or open tasks; requires plan_id"},
+		},
+		Response: []StudyTaskResponse{},
+	},
+	"GET /study-tasks/{id}": {
+		Summary: "Get a study task", Tag: "study-tasks",
+		Response: StudyTaskResponse{}, Legacy: StudyTaskResponse{},
+	},
+	"PUT /study-tasks/{id}": {
+		Summary: "Replace a study task", Tag: "study-tasks",
+		Request: UpdateStudyTaskRequest{}, Response: StudyTaskResponse{}, Legacy: StudyTaskResponse{},
+	},
+	"PATCH /study-tasks/{id}": {
+		Summary: "Update some fields of a study task", Tag: "study-tasks",
+		Request: PatchStudyTaskRequest{}, MergePatch: true, Response: StudyTaskResponse{}, Legacy: StudyTaskResponse{},
+	},
+	"DELETE /study-tasks/{id}": {
+		Summary: "Move a study task to the trash", Tag: "study-tasks",
+		Deleted: true,
+	},
+	"PATCH /study-tasks/{id}/status": {
+		Summary: "Complete or reopen a study task for the signed-in user", Tag: "study-tasks",
+		Request: UpdateStudyTaskStatusRequest{}, Response: StudyTaskResponse{}, Legacy: map[string]string{},
+	},
+
+	// Classrooms
+	"POST /classrooms": {
+		Summary: "Create a classroom around one of your study plans", Tag: "classrooms",
+		Request: CreateClassroomRequest{}, Response: ClassroomResponse{}, Status: http.StatusCreated,
+	},
+	"GET /classrooms": {
+		Summary: "List the classrooms the user teaches or attends", Tag: "classrooms",
+		Response: []ClassroomResponse{},
+	},
+	"POST /classrooms/join": {
+		Summary: "Join a classroom with its code", Tag: "classrooms",
+		Request: JoinClassroomRequest{}, Response: ClassroomResponse{},
+	},
+	"GET /classrooms/{id}": {
+		Summary: "Get a classroom", Tag: "classrooms",
+		Response: ClassroomResponse{},
+	},
+	"PUT /classrooms/{id}": {
+		Summary: "Rename a classroom or change its description", Tag: "classrooms",
+		Request: UpdateClassroomRequest{}, Response: ClassroomResponse{},
+	},
+	"DELETE /classrooms/{id}": {
+		Summary: "Delete a classroom; students keep their copies of the plan", Tag: "classrooms",
+		Deleted: true,
+	},
+	"POST /classrooms/{id}/join": {
+		Summary: "Join a classroom of one of your Clerk organizations without a code", Tag: "classrooms",
+		Response: ClassroomResponse{},
+	},
+	"POST /classrooms/{id}/join-code": {
+		Summary: "Replace the join code of a classroom", Tag: "classrooms",
+		Response: ClassroomResponse{},
+	},
+	"POST /classrooms/{id}/publish": {
+		Summary: "Copy the template plan to students and update their copies where unedited", Tag: "classrooms",
+		Response: PublishClassroomResponse{},
+	},
+	"GET /classrooms/{id}/students": {
+		Summary: "List the students of a classroom", Tag: "classrooms",
+		Response: []ClassroomStudentResponse{},
+	},
+	"DELETE /classrooms/{id}/students/{userID}": {
+		Summary: "Remove a student from a classroom, or leave it", Tag: "classrooms",
+		Deleted: true,
+	},
+	"GET /classrooms/{id}/progress": {
+		Summary: "Progress of every student on the classroom plan", Tag: "classrooms",
+		Response: ClassroomProgressResponse{},
+	},
+
+	// Plan templates
+	"POST /templates": {
+		Summary: "Create a draft study plan template", Tag: "templates",
+		Request: PlanTemplateRequest{}, Response: PlanTemplateResponse{}, Status: http.StatusCreated,
+	},
+	"GET /templates": {
+		Summary: "Search published templates and your drafts, most used first", Tag: "templates",
+		Query: []openAPIParam{
+			{Name: "q", Type: "string", Description: "Part of the title, subject or description"},
+			{Name: "tag", Type: "string", Description: "Only templates with this tag"},
+			{Name: "author", Type: "string", Description: "Only templates by this user; me for your own"},
+			{Name: "official", Type: "boolean", Description: "Only official or only community templates"},
+			{Name: "sort", Type: "string", Description: "popular (default) or newest"},
+		},
+		Response: []PlanTemplateResponse{}, Paginated: true,
+	},
+	"GET /templates/tags": {
+		Summary: "List the tags of published templates with how many use each", Tag: "templates",
+		Response: []PlanTemplateTagResponse{},
+	},
+	"GET /templates/{id}": {
+		Summary: "Get a template with its tasks", Tag: "templates",
+		Response: PlanTemplateResponse{},
+	},
+	"PUT /templates/{id}": {
+		Summary: "Replace a template and its tasks; authors only", Tag: "templates",
+		Request: PlanTemplateRequest{}, Response: PlanTemplateResponse{},
+	},
+	"DELETE /templates/{id}": {
+		Summary: "Delete a template; plans created from it are kept", Tag: "templates",
+		Deleted: true,
+	},
+	"POST /templates/{id}/publish": {
+		Summary: "List a template in the catalogue", Tag: "templates",
+		Response: PlanTemplateResponse{},
+	},
+	"DELETE /templates/{id}/publish": {
+		Summary: "Take a template out of the catalogue", Tag: "templates",
+		Response: PlanTemplateResponse{},
+	},
+	"POST /templates/{id}/use": {
+		Summary: "Create a study plan from a template, with tasks anchored on the exam date", Tag: "templates",
+		Request: UsePlanTemplateRequest{}, Response: StudyPlanResponse{}, Status: http.StatusCreated,
+	},
+
+	// Subjects
+	"POST /subjects": {
+		Summary: "Create a subject, or a unit or topic under parent_id", Tag: "subjects",
+		Request: SubjectRequest{}, Response: SubjectResponse{}, Status: http.StatusCreated,
+	},
+	"GET /subjects": {
+		Summary: "List your subjects, units and topics with their stats", Tag: "subjects",
+		Response: []SubjectResponse{},
+	},
+	"GET /subjects/{id}": {
+		Summary: "Get a subject with its stats", Tag: "subjects",
+		Response: SubjectResponse{},
+	},
+	"PUT /subjects/{id}": {
+		Summary: "Rename, restyle or move a subject; renaming a subject renames it on its plans", Tag: "subjects",
+		Request: SubjectRequest{}, Response: SubjectResponse{},
+	},
+	"DELETE /subjects/{id}": {
+		Summary: "Delete a subject with its units and topics; plans and tasks lose the link", Tag: "subjects",
+		Deleted: true,
+	},
+
+	// Exams
+	"POST /exams": {
+		Summary: "Create an exam, linked to the plans preparing for it", Tag: "exams",
+		Request: ExamRequest{}, Response: ExamResponse{}, Status: http.StatusCreated,
+	},
+	"GET /exams": {
+		Summary: "List your exams by date", Tag: "exams",
+		Query: []openAPIParam{
+			{Name: "subject_id", Type: "string", Description: "Only exams in this subject"},
+			{Name: "plan_id", Type: "string", Description: "Only exams this plan prepares for"},
+			{Name: "upcoming", Type: "boolean", Description: "Only upcoming or only past exams"},
+		},
+		Response: []ExamResponse{},
+	},
+	"GET /exams/countdown": {
+		Summary: "Upcoming exams, soonest first, with the time left before each", Tag: "exams",
+		Response: []ExamCountdownResponse{},
+	},
+	"GET /exams/insights": {
+		Summary: "Relate the study done for past exams to their scores", Tag: "exams",
+		Response: ExamInsightsResponse{},
+	},
+	"GET /exams/{id}": {
+		Summary: "Get an exam with its plans and preparation", Tag: "exams",
+		Response: ExamResponse{},
+	},
+	"PUT /exams/{id}": {
+		Summary: "Replace an exam and its plans, e.g. to record the actual score", Tag: "exams",
+		Request: ExamRequest{}, Response: ExamResponse{},
+	},
+	"DELETE /exams/{id}": {
+		Summary: "Delete an exam; the plans preparing for it are kept", Tag: "exams",
+		Deleted: true,
+	},
+
+	// Goals
+	"POST /goals": {
+		Summary: "Create a goal, such as a number of study minutes every week", Tag: "goals",
+		Request: GoalRequest{}, Response: GoalResponse{}, Status: http.StatusCreated,
+	},
+	"GET /goals": {
+		Summary: "List your goals with their progress in the current period", Tag: "goals",
+		Response: []GoalResponse{},
+	},
+	"GET /goals/{id}": {
+		Summary: "Get a goal with its progress in the current period", Tag: "goals",
+		Response: GoalResponse{},
+	},
+	"PUT /goals/{id}": {
+		Summary: "Change the title, target, plan, subject or end date of a goal", Tag: "goals",
+		Request: GoalRequest{}, Response: GoalResponse{},
+	},
+	"DELETE /goals/{id}": {
+		Summary: "Delete a goal with its history", Tag: "goals",
+		Deleted: true,
+	},
+	"GET /goals/{id}/history": {
+		Summary: "Ended periods of a goal, latest first, met or missed", Tag: "goals",
+		Response: []GoalProgressResponse{}, Paginated: true,
+	},
+
+	// Achievements
+	"GET /achievements": {
+		Summary: "Every badge with your progress towards it and when it was awarded", Tag: "achievements",
+		Response: []AchievementResponse{},
+	},
+
+	// Outbound webhooks
+	"POST /webhook-endpoints": {
+		Summary: "Register a webhook endpoint", Tag: "webhooks",
+		Request: CreateWebhookEndpointRequest{}, Response: WebhookEndpointResponse{}, Status: http.StatusCreated,
+	},
+	"GET /webhook-endpoints": {
+		Summary: "List the user's webhook endpoints", Tag: "webhooks",
+		Response: []WebhookEndpointResponse{},
+	},
+	"GET /webhook-endpoints/{id}": {
+		Summary: "Get a webhook endpoint", Tag: "webhooks",
+		Response: WebhookEndpointResponse{},
+	},
+	"PUT /webhook-endpoints/{id}": {
+		Summary: "Update a webhook endpoint", Tag: "webhooks",
+		Request: UpdateWebhookEndpointRequest{}, Response: WebhookEndpointResponse{},
+	},
+	"DELETE /webhook-endpoints/{id}": {
+		Summary: "Delete a webhook endpoint", Tag: "webhooks",
+		Deleted: true,
+	},
+	"POST /webhook-endpoints/{id}/rotate-secret": {
+		Summary: "Replace the signing secret of a webhook endpoint", Tag: "webhooks",
+		Response: WebhookEndpointResponse{},
+	},
+	"GET /webhook-endpoints/{id}/deliveries": {
+		Summary: "List the deliveries of a webhook endpoint", Tag: "webhooks",
+		Response: []WebhookDeliveryResponse{}, Paginated: true,
+	},
+	"GET /webhook-endpoints/{id}/deliveries/{deliveryID}": {
+		Summary: "Get a delivery with its attempts", Tag: "webhooks",
+		Response: WebhookDeliveryResponse{},
+	},
+	"POST /webhook-endpoints/{id}/deliveries/{deliveryID}/redeliver": {
+		Summary: "Send a delivery again", Tag: "webhooks",
+		Response: WebhookDeliveryResponse{}, Status: http.StatusAccepted,
+	},
+
+	// Activity
+	"GET /activity": {
+		Summary: "List the user's activity, newest first", Tag: "activity",
+		Query: []openAPIParam{
+			{Name: "entity_type", Type: "string", Description: "study_plan, study_task, user, access_token, classroom or plan_template"},
+			{Name: "entity_id", Type: "string", Description: "Only changes to this entity"},
+			{Name: "action", Type: "string", Description: "Only this action, e.g. task.updated"},
+			{Name: "since", Type: "string", Description: "RFC 3339 time or date"},
+			{Name: "until", Type: "string", Description: "RFC 3339 time or date"},
+		},
+		Response: []ActivityResponse{}, Paginated: true,
+	},
+
+	// Trash
+	"GET /trash": {
+		Summary: "List deleted plans and tasks", Tag: "trash",
+		Response: TrashResponse{},
+	},
+	"POST /trash/plans/{id}/restore": {
+		Summary: "Restore a deleted plan with its tasks", Tag: "trash",
+		Response: StudyPlanResponse{}, Legacy: StudyPlanResponse{},
+	},
+	"DELETE /trash/plans/{id}": {
+		Summary: "Permanently delete a plan from the trash", Tag: "trash",
+		Deleted: true,
+	},
+	"POST /trash/tasks/{id}/restore": {
+		Summary: "Restore a deleted task", Tag: "trash",
+		Response: StudyTaskResponse{}, Legacy: StudyTaskResponse{},
+	},
+	"DELETE /trash/tasks/{id}": {
+		Summary: "Permanently delete a task from the trash", Tag: "trash",
+		Deleted: true,
+	},
+
+	// Real-time events
+	"GET /events/stream": {
+		Summary: "Stream the user's events as Server-Sent Events", Tag: "events",
+		MediaType: "text/event-stream",
+	},
+	"POST /events/timer": {
+		Summary: "Broadcast the study timer to the user's other devices", Tag: "events",
+		Request: TimerTickRequest{}, Status: http.StatusNoContent,
+	},
+
+	// Admin
+	"GET /admin/users": {
+		Summary: "Search users (support or admin)", Tag: "admin",
+		Query: []openAPIParam{
+			{Name: "q", Type: "string", Description: "Part of the email or name, or a Clerk ID"},
+			{Name: "role", Type: "string", Description: "user, support or admin"},
+			{Name: "banned", Type: "boolean", Description: "Only banned or not banned users"},
+		},
+		Response: []AdminUserResponse{}, Paginated: true,
+	},
+	"GET /admin/users/{id}": {
+		Summary: "Get a user by Clerk ID (support or admin)", Tag: "admin",
+		Response: AdminUserResponse{},
+	},
+	"GET /admin/users/{id}/study-plans": {
+		Summary: "List a user's study plans, read-only (support or admin)", Tag: "admin",
+		Response: []StudyPlanResponse{},
+	},
+	"GET /admin/users/{id}/study-plans/{planID}/tasks": {
+		Summary: "List the tasks of a user's study plan, read-only (support or admin)", Tag: "admin",
+		Response: []StudyTaskResponse{},
+	},
+	"PUT /admin/users/{id}/role": {
+		Summary: "Change a user's role", Tag: "admin",
+		Request: SetUserRoleRequest{}, Response: AdminUserResponse{},
+	},
+	"POST /admin/users/{id}/ban": {
+		Summary: "Ban a user", Tag: "admin",
+		Response: AdminUserResponse{},
+	},
+	"POST /admin/users/{id}/unban": {
+		Summary: "Lift a user's ban", Tag: "admin",
+		Response: AdminUserResponse{},
+	},
+	"POST /admin/users/{id}/impersonate": {
+		Summary: "Start acting as a user; send the session ID in X-Impersonation-Session", Tag: "admin",
+		Request: StartImpersonationRequest{}, Response: ImpersonationResponse{}, Status: http.StatusCreated,
+	},
+	"GET /admin/impersonations": {
+		Summary: "List impersonation sessions, the audit trail of impersonation", Tag: "admin",
+		Query: []openAPIParam{
+			{Name: "user_id", Type: "string", Description: "Only sessions impersonating this user"},
+		},
+		Response: []ImpersonationResponse{}, Paginated: true,
+	},
+	"DELETE /admin/impersonations/{id}": {
+		Summary: "End an impersonation session", Tag: "admin",
+		Response: ImpersonationResponse{},
+	},
+	"GET /admin/webhook-events": {
+		Summary: "List inbound Clerk webhook events", Tag: "admin",
+		Query: []openAPIParam{
+			{Name: "status", Type: "string", Description: "processing, processed or failed"},
+		},
+		Response: []WebhookEventResponse{}, Paginated: true,
+	},
+	"GET /admin/webhook-events/{id}": {
+		Summary: "Get an inbound event with its payload", Tag: "admin",
+		Response: WebhookEventResponse{},
+	},
+	"POST /admin/webhook-events/{id}/replay": {
+		Summary: "Process a failed or abandoned inbound event again (admin)", Tag: "admin",
+		Response: WebhookEventResponse{},
+	},
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newTestRouter registers every route, including the development-only ones
+func newTestRouter(t *testing.T) (*Application, chi.Router) {
+	t.Helper()
+	app := &Application{Config: Config{Env: "development"}}
+	r := chi.NewRouter()
+	if err := app.RegisterRoutes(r); err != nil {
+		t.Fatalf("RegisterRoutes: %v", err)
+	}
+	return app, r
+}
+
+func TestOpenAPIOperationsMatchRoutes(t *testing.T) {
+	_, r := newTestRouter(t)
+
+	registered := map[string]bool{}
+	err := chi.Walk(r, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		route = strings.TrimSuffix(route, "/")
+		match := versionPrefix.FindStringSubmatch(route)
+		if match == nil {
+			t.Errorf("%s %s is not under a version prefix", method, route)
+			return nil
+		}
+		key := method + " " + strings.TrimPrefix(route, match[0])
+		registered[key] = true
+
+		op, ok := openAPIOperations[key]
+		switch {
+		case !ok:
+			t.Errorf("%s %s has no OpenAPI operation", method, route)
+		case op.Summary == "" || op.Tag == "":
+			t.Errorf("%s needs a summary and a tag", key)
+		case op.Deleted && (op.Response != nil || op.Legacy != nil):
+			t.Errorf("%s is described as deleted but also has a response", key)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk: %v", err)
+	}
+
+	for key := range openAPIOperations {
+		if !registered[key] {
+			t.Errorf("%s is described but not registered", key)
+		}
+	}
+}
+
+func TestOpenAPITaskStatusResponse(t *testing.T) {
+	app, _ := newTestRouter(t)
+
+	var document struct {
+		Paths map[string]map[string]struct {
+			Responses map[string]struct {
+				Content map[string]struct {
+					Schema map[string]any `json:"schema"`
+				} `json:"content"`
+			} `json:"responses"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(app.openAPIDocument, &document); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/v1/study-tasks/{id}/status", want: `"additionalProperties":{"type":"string"}`},
+		{path: "/v2/study-tasks/{id}/status", want: `"#/components/schemas/StudyTaskResponse"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			schema := document.Paths[tt.path]["patch"].Responses["200"].Content["application/json"].Schema
+			encoded, _ := json.Marshal(schema)
+			if !strings.Contains(string(encoded), tt.want) {
+				t.Errorf("schema = %s, want it to contain %s", encoded, tt.want)
+			}
+		})
+	}
+}
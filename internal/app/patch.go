@@ -0,0 +1,85 @@
+package app
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// mergePatchContentType is the media type for RFC 7396 JSON Merge Patch
+const mergePatchContentType = "application/merge-patch+json"
+
+// Optional is a field of a merge patch. Set is false when the field was
+// absent, which leaves the column unchanged. Null is true when it was an
+// explicit null, which clears the column.
+type Optional[T any] struct {
+	Set   bool
+	Null  bool
+	Value T
+}
+
+// UnmarshalJSON is only called for fields present in the document
+func (o *Optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		o.Null = true
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
+
+// HasValue reports whether the field was given a non-null value
+func (o Optional[T]) HasValue() bool {
+	return o.Set && !o.Null
+}
+
+// requireNonNull rejects an explicit null for a column that cannot be cleared
+func requireNonNull[T any](name string, o Optional[T]) error {
+	if o.Set && o.Null {
+		return &FieldError{Field: name, Code: "required", Message: "cannot be null"}
+	}
+	return nil
+}
+
+func optionalToNullString(o Optional[string]) sql.NullString {
+	return sql.NullString{String: o.Value, Valid: o.HasValue()}
+}
+
+func optionalToNullTime(o Optional[time.Time]) sql.NullTime {
+	return sql.NullTime{Time: o.Value, Valid: o.HasValue()}
+}
+
+func optionalToNullBool(o Optional[bool]) sql.NullBool {
+	return sql.NullBool{Bool: o.Value, Valid: o.HasValue()}
+}
+
+func optionalToNullInt32(o Optional[int32]) sql.NullInt32 {
+	return sql.NullInt32{Int32: o.Value, Valid: o.HasValue()}
+}
+
+func optionalToNullUUID(o Optional[uuid.UUID]) uuid.NullUUID {
+	return uuid.NullUUID{UUID: o.Value, Valid: o.HasValue()}
+}
+
+// readMergePatch decodes a merge patch body. Plain application/json is
+// accepted as well since most clients send it by default.
+func (app *Application) readMergePatch(w http.ResponseWriter, r *http.Request, data any) (ok bool) {
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || (mediaType != mergePatchContentType && mediaType != "application/json") {
+			app.writeJSONError(w, r, http.StatusUnsupportedMediaType, "Content-Type must be "+mergePatchContentType)
+			return false
+		}
+	}
+
+	if err := app.readJSON(w, r, data); err != nil {
+		app.badRequestError(w, r, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestOptionalUnmarshal(t *testing.T) {
+	type patch struct {
+		Title Optional[string] `json:"title"`
+		Count Optional[int32]  `json:"count"`
+	}
+
+	tests := []struct {
+		name      string
+		body      string
+		wantTitle Optional[string]
+		wantCount Optional[int32]
+		wantErr   bool
+	}{
+		{name: "absent", body: `{}`},
+		{name: "null", body: `{"title": null}`, wantTitle: Optional[string]{Set: true, Null: true}},
+		{name: "value", body: `{"title": "Algebra", "count": 3}`, wantTitle: Optional[string]{Set: true, Value: "Algebra"}, wantCount: Optional[int32]{Set: true, Value: 3}},
+		{name: "empty string", body: `{"title": ""}`, wantTitle: Optional[string]{Set: true}},
+		{name: "zero", body: `{"count": 0}`, wantCount: Optional[int32]{Set: true}},
+		{name: "wrong type", body: `{"count": "three"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got patch
+			err := json.Unmarshal([]byte(tt.body), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Title != tt.wantTitle || got.Count != tt.wantCount {
+				t.Errorf("Unmarshal = %+v, want title %+v count %+v", got, tt.wantTitle, tt.wantCount)
+			}
+			if got.Title.HasValue() != (tt.wantTitle.Set && !tt.wantTitle.Null) {
+				t.Errorf("HasValue = %v", got.Title.HasValue())
+			}
+		})
+	}
+}
+
+func TestReadMergePatch(t *testing.T) {
+	app := &Application{Config: Config{Env: "development"}}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantOK      bool
+		wantStatus  int
+	}{
+		{name: "merge patch", contentType: mergePatchContentType, body: `{"title": "a"}`, wantOK: true},
+		{name: "json", contentType: "application/json; charset=utf-8", body: `{"title": "a"}`, wantOK: true},
+		{name: "no content type", body: `{"title": null}`, wantOK: true},
+		{name: "form", contentType: "application/x-www-form-urlencoded", body: `title=a`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "bad content type", contentType: ";;", body: `{}`, wantStatus: http.StatusUnsupportedMediaType},
+		{name: "unknown field", contentType: mergePatchContentType, body: `{"titel": "a"}`, wantStatus: http.StatusBadRequest},
+		{name: "malformed", contentType: mergePatchContentType, body: `{"title": `, wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPatch, "/v2/study-plans/1", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			w := httptest.NewRecorder()
+
+			var data struct {
+				Title Optional[string] `json:"title"`
+			}
+			if ok := app.readMergePatch(w, r, &data); ok != tt.wantOK {
+				t.Fatalf("readMergePatch = %v, want %v", ok, tt.wantOK)
+			}
+			if !tt.wantOK && w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,658 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Plan member roles, from least to most access. Viewers can read a plan and
+// track their own progress on its tasks, editors can also change the plan
+// and its tasks, and owners can also manage members and delete the plan.
+const (
+	PlanRoleViewer = "viewer"
+	PlanRoleEditor = "editor"
+	PlanRoleOwner  = "owner"
+)
+
+var planRoleRanks = map[string]int{
+	PlanRoleViewer: 1,
+	PlanRoleEditor: 2,
+	PlanRoleOwner:  3,
+}
+
+// planRoleAtLeast reports whether role grants everything minRole does
+func planRoleAtLeast(role, minRole string) bool {
+	return planRoleRanks[role] >= planRoleRanks[minRole]
+}
+
+// planInvitePrefix starts every invite link token
+const planInvitePrefix = "pp_inv_"
+
+// planInviteDisplayLength is how much of a link token is kept in the clear
+const planInviteDisplayLength = len(planInvitePrefix) + 6
+
+// defaultPlanInviteLifetime is how long a link invite lasts when no expiry
+// is given
+const defaultPlanInviteLifetime = 7 * 24 * time.Hour
+
+var (
+	errAlreadyPlanMember = errors.New("already a member of this study plan")
+	errPlanInviteUsed    = errors.New("invite has already been used")
+	errPlanInviteUsedUp  = errors.New("invite has expired or has no uses left")
+)
+
+// PlanMemberResponse represents a plan member in API responses
+type PlanMemberResponse struct {
+	UserID    string    `json:"user_id"`
+	Email     string    `json:"email"`
+	FirstName *string   `json:"first_name"`
+	LastName  *string   `json:"last_name"`
+	ImageURL  *string   `json:"image_url"`
+	Role      string    `json:"role"`
+	InvitedBy *string   `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func convertPlanMemberToResponse(member store.ListPlanMembersRow) PlanMemberResponse {
+	return PlanMemberResponse{
+		UserID:    member.UserID,
+		Email:     member.Email,
+		FirstName: nullStringToPointer(member.FirstName),
+		LastName:  nullStringToPointer(member.LastName),
+		ImageURL:  nullStringToPointer(member.ImageUrl),
+		Role:      member.Role,
+		InvitedBy: nullStringToPointer(member.InvitedBy),
+		CreatedAt: member.CreatedAt,
+	}
+}
+
+// SetPlanMemberRoleRequest represents the request to change a member's role
+type SetPlanMemberRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=viewer editor owner"`
+}
+
+// CreatePlanInviteRequest represents the request to invite someone to a
+// plan. With an email the invite waits for that user; without one a link
+// token is returned that anyone can use until it expires, after a week by
+// default, is revoked or has been used max_uses times.
+type CreatePlanInviteRequest struct {
+	Role      string     `json:"role" validate:"required,oneof=viewer editor"`
+	Email     *string    `json:"email" validate:"omitempty,email,max=254"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int32     `json:"max_uses" validate:"omitempty,min=1,max=1000"`
+}
+
+// AcceptPlanInviteRequest represents the request to join a plan with a link token
+type AcceptPlanInviteRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// PlanInviteResponse represents an invite in API responses. Token is only
+// set when a link invite is created.
+type PlanInviteResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	PlanID    uuid.UUID  `json:"plan_id"`
+	PlanTitle string     `json:"plan_title,omitempty"`
+	Role      string     `json:"role"`
+	Email     *string    `json:"email"`
+	Prefix    *string    `json:"prefix"`
+	Token     string     `json:"token,omitempty"`
+	InvitedBy string     `json:"invited_by"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	MaxUses   *int32     `json:"max_uses"`
+	Uses      int32      `json:"uses"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func convertPlanInviteToResponse(invite store.PlanInvite) PlanInviteResponse {
+	return PlanInviteResponse{
+		ID:        invite.ID,
+		PlanID:    invite.PlanID,
+		Role:      invite.Role,
+		Email:     nullStringToPointer(invite.Email),
+		Prefix:    nullStringToPointer(invite.TokenPrefix),
+		InvitedBy: invite.InvitedBy,
+		ExpiresAt: nullTimeToPointer(invite.ExpiresAt),
+		MaxUses:   nullInt32ToPointer(invite.MaxUses),
+		Uses:      invite.Uses,
+		CreatedAt: invite.CreatedAt,
+	}
+}
+
+// getPlanForMember loads a plan and checks that the user is a member with at
+// least minRole. On failure an error response has been written and ok is
+// false.
+func (app *Application) getPlanForMember(w http.ResponseWriter, r *http.Request, user *UserClaims, planID uuid.UUID, minRole string) (plan store.StudyPlan, role string, ok bool) {
+	plan, err := app.Queries.GetStudyPlanByID(r.Context(), planID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Study plan not found")
+			return plan, "", false
+		}
+		app.internalServerError(w, r, err)
+		return plan, "", false
+	}
+
+	role, ok = app.checkPlanRole(w, r, user, planID, minRole)
+	return plan, role, ok
+}
+
+// checkPlanRole checks that the user is a member of a plan with at least
+// minRole, whether or not the plan is in the trash. On failure an error
+// response has been written and ok is false.
+func (app *Application) checkPlanRole(w http.ResponseWriter, r *http.Request, user *UserClaims, planID uuid.UUID, minRole string) (role string, ok bool) {
+	member, err := app.Queries.GetPlanMember(r.Context(), store.GetPlanMemberParams{
+		PlanID: planID,
+		UserID: user.ClerkID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusForbidden, "Access denied")
+			return "", false
+		}
+		app.internalServerError(w, r, err)
+		return "", false
+	}
+
+	if !planRoleAtLeast(member.Role, minRole) {
+		app.writeJSONError(w, r, http.StatusForbidden, fmt.Sprintf("Requires the %s role on this study plan", minRole))
+		return "", false
+	}
+	return member.Role, true
+}
+
+// publishPlanEvent sends an event to every member of a plan
+func (app *Application) publishPlanEvent(ctx context.Context, planID uuid.UUID, eventType string, data any) {
+	memberIDs, err := app.Queries.ListPlanMemberIDs(ctx, planID)
+	if err != nil {
+		log.Printf("events: failed to list members of plan %s: %v", planID, err)
+		return
+	}
+	for _, userID := range memberIDs {
+		app.publishEvent(ctx, userID, eventType, data)
+	}
+}
+
+// GetPlanMembersHandler lists the members of a study plan
+func (app *Application) GetPlanMembersHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleViewer); !ok {
+		return
+	}
+
+	members, err := app.Queries.ListPlanMembers(r.Context(), planID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]PlanMemberResponse, len(members))
+	for i, member := range members {
+		response[i] = convertPlanMemberToResponse(member)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// SetPlanMemberRoleHandler changes the role of a plan member. The plan's
+// creator always stays an owner.
+func (app *Application) SetPlanMemberRoleHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	memberID := chi.URLParam(r, "userID")
+
+	var req SetPlanMemberRoleRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	plan, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleOwner)
+	if !ok {
+		return
+	}
+
+	if memberID == plan.UserID {
+		app.writeJSONError(w, r, http.StatusConflict, "The creator of a study plan is always an owner")
+		return
+	}
+
+	previous, err := app.Queries.GetPlanMember(r.Context(), store.GetPlanMemberParams{PlanID: planID, UserID: memberID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Member not found")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	member, err := app.Queries.UpdatePlanMemberRole(r.Context(), store.UpdatePlanMemberRoleParams{
+		PlanID: planID,
+		UserID: memberID,
+		Role:   req.Role,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Member not found")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityPlanMemberRoleChanged,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+		Before:     map[string]string{"user_id": memberID, "role": previous.Role},
+		After:      map[string]string{"user_id": memberID, "role": member.Role},
+	})
+
+	app.jsonResponse(w, http.StatusOK, map[string]string{"user_id": member.UserID, "role": member.Role})
+}
+
+// RemovePlanMemberHandler removes someone from a plan. Owners can remove
+// anyone but the plan's creator; other members can only leave.
+func (app *Application) RemovePlanMemberHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	memberID := chi.URLParam(r, "userID")
+
+	minRole := PlanRoleOwner
+	if memberID == user.ClerkID {
+		minRole = PlanRoleViewer
+	}
+
+	plan, _, ok := app.getPlanForMember(w, r, user, planID, minRole)
+	if !ok {
+		return
+	}
+
+	if memberID == plan.UserID {
+		app.writeJSONError(w, r, http.StatusConflict, "The creator of a study plan cannot be removed from it")
+		return
+	}
+
+	removed, err := app.Queries.RemovePlanMember(r.Context(), store.RemovePlanMemberParams{PlanID: planID, UserID: memberID})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if removed == 0 {
+		app.writeJSONError(w, r, http.StatusNotFound, "Member not found")
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityPlanMemberRemoved,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+		Before:     map[string]string{"user_id": memberID},
+	})
+
+	app.respondDeleted(w, r, "Member removed")
+}
+
+// CreatePlanInviteHandler invites someone to a plan by email or link. A
+// link token is only returned in this response.
+func (app *Application) CreatePlanInviteHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var req CreatePlanInviteRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		app.badRequestError(w, r, &FieldError{Field: "expires_at", Code: "future", Message: "must be in the future"})
+		return
+	}
+	if req.Email != nil && req.MaxUses != nil {
+		app.badRequestError(w, r, &FieldError{Field: "max_uses", Code: "excluded_with", Message: "only applies to link invites"})
+		return
+	}
+
+	if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleOwner); !ok {
+		return
+	}
+
+	params := store.CreatePlanInviteParams{
+		PlanID:    planID,
+		Role:      req.Role,
+		InvitedBy: user.ClerkID,
+		ExpiresAt: timeToNullTime(req.ExpiresAt),
+		MaxUses:   int32ToNullInt32(req.MaxUses),
+	}
+
+	var secret string
+	if req.Email != nil {
+		params.Email = sql.NullString{String: strings.ToLower(strings.TrimSpace(*req.Email)), Valid: true}
+	} else {
+		if !params.ExpiresAt.Valid {
+			params.ExpiresAt = sql.NullTime{Time: time.Now().Add(defaultPlanInviteLifetime), Valid: true}
+		}
+		secret, err = newSecretToken(planInvitePrefix)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		params.TokenPrefix = sql.NullString{String: secret[:planInviteDisplayLength], Valid: true}
+		params.TokenHash = sql.NullString{String: hashSecretToken(secret), Valid: true}
+	}
+
+	invite, err := app.Queries.CreatePlanInvite(r.Context(), params)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertPlanInviteToResponse(invite)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityPlanInviteCreated,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+		After:      response,
+	})
+
+	response.Token = secret
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// GetPlanInvitesHandler lists the pending invites of a plan
+func (app *Application) GetPlanInvitesHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleOwner); !ok {
+		return
+	}
+
+	invites, err := app.Queries.ListPlanInvites(r.Context(), planID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]PlanInviteResponse, len(invites))
+	for i, invite := range invites {
+		response[i] = convertPlanInviteToResponse(invite)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RevokePlanInviteHandler revokes a pending invite
+func (app *Application) RevokePlanInviteHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	inviteID, err := uuid.Parse(chi.URLParam(r, "inviteID"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleOwner); !ok {
+		return
+	}
+
+	revoked, err := app.Queries.RevokePlanInvite(r.Context(), store.RevokePlanInviteParams{ID: inviteID, PlanID: planID})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if revoked == 0 {
+		app.writeJSONError(w, r, http.StatusNotFound, "Invite not found, accepted or already revoked")
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityPlanInviteRevoked,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+		Before:     map[string]string{"invite_id": inviteID.String()},
+	})
+
+	app.respondDeleted(w, r, "Invite revoked")
+}
+
+// AcceptPlanInviteHandler joins a plan with a link token
+func (app *Application) AcceptPlanInviteHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req AcceptPlanInviteRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	invite, err := app.Queries.GetPlanInviteByTokenHash(r.Context(), sql.NullString{
+		String: hashSecretToken(strings.TrimSpace(req.Token)),
+		Valid:  true,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Invite not found, revoked or expired")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.joinPlan(w, r, user, invite)
+}
+
+// GetMyPlanInvitesHandler lists the pending invites sent to the user's email
+func (app *Application) GetMyPlanInvitesHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	account, err := app.Queries.GetUserByClerkID(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	invites, err := app.Queries.ListPlanInvitesForEmail(r.Context(), account.Email)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]PlanInviteResponse, len(invites))
+	for i, invite := range invites {
+		response[i] = PlanInviteResponse{
+			ID:        invite.ID,
+			PlanID:    invite.PlanID,
+			PlanTitle: invite.PlanTitle,
+			Role:      invite.Role,
+			Email:     nullStringToPointer(invite.Email),
+			InvitedBy: invite.InvitedBy,
+			ExpiresAt: nullTimeToPointer(invite.ExpiresAt),
+			MaxUses:   nullInt32ToPointer(invite.MaxUses),
+			Uses:      invite.Uses,
+			CreatedAt: invite.CreatedAt,
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// AcceptMyPlanInviteHandler joins a plan with an invite sent to the user's email
+func (app *Application) AcceptMyPlanInviteHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	inviteID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	account, err := app.Queries.GetUserByClerkID(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	invite, err := app.Queries.GetPlanInviteForEmail(r.Context(), store.GetPlanInviteForEmailParams{
+		ID:    inviteID,
+		Email: account.Email,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Invite not found, revoked or expired")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.joinPlan(w, r, user, invite)
+}
+
+// joinPlan adds the user to the plan of an invite and responds with the
+// plan. Email invites are used up; link invites count a use and stay valid
+// until they run out.
+func (app *Application) joinPlan(w http.ResponseWriter, r *http.Request, user *UserClaims, invite store.PlanInvite) {
+	member, err := app.addPlanMember(r.Context(), user, invite)
+	if err != nil {
+		switch {
+		case errors.Is(err, errAlreadyPlanMember), errors.Is(err, errPlanInviteUsed), errors.Is(err, errPlanInviteUsedUp):
+			app.writeJSONError(w, r, http.StatusConflict, err.Error())
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	plan, err := app.Queries.GetStudyPlanByID(r.Context(), invite.PlanID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityPlanMemberAdded,
+		EntityType: activityStudyPlan,
+		EntityID:   plan.ID.String(),
+		After:      map[string]string{"user_id": member.UserID, "role": member.Role, "invite_id": invite.ID.String()},
+	})
+
+	response := convertStudyPlanToResponse(plan)
+	response.Role = member.Role
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+// addPlanMember records the use of the invite and adds the user to the plan
+// in one transaction
+func (app *Application) addPlanMember(ctx context.Context, user *UserClaims, invite store.PlanInvite) (store.PlanMember, error) {
+	_, err := app.Queries.GetPlanMember(ctx, store.GetPlanMemberParams{PlanID: invite.PlanID, UserID: user.ClerkID})
+	if err == nil {
+		return store.PlanMember{}, errAlreadyPlanMember
+	}
+	if err != sql.ErrNoRows {
+		return store.PlanMember{}, err
+	}
+
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return store.PlanMember{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	if invite.Email.Valid {
+		accepted, err := queries.AcceptPlanInvite(ctx, store.AcceptPlanInviteParams{
+			ID:         invite.ID,
+			AcceptedBy: sql.NullString{String: user.ClerkID, Valid: true},
+		})
+		if err != nil {
+			return store.PlanMember{}, err
+		}
+		if accepted == 0 {
+			return store.PlanMember{}, errPlanInviteUsed
+		}
+	} else {
+		// Checked again here as other people may be joining at the same time
+		used, err := queries.UsePlanInvite(ctx, invite.ID)
+		if err != nil {
+			return store.PlanMember{}, err
+		}
+		if used == 0 {
+			return store.PlanMember{}, errPlanInviteUsedUp
+		}
+	}
+
+	member, err := queries.AddPlanMember(ctx, store.AddPlanMemberParams{
+		PlanID:    invite.PlanID,
+		UserID:    user.ClerkID,
+		Role:      invite.Role,
+		InvitedBy: sql.NullString{String: invite.InvitedBy, Valid: true},
+	})
+	if err != nil {
+		return store.PlanMember{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.PlanMember{}, fmt.Errorf("failed to commit membership: %w", err)
+	}
+	return member, nil
+}
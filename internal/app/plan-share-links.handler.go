@@ -0,0 +1,349 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/events"
+	"github.com/mustaphalimar/prepilot/internal/store"
+	"github.com/mustaphalimar/prepilot/internal/webhooks"
+)
+
+// shareLinkDisplayLength is how much of a share token is kept in the clear
+const shareLinkDisplayLength = 6
+
+// CreatePlanShareLinkRequest represents the request to share a plan publicly
+type CreatePlanShareLinkRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// PlanShareLinkResponse represents a share link in API responses. Token and
+// Path are only set when the link is created.
+type PlanShareLinkResponse struct {
+	ID           uuid.UUID  `json:"id"`
+	PlanID       uuid.UUID  `json:"plan_id"`
+	Prefix       string     `json:"prefix"`
+	Token        string     `json:"token,omitempty"`
+	Path         string     `json:"path,omitempty"`
+	CreatedBy    string     `json:"created_by"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	ViewCount    int64      `json:"view_count"`
+	LastViewedAt *time.Time `json:"last_viewed_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func convertPlanShareLinkToResponse(link store.PlanShareLink) PlanShareLinkResponse {
+	return PlanShareLinkResponse{
+		ID:           link.ID,
+		PlanID:       link.PlanID,
+		Prefix:       link.TokenPrefix,
+		CreatedBy:    link.CreatedBy,
+		ExpiresAt:    nullTimeToPointer(link.ExpiresAt),
+		RevokedAt:    nullTimeToPointer(link.RevokedAt),
+		ViewCount:    link.ViewCount,
+		LastViewedAt: nullTimeToPointer(link.LastViewedAt),
+		CreatedAt:    link.CreatedAt,
+	}
+}
+
+// PublicPlanResponse is a shared plan as anyone with the link sees it. It
+// leaves out who owns the plan, their progress and their notes on tasks.
+type PublicPlanResponse struct {
+	Title       string               `json:"title"`
+	Subject     string               `json:"subject"`
+	Description *string              `json:"description"`
+	ExamDate    time.Time            `json:"exam_date"`
+	StartDate   time.Time            `json:"start_date"`
+	EndDate     time.Time            `json:"end_date"`
+	Tasks       []PublicTaskResponse `json:"tasks"`
+}
+
+// PublicTaskResponse is a task of a shared plan
+type PublicTaskResponse struct {
+	Title    string    `json:"title"`
+	DueDate  time.Time `json:"due_date"`
+	Priority *int32    `json:"priority"`
+}
+
+// shareTokenHash hashes the token in the URL of a public link
+func shareTokenHash(r *http.Request) string {
+	return hashSecretToken(strings.TrimSpace(chi.URLParam(r, "token")))
+}
+
+// CreatePlanShareLinkHandler creates a public link to a plan. The token is
+// only returned in this response.
+func (app *Application) CreatePlanShareLinkHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var req CreatePlanShareLinkRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		app.badRequestError(w, r, &FieldError{Field: "expires_at", Code: "future", Message: "must be in the future"})
+		return
+	}
+
+	if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleOwner); !ok {
+		return
+	}
+
+	secret, err := newSecretToken("")
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	link, err := app.Queries.CreatePlanShareLink(r.Context(), store.CreatePlanShareLinkParams{
+		PlanID:      planID,
+		CreatedBy:   user.ClerkID,
+		TokenPrefix: secret[:shareLinkDisplayLength],
+		TokenHash:   hashSecretToken(secret),
+		ExpiresAt:   timeToNullTime(req.ExpiresAt),
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertPlanShareLinkToResponse(link)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityPlanShared,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+		After:      response,
+	})
+
+	response.Token = secret
+	response.Path = fmt.Sprintf("/v%d/public/plans/%s", apiVersion(r), secret)
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// GetPlanShareLinksHandler lists the share links of a plan, including
+// revoked and expired ones, with their view counts
+func (app *Application) GetPlanShareLinksHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleOwner); !ok {
+		return
+	}
+
+	links, err := app.Queries.ListPlanShareLinks(r.Context(), planID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]PlanShareLinkResponse, len(links))
+	for i, link := range links {
+		response[i] = convertPlanShareLinkToResponse(link)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RevokePlanShareLinkHandler stops a share link from working
+func (app *Application) RevokePlanShareLinkHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	linkID, err := uuid.Parse(chi.URLParam(r, "linkID"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleOwner); !ok {
+		return
+	}
+
+	link, err := app.Queries.RevokePlanShareLink(r.Context(), store.RevokePlanShareLinkParams{ID: linkID, PlanID: planID})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Share link not found or already revoked")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityPlanShareRevoked,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+		Before:     map[string]any{"link_id": link.ID, "revoked_at": nil},
+		After:      map[string]any{"link_id": link.ID, "revoked_at": link.RevokedAt.Time},
+	})
+
+	app.respondDeleted(w, r, "Share link revoked")
+}
+
+// PublicPlanHandler shows a shared plan and its tasks without signing in.
+// Each request counts as a view.
+func (app *Application) PublicPlanHandler(w http.ResponseWriter, r *http.Request) {
+	link, err := app.Queries.ViewPlanShareLink(r.Context(), shareTokenHash(r))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Share link not found, revoked or expired")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	plan, err := app.Queries.GetStudyPlanByID(r.Context(), link.PlanID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	tasks, err := app.Queries.GetTasksByPlan(r.Context(), uuid.NullUUID{UUID: plan.ID, Valid: true})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := PublicPlanResponse{
+		Title:       plan.Title,
+		Subject:     plan.Subject,
+		Description: nullStringToPointer(plan.Description),
+		ExamDate:    plan.ExamDate,
+		StartDate:   plan.StartDate,
+		EndDate:     plan.EndDate,
+		Tasks:       make([]PublicTaskResponse, len(tasks)),
+	}
+	for i, task := range tasks {
+		response.Tasks[i] = PublicTaskResponse{
+			Title:    task.Title,
+			DueDate:  task.DueDate,
+			Priority: convertStudyTaskToResponse(task).Priority,
+		}
+	}
+
+	// Links can be revoked at any time, so nothing may keep a copy
+	w.Header().Set("Cache-Control", "no-store")
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CopySharedPlanHandler copies a shared plan and its tasks into the signed-in
+// user's account as a new plan of their own
+func (app *Application) CopySharedPlanHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	link, err := app.Queries.GetActivePlanShareLink(r.Context(), shareTokenHash(r))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Share link not found, revoked or expired")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	source, err := app.Queries.GetStudyPlanByID(r.Context(), link.PlanID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	studyPlan, err := app.copyStudyPlan(r.Context(), source, user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.publishEvent(r.Context(), user.ClerkID, events.PlanCreated, convertStudyPlanToResponse(studyPlan))
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.PlanCreated,
+		EntityType: activityStudyPlan,
+		EntityID:   studyPlan.ID.String(),
+		After:      planSnapshot(studyPlan),
+	})
+	app.enqueueWebhook(r.Context(), user.ClerkID, webhooks.PlanCreated, convertStudyPlanToResponse(studyPlan))
+
+	response := convertStudyPlanToResponse(studyPlan)
+	response.Role = PlanRoleOwner
+	w.Header().Set("ETag", planETag(studyPlan.Version, PlanRoleOwner))
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// copyStudyPlan creates a plan for userID with the content and tasks of
+// source. The copied tasks start out not completed and without the notes,
+// which stay private like in the public view.
+func (app *Application) copyStudyPlan(ctx context.Context, source store.StudyPlan, userID string) (store.StudyPlan, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return store.StudyPlan{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	subject, err := app.planSubject(ctx, queries, userID, nil, source.Subject)
+	if err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	plan, err := queries.CreateStudyPlan(ctx, store.CreateStudyPlanParams{
+		UserID:      userID,
+		Title:       source.Title,
+		Subject:     subject.Name,
+		SubjectID:   uuid.NullUUID{UUID: subject.ID, Valid: true},
+		Description: source.Description.String,
+		ExamDate:    source.ExamDate,
+		StartDate:   source.StartDate,
+		EndDate:     source.EndDate,
+	})
+	if err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if _, err := queries.AddPlanMember(ctx, store.AddPlanMemberParams{
+		PlanID: plan.ID,
+		UserID: userID,
+		Role:   PlanRoleOwner,
+	}); err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if _, err := queries.CopyPlanTasks(ctx, store.CopyPlanTasksParams{
+		PlanID:       plan.ID,
+		CreatedBy:    sql.NullString{String: userID, Valid: true},
+		SourcePlanID: source.ID,
+	}); err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.StudyPlan{}, fmt.Errorf("failed to commit study plan: %w", err)
+	}
+	return plan, nil
+}
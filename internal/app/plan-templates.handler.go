@@ -0,0 +1,589 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/events"
+	"github.com/mustaphalimar/prepilot/internal/store"
+	"github.com/mustaphalimar/prepilot/internal/templates"
+	"github.com/mustaphalimar/prepilot/internal/webhooks"
+)
+
+// PlanTemplateRequest represents the request body for creating or replacing
+// a template. Tasks replace the existing ones.
+type PlanTemplateRequest struct {
+	Title        string                    `json:"title" validate:"title"`
+	Subject      string                    `json:"subject" validate:"title"`
+	Description  *string                   `json:"description" validate:"omitempty,notes"`
+	DurationDays int32                     `json:"duration_days" validate:"min=1,max=366"`
+	Tags         []string                  `json:"tags" validate:"max=10,dive,notblank,max=30"`
+	Tasks        []PlanTemplateTaskRequest `json:"tasks" validate:"max=200,dive"`
+}
+
+// PlanTemplateTaskRequest is a task of a template, due Day days from the
+// exam date
+type PlanTemplateTaskRequest struct {
+	Day      int32   `json:"day" validate:"min=-366,max=0"`
+	Title    string  `json:"title" validate:"title"`
+	Priority *int32  `json:"priority" validate:"omitempty,priority"`
+	Notes    *string `json:"notes" validate:"omitempty,notes"`
+}
+
+// UsePlanTemplateRequest represents the request to create a plan from a
+// template. StartDate defaults to DurationDays before the exam, or today if
+// that has passed; Title defaults to the template's.
+type UsePlanTemplateRequest struct {
+	Title     *string    `json:"title" validate:"omitempty,title"`
+	ExamDate  time.Time  `json:"exam_date" validate:"required"`
+	StartDate *time.Time `json:"start_date"`
+}
+
+// PlanTemplateResponse represents a template in API responses. Tasks are
+// left out of lists.
+type PlanTemplateResponse struct {
+	ID           uuid.UUID                  `json:"id"`
+	Slug         *string                    `json:"slug"`
+	AuthorID     *string                    `json:"author_id"`
+	Title        string                     `json:"title"`
+	Subject      string                     `json:"subject"`
+	Description  *string                    `json:"description"`
+	DurationDays int32                      `json:"duration_days"`
+	Tags         []string                   `json:"tags"`
+	IsOfficial   bool                       `json:"is_official"`
+	PublishedAt  *time.Time                 `json:"published_at"`
+	UsageCount   int64                      `json:"usage_count"`
+	CreatedAt    time.Time                  `json:"created_at"`
+	UpdatedAt    time.Time                  `json:"updated_at"`
+	Tasks        []PlanTemplateTaskResponse `json:"tasks,omitempty"`
+}
+
+// PlanTemplateTaskResponse represents a template task in API responses
+type PlanTemplateTaskResponse struct {
+	ID       uuid.UUID `json:"id"`
+	Day      int32     `json:"day"`
+	Title    string    `json:"title"`
+	Priority *int32    `json:"priority"`
+	Notes    *string   `json:"notes"`
+}
+
+// PlanTemplateTagResponse is a tag with the number of published templates
+// that have it
+type PlanTemplateTagResponse struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+func convertPlanTemplateToResponse(template store.PlanTemplate) PlanTemplateResponse {
+	tags := template.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return PlanTemplateResponse{
+		ID:           template.ID,
+		Slug:         nullStringToPointer(template.Slug),
+		AuthorID:     nullStringToPointer(template.AuthorID),
+		Title:        template.Title,
+		Subject:      template.Subject,
+		Description:  nullStringToPointer(template.Description),
+		DurationDays: template.DurationDays,
+		Tags:         tags,
+		IsOfficial:   template.IsOfficial,
+		PublishedAt:  nullTimeToPointer(template.PublishedAt),
+		UsageCount:   template.UsageCount,
+		CreatedAt:    template.CreatedAt,
+		UpdatedAt:    template.UpdatedAt,
+	}
+}
+
+func convertPlanTemplateTaskToResponse(task store.PlanTemplateTask) PlanTemplateTaskResponse {
+	response := PlanTemplateTaskResponse{
+		ID:    task.ID,
+		Day:   task.DayOffset,
+		Title: task.Title,
+		Notes: nullStringToPointer(task.Notes),
+	}
+	if task.Priority.Valid {
+		response.Priority = &task.Priority.Int32
+	}
+	return response
+}
+
+// getPlanTemplate loads the template in the URL. Published templates can be
+// seen by everyone, drafts only by their author.
+func (app *Application) getPlanTemplate(w http.ResponseWriter, r *http.Request, user *UserClaims) (store.PlanTemplate, bool) {
+	templateID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return store.PlanTemplate{}, false
+	}
+
+	template, err := app.Queries.GetPlanTemplate(r.Context(), templateID)
+	if err == nil && !template.PublishedAt.Valid && template.AuthorID.String != user.ClerkID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Template not found")
+			return template, false
+		}
+		app.internalServerError(w, r, err)
+		return template, false
+	}
+	return template, true
+}
+
+// getPlanTemplateToEdit is getPlanTemplate for changes, which only the
+// author may make. Admins edit official templates, though reseeding
+// overwrites their changes.
+func (app *Application) getPlanTemplateToEdit(w http.ResponseWriter, r *http.Request, user *UserClaims) (store.PlanTemplate, bool) {
+	template, ok := app.getPlanTemplate(w, r, user)
+	if !ok {
+		return template, false
+	}
+	if template.AuthorID.String != user.ClerkID && !(template.IsOfficial && user.Role == RoleAdmin) {
+		app.writeJSONError(w, r, http.StatusForbidden, "Only the author can change this template")
+		return template, false
+	}
+	return template, true
+}
+
+// GetPlanTemplatesHandler searches the catalogue of published templates and
+// the user's own drafts
+func (app *Application) GetPlanTemplatesHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	limit, offset, err := parsePagination(r, 20, 100)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	query := r.URL.Query()
+	params := store.SearchPlanTemplatesParams{
+		UserID:    user.ClerkID,
+		Query:     stringValueToNullString(strings.TrimSpace(query.Get("q"))),
+		Tag:       stringValueToNullString(strings.ToLower(strings.TrimSpace(query.Get("tag")))),
+		Popular:   true,
+		RowLimit:  limit,
+		RowOffset: offset,
+	}
+
+	switch value := query.Get("author"); value {
+	case "":
+	case "me":
+		params.AuthorID = sql.NullString{String: user.ClerkID, Valid: true}
+	default:
+		params.AuthorID = sql.NullString{String: value, Valid: true}
+	}
+
+	if value := query.Get("official"); value != "" {
+		official, err := strconv.ParseBool(value)
+		if err != nil {
+			app.badRequestError(w, r, invalidParam("official", value))
+			return
+		}
+		params.Official = sql.NullBool{Bool: official, Valid: true}
+	}
+
+	switch value := query.Get("sort"); value {
+	case "", "popular":
+	case "newest":
+		params.Popular = false
+	default:
+		app.badRequestError(w, r, invalidParam("sort", value))
+		return
+	}
+
+	list, err := app.Queries.SearchPlanTemplates(r.Context(), params)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]PlanTemplateResponse, len(list))
+	for i, template := range list {
+		response[i] = convertPlanTemplateToResponse(template)
+	}
+
+	if err := app.pageResponse(w, r, response, len(response), limit, offset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetPlanTemplateTagsHandler lists the tags of published templates, most
+// used first
+func (app *Application) GetPlanTemplateTagsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	tags, err := app.Queries.ListPlanTemplateTags(r.Context())
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]PlanTemplateTagResponse, len(tags))
+	for i, tag := range tags {
+		response[i] = PlanTemplateTagResponse{Tag: tag.Tag, Count: tag.TemplateCount}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreatePlanTemplateHandler creates a draft template authored by the user
+func (app *Application) CreatePlanTemplateHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req PlanTemplateRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	template, tasks, err := app.savePlanTemplate(r.Context(), uuid.Nil, user.ClerkID, req)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertPlanTemplateToResponse(template)
+	response.Tasks = tasks
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityTemplateCreated,
+		EntityType: activityPlanTemplate,
+		EntityID:   template.ID.String(),
+		After:      response,
+	})
+
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// GetPlanTemplateHandler returns a template with its tasks
+func (app *Application) GetPlanTemplateHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	template, ok := app.getPlanTemplate(w, r, user)
+	if !ok {
+		return
+	}
+
+	tasks, err := app.Queries.ListPlanTemplateTasks(r.Context(), template.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertPlanTemplateToResponse(template)
+	response.Tasks = make([]PlanTemplateTaskResponse, len(tasks))
+	for i, task := range tasks {
+		response.Tasks[i] = convertPlanTemplateTaskToResponse(task)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdatePlanTemplateHandler replaces a template and its tasks
+func (app *Application) UpdatePlanTemplateHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	existing, ok := app.getPlanTemplateToEdit(w, r, user)
+	if !ok {
+		return
+	}
+
+	var req PlanTemplateRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	template, tasks, err := app.savePlanTemplate(r.Context(), existing.ID, "", req)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertPlanTemplateToResponse(template)
+	response.Tasks = tasks
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityTemplateUpdated,
+		EntityType: activityPlanTemplate,
+		EntityID:   template.ID.String(),
+		Before:     convertPlanTemplateToResponse(existing),
+		After:      response,
+	})
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+// savePlanTemplate creates a template when templateID is uuid.Nil, or
+// replaces it otherwise, along with its tasks
+func (app *Application) savePlanTemplate(ctx context.Context, templateID uuid.UUID, authorID string, req PlanTemplateRequest) (store.PlanTemplate, []PlanTemplateTaskResponse, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return store.PlanTemplate{}, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	var template store.PlanTemplate
+	if templateID == uuid.Nil {
+		template, err = queries.CreatePlanTemplate(ctx, store.CreatePlanTemplateParams{
+			AuthorID:     sql.NullString{String: authorID, Valid: true},
+			Title:        req.Title,
+			Subject:      req.Subject,
+			Description:  stringToNullString(req.Description),
+			DurationDays: req.DurationDays,
+			Tags:         templates.NormalizeTags(req.Tags),
+		})
+	} else {
+		template, err = queries.UpdatePlanTemplate(ctx, store.UpdatePlanTemplateParams{
+			ID:           templateID,
+			Title:        req.Title,
+			Subject:      req.Subject,
+			Description:  stringToNullString(req.Description),
+			DurationDays: req.DurationDays,
+			Tags:         templates.NormalizeTags(req.Tags),
+		})
+		if err == nil {
+			err = queries.DeletePlanTemplateTasks(ctx, templateID)
+		}
+	}
+	if err != nil {
+		return store.PlanTemplate{}, nil, err
+	}
+
+	for i, task := range req.Tasks {
+		params := store.AddPlanTemplateTaskParams{
+			TemplateID: template.ID,
+			Position:   int32(i),
+			Title:      task.Title,
+			DayOffset:  task.Day,
+			Notes:      stringToNullString(task.Notes),
+		}
+		if task.Priority != nil {
+			params.Priority = sql.NullInt32{Int32: *task.Priority, Valid: true}
+		}
+		if err := queries.AddPlanTemplateTask(ctx, params); err != nil {
+			return store.PlanTemplate{}, nil, err
+		}
+	}
+
+	tasks, err := queries.ListPlanTemplateTasks(ctx, template.ID)
+	if err != nil {
+		return store.PlanTemplate{}, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.PlanTemplate{}, nil, fmt.Errorf("failed to commit template: %w", err)
+	}
+
+	response := make([]PlanTemplateTaskResponse, len(tasks))
+	for i, task := range tasks {
+		response[i] = convertPlanTemplateTaskToResponse(task)
+	}
+	return template, response, nil
+}
+
+// DeletePlanTemplateHandler deletes a template. Plans created from it are
+// kept.
+func (app *Application) DeletePlanTemplateHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	template, ok := app.getPlanTemplateToEdit(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.Queries.DeletePlanTemplate(r.Context(), template.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityTemplateDeleted,
+		EntityType: activityPlanTemplate,
+		EntityID:   template.ID.String(),
+		Before:     convertPlanTemplateToResponse(template),
+	})
+
+	app.respondDeleted(w, r, "Template deleted successfully")
+}
+
+// PublishPlanTemplateHandler lists a template in the catalogue
+func (app *Application) PublishPlanTemplateHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	app.setPlanTemplatePublished(w, r, user, true)
+}
+
+// UnpublishPlanTemplateHandler takes a template out of the catalogue. It
+// becomes a draft again, visible only to its author.
+func (app *Application) UnpublishPlanTemplateHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	app.setPlanTemplatePublished(w, r, user, false)
+}
+
+func (app *Application) setPlanTemplatePublished(w http.ResponseWriter, r *http.Request, user *UserClaims, published bool) {
+	existing, ok := app.getPlanTemplateToEdit(w, r, user)
+	if !ok {
+		return
+	}
+
+	template, err := app.Queries.SetPlanTemplatePublished(r.Context(), store.SetPlanTemplatePublishedParams{
+		Published: published,
+		ID:        existing.ID,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	action := activityTemplatePublished
+	if !published {
+		action = activityTemplateUnpublished
+	}
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     action,
+		EntityType: activityPlanTemplate,
+		EntityID:   template.ID.String(),
+		Before:     map[string]any{"published_at": nullTimeToPointer(existing.PublishedAt)},
+		After:      map[string]any{"published_at": nullTimeToPointer(template.PublishedAt)},
+	})
+
+	app.jsonResponse(w, http.StatusOK, convertPlanTemplateToResponse(template))
+}
+
+// UsePlanTemplateHandler creates a study plan from a template. Tasks are due
+// their offset from the exam date, and never before the start date.
+func (app *Application) UsePlanTemplateHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	template, ok := app.getPlanTemplate(w, r, user)
+	if !ok {
+		return
+	}
+
+	var req UsePlanTemplateRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	startDate := req.ExamDate.AddDate(0, 0, -int(template.DurationDays))
+	if req.StartDate != nil {
+		startDate = *req.StartDate
+	} else if today := time.Now().UTC().Truncate(24 * time.Hour); startDate.Before(today) {
+		startDate = today
+	}
+	if startDate.After(req.ExamDate) {
+		app.badRequestError(w, r, &FieldError{Field: "start_date", Code: "ltefield", Message: "must be on or before exam_date"})
+		return
+	}
+
+	subject, err := app.planSubject(r.Context(), app.Queries, user.ClerkID, nil, template.Subject)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	params := store.CreateStudyPlanParams{
+		UserID:    user.ClerkID,
+		Title:     template.Title,
+		Subject:   subject.Name,
+		SubjectID: uuid.NullUUID{UUID: subject.ID, Valid: true},
+		ExamDate:  req.ExamDate,
+		StartDate: startDate,
+		EndDate:   req.ExamDate,
+	}
+	if req.Title != nil {
+		params.Title = *req.Title
+	}
+	if template.Description.Valid {
+		params.Description = template.Description.String
+	}
+
+	studyPlan, err := app.instantiatePlanTemplate(r.Context(), template.ID, params)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.publishEvent(r.Context(), user.ClerkID, events.PlanCreated, convertStudyPlanToResponse(studyPlan))
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.PlanCreated,
+		EntityType: activityStudyPlan,
+		EntityID:   studyPlan.ID.String(),
+		After:      planSnapshot(studyPlan),
+	})
+	app.enqueueWebhook(r.Context(), user.ClerkID, webhooks.PlanCreated, convertStudyPlanToResponse(studyPlan))
+
+	response := convertStudyPlanToResponse(studyPlan)
+	response.Role = PlanRoleOwner
+	w.Header().Set("ETag", planETag(studyPlan.Version, PlanRoleOwner))
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// instantiatePlanTemplate creates the plan, its owner and the tasks of the
+// template, and counts the use
+func (app *Application) instantiatePlanTemplate(ctx context.Context, templateID uuid.UUID, params store.CreateStudyPlanParams) (store.StudyPlan, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return store.StudyPlan{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	plan, err := queries.CreateStudyPlan(ctx, params)
+	if err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if _, err := queries.AddPlanMember(ctx, store.AddPlanMemberParams{
+		PlanID: plan.ID,
+		UserID: params.UserID,
+		Role:   PlanRoleOwner,
+	}); err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if _, err := queries.InstantiatePlanTemplateTasks(ctx, store.InstantiatePlanTemplateTasksParams{
+		PlanID:     plan.ID,
+		ExamDate:   params.ExamDate,
+		StartDate:  params.StartDate,
+		CreatedBy:  sql.NullString{String: params.UserID, Valid: true},
+		TemplateID: templateID,
+	}); err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if err := queries.IncrementPlanTemplateUsage(ctx, templateID); err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.StudyPlan{}, fmt.Errorf("failed to commit study plan: %w", err)
+	}
+	return plan, nil
+}
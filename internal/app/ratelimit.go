@@ -0,0 +1,96 @@
+package app
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+
+	"github.com/mustaphalimar/prepilot/internal/ratelimit"
+)
+
+// Rate limit budgets. Each route group has its own bucket per user, or per
+// client IP before the user is known.
+var (
+	// rateLimitClient applies to every request, by IP, before authentication
+	rateLimitClient = ratelimit.PerMinute(600)
+	// rateLimitAPI applies to every authenticated request
+	rateLimitAPI = ratelimit.PerMinute(300)
+	// rateLimitClerkWebhooks protects the inbound Clerk webhook
+	rateLimitClerkWebhooks = ratelimit.PerMinute(120)
+	// rateLimitWebhooks covers outbound webhook management, whose redeliveries
+	// and tests make requests to other servers
+	rateLimitWebhooks = ratelimit.PerMinute(30)
+	// rateLimitExports covers data exports, which are expensive to generate
+	rateLimitExports = ratelimit.PerHour(5)
+	// rateLimitPublic covers shared plans served without authentication, by IP
+	rateLimitPublic = ratelimit.PerMinute(60)
+)
+
+// newRateLimitStore picks the store named in the config. Anything other than
+// "postgres" keeps the buckets in memory.
+func newRateLimitStore(db *sql.DB, name string) ratelimit.Store {
+	if name == "postgres" {
+		return ratelimit.NewPostgresStore(db)
+	}
+	return ratelimit.NewMemoryStore()
+}
+
+// RateLimit limits requests to the route group to limit, per signed-in user
+// or per client IP. It sends the RateLimit headers on every response and
+// answers 429 with Retry-After once the budget is spent. If the store fails
+// the request is let through.
+func (app *Application) RateLimit(group string, limit ratelimit.Limit) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := group + ":" + rateLimitSubject(r)
+			result, err := app.RateLimits.Take(r.Context(), key, limit)
+			if err != nil {
+				log.Printf("ratelimit: failed to check %s: %v", key, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			header := w.Header()
+			header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			header.Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+			header.Set("RateLimit-Policy", limit.Policy())
+
+			if !result.Allowed {
+				retryAfter := ceilSeconds(result.RetryAfter.Seconds())
+				header.Set("Retry-After", strconv.Itoa(retryAfter))
+				app.writeJSONError(w, r, http.StatusTooManyRequests, fmt.Sprintf("Rate limit exceeded, retry in %d seconds", retryAfter))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitClients applies the per-IP budget to every request. It must run
+// after RealIP.
+func (app *Application) RateLimitClients(next http.Handler) http.Handler {
+	return app.RateLimit("client", rateLimitClient)(next)
+}
+
+// rateLimitSubject identifies who is making the request. RemoteAddr holds the
+// client IP once RealIP has run.
+func rateLimitSubject(r *http.Request) string {
+	if user, ok := GetUserFromContext(r.Context()); ok {
+		return "user:" + user.ClerkID
+	}
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return "ip:" + ip
+}
+
+// ceilSeconds rounds a wait up to whole seconds, at least 1
+func ceilSeconds(seconds float64) int {
+	return max(int(math.Ceil(seconds)), 1)
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses a space or comma separated list of CIDRs or
+// single addresses, such as "10.0.0.0/8 192.168.1.10"
+func ParseTrustedProxies(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Fields(strings.ReplaceAll(list, ",", " ")) {
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", field, err)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", field, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// RealIP replaces RemoteAddr with the client IP. X-Forwarded-For and
+// X-Real-IP are only believed when the connection comes from one of the
+// trusted proxies, since anyone else can set them to dodge the per-IP
+// rate limits. Without trusted proxies RemoteAddr is left as it is.
+func (app *Application) RealIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := clientIP(r, app.Config.TrustedProxies); ok {
+			r.RemoteAddr = ip.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP finds the client of a request that went through trusted proxies.
+// Each proxy appends the address it got the request from to
+// X-Forwarded-For, so the client is the rightmost address that is not a
+// trusted proxy. Addresses left of it were sent by the client and are
+// ignored.
+func clientIP(r *http.Request, trusted []netip.Prefix) (netip.Addr, bool) {
+	peer, ok := parseIP(r.RemoteAddr)
+	if !ok || !isTrustedProxy(peer, trusted) {
+		return netip.Addr{}, false
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip, ok := parseIP(strings.TrimSpace(forwarded[i]))
+		if !ok {
+			return netip.Addr{}, false
+		}
+		if !isTrustedProxy(ip, trusted) {
+			return ip, true
+		}
+	}
+	if len(forwarded) > 0 {
+		// Every hop is a proxy of ours, the leftmost one is the closest to the client
+		ip, _ := parseIP(strings.TrimSpace(forwarded[0]))
+		return ip, true
+	}
+
+	if ip, ok := parseIP(r.Header.Get("X-Real-IP")); ok {
+		return ip, true
+	}
+	return netip.Addr{}, false
+}
+
+// parseIP parses an address with or without a port
+func parseIP(s string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+func isTrustedProxy(ip netip.Addr, trusted []netip.Prefix) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
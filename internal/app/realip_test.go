@@ -0,0 +1,80 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10 fd00::/8")
+	if err != nil {
+		t.Fatalf("ParseTrustedProxies: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		realIP       string
+		want         string
+	}{
+		{name: "direct", remoteAddr: "203.0.113.7:51234", want: "203.0.113.7:51234"},
+		{name: "spoofed from the internet", remoteAddr: "203.0.113.7:51234", forwardedFor: []string{"1.2.3.4"}, realIP: "5.6.7.8", want: "203.0.113.7:51234"},
+		{name: "through a proxy", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "client prepends an address", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"1.2.3.4, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "through two proxies", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"1.2.3.4, 203.0.113.7", "192.168.1.10"}, want: "203.0.113.7"},
+		{name: "ipv6 proxy", remoteAddr: "[fd00::1]:443", forwardedFor: []string{"2001:db8::7"}, want: "2001:db8::7"},
+		{name: "mapped proxy", remoteAddr: "[::ffff:10.1.2.3]:443", forwardedFor: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "real ip header", remoteAddr: "192.168.1.10:443", realIP: "203.0.113.7", want: "203.0.113.7"},
+		{name: "garbage", remoteAddr: "10.1.2.3:443", forwardedFor: []string{"unknown"}, want: "10.1.2.3:443"},
+		{name: "untrusted neighbour", remoteAddr: "192.168.1.11:443", forwardedFor: []string{"1.2.3.4"}, want: "192.168.1.11:443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := &Application{Config: Config{TrustedProxies: trusted}}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwardedFor {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			var got string
+			app.RealIP(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			})).ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    int
+		wantErr bool
+	}{
+		{list: "", want: 0},
+		{list: "10.0.0.0/8", want: 1},
+		{list: "10.0.0.0/8,172.16.0.0/12 ::1", want: 3},
+		{list: "10.0.0.1/8", want: 1},
+		{list: "proxy.internal", wantErr: true},
+		{list: "10.0.0.0/33", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := ParseTrustedProxies(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTrustedProxies = %v, want error %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("ParseTrustedProxies = %v, want %d prefixes", got, tt.want)
+			}
+		})
+	}
+}
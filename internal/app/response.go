@@ -0,0 +1,103 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// apiVersionKey is the context key for the API version a request was routed to
+type apiVersionKey struct{}
+
+// withAPIVersion records which versioned prefix a request came through
+func withAPIVersion(version int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), apiVersionKey{}, version)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// apiVersion is the major API version of the request, 1 if unknown
+func apiVersion(r *http.Request) int {
+	if version, ok := r.Context().Value(apiVersionKey{}).(int); ok {
+		return version
+	}
+	return 1
+}
+
+// Envelope is the body of every successful /v2 response. /v1 endpoints
+// that already used jsonResponse have the same shape without meta and links.
+type Envelope struct {
+	Data  any       `json:"data"`
+	Meta  *PageMeta `json:"meta,omitempty"`
+	Links *Links    `json:"links,omitempty"`
+}
+
+// PageMeta describes the page of a paginated list
+type PageMeta struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+	Count  int   `json:"count"`
+}
+
+// Links holds URLs related to the response
+type Links struct {
+	Self string `json:"self"`
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// respond writes data in the envelope. On /v1, legacy is written instead:
+// it is the exact body the endpoint returned before /v2, so existing
+// clients keep working.
+func (app *Application) respond(w http.ResponseWriter, r *http.Request, status int, data, legacy any) error {
+	if apiVersion(r) == 1 {
+		return app.writeJSON(w, status, legacy)
+	}
+	return app.writeJSON(w, status, Envelope{
+		Data:  data,
+		Links: &Links{Self: r.URL.RequestURI()},
+	})
+}
+
+// respondDeleted confirms a deletion. /v1 answers with a message while /v2
+// answers 204 No Content.
+func (app *Application) respondDeleted(w http.ResponseWriter, r *http.Request, message string) error {
+	if apiVersion(r) == 1 {
+		return app.writeJSON(w, http.StatusOK, map[string]string{"message": message})
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// pageResponse writes one page of a limit/offset list along with its
+// pagination meta and links to the neighbouring pages. A full page is
+// assumed to have a next page.
+func (app *Application) pageResponse(w http.ResponseWriter, r *http.Request, data any, count int, limit, offset int32) error {
+	links := &Links{Self: r.URL.RequestURI()}
+	if count == int(limit) {
+		links.Next = pageURL(r.URL, limit, offset+limit)
+	}
+	if offset > 0 {
+		links.Prev = pageURL(r.URL, limit, max(offset-limit, 0))
+	}
+
+	return app.writeJSON(w, http.StatusOK, Envelope{
+		Data:  data,
+		Meta:  &PageMeta{Limit: limit, Offset: offset, Count: count},
+		Links: links,
+	})
+}
+
+// pageURL is u with its limit and offset replaced
+func pageURL(u *url.URL, limit, offset int32) string {
+	query := u.Query()
+	query.Set("limit", strconv.Itoa(int(limit)))
+	query.Set("offset", strconv.Itoa(int(offset)))
+	next := *u
+	next.RawQuery = query.Encode()
+	return next.RequestURI()
+}
@@ -4,14 +4,24 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-// RegisterRoutes sets up all the routes for the application
-func (app *Application) RegisterRoutes(r chi.Router) {
+// RegisterRoutes sets up all the routes for the application and builds the
+// OpenAPI document describing them
+func (app *Application) RegisterRoutes(r chi.Router) error {
+	r.NotFound(app.routeNotFoundHandler)
+	r.MethodNotAllowed(app.methodNotAllowedHandler)
+
 	// Public routes
 	r.Route("/v1", func(r chi.Router) {
-		r.Get("/health", app.healthCheckHandler)
+		r.Use(withAPIVersion(1))
+
+		// API reference
+		r.Get("/openapi.json", app.OpenAPIHandler)
+		if app.Config.Env == "development" {
+			r.Get("/docs", app.APIDocsHandler)
+		}
 
 		// Webhook routes (before auth to avoid middleware)
-		r.Post("/webhooks/clerk", app.ClerkWebhookHandler)
+		r.With(app.RateLimit("clerk-webhooks", rateLimitClerkWebhooks)).Post("/webhooks/clerk", app.ClerkWebhookHandler)
 
 		// Auth routes
 		r.Route("/auth", func(r chi.Router) {
@@ -19,37 +29,247 @@ func (app *Application) RegisterRoutes(r chi.Router) {
 			// r.Post("/login", a.LoginHandler)
 		})
 
-		// Protected routes
-		r.Group(func(r chi.Router) {
-			// Add authentication middleware
-			r.Use(app.AuthMiddleware)
+		app.registerAPIRoutes(r)
+	})
+
+	// /v2 serves the same API with DTOs and the envelope on every endpoint
+	r.Route("/v2", func(r chi.Router) {
+		r.Use(withAPIVersion(2))
+		app.registerAPIRoutes(r)
+	})
+
+	document, err := buildOpenAPIDocument(r, app.Version)
+	if err != nil {
+		return err
+	}
+	app.openAPIDocument = document
+	return nil
+}
+
+// registerAPIRoutes sets up the routes shared by every API version
+func (app *Application) registerAPIRoutes(r chi.Router) {
+	r.Get("/health", app.healthCheckHandler)
+	r.With(app.RateLimit("public", rateLimitPublic)).Get("/public/plans/{token}", app.PublicPlanHandler)
+
+	// Protected routes
+	r.Group(func(r chi.Router) {
+		// Add authentication middleware
+		r.Use(app.AuthMiddleware)
+		r.Use(app.RateLimit("api", rateLimitAPI))
+
+		// User routes. Account management needs a signed-in session.
+		r.Route("/user", func(r chi.Router) {
+			r.With(app.RequireScope(ScopeProfileRead)).Post("/initialize", app.WithAuth(app.InitializeUserHandler))
+			r.With(app.RequireScope(ScopeProfileRead)).Get("/profile", app.WithAuth(app.GetUserProfileHandler))
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.RequireSession)
 
-			// User routes
-			r.Route("/user", func(r chi.Router) {
-				r.Post("/initialize", app.WithAuth(app.InitializeUserHandler))
-				r.Get("/profile", app.WithAuth(app.GetUserProfileHandler))
+				r.Patch("/profile", app.WithAuth(app.UpdateUserProfileHandler))
+				r.With(app.RateLimit("exports", rateLimitExports)).Post("/data-export", app.WithAuth(app.RequestDataExportHandler))
+				r.Get("/data-exports", app.WithAuth(app.GetDataExportsHandler))
+				r.Get("/data-exports/{id}", app.WithAuth(app.GetDataExportHandler))
+				r.Get("/data-exports/{id}/download", app.WithAuth(app.DownloadDataExportHandler))
+
+				r.Post("/tokens", app.WithAuth(app.CreateAccessTokenHandler))
+				r.Get("/tokens", app.WithAuth(app.GetAccessTokensHandler))
+				r.Delete("/tokens/{id}", app.WithAuth(app.RevokeAccessTokenHandler))
+
+				r.Get("/plan-invites", app.WithAuth(app.GetMyPlanInvitesHandler))
+				r.Post("/plan-invites/accept", app.WithAuth(app.AcceptPlanInviteHandler))
+				r.Post("/plan-invites/{id}/accept", app.WithAuth(app.AcceptMyPlanInviteHandler))
 			})
+		})
+
+		// Study plans routes
+		r.Route("/study-plans", func(r chi.Router) {
+			read := r.With(app.RequireScope(ScopePlansRead))
+			write := r.With(app.RequireScope(ScopePlansWrite))
+
+			write.Post("/", app.WithAuth(app.createStudyPlanHandler))
+			read.Get("/", app.WithAuth(app.GetStudyPlansHandler))
+			read.Get("/{id}", app.WithAuth(app.GetStudyPlanHandler))
+			write.Put("/{id}", app.WithAuth(app.UpdateStudyPlanHandler))
+			write.Patch("/{id}", app.WithAuth(app.PatchStudyPlanHandler))
+			write.Delete("/{id}", app.WithAuth(app.DeleteStudyPlanHandler))
+			r.With(app.RequireScope(ScopePlansRead, ScopeTasksRead)).Get("/{id}/tasks", app.WithAuth(app.GetStudyPlanTasksHandler))
+
+			// Sharing
+			read.Get("/{id}/members", app.WithAuth(app.GetPlanMembersHandler))
+			write.Put("/{id}/members/{userID}", app.WithAuth(app.SetPlanMemberRoleHandler))
+			write.Delete("/{id}/members/{userID}", app.WithAuth(app.RemovePlanMemberHandler))
+			write.Post("/{id}/invites", app.WithAuth(app.CreatePlanInviteHandler))
+			read.Get("/{id}/invites", app.WithAuth(app.GetPlanInvitesHandler))
+			write.Delete("/{id}/invites/{inviteID}", app.WithAuth(app.RevokePlanInviteHandler))
+			write.Post("/{id}/share", app.WithAuth(app.CreatePlanShareLinkHandler))
+			read.Get("/{id}/share", app.WithAuth(app.GetPlanShareLinksHandler))
+			write.Delete("/{id}/share/{linkID}", app.WithAuth(app.RevokePlanShareLinkHandler))
+		})
+
+		r.With(app.RequireScope(ScopePlansWrite)).Post("/public/plans/{token}/copy", app.WithAuth(app.CopySharedPlanHandler))
+
+		// Study tasks routes
+		r.Route("/study-tasks", func(r chi.Router) {
+			read := r.With(app.RequireScope(ScopeTasksRead))
+			write := r.With(app.RequireScope(ScopeTasksWrite))
+
+			write.Post("/", app.WithAuth(app.CreateStudyTaskHandler))
+			read.Get("/", app.WithAuth(app.GetStudyTasksHandler))
+			read.Get("/{id}", app.WithAuth(app.GetStudyTaskHandler))
+			write.Put("/{id}", app.WithAuth(app.UpdateStudyTaskHandler))
+			write.Patch("/{id}", app.WithAuth(app.PatchStudyTaskHandler))
+			write.Delete("/{id}", app.WithAuth(app.DeleteStudyTaskHandler))
+			write.Patch("/{id}/status", app.WithAuth(app.UpdateStudyTaskStatusHandler))
+		})
+
+		// Classrooms
+		r.Route("/classrooms", func(r chi.Router) {
+			read := r.With(app.RequireScope(ScopeClassroomsRead))
+			write := r.With(app.RequireScope(ScopeClassroomsWrite))
+
+			write.Post("/", app.WithAuth(app.CreateClassroomHandler))
+			read.Get("/", app.WithAuth(app.GetClassroomsHandler))
+			write.Post("/join", app.WithAuth(app.JoinClassroomHandler))
+			read.Get("/{id}", app.WithAuth(app.GetClassroomHandler))
+			write.Put("/{id}", app.WithAuth(app.UpdateClassroomHandler))
+			write.Delete("/{id}", app.WithAuth(app.DeleteClassroomHandler))
+			write.Post("/{id}/join", app.WithAuth(app.JoinOrganizationClassroomHandler))
+			write.Post("/{id}/join-code", app.WithAuth(app.RotateClassroomJoinCodeHandler))
+			write.Post("/{id}/publish", app.WithAuth(app.PublishClassroomHandler))
+			read.Get("/{id}/students", app.WithAuth(app.GetClassroomStudentsHandler))
+			write.Delete("/{id}/students/{userID}", app.WithAuth(app.RemoveClassroomStudentHandler))
+			read.Get("/{id}/progress", app.WithAuth(app.GetClassroomProgressHandler))
+		})
+
+		// Plan templates
+		r.Route("/templates", func(r chi.Router) {
+			read := r.With(app.RequireScope(ScopeTemplatesRead))
+			write := r.With(app.RequireScope(ScopeTemplatesWrite))
+
+			write.Post("/", app.WithAuth(app.CreatePlanTemplateHandler))
+			read.Get("/", app.WithAuth(app.GetPlanTemplatesHandler))
+			read.Get("/tags", app.WithAuth(app.GetPlanTemplateTagsHandler))
+			read.Get("/{id}", app.WithAuth(app.GetPlanTemplateHandler))
+			write.Put("/{id}", app.WithAuth(app.UpdatePlanTemplateHandler))
+			write.Delete("/{id}", app.WithAuth(app.DeletePlanTemplateHandler))
+			write.Post("/{id}/publish", app.WithAuth(app.PublishPlanTemplateHandler))
+			write.Delete("/{id}/publish", app.WithAuth(app.UnpublishPlanTemplateHandler))
+			r.With(app.RequireScope(ScopeTemplatesRead, ScopePlansWrite)).Post("/{id}/use", app.WithAuth(app.UsePlanTemplateHandler))
+		})
+
+		// Subjects, units and topics
+		r.Route("/subjects", func(r chi.Router) {
+			read := r.With(app.RequireScope(ScopeSubjectsRead))
+			write := r.With(app.RequireScope(ScopeSubjectsWrite))
+
+			write.Post("/", app.WithAuth(app.CreateSubjectHandler))
+			read.Get("/", app.WithAuth(app.GetSubjectsHandler))
+			read.Get("/{id}", app.WithAuth(app.GetSubjectHandler))
+			write.Put("/{id}", app.WithAuth(app.UpdateSubjectHandler))
+			write.Delete("/{id}", app.WithAuth(app.DeleteSubjectHandler))
+		})
+
+		// Exams
+		r.Route("/exams", func(r chi.Router) {
+			read := r.With(app.RequireScope(ScopeExamsRead))
+			write := r.With(app.RequireScope(ScopeExamsWrite))
+
+			write.Post("/", app.WithAuth(app.CreateExamHandler))
+			read.Get("/", app.WithAuth(app.GetExamsHandler))
+			read.Get("/countdown", app.WithAuth(app.GetExamCountdownHandler))
+			read.Get("/insights", app.WithAuth(app.GetExamInsightsHandler))
+			read.Get("/{id}", app.WithAuth(app.GetExamHandler))
+			write.Put("/{id}", app.WithAuth(app.UpdateExamHandler))
+			write.Delete("/{id}", app.WithAuth(app.DeleteExamHandler))
+		})
+
+		// Goals
+		r.Route("/goals", func(r chi.Router) {
+			read := r.With(app.RequireScope(ScopeGoalsRead))
+			write := r.With(app.RequireScope(ScopeGoalsWrite))
+
+			write.Post("/", app.WithAuth(app.CreateGoalHandler))
+			read.Get("/", app.WithAuth(app.GetGoalsHandler))
+			read.Get("/{id}", app.WithAuth(app.GetGoalHandler))
+			write.Put("/{id}", app.WithAuth(app.UpdateGoalHandler))
+			write.Delete("/{id}", app.WithAuth(app.DeleteGoalHandler))
+			read.Get("/{id}/history", app.WithAuth(app.GetGoalHistoryHandler))
+		})
+
+		// Achievements
+		r.Route("/achievements", func(r chi.Router) {
+			read := r.With(app.RequireScope(ScopeAchievementsRead))
+
+			read.Get("/", app.WithAuth(app.GetAchievementsHandler))
+		})
+
+		// Outbound webhook endpoints
+		r.Route("/webhook-endpoints", func(r chi.Router) {
+			r.Use(app.RateLimit("webhooks", rateLimitWebhooks))
+
+			read := r.With(app.RequireScope(ScopeWebhooksRead))
+			write := r.With(app.RequireScope(ScopeWebhooksWrite))
+
+			write.Post("/", app.WithAuth(app.CreateWebhookEndpointHandler))
+			read.Get("/", app.WithAuth(app.GetWebhookEndpointsHandler))
+			read.Get("/{id}", app.WithAuth(app.GetWebhookEndpointHandler))
+			write.Put("/{id}", app.WithAuth(app.UpdateWebhookEndpointHandler))
+			write.Delete("/{id}", app.WithAuth(app.DeleteWebhookEndpointHandler))
+			write.Post("/{id}/rotate-secret", app.WithAuth(app.RotateWebhookEndpointSecretHandler))
+			read.Get("/{id}/deliveries", app.WithAuth(app.GetWebhookDeliveriesHandler))
+			read.Get("/{id}/deliveries/{deliveryID}", app.WithAuth(app.GetWebhookDeliveryHandler))
+			write.Post("/{id}/deliveries/{deliveryID}/redeliver", app.WithAuth(app.RedeliverWebhookDeliveryHandler))
+		})
+
+		// Activity log
+		r.With(app.RequireScope(ScopeActivityRead)).Get("/activity", app.WithAuth(app.GetActivityHandler))
+
+		// Trash routes
+		r.Route("/trash", func(r chi.Router) {
+			r.With(app.RequireScope(ScopePlansRead, ScopeTasksRead)).Get("/", app.WithAuth(app.GetTrashHandler))
+			r.With(app.RequireScope(ScopePlansWrite)).Post("/plans/{id}/restore", app.WithAuth(app.RestoreStudyPlanHandler))
+			r.With(app.RequireScope(ScopePlansWrite)).Delete("/plans/{id}", app.WithAuth(app.PurgeStudyPlanHandler))
+			r.With(app.RequireScope(ScopeTasksWrite)).Post("/tasks/{id}/restore", app.WithAuth(app.RestoreStudyTaskHandler))
+			r.With(app.RequireScope(ScopeTasksWrite)).Delete("/tasks/{id}", app.WithAuth(app.PurgeStudyTaskHandler))
+		})
+
+		// Real-time events are for the apps, not for scripts
+		r.Route("/events", func(r chi.Router) {
+			r.Use(app.RequireSession)
+
+			r.Get("/stream", app.WithAuth(app.EventsStreamHandler))
+			r.Post("/timer", app.WithAuth(app.TimerTickHandler))
+		})
+
+		// Admin routes. Support staff can look; only admins can act.
+		r.Route("/admin", func(r chi.Router) {
+			r.Use(app.RequireSession)
+			r.Use(app.RequireRole(RoleSupport, RoleAdmin))
+
+			r.Route("/users", func(r chi.Router) {
+				admin := r.With(app.RequireRole(RoleAdmin))
+
+				r.Get("/", app.WithAuth(app.SearchUsersHandler))
+				r.Get("/{id}", app.WithAuth(app.GetUserHandler))
+				r.Get("/{id}/study-plans", app.WithAuth(app.GetUserStudyPlansHandler))
+				r.Get("/{id}/study-plans/{planID}/tasks", app.WithAuth(app.GetUserStudyPlanTasksHandler))
+				admin.Put("/{id}/role", app.WithAuth(app.SetUserRoleHandler))
+				admin.Post("/{id}/ban", app.WithAuth(app.BanUserHandler))
+				admin.Post("/{id}/unban", app.WithAuth(app.UnbanUserHandler))
+				admin.Post("/{id}/impersonate", app.WithAuth(app.StartImpersonationHandler))
+			})
+
+			r.Route("/impersonations", func(r chi.Router) {
+				r.Use(app.RequireRole(RoleAdmin))
 
-			// Study plans routes
-			r.Route("/study-plans", func(r chi.Router) {
-				r.Post("/", app.WithAuth(app.createStudyPlanHandler))
-				r.Get("/", app.WithAuth(app.GetStudyPlansHandler))
-				r.Route("/{id}", func(r chi.Router) {
-					r.Get("/", app.WithAuth(app.GetStudyPlanHandler))
-					r.Get("/tasks", app.WithAuth(app.GetStudyPlanTasksHandler))
-				})
+				r.Get("/", app.WithAuth(app.GetImpersonationsHandler))
+				r.Delete("/{id}", app.WithAuth(app.EndImpersonationHandler))
 			})
 
-			// Study tasks routes
-			r.Route("/study-tasks", func(r chi.Router) {
-				r.Post("/", app.WithAuth(app.CreateStudyTaskHandler))
-				r.Get("/", app.WithAuth(app.GetStudyTasksHandler))
-				r.Route("/{id}", func(r chi.Router) {
-					r.Get("/", app.WithAuth(app.GetStudyTaskHandler))
-					r.Put("/", app.WithAuth(app.UpdateStudyTaskHandler))
-					r.Delete("/", app.WithAuth(app.DeleteStudyTaskHandler))
-					r.Patch("/status", app.WithAuth(app.UpdateStudyTaskStatusHandler))
-				})
+			r.Route("/webhook-events", func(r chi.Router) {
+				r.Get("/", app.WithAuth(app.GetWebhookEventsHandler))
+				r.Get("/{id}", app.WithAuth(app.GetWebhookEventHandler))
+				r.With(app.RequireRole(RoleAdmin)).Post("/{id}/replay", app.WithAuth(app.ReplayWebhookEventHandler))
 			})
 		})
 	})
@@ -1,53 +1,292 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/events"
 	"github.com/mustaphalimar/prepilot/internal/store"
+	"github.com/mustaphalimar/prepilot/internal/webhooks"
 )
 
-func (app *Application) createStudyPlanHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
-	var studyPlanPayload store.CreateStudyPlanParams
+// CreateStudyPlanRequest represents the request body for creating a study plan.
+// A plan runs from start_date to end_date, which is no later than the exam.
+// It belongs to the subject with subject_id, whose name replaces subject,
+// or else to the top-level subject named subject, created if needed.
+type CreateStudyPlanRequest struct {
+	Title       string     `json:"title" validate:"title"`
+	Subject     string     `json:"subject" validate:"omitempty,title"`
+	SubjectID   *uuid.UUID `json:"subject_id"`
+	Description *string    `json:"description" validate:"omitempty,notes"`
+	ExamDate    time.Time  `json:"exam_date" validate:"required,gtefield=EndDate"`
+	StartDate   time.Time  `json:"start_date" validate:"required"`
+	EndDate     time.Time  `json:"end_date" validate:"required,gtefield=StartDate"`
+}
+
+// UpdateStudyPlanRequest represents the request body for updating a study plan
+type UpdateStudyPlanRequest struct {
+	Title       string     `json:"title" validate:"title"`
+	Subject     string     `json:"subject" validate:"omitempty,title"`
+	SubjectID   *uuid.UUID `json:"subject_id"`
+	Description *string    `json:"description" validate:"omitempty,notes"`
+	ExamDate    time.Time  `json:"exam_date" validate:"required,gtefield=EndDate"`
+	StartDate   time.Time  `json:"start_date" validate:"required"`
+	EndDate     time.Time  `json:"end_date" validate:"required,gtefield=StartDate"`
+}
+
+// StudyPlanResponse represents the response format for study plans
+type StudyPlanResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Subject     string     `json:"subject"`
+	SubjectID   *uuid.UUID `json:"subject_id"`
+	Description *string    `json:"description"`
+	ExamDate    time.Time  `json:"exam_date"`
+	StartDate   time.Time  `json:"start_date"`
+	EndDate     time.Time  `json:"end_date"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	Version     int32      `json:"version"`
+	// Role is the caller's role on the plan, when known
+	Role string `json:"role,omitempty"`
+	// ClassroomID is set on a student's copy of a classroom plan
+	ClassroomID *uuid.UUID `json:"classroom_id,omitempty"`
+}
+
+// convertStudyPlanToResponse converts a store.StudyPlan to StudyPlanResponse
+func convertStudyPlanToResponse(plan store.StudyPlan) StudyPlanResponse {
+	response := StudyPlanResponse{
+		ID:        plan.ID,
+		Title:     plan.Title,
+		Subject:   plan.Subject,
+		ExamDate:  plan.ExamDate,
+		StartDate: plan.StartDate,
+		EndDate:   plan.EndDate,
+		CreatedAt: plan.CreatedAt.Time,
+		UpdatedAt: plan.UpdatedAt.Time,
+		Version:   plan.Version,
+	}
+
+	if plan.SubjectID.Valid {
+		subjectID := plan.SubjectID.UUID
+		response.SubjectID = &subjectID
+	}
+
+	if plan.ClassroomID.Valid {
+		classroomID := plan.ClassroomID.UUID
+		response.ClassroomID = &classroomID
+	}
+
+	if plan.Description.Valid {
+		description := plan.Description.String
+		response.Description = &description
+	}
+
+	return response
+}
+
+// PatchStudyPlanRequest is a JSON merge patch for a study plan. Absent
+// fields are left unchanged and null clears the description. Setting only
+// subject moves the plan to the top-level subject of that name.
+type PatchStudyPlanRequest struct {
+	Title       Optional[string]    `json:"title"`
+	Subject     Optional[string]    `json:"subject"`
+	SubjectID   Optional[uuid.UUID] `json:"subject_id"`
+	Description Optional[string]    `json:"description"`
+	ExamDate    Optional[time.Time] `json:"exam_date"`
+	StartDate   Optional[time.Time] `json:"start_date"`
+	EndDate     Optional[time.Time] `json:"end_date"`
+}
+
+func (req PatchStudyPlanRequest) validate() error {
+	if err := requireNonNull("title", req.Title); err != nil {
+		return err
+	}
+	if err := requireNonNull("subject", req.Subject); err != nil {
+		return err
+	}
+	if err := requireNonNull("subject_id", req.SubjectID); err != nil {
+		return err
+	}
+	if err := requireNonNull("exam_date", req.ExamDate); err != nil {
+		return err
+	}
+	if err := requireNonNull("start_date", req.StartDate); err != nil {
+		return err
+	}
+	return requireNonNull("end_date", req.EndDate)
+}
+
+// apply returns the plan as it will be after the patch, so the result can
+// be validated like a full update
+func (req PatchStudyPlanRequest) apply(plan store.StudyPlan) UpdateStudyPlanRequest {
+	merged := UpdateStudyPlanRequest{
+		Title:       plan.Title,
+		Subject:     plan.Subject,
+		SubjectID:   convertStudyPlanToResponse(plan).SubjectID,
+		Description: convertStudyPlanToResponse(plan).Description,
+		ExamDate:    plan.ExamDate,
+		StartDate:   plan.StartDate,
+		EndDate:     plan.EndDate,
+	}
 
-	if err := app.readJSON(w, r, &studyPlanPayload); err != nil {
+	if req.Title.Set {
+		merged.Title = req.Title.Value
+	}
+	if req.Subject.Set {
+		merged.Subject = req.Subject.Value
+		merged.SubjectID = nil
+	}
+	if req.SubjectID.Set {
+		merged.SubjectID = &req.SubjectID.Value
+	}
+	if req.Description.Set {
+		merged.Description = nil
+		if !req.Description.Null {
+			merged.Description = &req.Description.Value
+		}
+	}
+	if req.ExamDate.Set {
+		merged.ExamDate = req.ExamDate.Value
+	}
+	if req.StartDate.Set {
+		merged.StartDate = req.StartDate.Value
+	}
+	if req.EndDate.Set {
+		merged.EndDate = req.EndDate.Value
+	}
+	return merged
+}
+
+func (app *Application) createStudyPlanHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req CreateStudyPlanRequest
+	if err := app.readJSON(w, r, &req); err != nil {
 		app.badRequestError(w, r, err)
 		return
 	}
 
-	if err := Validate.Struct(studyPlanPayload); err != nil {
+	if err := Validate.Struct(req); err != nil {
 		app.badRequestError(w, r, err)
 		return
 	}
 
-	// Associate with authenticated user
-	studyPlanPayload.UserID = user.ClerkID
+	subject, err := app.planSubject(r.Context(), app.Queries, user.ClerkID, req.SubjectID, req.Subject)
+	if err != nil {
+		app.writeSubjectError(w, r, err)
+		return
+	}
 
-	studyPlan, err := app.Queries.CreateStudyPlan(r.Context(), studyPlanPayload)
+	params := store.CreateStudyPlanParams{
+		UserID:    user.ClerkID,
+		Title:     req.Title,
+		Subject:   subject.Name,
+		SubjectID: uuid.NullUUID{UUID: subject.ID, Valid: true},
+		ExamDate:  req.ExamDate,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	}
+	if req.Description != nil {
+		params.Description = *req.Description
+	}
+
+	studyPlan, err := app.createStudyPlan(r.Context(), params)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	app.writeJSON(w, http.StatusCreated, studyPlan)
+	app.publishEvent(r.Context(), user.ClerkID, events.PlanCreated, convertStudyPlanToResponse(studyPlan))
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.PlanCreated,
+		EntityType: activityStudyPlan,
+		EntityID:   studyPlan.ID.String(),
+		After:      planSnapshot(studyPlan),
+	})
+	app.enqueueWebhook(r.Context(), user.ClerkID, webhooks.PlanCreated, convertStudyPlanToResponse(studyPlan))
+
+	response := convertStudyPlanToResponse(studyPlan)
+	response.Role = PlanRoleOwner
+	w.Header().Set("ETag", planETag(studyPlan.Version, PlanRoleOwner))
+	app.respond(w, r, http.StatusCreated, response, studyPlan)
 }
 
-// GetStudyPlansHandler retrieves all study plans for the authenticated user
+// createStudyPlan creates a plan with its creator as the owner
+func (app *Application) createStudyPlan(ctx context.Context, params store.CreateStudyPlanParams) (store.StudyPlan, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return store.StudyPlan{}, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	plan, err := queries.CreateStudyPlan(ctx, params)
+	if err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if _, err := queries.AddPlanMember(ctx, store.AddPlanMemberParams{
+		PlanID: plan.ID,
+		UserID: params.UserID,
+		Role:   PlanRoleOwner,
+	}); err != nil {
+		return store.StudyPlan{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return store.StudyPlan{}, fmt.Errorf("failed to commit study plan: %w", err)
+	}
+	return plan, nil
+}
+
+// GetStudyPlansHandler retrieves the study plans the authenticated user
+// created or was invited to
 func (app *Application) GetStudyPlansHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
-	studyPlans, err := app.Queries.GetStudyPlansByUserId(r.Context(), user.ClerkID)
+	studyPlans, err := app.Queries.GetStudyPlansForMember(r.Context(), user.ClerkID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	memberships, err := app.Queries.GetPlanMembershipsByUser(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	roles := make(map[uuid.UUID]string, len(memberships))
+	for _, member := range memberships {
+		roles[member.PlanID] = member.Role
+	}
+
 	// Ensure we always return an empty array instead of null when no study plans exist
 	if studyPlans == nil {
 		studyPlans = []store.StudyPlan{}
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, studyPlans); err != nil {
+	ids := make([]uuid.UUID, len(studyPlans))
+	versions := make([]int32, len(studyPlans))
+	planRoles := make([]string, len(studyPlans))
+	for i, plan := range studyPlans {
+		ids[i], versions[i], planRoles[i] = plan.ID, plan.Version, roles[plan.ID]
+	}
+	if notModified(w, r, listETag(ids, versions, planRoles)) {
+		return
+	}
+
+	response := make([]StudyPlanResponse, len(studyPlans))
+	for i, plan := range studyPlans {
+		response[i] = convertStudyPlanToResponse(plan)
+		response[i].Role = roles[plan.ID]
+	}
+
+	if err := app.respond(w, r, http.StatusOK, response, Envelope{Data: studyPlans}); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
@@ -61,23 +300,18 @@ func (app *Application) GetStudyPlanHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	studyPlan, err := app.Queries.GetStudyPlanByID(r.Context(), planID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			app.writeJSONError(w, http.StatusNotFound, "Study plan not found")
-			return
-		}
-		app.internalServerError(w, r, err)
+	studyPlan, role, ok := app.getPlanForMember(w, r, user, planID, PlanRoleViewer)
+	if !ok {
 		return
 	}
 
-	// Verify the plan belongs to the authenticated user
-	if studyPlan.UserID != user.ClerkID {
-		app.writeJSONError(w, http.StatusForbidden, "Access denied")
+	if notModified(w, r, planETag(studyPlan.Version, role)) {
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, studyPlan)
+	response := convertStudyPlanToResponse(studyPlan)
+	response.Role = role
+	app.respond(w, r, http.StatusOK, response, studyPlan)
 }
 
 // GetStudyPlanTasksHandler retrieves all tasks for a specific study plan
@@ -89,26 +323,22 @@ func (app *Application) GetStudyPlanTasksHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
-	// First verify the plan exists and belongs to the user
-	studyPlan, err := app.Queries.GetStudyPlanByID(r.Context(), planID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			app.writeJSONError(w, http.StatusNotFound, "Study plan not found")
-			return
-		}
-		app.internalServerError(w, r, err)
+	// First verify the user is a member of the plan
+	if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleViewer); !ok {
 		return
 	}
 
-	if studyPlan.UserID != user.ClerkID {
-		app.writeJSONError(w, http.StatusForbidden, "Access denied")
+	// Get tasks for the plan, with the user's own progress
+	tasks, err := app.Queries.GetTasksByPlanForMember(r.Context(), store.GetTasksByPlanForMemberParams{
+		UserID: user.ClerkID,
+		PlanID: planID,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
 		return
 	}
 
-	// Get tasks for the plan
-	tasks, err := app.Queries.GetTasksByPlan(r.Context(), uuid.NullUUID{UUID: planID, Valid: true})
-	if err != nil {
-		app.internalServerError(w, r, err)
+	if notModified(w, r, studyTasksETag(tasks)) {
 		return
 	}
 
@@ -123,5 +353,221 @@ func (app *Application) GetStudyPlanTasksHandler(w http.ResponseWriter, r *http.
 		response = []StudyTaskResponse{}
 	}
 
-	app.writeJSON(w, http.StatusOK, response)
+	app.respond(w, r, http.StatusOK, response, response)
+}
+
+// UpdateStudyPlanHandler updates a study plan
+func (app *Application) UpdateStudyPlanHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planIDStr := chi.URLParam(r, "id")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var req UpdateStudyPlanRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	studyPlan, role, ok := app.getPlanForMember(w, r, user, planID, PlanRoleEditor)
+	if !ok {
+		return
+	}
+
+	expectedVersion, ok := app.checkIfMatch(w, r, studyPlan.Version)
+	if !ok {
+		return
+	}
+
+	// The subject is only looked up when the request names one, so one
+	// deleted since is not created again
+	subjectName, subjectID := studyPlan.Subject, studyPlan.SubjectID
+	if req.SubjectID != nil || req.Subject != "" {
+		subject, err := app.planSubject(r.Context(), app.Queries, studyPlan.UserID, req.SubjectID, req.Subject)
+		if err != nil {
+			app.writeSubjectError(w, r, err)
+			return
+		}
+		subjectName, subjectID = subject.Name, uuid.NullUUID{UUID: subject.ID, Valid: true}
+	}
+
+	params := store.UpdateStudyPlanParams{
+		ID:              planID,
+		ExpectedVersion: expectedVersion,
+		Title:           req.Title,
+		Subject:         subjectName,
+		SubjectID:       subjectID,
+		Description:     stringToNullString(req.Description),
+		ExamDate:        req.ExamDate,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+	}
+
+	previous := studyPlan
+	studyPlan, err = app.Queries.UpdateStudyPlan(r.Context(), params)
+	if err != nil {
+		// Changed or deleted since it was read
+		if err == sql.ErrNoRows {
+			app.preconditionFailed(w, r)
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.publishPlanEvent(r.Context(), studyPlan.ID, events.PlanUpdated, convertStudyPlanToResponse(studyPlan))
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.PlanUpdated,
+		EntityType: activityStudyPlan,
+		EntityID:   studyPlan.ID.String(),
+		Before:     planSnapshot(previous),
+		After:      planSnapshot(studyPlan),
+	})
+
+	response := convertStudyPlanToResponse(studyPlan)
+	response.Role = role
+	w.Header().Set("ETag", planETag(studyPlan.Version, role))
+	app.respond(w, r, http.StatusOK, response, response)
+}
+
+// PatchStudyPlanHandler applies a JSON merge patch to a study plan
+func (app *Application) PatchStudyPlanHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var req PatchStudyPlanRequest
+	if !app.readMergePatch(w, r, &req) {
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	studyPlan, role, ok := app.getPlanForMember(w, r, user, planID, PlanRoleEditor)
+	if !ok {
+		return
+	}
+
+	merged := req.apply(studyPlan)
+	if err := Validate.Struct(merged); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	expectedVersion, ok := app.checkIfMatch(w, r, studyPlan.Version)
+	if !ok {
+		return
+	}
+
+	// Left as is unless the patch names a subject
+	var subjectName sql.NullString
+	var subjectID uuid.NullUUID
+	if req.Subject.Set || req.SubjectID.Set {
+		subject, err := app.planSubject(r.Context(), app.Queries, studyPlan.UserID, merged.SubjectID, merged.Subject)
+		if err != nil {
+			app.writeSubjectError(w, r, err)
+			return
+		}
+		subjectName = sql.NullString{String: subject.Name, Valid: true}
+		subjectID = uuid.NullUUID{UUID: subject.ID, Valid: true}
+	}
+
+	previous := studyPlan
+	studyPlan, err = app.Queries.PatchStudyPlan(r.Context(), store.PatchStudyPlanParams{
+		ID:              planID,
+		Title:           optionalToNullString(req.Title),
+		Subject:         subjectName,
+		SubjectID:       subjectID,
+		SetDescription:  req.Description.Set,
+		Description:     optionalToNullString(req.Description),
+		ExamDate:        optionalToNullTime(req.ExamDate),
+		StartDate:       optionalToNullTime(req.StartDate),
+		EndDate:         optionalToNullTime(req.EndDate),
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		// Changed or deleted since it was read
+		if err == sql.ErrNoRows {
+			app.preconditionFailed(w, r)
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.publishPlanEvent(r.Context(), studyPlan.ID, events.PlanUpdated, convertStudyPlanToResponse(studyPlan))
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.PlanUpdated,
+		EntityType: activityStudyPlan,
+		EntityID:   studyPlan.ID.String(),
+		Before:     planSnapshot(previous),
+		After:      planSnapshot(studyPlan),
+	})
+
+	response := convertStudyPlanToResponse(studyPlan)
+	response.Role = role
+	w.Header().Set("ETag", planETag(studyPlan.Version, role))
+	app.respond(w, r, http.StatusOK, response, response)
+}
+
+// DeleteStudyPlanHandler moves a study plan and its tasks to the trash
+func (app *Application) DeleteStudyPlanHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	planIDStr := chi.URLParam(r, "id")
+	planID, err := uuid.Parse(planIDStr)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	studyPlan, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleOwner)
+	if !ok {
+		return
+	}
+
+	expectedVersion, ok := app.checkIfMatch(w, r, studyPlan.Version)
+	if !ok {
+		return
+	}
+
+	_, err = app.Queries.DeleteStudyPlan(r.Context(), store.DeleteStudyPlanParams{
+		ID:              planID,
+		ExpectedVersion: expectedVersion,
+	})
+	if err != nil {
+		// Changed or deleted since it was read
+		if err == sql.ErrNoRows {
+			app.preconditionFailed(w, r)
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.publishPlanEvent(r.Context(), planID, events.PlanDeleted, map[string]uuid.UUID{"id": planID})
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.PlanDeleted,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+		Before:     planSnapshot(studyPlan),
+	})
+
+	app.respondDeleted(w, r, "Study plan moved to trash")
 }
@@ -1,46 +1,138 @@
 package app
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
 	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/events"
 	"github.com/mustaphalimar/prepilot/internal/store"
 )
 
-// CreateStudyTaskRequest represents the request body for creating a study task
+// CreateStudyTaskRequest represents the request body for creating a study
+// task. SubjectID narrows the plan's subject down, usually to a unit or
+// topic; MinutesSpent is the time the user spent on the task.
 type CreateStudyTaskRequest struct {
-	PlanID      *uuid.UUID `json:"plan_id"`
-	Title       string     `json:"title" validate:"required"`
-	DueDate     time.Time  `json:"due_date" validate:"required"`
-	IsCompleted *bool      `json:"is_completed"`
-	Priority    *int32     `json:"priority"`
-	Notes       *string    `json:"notes"`
+	PlanID       *uuid.UUID `json:"plan_id"`
+	Title        string     `json:"title" validate:"title"`
+	DueDate      time.Time  `json:"due_date" validate:"required"`
+	IsCompleted  *bool      `json:"is_completed"`
+	Priority     *int32     `json:"priority" validate:"omitempty,priority"`
+	Notes        *string    `json:"notes" validate:"omitempty,notes"`
+	SubjectID    *uuid.UUID `json:"subject_id"`
+	MinutesSpent *int32     `json:"minutes_spent" validate:"omitempty,minutes"`
 }
 
 // UpdateStudyTaskRequest represents the request body for updating a study task
 type UpdateStudyTaskRequest struct {
-	Title       string    `json:"title" validate:"required"`
-	DueDate     time.Time `json:"due_date" validate:"required"`
-	IsCompleted bool      `json:"is_completed"`
-	Priority    *int32    `json:"priority"`
-	Notes       *string   `json:"notes"`
+	Title        string     `json:"title" validate:"title"`
+	DueDate      time.Time  `json:"due_date" validate:"required"`
+	IsCompleted  bool       `json:"is_completed"`
+	Priority     *int32     `json:"priority" validate:"omitempty,priority"`
+	Notes        *string    `json:"notes" validate:"omitempty,notes"`
+	SubjectID    *uuid.UUID `json:"subject_id"`
+	MinutesSpent *int32     `json:"minutes_spent" validate:"omitempty,minutes"`
+}
+
+// UpdateStudyTaskStatusRequest represents the request body for completing or reopening a task
+type UpdateStudyTaskStatusRequest struct {
+	IsCompleted bool `json:"is_completed"`
+}
+
+// PatchStudyTaskRequest is a JSON merge patch for a study task. Absent
+// fields are left unchanged and null clears priority, notes, subject_id or
+// minutes_spent.
+type PatchStudyTaskRequest struct {
+	Title        Optional[string]    `json:"title"`
+	DueDate      Optional[time.Time] `json:"due_date"`
+	IsCompleted  Optional[bool]      `json:"is_completed"`
+	Priority     Optional[int32]     `json:"priority"`
+	Notes        Optional[string]    `json:"notes"`
+	SubjectID    Optional[uuid.UUID] `json:"subject_id"`
+	MinutesSpent Optional[int32]     `json:"minutes_spent"`
+}
+
+func (req PatchStudyTaskRequest) validate() error {
+	if err := requireNonNull("title", req.Title); err != nil {
+		return err
+	}
+	if err := requireNonNull("due_date", req.DueDate); err != nil {
+		return err
+	}
+	return requireNonNull("is_completed", req.IsCompleted)
+}
+
+// apply returns the task as it will be after the patch, so the result can
+// be validated like a full update
+func (req PatchStudyTaskRequest) apply(task store.StudyTask) UpdateStudyTaskRequest {
+	response := convertStudyTaskToResponse(task)
+	merged := UpdateStudyTaskRequest{
+		Title:        response.Title,
+		DueDate:      response.DueDate,
+		IsCompleted:  response.IsCompleted,
+		Priority:     response.Priority,
+		Notes:        response.Notes,
+		SubjectID:    response.SubjectID,
+		MinutesSpent: response.MinutesSpent,
+	}
+
+	if req.Title.Set {
+		merged.Title = req.Title.Value
+	}
+	if req.DueDate.Set {
+		merged.DueDate = req.DueDate.Value
+	}
+	if req.IsCompleted.Set {
+		merged.IsCompleted = req.IsCompleted.Value
+	}
+	if req.Priority.Set {
+		merged.Priority = nil
+		if !req.Priority.Null {
+			merged.Priority = &req.Priority.Value
+		}
+	}
+	if req.Notes.Set {
+		merged.Notes = nil
+		if !req.Notes.Null {
+			merged.Notes = &req.Notes.Value
+		}
+	}
+	if req.SubjectID.Set {
+		merged.SubjectID = nil
+		if !req.SubjectID.Null {
+			merged.SubjectID = &req.SubjectID.Value
+		}
+	}
+	if req.MinutesSpent.Set {
+		merged.MinutesSpent = nil
+		if !req.MinutesSpent.Null {
+			merged.MinutesSpent = &req.MinutesSpent.Value
+		}
+	}
+	return merged
 }
 
 // StudyTaskResponse represents the response format for study tasks
 type StudyTaskResponse struct {
-	ID          uuid.UUID  `json:"id"`
-	PlanID      *uuid.UUID `json:"plan_id"`
-	Title       string     `json:"title"`
-	DueDate     time.Time  `json:"due_date"`
-	IsCompleted bool       `json:"is_completed"`
-	Priority    *int32     `json:"priority"`
-	Notes       *string    `json:"notes"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID           uuid.UUID  `json:"id"`
+	PlanID       *uuid.UUID `json:"plan_id"`
+	Title        string     `json:"title"`
+	DueDate      time.Time  `json:"due_date"`
+	IsCompleted  bool       `json:"is_completed"`
+	Priority     *int32     `json:"priority"`
+	Notes        *string    `json:"notes"`
+	SubjectID    *uuid.UUID `json:"subject_id"`
+	MinutesSpent *int32     `json:"minutes_spent"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	Version      int32      `json:"version"`
 }
 
 // convertStudyTaskToResponse converts a store.StudyTask to StudyTaskResponse
@@ -51,6 +143,7 @@ func convertStudyTaskToResponse(task store.StudyTask) StudyTaskResponse {
 		DueDate:   task.DueDate,
 		CreatedAt: task.CreatedAt.Time,
 		UpdatedAt: task.UpdatedAt.Time,
+		Version:   task.Version,
 	}
 
 	if task.PlanID.Valid {
@@ -72,9 +165,178 @@ func convertStudyTaskToResponse(task store.StudyTask) StudyTaskResponse {
 		response.Notes = &notes
 	}
 
+	if task.SubjectID.Valid {
+		subjectID := task.SubjectID.UUID
+		response.SubjectID = &subjectID
+	}
+
+	if task.MinutesSpent.Valid {
+		minutes := task.MinutesSpent.Int32
+		response.MinutesSpent = &minutes
+	}
+
 	return response
 }
 
+// checkDueDateInPlan requires a due date to fall within the start and end
+// dates of the task's plan
+func checkDueDateInPlan(dueDate time.Time, plan store.StudyPlan) error {
+	due := dueDate.UTC().Format(time.DateOnly)
+	start := plan.StartDate.Format(time.DateOnly)
+	end := plan.EndDate.Format(time.DateOnly)
+	if due < start || due > end {
+		return &FieldError{
+			Field:   "due_date",
+			Code:    "plan_window",
+			Message: fmt.Sprintf("must be between %s and %s, the dates of the study plan", start, end),
+		}
+	}
+	return nil
+}
+
+// taskAccess is what a user can do with a task
+type taskAccess struct {
+	// plan is the task's plan, zero for tasks without one
+	plan store.StudyPlan
+	role string
+	// ownProgress is set when the user's progress is kept on the task
+	// itself: they created its plan, or the task when it has no plan.
+	// Other members' progress is kept in task_completions.
+	ownProgress bool
+	// completed is whether the user has completed the task
+	completed bool
+}
+
+// ownerID is who the task's subjects must belong to: the owner of its plan,
+// or the user for tasks without one
+func (access taskAccess) ownerID(user *UserClaims) string {
+	if access.plan.ID == uuid.Nil {
+		return user.ClerkID
+	}
+	return access.plan.UserID
+}
+
+// checkTaskSubject requires the subject of a task to belong to ownerID. On
+// failure an error response has been written and ok is false.
+func (app *Application) checkTaskSubject(w http.ResponseWriter, r *http.Request, ownerID string, subjectID *uuid.UUID) (ok bool) {
+	if subjectID == nil {
+		return true
+	}
+	if _, err := app.ownedSubject(r.Context(), app.Queries, "subject_id", ownerID, *subjectID); err != nil {
+		app.writeSubjectError(w, r, err)
+		return false
+	}
+	return true
+}
+
+// view returns the task as the user sees it, with their own progress
+func (access taskAccess) view(task store.StudyTask) store.StudyTask {
+	if !access.ownProgress {
+		task.IsCompleted = sql.NullBool{Bool: access.completed, Valid: true}
+	}
+	return task
+}
+
+// getTaskForMember loads a task and checks that the user has at least
+// minRole on its plan. Tasks without a plan belong to whoever created them.
+// On failure an error response has been written and ok is false.
+func (app *Application) getTaskForMember(w http.ResponseWriter, r *http.Request, user *UserClaims, taskID uuid.UUID, minRole string) (task store.StudyTask, access taskAccess, ok bool) {
+	task, err := app.Queries.GetTaskByID(r.Context(), taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Task not found")
+			return task, access, false
+		}
+		app.internalServerError(w, r, err)
+		return task, access, false
+	}
+
+	if !task.PlanID.Valid {
+		if task.CreatedBy.String != user.ClerkID {
+			app.writeJSONError(w, r, http.StatusForbidden, "Access denied")
+			return task, access, false
+		}
+		return task, taskAccess{role: PlanRoleOwner, ownProgress: true, completed: task.IsCompleted.Bool}, true
+	}
+
+	plan, role, ok := app.getPlanForMember(w, r, user, task.PlanID.UUID, minRole)
+	if !ok {
+		return task, access, false
+	}
+
+	access = taskAccess{plan: plan, role: role, ownProgress: plan.UserID == user.ClerkID}
+	if access.ownProgress {
+		access.completed = task.IsCompleted.Bool
+		return task, access, true
+	}
+
+	access.completed, err = app.Queries.IsTaskCompletedBy(r.Context(), store.IsTaskCompletedByParams{
+		TaskID: task.ID,
+		UserID: user.ClerkID,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return task, access, false
+	}
+	return task, access, true
+}
+
+// setMemberProgress records whether a plan member other than its creator
+// has completed a task
+func (app *Application) setMemberProgress(ctx context.Context, taskID uuid.UUID, userID string, completed bool) error {
+	if completed {
+		return app.Queries.CompleteTaskForMember(ctx, store.CompleteTaskForMemberParams{TaskID: taskID, UserID: userID})
+	}
+	return app.Queries.ReopenTaskForMember(ctx, store.ReopenTaskForMemberParams{TaskID: taskID, UserID: userID})
+}
+
+// sharedTaskEvent is a task as sent to the plan members who did not change
+// it. Completion is personal, so is_completed is left out rather than
+// showing them the other member's progress.
+type sharedTaskEvent struct {
+	StudyTaskResponse
+	IsCompleted *bool `json:"is_completed,omitempty"`
+}
+
+// publishTaskEvent sends an event about a change to a task to every member
+// of its plan, or to the user for tasks without a plan. Only the user's own
+// clients get response as is, with their progress.
+func (app *Application) publishTaskEvent(ctx context.Context, user *UserClaims, task store.StudyTask, eventType string, response StudyTaskResponse) {
+	if !task.PlanID.Valid {
+		app.publishEvent(ctx, user.ClerkID, eventType, response)
+		return
+	}
+
+	memberIDs, err := app.Queries.ListPlanMemberIDs(ctx, task.PlanID.UUID)
+	if err != nil {
+		log.Printf("events: failed to list members of plan %s: %v", task.PlanID.UUID, err)
+		return
+	}
+	shared := sharedTaskEvent{StudyTaskResponse: response}
+	for _, userID := range memberIDs {
+		if userID == user.ClerkID {
+			app.publishEvent(ctx, userID, eventType, response)
+		} else {
+			app.publishEvent(ctx, userID, eventType, shared)
+		}
+	}
+}
+
+// checkTaskDueDate checks a new due date for an existing task against its
+// plan. Tasks without a plan accept any date. On failure an error response
+// has been written and ok is false.
+func (app *Application) checkTaskDueDate(w http.ResponseWriter, r *http.Request, access taskAccess, dueDate time.Time) (ok bool) {
+	if access.plan.ID == uuid.Nil {
+		return true
+	}
+
+	if err := checkDueDateInPlan(dueDate, access.plan); err != nil {
+		app.badRequestError(w, r, err)
+		return false
+	}
+	return true
+}
+
 // CreateStudyTaskHandler creates a new study task
 func (app *Application) CreateStudyTaskHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
 	var req CreateStudyTaskRequest
@@ -88,17 +350,40 @@ func (app *Application) CreateStudyTaskHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
+	// Whether the task's completion is stored on the task or, for plan
+	// members other than its creator, in task_completions
+	ownProgress := true
+	access := taskAccess{}
+	if req.PlanID != nil {
+		plan, _, ok := app.getPlanForMember(w, r, user, *req.PlanID, PlanRoleEditor)
+		if !ok {
+			return
+		}
+
+		if err := checkDueDateInPlan(req.DueDate, plan); err != nil {
+			app.badRequestError(w, r, err)
+			return
+		}
+		ownProgress = plan.UserID == user.ClerkID
+		access.plan = plan
+	}
+
+	if !app.checkTaskSubject(w, r, access.ownerID(user), req.SubjectID) {
+		return
+	}
+
 	// Prepare parameters for database insertion
 	params := store.CreateTaskParams{
-		Title:   req.Title,
-		DueDate: req.DueDate,
+		Title:     req.Title,
+		DueDate:   req.DueDate,
+		CreatedBy: sql.NullString{String: user.ClerkID, Valid: true},
 	}
 
 	if req.PlanID != nil {
 		params.PlanID = uuid.NullUUID{UUID: *req.PlanID, Valid: true}
 	}
 
-	if req.IsCompleted != nil {
+	if req.IsCompleted != nil && ownProgress {
 		params.IsCompleted = sql.NullBool{Bool: *req.IsCompleted, Valid: true}
 	}
 
@@ -110,6 +395,14 @@ func (app *Application) CreateStudyTaskHandler(w http.ResponseWriter, r *http.Re
 		params.Notes = sql.NullString{String: *req.Notes, Valid: true}
 	}
 
+	if req.SubjectID != nil {
+		params.SubjectID = uuid.NullUUID{UUID: *req.SubjectID, Valid: true}
+	}
+
+	if req.MinutesSpent != nil {
+		params.MinutesSpent = sql.NullInt32{Int32: *req.MinutesSpent, Valid: true}
+	}
+
 	// Create the task
 	task, err := app.Queries.CreateTask(r.Context(), params)
 	if err != nil {
@@ -117,11 +410,33 @@ func (app *Application) CreateStudyTaskHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	response := convertStudyTaskToResponse(task)
-	app.writeJSON(w, http.StatusCreated, response)
+	access.ownProgress = ownProgress
+	if !ownProgress && req.IsCompleted != nil {
+		if err := app.setMemberProgress(r.Context(), task.ID, user.ClerkID, *req.IsCompleted); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		access.completed = *req.IsCompleted
+	}
+
+	response := convertStudyTaskToResponse(access.view(task))
+	app.publishTaskEvent(r.Context(), user, task, events.TaskCreated, response)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.TaskCreated,
+		EntityType: activityStudyTask,
+		EntityID:   task.ID.String(),
+		After:      response,
+	})
+
+	w.Header().Set("ETag", versionETag(task.Version))
+	app.respond(w, r, http.StatusCreated, response, response)
 }
 
-// GetStudyTasksHandler retrieves all study tasks for the authenticated user
+// GetStudyTasksHandler retrieves the study tasks of every plan the
+// authenticated user is a member of, or of one plan. Completion is the
+// user's own.
 func (app *Application) GetStudyTasksHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
 	// Get query parameters for filtering
 	planIDStr := r.URL.Query().Get("plan_id")
@@ -129,63 +444,59 @@ func (app *Application) GetStudyTasksHandler(w http.ResponseWriter, r *http.Requ
 	statusStr := r.URL.Query().Get("status")
 
 	var tasks []store.StudyTask
-	var err error
 
-	// Handle different filtering scenarios
-	if planIDStr != "" && priorityStr != "" {
-		// Filter by plan and priority
+	if planIDStr != "" {
 		planID, err := uuid.Parse(planIDStr)
 		if err != nil {
 			app.badRequestError(w, r, err)
 			return
 		}
 
-		priority, err := strconv.ParseInt(priorityStr, 10, 32)
-		if err != nil {
-			app.badRequestError(w, r, err)
+		if _, _, ok := app.getPlanForMember(w, r, user, planID, PlanRoleViewer); !ok {
 			return
 		}
 
-		params := store.GetTasksByPriorityParams{
-			PlanID:   uuid.NullUUID{UUID: planID, Valid: true},
-			Priority: sql.NullInt32{Int32: int32(priority), Valid: true},
-		}
-		tasks, err = app.Queries.GetTasksByPriority(r.Context(), params)
-	} else if planIDStr != "" && statusStr != "" {
-		// Filter by plan and status
-		planID, err := uuid.Parse(planIDStr)
+		tasks, err = app.Queries.GetTasksByPlanForMember(r.Context(), store.GetTasksByPlanForMemberParams{
+			UserID: user.ClerkID,
+			PlanID: planID,
+		})
 		if err != nil {
-			app.badRequestError(w, r, err)
+			app.internalServerError(w, r, err)
 			return
 		}
 
-		isCompleted, err := strconv.ParseBool(statusStr)
-		if err != nil {
-			app.badRequestError(w, r, err)
-			return
+		// Filter by priority and status within the plan
+		if priorityStr != "" {
+			priority, err := strconv.ParseInt(priorityStr, 10, 32)
+			if err != nil {
+				app.badRequestError(w, r, invalidParam("priority", priorityStr))
+				return
+			}
+			tasks = slices.DeleteFunc(tasks, func(task store.StudyTask) bool {
+				return !task.Priority.Valid || task.Priority.Int32 != int32(priority)
+			})
 		}
-
-		params := store.GetTasksByStatusParams{
-			PlanID:      uuid.NullUUID{UUID: planID, Valid: true},
-			IsCompleted: sql.NullBool{Bool: isCompleted, Valid: true},
+		if statusStr != "" {
+			isCompleted, err := strconv.ParseBool(statusStr)
+			if err != nil {
+				app.badRequestError(w, r, invalidParam("status", statusStr))
+				return
+			}
+			tasks = slices.DeleteFunc(tasks, func(task store.StudyTask) bool {
+				return !task.IsCompleted.Valid || task.IsCompleted.Bool != isCompleted
+			})
 		}
-		tasks, err = app.Queries.GetTasksByStatus(r.Context(), params)
-	} else if planIDStr != "" {
-		// Filter by plan only
-		planID, err := uuid.Parse(planIDStr)
+	} else {
+		// Get all tasks for the authenticated user
+		var err error
+		tasks, err = app.Queries.GetTasksForMember(r.Context(), user.ClerkID)
 		if err != nil {
-			app.badRequestError(w, r, err)
+			app.internalServerError(w, r, err)
 			return
 		}
-
-		tasks, err = app.Queries.GetTasksByPlan(r.Context(), uuid.NullUUID{UUID: planID, Valid: true})
-	} else {
-		// Get all tasks for the authenticated user
-		tasks, err = app.Queries.GetTasksByUser(r.Context(), user.ClerkID)
 	}
 
-	if err != nil {
-		app.internalServerError(w, r, err)
+	if notModified(w, r, studyTasksETag(tasks)) {
 		return
 	}
 
@@ -200,7 +511,7 @@ func (app *Application) GetStudyTasksHandler(w http.ResponseWriter, r *http.Requ
 		response = []StudyTaskResponse{}
 	}
 
-	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+	if err := app.respond(w, r, http.StatusOK, response, Envelope{Data: response}); err != nil {
 		app.internalServerError(w, r, err)
 	}
 }
@@ -214,18 +525,21 @@ func (app *Application) GetStudyTaskHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	task, err := app.Queries.GetTaskByID(r.Context(), taskID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			app.writeJSONError(w, http.StatusNotFound, "Task not found")
-			return
-		}
-		app.internalServerError(w, r, err)
+	task, access, ok := app.getTaskForMember(w, r, user, taskID, PlanRoleViewer)
+	if !ok {
 		return
 	}
 
-	response := convertStudyTaskToResponse(task)
-	app.writeJSON(w, http.StatusOK, response)
+	// Other members' progress does not change the version, so only the
+	// plan creator's copy can be revalidated
+	if !access.ownProgress {
+		w.Header().Set("ETag", versionETag(task.Version))
+	} else if notModified(w, r, versionETag(task.Version)) {
+		return
+	}
+
+	response := convertStudyTaskToResponse(access.view(task))
+	app.respond(w, r, http.StatusOK, response, response)
 }
 
 // UpdateStudyTaskHandler updates a study task
@@ -248,23 +562,36 @@ func (app *Application) UpdateStudyTaskHandler(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	// Check if task exists
-	_, err = app.Queries.GetTaskByID(r.Context(), taskID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			app.writeJSONError(w, http.StatusNotFound, "Task not found")
-			return
-		}
-		app.internalServerError(w, r, err)
+	// Check if task exists and the user may edit it
+	existing, access, ok := app.getTaskForMember(w, r, user, taskID, PlanRoleEditor)
+	if !ok {
 		return
 	}
 
-	// Prepare update parameters
+	if !req.DueDate.Equal(existing.DueDate) && !app.checkTaskDueDate(w, r, access, req.DueDate) {
+		return
+	}
+
+	if !app.checkTaskSubject(w, r, access.ownerID(user), req.SubjectID) {
+		return
+	}
+
+	expectedVersion, ok := app.checkIfMatch(w, r, existing.Version)
+	if !ok {
+		return
+	}
+
+	// Prepare update parameters. The completion of other members is kept
+	// apart from the task, so theirs leaves the stored one unchanged.
 	params := store.UpdateTaskParams{
-		ID:          taskID,
-		Title:       req.Title,
-		DueDate:     req.DueDate,
-		IsCompleted: sql.NullBool{Bool: req.IsCompleted, Valid: true},
+		ID:              taskID,
+		Title:           req.Title,
+		DueDate:         req.DueDate,
+		ExpectedVersion: expectedVersion,
+	}
+
+	if access.ownProgress {
+		params.IsCompleted = sql.NullBool{Bool: req.IsCompleted, Valid: true}
 	}
 
 	if req.Priority != nil {
@@ -275,50 +602,208 @@ func (app *Application) UpdateStudyTaskHandler(w http.ResponseWriter, r *http.Re
 		params.Notes = sql.NullString{String: *req.Notes, Valid: true}
 	}
 
+	if req.SubjectID != nil {
+		params.SubjectID = uuid.NullUUID{UUID: *req.SubjectID, Valid: true}
+	}
+
+	if req.MinutesSpent != nil {
+		params.MinutesSpent = sql.NullInt32{Int32: *req.MinutesSpent, Valid: true}
+	}
+
 	// Update the task
 	task, err := app.Queries.UpdateTask(r.Context(), params)
 	if err != nil {
+		// Changed or deleted since it was read
+		if err == sql.ErrNoRows {
+			app.preconditionFailed(w, r)
+			return
+		}
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	response := convertStudyTaskToResponse(task)
-	app.writeJSON(w, http.StatusOK, response)
+	previous := convertStudyTaskToResponse(access.view(existing))
+	if !access.ownProgress && req.IsCompleted != access.completed {
+		if err := app.setMemberProgress(r.Context(), taskID, user.ClerkID, req.IsCompleted); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		access.completed = req.IsCompleted
+	}
+
+	response := convertStudyTaskToResponse(access.view(task))
+	app.publishTaskEvent(r.Context(), user, task, events.TaskUpdated, response)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.TaskUpdated,
+		EntityType: activityStudyTask,
+		EntityID:   task.ID.String(),
+		Before:     previous,
+		After:      response,
+	})
+	if response.IsCompleted && !previous.IsCompleted {
+		app.recordTaskCompleted(r.Context(), user.ClerkID, response)
+	}
+
+	w.Header().Set("ETag", versionETag(task.Version))
+	app.respond(w, r, http.StatusOK, response, response)
 }
 
-// DeleteStudyTaskHandler deletes a study task
-func (app *Application) DeleteStudyTaskHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
-	taskIDStr := chi.URLParam(r, "id")
-	taskID, err := uuid.Parse(taskIDStr)
+// PatchStudyTaskHandler applies a JSON merge patch to a study task
+func (app *Application) PatchStudyTaskHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		app.badRequestError(w, r, err)
 		return
 	}
 
-	// Check if task exists
-	_, err = app.Queries.GetTaskByID(r.Context(), taskID)
+	var req PatchStudyTaskRequest
+	if !app.readMergePatch(w, r, &req) {
+		return
+	}
+
+	if err := req.validate(); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	existing, access, ok := app.getTaskForMember(w, r, user, taskID, PlanRoleEditor)
+	if !ok {
+		return
+	}
+
+	merged := req.apply(access.view(existing))
+	if err := Validate.Struct(merged); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if req.DueDate.Set && !app.checkTaskDueDate(w, r, access, merged.DueDate) {
+		return
+	}
+
+	if req.SubjectID.Set && !app.checkTaskSubject(w, r, access.ownerID(user), merged.SubjectID) {
+		return
+	}
+
+	expectedVersion, ok := app.checkIfMatch(w, r, existing.Version)
+	if !ok {
+		return
+	}
+
+	params := store.PatchTaskParams{
+		ID:              taskID,
+		Title:           optionalToNullString(req.Title),
+		DueDate:         optionalToNullTime(req.DueDate),
+		SetPriority:     req.Priority.Set,
+		Priority:        optionalToNullInt32(req.Priority),
+		SetNotes:        req.Notes.Set,
+		Notes:           optionalToNullString(req.Notes),
+		SetSubjectID:    req.SubjectID.Set,
+		SubjectID:       optionalToNullUUID(req.SubjectID),
+		SetMinutesSpent: req.MinutesSpent.Set,
+		MinutesSpent:    optionalToNullInt32(req.MinutesSpent),
+		ExpectedVersion: expectedVersion,
+	}
+	if access.ownProgress {
+		params.IsCompleted = optionalToNullBool(req.IsCompleted)
+	}
+
+	task, err := app.Queries.PatchTask(r.Context(), params)
 	if err != nil {
+		// Changed or deleted since it was read
 		if err == sql.ErrNoRows {
-			app.writeJSONError(w, http.StatusNotFound, "Task not found")
+			app.preconditionFailed(w, r)
 			return
 		}
 		app.internalServerError(w, r, err)
 		return
 	}
 
-	// Delete the task
-	err = app.Queries.DeleteTask(r.Context(), taskID)
+	previous := convertStudyTaskToResponse(access.view(existing))
+	if !access.ownProgress && req.IsCompleted.Set && req.IsCompleted.Value != access.completed {
+		if err := app.setMemberProgress(r.Context(), taskID, user.ClerkID, req.IsCompleted.Value); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		access.completed = req.IsCompleted.Value
+	}
+
+	response := convertStudyTaskToResponse(access.view(task))
+	app.publishTaskEvent(r.Context(), user, task, events.TaskUpdated, response)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.TaskUpdated,
+		EntityType: activityStudyTask,
+		EntityID:   task.ID.String(),
+		Before:     previous,
+		After:      response,
+	})
+	if response.IsCompleted && !previous.IsCompleted {
+		app.recordTaskCompleted(r.Context(), user.ClerkID, response)
+	}
+
+	w.Header().Set("ETag", versionETag(task.Version))
+	app.respond(w, r, http.StatusOK, response, response)
+}
+
+// DeleteStudyTaskHandler deletes a study task
+func (app *Application) DeleteStudyTaskHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	taskIDStr := chi.URLParam(r, "id")
+	taskID, err := uuid.Parse(taskIDStr)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	// Check if task exists and the user may edit it
+	existing, access, ok := app.getTaskForMember(w, r, user, taskID, PlanRoleEditor)
+	if !ok {
+		return
+	}
+
+	expectedVersion, ok := app.checkIfMatch(w, r, existing.Version)
+	if !ok {
+		return
+	}
+
+	// Move the task to the trash
+	deleted, err := app.Queries.DeleteTask(r.Context(), store.DeleteTaskParams{
+		ID:              taskID,
+		ExpectedVersion: expectedVersion,
+	})
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
+	if deleted == 0 {
+		// Changed or deleted since it was read
+		app.preconditionFailed(w, r)
+		return
+	}
 
-	app.writeJSON(w, http.StatusOK, map[string]string{
-		"message": "Task deleted successfully",
+	if existing.PlanID.Valid {
+		app.publishPlanEvent(r.Context(), existing.PlanID.UUID, events.TaskDeleted, map[string]uuid.UUID{"id": taskID})
+	} else {
+		app.publishEvent(r.Context(), user.ClerkID, events.TaskDeleted, map[string]uuid.UUID{"id": taskID})
+	}
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.TaskDeleted,
+		EntityType: activityStudyTask,
+		EntityID:   taskID.String(),
+		Before:     convertStudyTaskToResponse(access.view(existing)),
 	})
+
+	app.respondDeleted(w, r, "Task moved to trash")
 }
 
-// UpdateStudyTaskStatusHandler updates only the completion status of a task
+// UpdateStudyTaskStatusHandler completes or reopens a task for the user.
+// Any plan member can track their own progress; only the plan's creator
+// changes the task itself.
 func (app *Application) UpdateStudyTaskStatusHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
 	taskIDStr := chi.URLParam(r, "id")
 	taskID, err := uuid.Parse(taskIDStr)
@@ -327,39 +812,69 @@ func (app *Application) UpdateStudyTaskStatusHandler(w http.ResponseWriter, r *h
 		return
 	}
 
-	var req struct {
-		IsCompleted bool `json:"is_completed"`
-	}
+	var req UpdateStudyTaskStatusRequest
 
 	if err := app.readJSON(w, r, &req); err != nil {
 		app.badRequestError(w, r, err)
 		return
 	}
 
-	// Check if task exists
-	_, err = app.Queries.GetTaskByID(r.Context(), taskID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			app.writeJSONError(w, http.StatusNotFound, "Task not found")
-			return
-		}
-		app.internalServerError(w, r, err)
+	// Check if task exists and the user can see it
+	task, access, ok := app.getTaskForMember(w, r, user, taskID, PlanRoleViewer)
+	if !ok {
 		return
 	}
 
-	// Update task status
-	params := store.UpdateTaskStatusParams{
-		ID:          taskID,
-		IsCompleted: sql.NullBool{Bool: req.IsCompleted, Valid: true},
-	}
-
-	err = app.Queries.UpdateTaskStatus(r.Context(), params)
-	if err != nil {
-		app.internalServerError(w, r, err)
+	expectedVersion, ok := app.checkIfMatch(w, r, task.Version)
+	if !ok {
 		return
 	}
 
-	app.writeJSON(w, http.StatusOK, map[string]string{
-		"message": "Task status updated successfully",
+	wasCompleted := access.completed
+	previous := convertStudyTaskToResponse(access.view(task))
+
+	if access.ownProgress {
+		// Update task status
+		task, err = app.Queries.UpdateTaskStatus(r.Context(), store.UpdateTaskStatusParams{
+			ID:              taskID,
+			IsCompleted:     sql.NullBool{Bool: req.IsCompleted, Valid: true},
+			ExpectedVersion: expectedVersion,
+		})
+		if err != nil {
+			// Changed or deleted since it was read
+			if err == sql.ErrNoRows {
+				app.preconditionFailed(w, r)
+				return
+			}
+			app.internalServerError(w, r, err)
+			return
+		}
+	} else {
+		if err := app.setMemberProgress(r.Context(), taskID, user.ClerkID, req.IsCompleted); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		access.completed = req.IsCompleted
+	}
+
+	// Progress is personal, so only the user's own clients are told
+	response := convertStudyTaskToResponse(access.view(task))
+	app.publishEvent(r.Context(), user.ClerkID, events.TaskUpdated, response)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.TaskUpdated,
+		EntityType: activityStudyTask,
+		EntityID:   taskID.String(),
+		Before:     previous,
+		After:      response,
 	})
+	if req.IsCompleted && !wasCompleted {
+		app.recordTaskCompleted(r.Context(), user.ClerkID, response)
+	}
+
+	// /v1 clients only ever got a confirmation message
+	legacy := map[string]string{"message": "Task status updated successfully"}
+	w.Header().Set("ETag", versionETag(task.Version))
+	app.respond(w, r, http.StatusOK, response, legacy)
 }
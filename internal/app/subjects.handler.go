@@ -0,0 +1,370 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Subject kinds, from the top of the hierarchy down. A subject is split
+// into units, and a unit into topics.
+const (
+	SubjectKindSubject = "subject"
+	SubjectKindUnit    = "unit"
+	SubjectKindTopic   = "topic"
+)
+
+// subjectChildKinds gives the kind of the children of each kind
+var subjectChildKinds = map[string]string{
+	SubjectKindSubject: SubjectKindUnit,
+	SubjectKindUnit:    SubjectKindTopic,
+}
+
+// SubjectRequest represents the request body for creating or updating a
+// subject. Without a parent it is a top-level subject. Moving it changes
+// the parent but not the kind, so the new parent must be of the same kind
+// as the old one.
+type SubjectRequest struct {
+	Name     string     `json:"name" validate:"title"`
+	ParentID *uuid.UUID `json:"parent_id"`
+	Color    *string    `json:"color" validate:"omitempty,hexcolor"`
+	Icon     *string    `json:"icon" validate:"omitempty,max=50"`
+}
+
+// SubjectResponse represents a subject in API responses, with what the
+// user has done in it and everything below it
+type SubjectResponse struct {
+	ID        uuid.UUID    `json:"id"`
+	ParentID  *uuid.UUID   `json:"parent_id"`
+	Kind      string       `json:"kind"`
+	Name      string       `json:"name"`
+	Color     *string      `json:"color"`
+	Icon      *string      `json:"icon"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	Stats     SubjectStats `json:"stats"`
+}
+
+// SubjectStats aggregates the plans and tasks of a subject and its units
+// and topics. Tasks without a subject of their own count towards their
+// plan's. Time studied is the sum of the minutes spent reported on tasks.
+type SubjectStats struct {
+	PlanCount      int64   `json:"plan_count"`
+	TotalTasks     int64   `json:"total_tasks"`
+	CompletedTasks int64   `json:"completed_tasks"`
+	OverdueTasks   int64   `json:"overdue_tasks"`
+	CompletionRate float64 `json:"completion_rate"`
+	MinutesStudied int64   `json:"minutes_studied"`
+}
+
+func convertSubjectToResponse(subject store.Subject) SubjectResponse {
+	response := SubjectResponse{
+		ID:        subject.ID,
+		Kind:      subject.Kind,
+		Name:      subject.Name,
+		Color:     nullStringToPointer(subject.Color),
+		Icon:      nullStringToPointer(subject.Icon),
+		CreatedAt: subject.CreatedAt,
+		UpdatedAt: subject.UpdatedAt,
+	}
+	if subject.ParentID.Valid {
+		parentID := subject.ParentID.UUID
+		response.ParentID = &parentID
+	}
+	return response
+}
+
+func convertSubjectStats(row store.GetSubjectStatsRow) SubjectStats {
+	stats := SubjectStats{
+		PlanCount:      row.PlanCount,
+		TotalTasks:     row.TotalTasks,
+		CompletedTasks: row.CompletedTasks,
+		OverdueTasks:   row.OverdueTasks,
+		MinutesStudied: row.MinutesStudied,
+	}
+	if row.TotalTasks > 0 {
+		stats.CompletionRate = float64(row.CompletedTasks) / float64(row.TotalTasks)
+	}
+	return stats
+}
+
+// subjectStats returns the stats of every subject of the user by ID
+func (app *Application) subjectStats(ctx context.Context, userID string) (map[uuid.UUID]SubjectStats, error) {
+	rows, err := app.Queries.GetSubjectStats(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	stats := make(map[uuid.UUID]SubjectStats, len(rows))
+	for _, row := range rows {
+		stats[row.SubjectID] = convertSubjectStats(row)
+	}
+	return stats, nil
+}
+
+// isUniqueViolation reports whether err is a Postgres unique violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// getSubject loads the subject in the URL if it belongs to the user
+func (app *Application) getSubject(w http.ResponseWriter, r *http.Request, user *UserClaims) (store.Subject, bool) {
+	subjectID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return store.Subject{}, false
+	}
+
+	subject, err := app.Queries.GetSubject(r.Context(), subjectID)
+	if err == nil && subject.UserID != user.ClerkID {
+		err = sql.ErrNoRows
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Subject not found")
+			return subject, false
+		}
+		app.internalServerError(w, r, err)
+		return subject, false
+	}
+	return subject, true
+}
+
+// ownedSubject loads a subject that must belong to userID, for a reference
+// from field. A missing or foreign subject is a FieldError.
+func (app *Application) ownedSubject(ctx context.Context, queries *store.Queries, field, userID string, subjectID uuid.UUID) (store.Subject, error) {
+	subject, err := queries.GetSubject(ctx, subjectID)
+	if err == sql.ErrNoRows || (err == nil && subject.UserID != userID) {
+		return store.Subject{}, &FieldError{Field: field, Code: "not_found", Message: "is not a subject of the owner"}
+	}
+	return subject, err
+}
+
+// planSubject finds the subject of a plan owned by userID: the one with
+// subjectID, or else the top-level subject called name, which is created
+// when the user has none by that name
+func (app *Application) planSubject(ctx context.Context, queries *store.Queries, userID string, subjectID *uuid.UUID, name string) (store.Subject, error) {
+	if subjectID != nil {
+		return app.ownedSubject(ctx, queries, "subject_id", userID, *subjectID)
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return store.Subject{}, &FieldError{Field: "subject", Code: "required", Message: "is required without subject_id"}
+	}
+	return queries.EnsureSubject(ctx, store.EnsureSubjectParams{UserID: userID, Name: name})
+}
+
+// writeSubjectError answers with a 400 for a FieldError and a 500 otherwise
+func (app *Application) writeSubjectError(w http.ResponseWriter, r *http.Request, err error) {
+	var fieldError *FieldError
+	if errors.As(err, &fieldError) {
+		app.badRequestError(w, r, err)
+		return
+	}
+	app.internalServerError(w, r, err)
+}
+
+// GetSubjectsHandler lists the user's subjects, units and topics with their
+// stats. Clients build the tree from parent_id.
+func (app *Application) GetSubjectsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	subjects, err := app.Queries.ListSubjects(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	stats, err := app.subjectStats(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]SubjectResponse, len(subjects))
+	for i, subject := range subjects {
+		response[i] = convertSubjectToResponse(subject)
+		response[i].Stats = stats[subject.ID]
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// CreateSubjectHandler creates a subject, or a unit or topic under parent_id
+func (app *Application) CreateSubjectHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req SubjectRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	params := store.CreateSubjectParams{
+		UserID: user.ClerkID,
+		Kind:   SubjectKindSubject,
+		Name:   strings.TrimSpace(req.Name),
+		Color:  stringToNullString(req.Color),
+		Icon:   stringToNullString(req.Icon),
+	}
+
+	if req.ParentID != nil {
+		parent, err := app.ownedSubject(r.Context(), app.Queries, "parent_id", user.ClerkID, *req.ParentID)
+		if err != nil {
+			app.writeSubjectError(w, r, err)
+			return
+		}
+		kind, ok := subjectChildKinds[parent.Kind]
+		if !ok {
+			app.badRequestError(w, r, &FieldError{Field: "parent_id", Code: "kind", Message: "topics cannot have children"})
+			return
+		}
+		params.ParentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+		params.Kind = kind
+	}
+
+	subject, err := app.Queries.CreateSubject(r.Context(), params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			app.writeJSONError(w, r, http.StatusConflict, "A subject with this name already exists here")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusCreated, convertSubjectToResponse(subject))
+}
+
+// GetSubjectHandler returns a subject with its stats
+func (app *Application) GetSubjectHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	subject, ok := app.getSubject(w, r, user)
+	if !ok {
+		return
+	}
+
+	stats, err := app.subjectStats(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertSubjectToResponse(subject)
+	response.Stats = stats[subject.ID]
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// UpdateSubjectHandler renames, recolours or moves a subject. Plans linked
+// to it take the new name.
+func (app *Application) UpdateSubjectHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	existing, ok := app.getSubject(w, r, user)
+	if !ok {
+		return
+	}
+
+	var req SubjectRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	params := store.UpdateSubjectParams{
+		ID:       existing.ID,
+		ParentID: existing.ParentID,
+		Name:     strings.TrimSpace(req.Name),
+		Color:    stringToNullString(req.Color),
+		Icon:     stringToNullString(req.Icon),
+	}
+
+	if req.ParentID == nil {
+		params.ParentID = uuid.NullUUID{}
+	} else {
+		params.ParentID = uuid.NullUUID{UUID: *req.ParentID, Valid: true}
+	}
+	if params.ParentID != existing.ParentID {
+		// Only the parent changes, not the kind, so a subject stays top
+		// level and units and topics move to a parent like their old one
+		if !params.ParentID.Valid || !existing.ParentID.Valid {
+			app.badRequestError(w, r, &FieldError{Field: "parent_id", Code: "kind", Message: "cannot change the kind of a " + existing.Kind})
+			return
+		}
+		parent, err := app.ownedSubject(r.Context(), app.Queries, "parent_id", user.ClerkID, params.ParentID.UUID)
+		if err != nil {
+			app.writeSubjectError(w, r, err)
+			return
+		}
+		if subjectChildKinds[parent.Kind] != existing.Kind {
+			app.badRequestError(w, r, &FieldError{Field: "parent_id", Code: "kind", Message: "a " + existing.Kind + " must be under a subject of the same kind as before"})
+			return
+		}
+	}
+
+	tx, err := app.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	defer tx.Rollback()
+
+	queries := app.Queries.WithTx(tx)
+
+	subject, err := queries.UpdateSubject(r.Context(), params)
+	if err != nil {
+		if isUniqueViolation(err) {
+			app.writeJSONError(w, r, http.StatusConflict, "A subject with this name already exists here")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := queries.RenamePlanSubjects(r.Context(), store.RenamePlanSubjectsParams{
+		SubjectID: uuid.NullUUID{UUID: subject.ID, Valid: true},
+		Subject:   subject.Name,
+	}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, convertSubjectToResponse(subject))
+}
+
+// DeleteSubjectHandler deletes a subject with its units and topics. Plans
+// and tasks keep their free text subject and lose the link.
+func (app *Application) DeleteSubjectHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	subject, ok := app.getSubject(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.Queries.DeleteSubject(r.Context(), subject.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.respondDeleted(w, r, "Subject deleted successfully")
+}
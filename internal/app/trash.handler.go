@@ -0,0 +1,261 @@
+package app
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/events"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// TrashedStudyPlanResponse is a deleted plan. Tasks deleted along with the
+// plan are counted here and come back when it is restored.
+type TrashedStudyPlanResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	Subject   string    `json:"subject"`
+	ExamDate  time.Time `json:"exam_date"`
+	TaskCount int64     `json:"task_count"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashedStudyTaskResponse is a task deleted on its own
+type TrashedStudyTaskResponse struct {
+	StudyTaskResponse
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
+// TrashResponse lists everything the user can still restore
+type TrashResponse struct {
+	Plans []TrashedStudyPlanResponse `json:"plans"`
+	Tasks []TrashedStudyTaskResponse `json:"tasks"`
+}
+
+// getTrashedPlan loads a deleted plan and checks that the user is one of its
+// owners, who alone can delete it. On failure an error response has been
+// written and ok is false.
+func (app *Application) getTrashedPlan(w http.ResponseWriter, r *http.Request, user *UserClaims) (plan store.StudyPlan, ok bool) {
+	planID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return plan, false
+	}
+
+	plan, err = app.Queries.GetDeletedStudyPlanByID(r.Context(), planID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Study plan not found in trash")
+			return plan, false
+		}
+		app.internalServerError(w, r, err)
+		return plan, false
+	}
+
+	_, ok = app.checkPlanRole(w, r, user, plan.ID, PlanRoleOwner)
+	return plan, ok
+}
+
+// getTrashedTask loads a task deleted on its own and checks that the user
+// could have deleted it, like getTaskForMember does for live tasks. On
+// failure an error response has been written and ok is false.
+func (app *Application) getTrashedTask(w http.ResponseWriter, r *http.Request, user *UserClaims) (task store.StudyTask, ok bool) {
+	taskID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return task, false
+	}
+
+	task, err = app.Queries.GetDeletedTaskByID(r.Context(), taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Task not found in trash")
+			return task, false
+		}
+		app.internalServerError(w, r, err)
+		return task, false
+	}
+
+	if !task.PlanID.Valid {
+		if task.CreatedBy.String != user.ClerkID {
+			app.writeJSONError(w, r, http.StatusForbidden, "Access denied")
+			return task, false
+		}
+		return task, true
+	}
+	_, ok = app.checkPlanRole(w, r, user, task.PlanID.UUID, PlanRoleEditor)
+	return task, ok
+}
+
+// GetTrashHandler lists the deleted plans and tasks the user can restore
+func (app *Application) GetTrashHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	plans, err := app.Queries.GetDeletedStudyPlans(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	tasks, err := app.Queries.GetDeletedTasksByUser(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	retention := app.Trash.Retention()
+	response := TrashResponse{
+		Plans: make([]TrashedStudyPlanResponse, len(plans)),
+		Tasks: make([]TrashedStudyTaskResponse, len(tasks)),
+	}
+
+	for i, plan := range plans {
+		response.Plans[i] = TrashedStudyPlanResponse{
+			ID:        plan.ID,
+			Title:     plan.Title,
+			Subject:   plan.Subject,
+			ExamDate:  plan.ExamDate,
+			TaskCount: plan.TaskCount,
+			DeletedAt: plan.DeletedAt.Time,
+			PurgeAt:   plan.DeletedAt.Time.Add(retention),
+		}
+	}
+
+	for i, task := range tasks {
+		response.Tasks[i] = TrashedStudyTaskResponse{
+			StudyTaskResponse: convertStudyTaskToResponse(task),
+			DeletedAt:         task.DeletedAt.Time,
+			PurgeAt:           task.DeletedAt.Time.Add(retention),
+		}
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// RestoreStudyPlanHandler restores a deleted plan along with the tasks that
+// were deleted with it
+func (app *Application) RestoreStudyPlanHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	trashed, ok := app.getTrashedPlan(w, r, user)
+	if !ok {
+		return
+	}
+	planID := trashed.ID
+
+	studyPlan, err := app.Queries.RestoreStudyPlan(r.Context(), planID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Study plan not found in trash")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertStudyPlanToResponse(studyPlan)
+	app.publishPlanEvent(r.Context(), planID, events.PlanRestored, response)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.PlanRestored,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+		After:      planSnapshot(studyPlan),
+	})
+
+	app.respond(w, r, http.StatusOK, response, response)
+}
+
+// PurgeStudyPlanHandler permanently deletes a plan from the trash
+func (app *Application) PurgeStudyPlanHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	trashed, ok := app.getTrashedPlan(w, r, user)
+	if !ok {
+		return
+	}
+	planID := trashed.ID
+
+	deleted, err := app.Queries.PurgeStudyPlan(r.Context(), planID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.writeJSONError(w, r, http.StatusNotFound, "Study plan not found in trash")
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityPlanPurged,
+		EntityType: activityStudyPlan,
+		EntityID:   planID.String(),
+	})
+
+	app.respondDeleted(w, r, "Study plan permanently deleted")
+}
+
+// RestoreStudyTaskHandler restores a task deleted on its own. Tasks deleted
+// with their plan are restored by restoring the plan.
+func (app *Application) RestoreStudyTaskHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	trashed, ok := app.getTrashedTask(w, r, user)
+	if !ok {
+		return
+	}
+	taskID := trashed.ID
+
+	task, err := app.Queries.RestoreTask(r.Context(), taskID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Task not found in trash")
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertStudyTaskToResponse(task)
+	app.publishTaskEvent(r.Context(), user, task, events.TaskRestored, response)
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     events.TaskRestored,
+		EntityType: activityStudyTask,
+		EntityID:   taskID.String(),
+		After:      response,
+	})
+
+	app.respond(w, r, http.StatusOK, response, response)
+}
+
+// PurgeStudyTaskHandler permanently deletes a task from the trash
+func (app *Application) PurgeStudyTaskHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	trashed, ok := app.getTrashedTask(w, r, user)
+	if !ok {
+		return
+	}
+	taskID := trashed.ID
+
+	deleted, err := app.Queries.PurgeTask(r.Context(), taskID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	if deleted == 0 {
+		app.writeJSONError(w, r, http.StatusNotFound, "Task not found in trash")
+		return
+	}
+
+	app.recordActivity(r.Context(), activityEntry{
+		UserID:     user.ClerkID,
+		ActorID:    user.ActorID(),
+		Action:     activityTaskPurged,
+		EntityType: activityStudyTask,
+		EntityID:   taskID.String(),
+	})
+
+	app.respondDeleted(w, r, "Task permanently deleted")
+}
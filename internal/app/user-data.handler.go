@@ -0,0 +1,221 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// DataExportResponse represents a data export in API responses
+type DataExportResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Status      string     `json:"status"`
+	SizeBytes   *int64     `json:"size_bytes,omitempty"`
+	Error       *string    `json:"error,omitempty"`
+	DownloadURL *string    `json:"download_url,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// convertDataExportToResponse converts a data export row to its response.
+// All export queries return the same columns, so they share one row type.
+func convertDataExportToResponse(export store.GetDataExportRow) DataExportResponse {
+	response := DataExportResponse{
+		ID:        export.ID,
+		Status:    export.Status,
+		CreatedAt: export.CreatedAt,
+	}
+	if export.SizeBytes.Valid {
+		response.SizeBytes = &export.SizeBytes.Int64
+	}
+	if export.Error.Valid {
+		response.Error = &export.Error.String
+	}
+	if export.CompletedAt.Valid {
+		response.CompletedAt = &export.CompletedAt.Time
+	}
+	if export.ExpiresAt.Valid {
+		response.ExpiresAt = &export.ExpiresAt.Time
+		if export.ExpiresAt.Time.After(time.Now()) && export.Status == "completed" {
+			url := fmt.Sprintf("/v1/user/data-exports/%s/download", export.ID)
+			response.DownloadURL = &url
+		}
+	}
+	return response
+}
+
+// RequestDataExportHandler queues an archive of all the user's data. If an
+// export is already queued or running, that one is returned instead.
+func (app *Application) RequestDataExportHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	active, err := app.Queries.GetActiveDataExport(r.Context(), user.ClerkID)
+	if err == nil {
+		if err := app.jsonResponse(w, http.StatusAccepted, convertDataExportToResponse(store.GetDataExportRow(active))); err != nil {
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	if err != sql.ErrNoRows {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	export, err := app.Queries.CreateDataExport(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/user/data-exports/%s", export.ID))
+	if err := app.jsonResponse(w, http.StatusAccepted, convertDataExportToResponse(store.GetDataExportRow(export))); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetDataExportsHandler lists the user's data exports
+func (app *Application) GetDataExportsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	exports, err := app.Queries.GetDataExportsByUser(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]DataExportResponse, len(exports))
+	for i, export := range exports {
+		response[i] = convertDataExportToResponse(store.GetDataExportRow(export))
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetDataExportHandler retrieves the status of a data export
+func (app *Application) GetDataExportHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	exportID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	export, err := app.Queries.GetDataExport(r.Context(), store.GetDataExportParams{
+		ID:     exportID,
+		UserID: user.ClerkID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, convertDataExportToResponse(export)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// DownloadDataExportHandler serves a completed export as a zip archive
+func (app *Application) DownloadDataExportHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	exportID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	archive, err := app.Queries.GetDataExportArchive(r.Context(), store.GetDataExportArchiveParams{
+		ID:     exportID,
+		UserID: user.ClerkID,
+	})
+	if err != nil {
+		// Not found, not finished and expired all look the same here
+		if err == sql.ErrNoRows {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="prepilot-export-%s.zip"`, exportID))
+	w.Header().Set("Content-Length", fmt.Sprint(len(archive)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// eraseUserData removes every row owned by a user and records a tombstone.
+// It must run inside a transaction so the account is erased completely or
+// not at all. Clerk event payloads that mention the user are redacted rather
+// than deleted, so the event log stays intact.
+func (app *Application) eraseUserData(ctx context.Context, queries *store.Queries, clerkID, source string) error {
+	summary := map[string]int64{}
+
+	tasks, err := queries.CountUserStudyTasks(ctx, clerkID)
+	if err != nil {
+		return fmt.Errorf("failed to count study tasks: %w", err)
+	}
+
+	// Tasks without a plan would outlive the user with no creator
+	planless, err := queries.DeleteUserPlanlessTasks(ctx, clerkID)
+	if err != nil {
+		return fmt.Errorf("failed to erase study tasks: %w", err)
+	}
+	summary["study_tasks"] = tasks + planless
+
+	steps := []struct {
+		table string
+		run   func(context.Context, string) (int64, error)
+	}{
+		// Tasks go with their plans, deliveries with their endpoints
+		{"study_plans", queries.DeleteUserStudyPlans},
+		{"study_activity", queries.DeleteUserStudyActivity},
+		{"webhook_endpoints", queries.DeleteUserWebhookEndpoints},
+		{"events", queries.DeleteUserEvents},
+		{"user_external_accounts", queries.DeleteUserExternalAccounts},
+		{"webhook_events_redacted", queries.RedactUserWebhookEvents},
+	}
+	for _, step := range steps {
+		n, err := step.run(ctx, clerkID)
+		if err != nil {
+			return fmt.Errorf("failed to erase %s: %w", step.table, err)
+		}
+		summary[step.table] = n
+	}
+
+	// Sessions and memberships are not tied to the user by a foreign key
+	if err := queries.DeleteUserSessions(ctx, clerkID); err != nil {
+		return fmt.Errorf("failed to erase sessions: %w", err)
+	}
+	if err := queries.DeleteOrganizationMembershipsByUser(ctx, clerkID); err != nil {
+		return fmt.Errorf("failed to erase memberships: %w", err)
+	}
+
+	// Data exports and access tokens are removed with the user
+	if err := queries.DeleteUserByClerkID(ctx, clerkID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode erasure summary: %w", err)
+	}
+
+	if err := queries.CreateUserTombstone(ctx, store.CreateUserTombstoneParams{
+		ClerkID: clerkID,
+		Source:  source,
+		Summary: encoded,
+	}); err != nil {
+		return fmt.Errorf("failed to record tombstone: %w", err)
+	}
+
+	return nil
+}
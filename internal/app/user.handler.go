@@ -2,8 +2,54 @@ package app
 
 import (
 	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
 )
 
+// UserResponse represents the response format for users
+type UserResponse struct {
+	ID            uuid.UUID  `json:"id"`
+	ClerkID       string     `json:"clerk_id"`
+	Email         string     `json:"email"`
+	FirstName     *string    `json:"first_name"`
+	LastName      *string    `json:"last_name"`
+	FullName      *string    `json:"full_name"`
+	ImageURL      *string    `json:"image_url"`
+	EmailVerified bool       `json:"email_verified"`
+	LastSignInAt  *time.Time `json:"last_sign_in_at"`
+	Role          string     `json:"role"`
+	TimeZone      string     `json:"time_zone"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// convertUserToResponse converts a store.User to UserResponse
+func convertUserToResponse(user store.User) UserResponse {
+	response := UserResponse{
+		ID:            user.ID,
+		ClerkID:       user.ClerkID,
+		Email:         user.Email,
+		FirstName:     nullStringToPointer(user.FirstName),
+		LastName:      nullStringToPointer(user.LastName),
+		FullName:      nullStringToPointer(user.Name),
+		ImageURL:      nullStringToPointer(user.ImageUrl),
+		EmailVerified: user.EmailVerified.Valid && user.EmailVerified.Bool,
+		Role:          user.Role,
+		TimeZone:      user.TimeZone,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+
+	if user.LastSignInAt.Valid {
+		lastSignInAt := user.LastSignInAt.Time
+		response.LastSignInAt = &lastSignInAt
+	}
+
+	return response
+}
+
 // InitializeUserHandler ensures user exists in database and returns profile
 // This is called immediately after sign-in to trigger user creation
 func (app *Application) InitializeUserHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
@@ -31,7 +77,8 @@ func (app *Application) InitializeUserHandler(w http.ResponseWriter, r *http.Req
 		"message":        "User initialized successfully",
 	}
 
-	app.writeJSON(w, http.StatusOK, response)
+	dbUser.Role = app.userRole(dbUser.ClerkID, dbUser.Role)
+	app.respond(w, r, http.StatusOK, convertUserToResponse(dbUser), response)
 }
 
 // GetUserProfileHandler retrieves the current user's profile
@@ -58,5 +105,38 @@ func (app *Application) GetUserProfileHandler(w http.ResponseWriter, r *http.Req
 		"updated_at":     dbUser.UpdatedAt,
 	}
 
-	app.writeJSON(w, http.StatusOK, response)
+	dbUser.Role = app.userRole(dbUser.ClerkID, dbUser.Role)
+	app.respond(w, r, http.StatusOK, convertUserToResponse(dbUser), response)
+}
+
+// UpdateUserProfileRequest represents the request body for updating the profile.
+// Names and email are managed in Clerk.
+type UpdateUserProfileRequest struct {
+	TimeZone string `json:"time_zone" validate:"required,timezone"`
+}
+
+// UpdateUserProfileHandler updates the current user's settings
+func (app *Application) UpdateUserProfileHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req UpdateUserProfileRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	dbUser, err := app.Queries.SetUserTimeZone(r.Context(), store.SetUserTimeZoneParams{
+		ClerkID:  user.ClerkID,
+		TimeZone: req.TimeZone,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	dbUser.Role = app.userRole(dbUser.ClerkID, dbUser.Role)
+	response := convertUserToResponse(dbUser)
+	app.respond(w, r, http.StatusOK, response, response)
 }
\ No newline at end of file
@@ -0,0 +1,115 @@
+package app
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Length limits for free text fields
+const (
+	maxTitleLength = 200
+	maxNotesLength = 5000
+)
+
+// maxMinutesSpent caps the time logged on a single task, a full day
+const maxMinutesSpent = 24 * 60
+
+// validationAliases name the domain rules shared by request types, so a
+// limit is changed in one place. The OpenAPI document expands them too.
+var validationAliases = map[string]string{
+	"title":    fmt.Sprintf("notblank,max=%d", maxTitleLength),
+	"priority": "min=0,max=2",
+	"notes":    fmt.Sprintf("max=%d", maxNotesLength),
+	"minutes":  fmt.Sprintf("min=0,max=%d", maxMinutesSpent),
+	"scope":    "oneof=" + strings.Join(accessTokenScopes, " "),
+}
+
+// registerValidators adds the custom tags and aliases to v
+func registerValidators(v *validator.Validate) {
+	v.RegisterValidation("notblank", validateNotBlank)
+	v.RegisterValidation("timezone", validateTimezone)
+	for alias, tags := range validationAliases {
+		v.RegisterAlias(alias, tags)
+	}
+}
+
+// validateNotBlank requires a string with something other than whitespace
+func validateNotBlank(fl validator.FieldLevel) bool {
+	return strings.TrimSpace(fl.Field().String()) != ""
+}
+
+// validateTimezone requires an IANA time zone name such as Europe/Paris
+func validateTimezone(fl validator.FieldLevel) bool {
+	name := fl.Field().String()
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// normalizeTitles trims the whitespace around every title field of a
+// decoded request, nested ones and merge patch ones included, so handlers
+// store them as given and notblank sees the same value
+func normalizeTitles(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if !v.IsNil() {
+			normalizeTitles(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		if kind := v.Type().Elem().Kind(); kind != reflect.Struct && kind != reflect.Pointer {
+			return
+		}
+		for i := range v.Len() {
+			normalizeTitles(v.Index(i))
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := range t.NumField() {
+			field := v.Field(i)
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name == "title" {
+				trimString(field)
+			} else {
+				normalizeTitles(field)
+			}
+		}
+	}
+}
+
+// trimString trims a string, *string or Optional[string] in place
+func trimString(v reflect.Value) {
+	switch {
+	case v.Kind() == reflect.Pointer && !v.IsNil():
+		trimString(v.Elem())
+	case v.Kind() == reflect.String && v.CanSet():
+		v.SetString(strings.TrimSpace(v.String()))
+	case v.Kind() == reflect.Struct && v.FieldByName("Set").IsValid():
+		trimString(v.FieldByName("Value"))
+	}
+}
+
+// jsonFieldName converts a Go field name, as found in the params of
+// cross-field tags, to the snake_case name used in JSON
+func jsonFieldName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 && !unicode.IsUpper(rune(name[i-1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidationAliases(t *testing.T) {
+	tests := []struct {
+		tag   string
+		value any
+		want  bool
+	}{
+		{tag: "title", value: "Linear algebra", want: true},
+		{tag: "title", value: strings.Repeat("a", maxTitleLength), want: true},
+		{tag: "title", value: strings.Repeat("a", maxTitleLength+1)},
+		{tag: "title", value: ""},
+		{tag: "title", value: " \t\n"},
+		{tag: "priority", value: 0, want: true},
+		{tag: "priority", value: 2, want: true},
+		{tag: "priority", value: 3},
+		{tag: "priority", value: -1},
+		{tag: "notes", value: strings.Repeat("a", maxNotesLength), want: true},
+		{tag: "notes", value: strings.Repeat("a", maxNotesLength+1)},
+		{tag: "minutes", value: maxMinutesSpent, want: true},
+		{tag: "minutes", value: maxMinutesSpent + 1},
+		{tag: "minutes", value: -5},
+		{tag: "scope", value: ScopePlansRead, want: true},
+		{tag: "scope", value: "plans:delete"},
+		{tag: "timezone", value: "Europe/Paris", want: true},
+		{tag: "timezone", value: "UTC", want: true},
+		{tag: "timezone", value: "America/Argentina/Buenos_Aires", want: true},
+		{tag: "timezone", value: "Mars/Olympus_Mons"},
+		{tag: "timezone", value: "europe/paris"},
+		{tag: "timezone", value: "Local"},
+		{tag: "timezone", value: ""},
+		{tag: "timezone", value: "../../etc/passwd"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			err := Validate.Var(tt.value, tt.tag)
+			if got := err == nil; got != tt.want {
+				t.Errorf("Validate.Var(%v, %q) = %v, want valid %v", tt.value, tt.tag, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeTitles(t *testing.T) {
+	type task struct {
+		Title string `json:"title"`
+		Notes string `json:"notes"`
+	}
+	type request struct {
+		Title    *string          `json:"title"`
+		Patch    Optional[string] `json:"patch"`
+		Subtitle string           `json:"subtitle"`
+		Tasks    []task           `json:"tasks"`
+		Next     *request         `json:"next"`
+		Labels   []string         `json:"labels"`
+		hidden   task
+	}
+	type patch struct {
+		Title Optional[string] `json:"title"`
+	}
+
+	title := "  Algebra  "
+	tests := []struct {
+		name string
+		data any
+		want any
+	}{
+		{
+			name: "nested",
+			data: &request{
+				Title:    &title,
+				Subtitle: " kept ",
+				Tasks:    []task{{Title: " Read\n", Notes: " kept "}},
+				Next:     &request{Tasks: []task{{Title: "\tWrite"}}},
+				Labels:   []string{" kept "},
+				hidden:   task{Title: " kept "},
+			},
+			want: &request{
+				Title:    ptr("Algebra"),
+				Subtitle: " kept ",
+				Tasks:    []task{{Title: "Read", Notes: " kept "}},
+				Next:     &request{Tasks: []task{{Title: "Write"}}},
+				Labels:   []string{" kept "},
+				hidden:   task{Title: " kept "},
+			},
+		},
+		{
+			name: "merge patch",
+			data: &patch{Title: Optional[string]{Set: true, Value: " Algebra "}},
+			want: &patch{Title: Optional[string]{Set: true, Value: "Algebra"}},
+		},
+		{
+			name: "slice of requests",
+			data: &[]task{{Title: " a "}, {Title: "b "}},
+			want: &[]task{{Title: "a"}, {Title: "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizeTitles(reflect.ValueOf(tt.data))
+			if !reflect.DeepEqual(tt.data, tt.want) {
+				t.Errorf("normalizeTitles = %+v, want %+v", tt.data, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
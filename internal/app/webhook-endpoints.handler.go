@@ -0,0 +1,484 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/achievements"
+	"github.com/mustaphalimar/prepilot/internal/store"
+	"github.com/mustaphalimar/prepilot/internal/webhooks"
+)
+
+// CreateWebhookEndpointRequest represents the request body for registering a webhook endpoint
+type CreateWebhookEndpointRequest struct {
+	URL         string   `json:"url" validate:"required,url,startswith=https://"`
+	Description *string  `json:"description"`
+	EventTypes  []string `json:"event_types" validate:"required,min=1,dive,oneof=task.completed plan.created exam.approaching streak.broken"`
+}
+
+// UpdateWebhookEndpointRequest represents the request body for updating a webhook endpoint
+type UpdateWebhookEndpointRequest struct {
+	URL         string   `json:"url" validate:"required,url,startswith=https://"`
+	Description *string  `json:"description"`
+	EventTypes  []string `json:"event_types" validate:"required,min=1,dive,oneof=task.completed plan.created exam.approaching streak.broken"`
+	Enabled     bool     `json:"enabled"`
+}
+
+// WebhookEndpointResponse represents the response format for webhook endpoints.
+// The secret is only included when it is created or rotated.
+type WebhookEndpointResponse struct {
+	ID                  uuid.UUID  `json:"id"`
+	URL                 string     `json:"url"`
+	Description         *string    `json:"description"`
+	EventTypes          []string   `json:"event_types"`
+	Enabled             bool       `json:"enabled"`
+	ConsecutiveFailures int32      `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at"`
+	Secret              string     `json:"secret,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// WebhookDeliveryResponse represents the response format for webhook deliveries
+type WebhookDeliveryResponse struct {
+	ID               uuid.UUID                        `json:"id"`
+	MessageID        string                           `json:"message_id"`
+	EventType        string                           `json:"event_type"`
+	Payload          json.RawMessage                  `json:"payload"`
+	Status           string                           `json:"status"`
+	Attempts         int32                            `json:"attempts"`
+	LastResponseCode *int32                           `json:"last_response_code"`
+	NextAttemptAt    *time.Time                       `json:"next_attempt_at"`
+	DeliveredAt      *time.Time                       `json:"delivered_at"`
+	CreatedAt        time.Time                        `json:"created_at"`
+	AttemptLog       []WebhookDeliveryAttemptResponse `json:"attempt_log,omitempty"`
+}
+
+// WebhookDeliveryAttemptResponse represents a single HTTP attempt of a delivery
+type WebhookDeliveryAttemptResponse struct {
+	ResponseCode *int32    `json:"response_code"`
+	Error        *string   `json:"error"`
+	DurationMs   int32     `json:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// convertWebhookEndpointToResponse converts a store.WebhookEndpoint to WebhookEndpointResponse
+func convertWebhookEndpointToResponse(endpoint store.WebhookEndpoint) WebhookEndpointResponse {
+	response := WebhookEndpointResponse{
+		ID:                  endpoint.ID,
+		URL:                 endpoint.Url,
+		EventTypes:          endpoint.EventTypes,
+		Enabled:             endpoint.Enabled,
+		ConsecutiveFailures: endpoint.ConsecutiveFailures,
+		CreatedAt:           endpoint.CreatedAt,
+		UpdatedAt:           endpoint.UpdatedAt,
+	}
+
+	if endpoint.Description.Valid {
+		description := endpoint.Description.String
+		response.Description = &description
+	}
+
+	if endpoint.DisabledAt.Valid {
+		disabledAt := endpoint.DisabledAt.Time
+		response.DisabledAt = &disabledAt
+	}
+
+	return response
+}
+
+// convertWebhookDeliveryToResponse converts a store.WebhookDelivery to WebhookDeliveryResponse
+func convertWebhookDeliveryToResponse(delivery store.WebhookDelivery) WebhookDeliveryResponse {
+	response := WebhookDeliveryResponse{
+		ID:        delivery.ID,
+		MessageID: delivery.MessageID,
+		EventType: delivery.EventType,
+		Payload:   delivery.Payload,
+		Status:    delivery.Status,
+		Attempts:  delivery.Attempts,
+		CreatedAt: delivery.CreatedAt,
+	}
+
+	if delivery.LastResponseCode.Valid {
+		code := delivery.LastResponseCode.Int32
+		response.LastResponseCode = &code
+	}
+
+	if delivery.Status == webhooks.StatusPending {
+		nextAttemptAt := delivery.NextAttemptAt
+		response.NextAttemptAt = &nextAttemptAt
+	}
+
+	if delivery.DeliveredAt.Valid {
+		deliveredAt := delivery.DeliveredAt.Time
+		response.DeliveredAt = &deliveredAt
+	}
+
+	return response
+}
+
+// convertWebhookDeliveryAttemptToResponse converts a store.WebhookDeliveryAttempt to WebhookDeliveryAttemptResponse
+func convertWebhookDeliveryAttemptToResponse(attempt store.WebhookDeliveryAttempt) WebhookDeliveryAttemptResponse {
+	response := WebhookDeliveryAttemptResponse{
+		DurationMs: attempt.DurationMs,
+		CreatedAt:  attempt.CreatedAt,
+	}
+
+	if attempt.ResponseCode.Valid {
+		code := attempt.ResponseCode.Int32
+		response.ResponseCode = &code
+	}
+
+	if attempt.Error.Valid {
+		message := attempt.Error.String
+		response.Error = &message
+	}
+
+	return response
+}
+
+// CreateWebhookEndpointHandler registers a new webhook endpoint
+func (app *Application) CreateWebhookEndpointHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	var req CreateWebhookEndpointRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		app.badRequestError(w, r, &FieldError{Field: "url", Code: "public", Message: "must point to a public internet address"})
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	endpoint, err := app.Queries.CreateWebhookEndpoint(r.Context(), store.CreateWebhookEndpointParams{
+		UserID:      user.ClerkID,
+		Url:         req.URL,
+		Description: stringToNullString(req.Description),
+		Secret:      secret,
+		EventTypes:  req.EventTypes,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertWebhookEndpointToResponse(endpoint)
+	response.Secret = endpoint.Secret
+
+	app.jsonResponse(w, http.StatusCreated, response)
+}
+
+// GetWebhookEndpointsHandler lists the authenticated user's webhook endpoints
+func (app *Application) GetWebhookEndpointsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	endpoints, err := app.Queries.GetWebhookEndpointsByUser(r.Context(), user.ClerkID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]WebhookEndpointResponse, len(endpoints))
+	for i, endpoint := range endpoints {
+		response[i] = convertWebhookEndpointToResponse(endpoint)
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, response); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetWebhookEndpointHandler retrieves a specific webhook endpoint
+func (app *Application) GetWebhookEndpointHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	endpoint, ok := app.getOwnedWebhookEndpoint(w, r, user)
+	if !ok {
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, convertWebhookEndpointToResponse(endpoint))
+}
+
+// UpdateWebhookEndpointHandler updates a webhook endpoint. Re-enabling an
+// endpoint resets its failure count and resumes pending deliveries.
+func (app *Application) UpdateWebhookEndpointHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	endpoint, ok := app.getOwnedWebhookEndpoint(w, r, user)
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookEndpointRequest
+	if err := app.readJSON(w, r, &req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(req); err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		app.badRequestError(w, r, &FieldError{Field: "url", Code: "public", Message: "must point to a public internet address"})
+		return
+	}
+
+	endpoint, err := app.Queries.UpdateWebhookEndpoint(r.Context(), store.UpdateWebhookEndpointParams{
+		Url:         req.URL,
+		Description: stringToNullString(req.Description),
+		EventTypes:  req.EventTypes,
+		Enabled:     req.Enabled,
+		ID:          endpoint.ID,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusOK, convertWebhookEndpointToResponse(endpoint))
+}
+
+// DeleteWebhookEndpointHandler deletes a webhook endpoint and its delivery log
+func (app *Application) DeleteWebhookEndpointHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	endpoint, ok := app.getOwnedWebhookEndpoint(w, r, user)
+	if !ok {
+		return
+	}
+
+	if err := app.Queries.DeleteWebhookEndpoint(r.Context(), endpoint.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.respondDeleted(w, r, "Webhook endpoint deleted successfully")
+}
+
+// RotateWebhookEndpointSecretHandler replaces an endpoint's signing secret
+func (app *Application) RotateWebhookEndpointSecretHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	endpoint, ok := app.getOwnedWebhookEndpoint(w, r, user)
+	if !ok {
+		return
+	}
+
+	secret, err := webhooks.NewSecret()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	endpoint, err = app.Queries.RotateWebhookEndpointSecret(r.Context(), store.RotateWebhookEndpointSecretParams{
+		ID:     endpoint.ID,
+		Secret: secret,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertWebhookEndpointToResponse(endpoint)
+	response.Secret = endpoint.Secret
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+// GetWebhookDeliveriesHandler lists the delivery log of an endpoint
+func (app *Application) GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	endpoint, ok := app.getOwnedWebhookEndpoint(w, r, user)
+	if !ok {
+		return
+	}
+
+	limit, offset, err := parsePagination(r, 50, 100)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	deliveries, err := app.Queries.GetWebhookDeliveriesByEndpoint(r.Context(), store.GetWebhookDeliveriesByEndpointParams{
+		EndpointID: endpoint.ID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]WebhookDeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		response[i] = convertWebhookDeliveryToResponse(delivery)
+	}
+
+	if err := app.pageResponse(w, r, response, len(response), limit, offset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetWebhookDeliveryHandler retrieves a delivery with every attempt made for it
+func (app *Application) GetWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	delivery, ok := app.getOwnedWebhookDelivery(w, r, user)
+	if !ok {
+		return
+	}
+
+	attempts, err := app.Queries.GetWebhookDeliveryAttempts(r.Context(), delivery.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := convertWebhookDeliveryToResponse(delivery)
+	response.AttemptLog = make([]WebhookDeliveryAttemptResponse, len(attempts))
+	for i, attempt := range attempts {
+		response.AttemptLog[i] = convertWebhookDeliveryAttemptToResponse(attempt)
+	}
+
+	app.jsonResponse(w, http.StatusOK, response)
+}
+
+// RedeliverWebhookDeliveryHandler queues a delivery to be sent again
+func (app *Application) RedeliverWebhookDeliveryHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	delivery, ok := app.getOwnedWebhookDelivery(w, r, user)
+	if !ok {
+		return
+	}
+
+	endpoint, err := app.Queries.GetWebhookEndpointByID(r.Context(), delivery.EndpointID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if !endpoint.Enabled {
+		app.writeJSONError(w, r, http.StatusConflict, "Webhook endpoint is disabled")
+		return
+	}
+
+	delivery, err = app.Queries.RedeliverWebhookDelivery(r.Context(), delivery.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.jsonResponse(w, http.StatusAccepted, convertWebhookDeliveryToResponse(delivery))
+}
+
+// getOwnedWebhookEndpoint loads the endpoint from the URL and checks that it
+// belongs to the user, writing the error response if not
+func (app *Application) getOwnedWebhookEndpoint(w http.ResponseWriter, r *http.Request, user *UserClaims) (store.WebhookEndpoint, bool) {
+	endpointID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return store.WebhookEndpoint{}, false
+	}
+
+	endpoint, err := app.Queries.GetWebhookEndpointByID(r.Context(), endpointID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Webhook endpoint not found")
+			return store.WebhookEndpoint{}, false
+		}
+		app.internalServerError(w, r, err)
+		return store.WebhookEndpoint{}, false
+	}
+
+	if endpoint.UserID != user.ClerkID {
+		app.writeJSONError(w, r, http.StatusNotFound, "Webhook endpoint not found")
+		return store.WebhookEndpoint{}, false
+	}
+
+	return endpoint, true
+}
+
+// getOwnedWebhookDelivery loads the delivery from the URL and checks that it
+// belongs to the endpoint in the URL
+func (app *Application) getOwnedWebhookDelivery(w http.ResponseWriter, r *http.Request, user *UserClaims) (store.WebhookDelivery, bool) {
+	endpoint, ok := app.getOwnedWebhookEndpoint(w, r, user)
+	if !ok {
+		return store.WebhookDelivery{}, false
+	}
+
+	deliveryID, err := uuid.Parse(chi.URLParam(r, "deliveryID"))
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return store.WebhookDelivery{}, false
+	}
+
+	delivery, err := app.Queries.GetWebhookDeliveryByID(r.Context(), deliveryID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.writeJSONError(w, r, http.StatusNotFound, "Webhook delivery not found")
+			return store.WebhookDelivery{}, false
+		}
+		app.internalServerError(w, r, err)
+		return store.WebhookDelivery{}, false
+	}
+
+	if delivery.EndpointID != endpoint.ID {
+		app.writeJSONError(w, r, http.StatusNotFound, "Webhook delivery not found")
+		return store.WebhookDelivery{}, false
+	}
+
+	return delivery, true
+}
+
+// enqueueWebhook queues an outbound webhook for the user's endpoints.
+// Failures are logged rather than returned so they never fail the request.
+func (app *Application) enqueueWebhook(ctx context.Context, userID, eventType string, data any) {
+	if err := app.Webhooks.Enqueue(ctx, userID, eventType, "", data); err != nil {
+		log.Printf("webhooks: failed to enqueue %s for %s: %v", eventType, userID, err)
+	}
+}
+
+// recordTaskCompleted tracks study activity for streaks and goals and
+// notifies webhooks. Goals and badges are checked in the background, as
+// they measure the user's whole history.
+func (app *Application) recordTaskCompleted(ctx context.Context, userID string, task StudyTaskResponse) {
+	if err := app.Queries.RecordTaskCompleted(ctx, userID); err != nil {
+		log.Printf("failed to record study activity for %s: %v", userID, err)
+	}
+	app.runInBackground(ctx, func(ctx context.Context) {
+		app.checkGoalMilestones(ctx, userID)
+		app.checkAchievements(ctx, userID, achievements.EventTaskCompleted)
+	})
+	app.enqueueWebhook(ctx, userID, webhooks.TaskCompleted, task)
+}
+
+// parsePagination reads limit and offset query parameters
+func parsePagination(r *http.Request, defaultLimit, maxLimit int32) (int32, int32, error) {
+	limit := defaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || parsed < 1 {
+			return 0, 0, invalidParam("limit", value)
+		}
+		limit = int32(parsed)
+		if limit > maxLimit {
+			limit = maxLimit
+		}
+	}
+
+	var offset int32
+	if value := r.URL.Query().Get("offset"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || parsed < 0 {
+			return 0, 0, invalidParam("offset", value)
+		}
+		offset = int32(parsed)
+	}
+
+	return limit, offset, nil
+}
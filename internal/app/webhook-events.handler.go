@@ -0,0 +1,165 @@
+package app
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Inbound webhook event statuses
+const (
+	webhookEventProcessing = "processing"
+	webhookEventProcessed  = "processed"
+	webhookEventFailed     = "failed"
+)
+
+// WebhookEventResponse is an inbound Clerk event as shown to admins
+type WebhookEventResponse struct {
+	SvixID      string          `json:"svix_id"`
+	Type        string          `json:"type"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	LastError   *string         `json:"last_error,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt *time.Time      `json:"processed_at,omitempty"`
+	ClaimedAt   *time.Time      `json:"claimed_at,omitempty"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// convertWebhookEventToResponse converts a stored event to its response.
+// The payload is only included when requested, since lists can get large.
+func convertWebhookEventToResponse(event store.WebhookEvent, withPayload bool) WebhookEventResponse {
+	response := WebhookEventResponse{
+		SvixID:     event.SvixID,
+		Type:       event.Type,
+		Status:     event.Status,
+		Attempts:   event.Attempts,
+		ReceivedAt: event.ReceivedAt,
+		UpdatedAt:  event.UpdatedAt,
+	}
+	if event.LastError.Valid {
+		response.LastError = &event.LastError.String
+	}
+	if event.ProcessedAt.Valid {
+		response.ProcessedAt = &event.ProcessedAt.Time
+	}
+	if event.ClaimedAt.Valid {
+		response.ClaimedAt = &event.ClaimedAt.Time
+	}
+	if withPayload {
+		response.Payload = event.Payload
+	}
+	return response
+}
+
+// GetWebhookEventsHandler lists inbound webhook events, optionally by status
+func (app *Application) GetWebhookEventsHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	limit, offset, err := parsePagination(r, 50, 200)
+	if err != nil {
+		app.badRequestError(w, r, err)
+		return
+	}
+
+	var status sql.NullString
+	if value := r.URL.Query().Get("status"); value != "" {
+		switch value {
+		case webhookEventProcessing, webhookEventProcessed, webhookEventFailed:
+			status = sql.NullString{String: value, Valid: true}
+		default:
+			app.badRequestError(w, r, invalidParam("status", value))
+			return
+		}
+	}
+
+	events, err := app.Queries.ListWebhookEvents(r.Context(), store.ListWebhookEventsParams{
+		Status:    status,
+		RowLimit:  limit,
+		RowOffset: offset,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	response := make([]WebhookEventResponse, len(events))
+	for i, event := range events {
+		response[i] = convertWebhookEventToResponse(event, false)
+	}
+
+	if err := app.pageResponse(w, r, response, len(response), limit, offset); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetWebhookEventHandler retrieves a single inbound event with its payload
+func (app *Application) GetWebhookEventHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	event, err := app.Queries.GetWebhookEvent(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			app.notFoundError(w, r, err)
+			return
+		}
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, convertWebhookEventToResponse(event, true)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// ReplayWebhookEventHandler re-runs a failed inbound event from its stored
+// payload. Events stuck in processing past their lease can be replayed too.
+func (app *Application) ReplayWebhookEventHandler(w http.ResponseWriter, r *http.Request, user *UserClaims) {
+	svixID := chi.URLParam(r, "id")
+
+	event, err := app.Queries.StartWebhookEventReplay(r.Context(), store.StartWebhookEventReplayParams{
+		SvixID:       svixID,
+		LeaseSeconds: int32(clerkEventLease.Seconds()),
+	})
+	if err == sql.ErrNoRows {
+		// Either the event does not exist or it is not failed or abandoned
+		if _, err := app.Queries.GetWebhookEvent(r.Context(), svixID); err != nil {
+			if err == sql.ErrNoRows {
+				app.notFoundError(w, r, err)
+				return
+			}
+			app.internalServerError(w, r, err)
+			return
+		}
+		app.conflictError(w, r, errors.New("only failed events, or events stuck in processing, can be replayed"))
+		return
+	}
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	var clerkEvent ClerkWebhookEvent
+	if err := json.Unmarshal(event.Payload, &clerkEvent); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// Failures are recorded on the event itself, so report its final state
+	if _, err := app.runClerkEvent(r.Context(), svixID, clerkEvent); err != nil {
+		fmt.Printf("❌ Replay of event %s failed: %v\n", svixID, err)
+	}
+
+	event, err = app.Queries.GetWebhookEvent(r.Context(), svixID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.jsonResponse(w, http.StatusOK, convertWebhookEventToResponse(event, false)); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
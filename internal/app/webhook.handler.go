@@ -1,19 +1,18 @@
 package app
 
 import (
-	"crypto/hmac"
-	"crypto/sha256"
+	"context"
 	"database/sql"
-	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/mustaphalimar/prepilot/internal/store"
+	"github.com/mustaphalimar/prepilot/internal/svix"
 )
 
 // Helper functions to convert to SQL types
@@ -45,6 +44,48 @@ func stringValueToNullString(s string) sql.NullString {
 	return sql.NullString{String: s, Valid: true}
 }
 
+func nullStringToPointer(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullTimeToPointer(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func float64ToNullFloat64(f *float64) sql.NullFloat64 {
+	if f == nil {
+		return sql.NullFloat64{Valid: false}
+	}
+	return sql.NullFloat64{Float64: *f, Valid: true}
+}
+
+func nullFloat64ToPointer(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
+
+func int32ToNullInt32(i *int32) sql.NullInt32 {
+	if i == nil {
+		return sql.NullInt32{Valid: false}
+	}
+	return sql.NullInt32{Int32: *i, Valid: true}
+}
+
+func nullInt32ToPointer(i sql.NullInt32) *int32 {
+	if !i.Valid {
+		return nil
+	}
+	return &i.Int32
+}
+
 // ClerkWebhookEvent represents the structure of Clerk webhook events
 type ClerkWebhookEvent struct {
 	Type   string          `json:"type"`
@@ -54,16 +95,17 @@ type ClerkWebhookEvent struct {
 
 // ClerkUser represents user data from Clerk webhooks
 type ClerkUser struct {
-	ID                string                 `json:"id"`
-	EmailAddresses    []ClerkEmailAddress    `json:"email_addresses"`
-	FirstName         *string                `json:"first_name"`
-	LastName          *string                `json:"last_name"`
-	ImageURL          string                 `json:"image_url"`
-	Banned            bool                   `json:"banned"`
-	CreatedAt         int64                  `json:"created_at"`
-	UpdatedAt         int64                  `json:"updated_at"`
-	LastSignInAt      *int64                 `json:"last_sign_in_at"`
-	ExternalAccounts  []ClerkExternalAccount `json:"external_accounts"`
+	ID                    string                 `json:"id"`
+	PrimaryEmailAddressID *string                `json:"primary_email_address_id"`
+	EmailAddresses        []ClerkEmailAddress    `json:"email_addresses"`
+	FirstName             *string                `json:"first_name"`
+	LastName              *string                `json:"last_name"`
+	ImageURL              string                 `json:"image_url"`
+	Banned                bool                   `json:"banned"`
+	CreatedAt             int64                  `json:"created_at"`
+	UpdatedAt             int64                  `json:"updated_at"`
+	LastSignInAt          *int64                 `json:"last_sign_in_at"`
+	ExternalAccounts      []ClerkExternalAccount `json:"external_accounts"`
 }
 
 type ClerkEmailAddress struct {
@@ -78,78 +120,142 @@ type ClerkExternalAccount struct {
 	ID               string `json:"id"`
 	Provider         string `json:"provider"`
 	IdentificationID string `json:"identification_id"`
+	ProviderUserID   string `json:"provider_user_id"`
 	EmailAddress     string `json:"email_address"`
 	FirstName        string `json:"first_name"`
 	LastName         string `json:"last_name"`
 	ImageURL         string `json:"image_url"`
 }
 
-// ClerkWebhookHandler handles webhook events from Clerk
+// ClerkSession represents session data from Clerk webhooks
+type ClerkSession struct {
+	ID           string `json:"id"`
+	UserID       string `json:"user_id"`
+	Status       string `json:"status"`
+	LastActiveAt *int64 `json:"last_active_at"`
+}
+
+// ClerkOrganization represents organization data from Clerk webhooks
+type ClerkOrganization struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Slug      string `json:"slug"`
+	ImageURL  string `json:"image_url"`
+	CreatedBy string `json:"created_by"`
+}
+
+// ClerkOrganizationMembership represents membership data from Clerk webhooks
+type ClerkOrganizationMembership struct {
+	ID             string            `json:"id"`
+	Role           string            `json:"role"`
+	Organization   ClerkOrganization `json:"organization"`
+	PublicUserData struct {
+		UserID string `json:"user_id"`
+	} `json:"public_user_data"`
+}
+
+// ClerkEmail represents an email Clerk is sending on behalf of the app
+type ClerkEmail struct {
+	ID             string `json:"id"`
+	Slug           string `json:"slug"`
+	UserID         string `json:"user_id"`
+	EmailAddressID string `json:"email_address_id"`
+	ToEmailAddress string `json:"to_email_address"`
+	Status         string `json:"status"`
+}
+
+// errInvalidClerkPayload marks events that can never succeed on retry
+var errInvalidClerkPayload = errors.New("invalid payload")
+
+// ClerkWebhookHandler handles webhook events from Clerk. Every event is
+// recorded by its svix-id before processing, so retries of an event that
+// was already applied are acknowledged without running it again.
 func (app *Application) ClerkWebhookHandler(w http.ResponseWriter, r *http.Request) {
 	fmt.Printf("🔔 Clerk webhook received: %s %s\n", r.Method, r.URL.Path)
-	fmt.Printf("📋 Headers: %+v\n", r.Header)
 
 	// Read the request body
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		fmt.Printf("❌ Failed to read request body: %v\n", err)
-		app.writeJSONError(w, http.StatusBadRequest, "Failed to read request body")
+		app.writeJSONError(w, r, http.StatusBadRequest, "Failed to read request body")
 		return
 	}
 	defer r.Body.Close()
 
-	fmt.Printf("📄 Raw webhook body: %s\n", string(body))
-
 	// Verify the webhook signature
 	if !app.verifyClerkWebhook(r, body) {
 		fmt.Printf("❌ Webhook signature verification failed\n")
-		app.writeJSONError(w, http.StatusUnauthorized, "Invalid webhook signature")
+		app.writeJSONError(w, r, http.StatusUnauthorized, "Invalid webhook signature")
 		return
 	}
 
 	fmt.Printf("✅ Webhook signature verified\n")
 
+	svixID := r.Header.Get("svix-id")
+	if svixID == "" {
+		app.writeJSONError(w, r, http.StatusBadRequest, "Missing svix-id header")
+		return
+	}
+
 	// Parse the webhook event
 	var event ClerkWebhookEvent
 	if err := json.Unmarshal(body, &event); err != nil {
 		fmt.Printf("❌ Failed to parse JSON: %v\n", err)
-		app.writeJSONError(w, http.StatusBadRequest, "Invalid JSON payload")
+		app.writeJSONError(w, r, http.StatusBadRequest, "Invalid JSON payload")
 		return
 	}
 
-	fmt.Printf("📦 Parsed event - Type: %s, Object: %s\n", event.Type, event.Object)
+	fmt.Printf("📦 Parsed event - ID: %s, Type: %s, Object: %s\n", svixID, event.Type, event.Object)
 
-	// Handle different event types
-	switch event.Type {
-	case "user.created":
-		fmt.Printf("👤 Processing user.created event\n")
-		app.handleUserCreated(w, r, event.Data)
-	case "user.updated":
-		fmt.Printf("👤 Processing user.updated event\n")
-		app.handleUserUpdated(w, r, event.Data)
-	case "user.deleted":
-		fmt.Printf("👤 Processing user.deleted event\n")
-		app.handleUserDeleted(w, r, event.Data)
-	case "session.created":
-		fmt.Printf("🔐 Processing session.created event\n")
-		app.handleSessionCreated(w, r, event.Data)
-	default:
-		fmt.Printf("⚠️ Unhandled event type: %s\n", event.Type)
-		// Return 200 for unhandled events to acknowledge receipt
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{
-			"message": fmt.Sprintf("Event type %s received but not handled", event.Type),
+	// Record the event. No row back means it was already processed or is
+	// being processed by another request right now. A claim left behind by
+	// a crash expires after clerkEventLease and the event is run again.
+	_, err = app.Queries.ClaimWebhookEvent(r.Context(), store.ClaimWebhookEventParams{
+		SvixID:       svixID,
+		Type:         event.Type,
+		Payload:      body,
+		LeaseSeconds: int32(clerkEventLease.Seconds()),
+	})
+	if err == sql.ErrNoRows {
+		fmt.Printf("♻️ Duplicate event %s acknowledged\n", svixID)
+		app.writeJSON(w, http.StatusOK, map[string]string{
+			"message": "Event already received",
 		})
+		return
 	}
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	message, err := app.runClerkEvent(r.Context(), svixID, event)
+	if err != nil {
+		fmt.Printf("❌ Failed to process event %s: %v\n", svixID, err)
+		if errors.Is(err, errInvalidClerkPayload) {
+			app.writeJSONError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		// A 5xx makes Clerk retry the event later
+		app.writeJSONError(w, r, http.StatusInternalServerError, "Failed to process event")
+		return
+	}
+
+	app.writeJSON(w, http.StatusOK, map[string]string{
+		"message": message,
+	})
 }
 
-// verifyClerkWebhook verifies that the webhook request is from Clerk
+// verifyClerkWebhook verifies that the webhook request is from Clerk.
+// CLERK_WEBHOOK_SECRET may hold several space or comma separated secrets
+// so that a secret can be rotated without rejecting in-flight deliveries.
 func (app *Application) verifyClerkWebhook(r *http.Request, body []byte) bool {
-	// Get the webhook secret from environment variables
-	webhookSecret := app.Config.ClerkWebhookSecret
-	fmt.Printf("🔐 Webhook secret configured: %v\n", webhookSecret != "")
-	
-	if webhookSecret == "" {
+	// Get the webhook secrets from environment variables
+	secrets := strings.FieldsFunc(app.Config.ClerkWebhookSecret, func(c rune) bool {
+		return c == ',' || c == ' '
+	})
+	fmt.Printf("🔐 Webhook secrets configured: %d\n", len(secrets))
+
+	if len(secrets) == 0 {
 		// In development, you might want to skip verification
 		if app.Config.Env == "development" {
 			fmt.Printf("⚠️ Skipping webhook verification in development mode\n")
@@ -159,181 +265,175 @@ func (app *Application) verifyClerkWebhook(r *http.Request, body []byte) bool {
 		return false
 	}
 
-	// Get the signature from headers
-	signature := r.Header.Get("svix-signature")
-	if signature == "" {
-		fmt.Printf("❌ No svix-signature header found\n")
+	verifier, err := svix.NewVerifier(secrets, svix.DefaultTolerance)
+	if err != nil {
+		fmt.Printf("❌ Invalid webhook secret configuration: %v\n", err)
 		return false
 	}
 
-	fmt.Printf("🔑 Signature header: %s\n", signature)
+	if err := verifier.Verify(r.Header, body); err != nil {
+		fmt.Printf("❌ Webhook verification failed: %v\n", err)
+		return false
+	}
 
-	// Parse the signature header - Clerk format: "v1,signature_value"
-	signatures := make(map[string]string)
-	
-	// Clerk sends signature in format: "v1,actual_signature_value"
-	if strings.Contains(signature, ",") {
-		parts := strings.SplitN(signature, ",", 2)
-		if len(parts) == 2 {
-			version := strings.TrimSpace(parts[0])
-			signatureValue := strings.TrimSpace(parts[1])
-			signatures[version] = signatureValue
-			fmt.Printf("🔧 Parsed signature - Version: %s, Value: %s\n", version, signatureValue[:10]+"...")
-		}
-	} else {
-		// Fallback for other formats
-		for _, sig := range strings.Split(signature, " ") {
-			if strings.Contains(sig, "=") {
-				parts := strings.SplitN(sig, "=", 2)
-				if len(parts) == 2 {
-					signatures[parts[0]] = parts[1]
-				}
-			}
+	return true
+}
+
+// clerkEventLease is how long a claimed event may stay in processing before
+// it is considered abandoned and can be claimed again. It is well above the
+// request timeout.
+const clerkEventLease = 5 * time.Minute
+
+// runClerkEvent applies an event in a transaction and records the outcome.
+// The event is only marked processed if everything it changed is committed.
+// A failure is recorded even if the request was cancelled, so the event can
+// be retried or replayed right away.
+func (app *Application) runClerkEvent(ctx context.Context, svixID string, event ClerkWebhookEvent) (string, error) {
+	message, err := app.applyClerkEvent(ctx, svixID, event)
+	if err != nil {
+		if markErr := app.Queries.MarkWebhookEventFailed(context.WithoutCancel(ctx), store.MarkWebhookEventFailedParams{
+			SvixID:    svixID,
+			LastError: sql.NullString{String: err.Error(), Valid: true},
+		}); markErr != nil {
+			fmt.Printf("❌ Failed to record failure for event %s: %v\n", svixID, markErr)
 		}
+		return "", err
 	}
+	return message, nil
+}
 
-	// Get the timestamp
-	timestamp := r.Header.Get("svix-timestamp")
-	if timestamp == "" {
-		fmt.Printf("❌ No svix-timestamp header found\n")
-		return false
+func (app *Application) applyClerkEvent(ctx context.Context, svixID string, event ClerkWebhookEvent) (string, error) {
+	tx, err := app.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to start transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	fmt.Printf("⏰ Timestamp: %s\n", timestamp)
+	queries := app.Queries.WithTx(tx)
 
-	// Verify the timestamp is recent (within 5 minutes)
-	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	message, err := app.processClerkEvent(ctx, queries, event)
 	if err != nil {
-		fmt.Printf("❌ Invalid timestamp format: %v\n", err)
-		return false
-	}
-	if time.Now().Unix()-ts > 300 {
-		fmt.Printf("❌ Timestamp too old: %d seconds ago\n", time.Now().Unix()-ts)
-		return false
+		return "", err
 	}
 
-	// Create the expected signature using Clerk's algorithm
-	id := r.Header.Get("svix-id")
-	payload := fmt.Sprintf("%s.%s.%s", id, timestamp, string(body))
-	
-	// Decode the webhook secret from base64 (Clerk secrets are base64 encoded)
-	secretBytes := []byte(webhookSecret)
-	if strings.HasPrefix(webhookSecret, "whsec_") {
-		// Remove the whsec_ prefix and decode from base64
-		secretWithoutPrefix := strings.TrimPrefix(webhookSecret, "whsec_")
-		decoded, err := base64.StdEncoding.DecodeString(secretWithoutPrefix)
-		if err == nil {
-			secretBytes = decoded
-		}
+	if err := queries.MarkWebhookEventProcessed(ctx, svixID); err != nil {
+		return "", fmt.Errorf("failed to mark event processed: %w", err)
 	}
-	
-	mac := hmac.New(sha256.New, secretBytes)
-	mac.Write([]byte(payload))
-	expectedSignature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
 
-	// Compare with provided signature
-	providedSignature, exists := signatures["v1"]
-	if !exists {
-		fmt.Printf("❌ No v1 signature found in header\n")
-		return false
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit event: %w", err)
 	}
 
-	isValid := hmac.Equal([]byte(expectedSignature), []byte(providedSignature))
-	fmt.Printf("🔍 Expected signature: %s\n", expectedSignature[:10]+"...")
-	fmt.Printf("🔍 Provided signature: %s\n", providedSignature[:10]+"...")
-	fmt.Printf("🔍 Signature verification result: %v\n", isValid)
-	
-	return isValid
+	return message, nil
 }
 
-// handleUserCreated processes user.created webhook events
-func (app *Application) handleUserCreated(w http.ResponseWriter, r *http.Request, data json.RawMessage) {
-	fmt.Printf("🔄 Starting handleUserCreated\n")
-	fmt.Printf("📄 User data JSON: %s\n", string(data))
+// processClerkEvent dispatches an event to its handler
+func (app *Application) processClerkEvent(ctx context.Context, queries *store.Queries, event ClerkWebhookEvent) (string, error) {
+	switch event.Type {
+	case "user.created":
+		fmt.Printf("👤 Processing user.created event\n")
+		return app.handleUserCreated(ctx, queries, event.Data)
+	case "user.updated":
+		fmt.Printf("👤 Processing user.updated event\n")
+		return app.handleUserUpdated(ctx, queries, event.Data)
+	case "user.deleted":
+		fmt.Printf("👤 Processing user.deleted event\n")
+		return app.handleUserDeleted(ctx, queries, event.Data)
+	case "session.created":
+		fmt.Printf("🔐 Processing session.created event\n")
+		return app.handleSessionCreated(ctx, queries, event.Data)
+	case "session.ended", "session.revoked", "session.removed":
+		fmt.Printf("🔐 Processing %s event\n", event.Type)
+		return app.handleSessionEnded(ctx, queries, event.Data)
+	case "email.created":
+		fmt.Printf("📧 Processing email.created event\n")
+		return app.handleEmailCreated(ctx, queries, event.Data)
+	case "organization.created", "organization.updated":
+		fmt.Printf("🏢 Processing %s event\n", event.Type)
+		return app.handleOrganizationUpserted(ctx, queries, event.Data)
+	case "organization.deleted":
+		fmt.Printf("🏢 Processing organization.deleted event\n")
+		return app.handleOrganizationDeleted(ctx, queries, event.Data)
+	case "organizationMembership.created", "organizationMembership.updated":
+		fmt.Printf("🏢 Processing %s event\n", event.Type)
+		return app.handleOrganizationMembershipUpserted(ctx, queries, event.Data)
+	case "organizationMembership.deleted":
+		fmt.Printf("🏢 Processing organizationMembership.deleted event\n")
+		return app.handleOrganizationMembershipDeleted(ctx, queries, event.Data)
+	default:
+		fmt.Printf("⚠️ Unhandled event type: %s\n", event.Type)
+		return fmt.Sprintf("Event type %s received but not handled", event.Type), nil
+	}
+}
 
+// handleUserCreated processes user.created webhook events
+func (app *Application) handleUserCreated(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
 	var user ClerkUser
 	if err := json.Unmarshal(data, &user); err != nil {
-		fmt.Printf("❌ Failed to unmarshal user data: %v\n", err)
-		app.writeJSONError(w, http.StatusBadRequest, "Invalid user data")
-		return
+		return "", fmt.Errorf("%w: user data: %v", errInvalidClerkPayload, err)
 	}
 
-	fmt.Printf("👤 Parsed user: ID=%s, FirstName=%v, LastName=%v, ImageURL=%s\n", 
-		user.ID, user.FirstName, user.LastName, user.ImageURL)
-	fmt.Printf("📧 Email addresses: %+v\n", user.EmailAddresses)
+	fmt.Printf("👤 Parsed user: ID=%s\n", user.ID)
 
 	// Get primary email address
-	primaryEmail := app.getPrimaryEmail(user.EmailAddresses)
+	primaryEmail := app.getPrimaryEmail(user)
 	if primaryEmail == "" {
-		fmt.Printf("❌ No primary email found for user %s\n", user.ID)
-		app.writeJSONError(w, http.StatusBadRequest, "User has no email address")
-		return
+		return "", fmt.Errorf("%w: user %s has no email address", errInvalidClerkPayload, user.ID)
 	}
 
-	fmt.Printf("📧 Primary email: %s\n", primaryEmail)
-
 	// Prepare user data for database
-	emailVerified := app.isEmailVerified(user.EmailAddresses)
-	fullName := app.getFullName(user.FirstName, user.LastName)
-	
-	fmt.Printf("✅ Email verified: %v\n", emailVerified)
-	fmt.Printf("👤 Full name: %v\n", fullName)
-
+	emailVerified := app.isEmailVerified(user)
 	params := store.UpsertUserByClerkIDParams{
 		ClerkID:       user.ID,
 		Email:         primaryEmail,
 		FirstName:     stringToNullString(user.FirstName),
 		LastName:      stringToNullString(user.LastName),
-		Name:          stringToNullString(fullName),
+		Name:          stringToNullString(app.getFullName(user.FirstName, user.LastName)),
 		ImageUrl:      stringValueToNullString(user.ImageURL),
 		EmailVerified: boolToNullBool(&emailVerified),
 		LastSignInAt:  timeToNullTime(app.convertTimestamp(user.LastSignInAt)),
 	}
 
-	fmt.Printf("🔧 Database params prepared: ClerkID=%s, Email=%s\n", params.ClerkID, params.Email)
-
-	// Save user to database
-	fmt.Printf("💾 Attempting to save user to database...\n")
-	queries := store.New(app.DB)
-	dbUser, err := queries.UpsertUserByClerkID(r.Context(), params)
+	dbUser, err := queries.UpsertUserByClerkID(ctx, params)
 	if err != nil {
-		fmt.Printf("❌ Database error: %v\n", err)
-		app.writeJSONError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to save user: %v", err))
-		return
+		return "", fmt.Errorf("failed to save user: %w", err)
 	}
 
-	fmt.Printf("✅ User saved successfully! Database ID: %s\n", dbUser.ID)
+	if err := app.syncExternalAccounts(ctx, queries, user); err != nil {
+		return "", err
+	}
 
-	// Return success response
-	w.WriteHeader(http.StatusOK)
-	response := map[string]interface{}{
-		"message": "User created successfully",
-		"user_id": dbUser.ID,
-		"clerk_id": dbUser.ClerkID,
+	if err := logActivity(ctx, queries, activityEntry{
+		UserID:     user.ID,
+		ActorID:    activityActorClerk,
+		Action:     activityUserCreated,
+		EntityType: activityUser,
+		EntityID:   user.ID,
+		After:      userSnapshot(dbUser),
+	}); err != nil {
+		return "", fmt.Errorf("failed to record activity: %w", err)
 	}
-	
-	fmt.Printf("📤 Sending response: %+v\n", response)
-	json.NewEncoder(w).Encode(response)
+
+	fmt.Printf("✅ User saved successfully! Database ID: %s\n", dbUser.ID)
+	return "User created successfully", nil
 }
 
 // handleUserUpdated processes user.updated webhook events
-func (app *Application) handleUserUpdated(w http.ResponseWriter, r *http.Request, data json.RawMessage) {
+func (app *Application) handleUserUpdated(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
 	var user ClerkUser
 	if err := json.Unmarshal(data, &user); err != nil {
-		app.writeJSONError(w, http.StatusBadRequest, "Invalid user data")
-		return
+		return "", fmt.Errorf("%w: user data: %v", errInvalidClerkPayload, err)
 	}
 
 	// Get primary email address
-	primaryEmail := app.getPrimaryEmail(user.EmailAddresses)
+	primaryEmail := app.getPrimaryEmail(user)
 	if primaryEmail == "" {
-		app.writeJSONError(w, http.StatusBadRequest, "User has no email address")
-		return
+		return "", fmt.Errorf("%w: user %s has no email address", errInvalidClerkPayload, user.ID)
 	}
 
 	// Update user in database
-	emailVerified := app.isEmailVerified(user.EmailAddresses)
-	params := store.UpdateUserParams{
+	emailVerified := app.isEmailVerified(user)
+	params := store.UpsertUserByClerkIDParams{
 		ClerkID:       user.ID,
 		Email:         primaryEmail,
 		FirstName:     stringToNullString(user.FirstName),
@@ -344,115 +444,304 @@ func (app *Application) handleUserUpdated(w http.ResponseWriter, r *http.Request
 		LastSignInAt:  timeToNullTime(app.convertTimestamp(user.LastSignInAt)),
 	}
 
-	queries := store.New(app.DB)
-	_, err := queries.UpdateUser(r.Context(), params)
+	// Keep the previous state for the activity log
+	var before any
+	if previous, err := queries.GetUserByClerkID(ctx, user.ID); err == nil {
+		before = userSnapshot(previous)
+	} else if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to load user: %w", err)
+	}
+
+	// Upsert rather than update, so an update that arrives before
+	// user.created still leaves the user in place
+	dbUser, err := queries.UpsertUserByClerkID(ctx, params)
 	if err != nil {
-		app.writeJSONError(w, http.StatusInternalServerError, "Failed to update user")
-		return
+		return "", fmt.Errorf("failed to update user: %w", err)
+	}
+
+	if err := app.syncExternalAccounts(ctx, queries, user); err != nil {
+		return "", err
 	}
 
 	// Handle ban/unban
 	if user.Banned {
-		queries.BanUser(r.Context(), user.ID)
+		if err := queries.BanUser(ctx, user.ID); err != nil {
+			return "", fmt.Errorf("failed to ban user: %w", err)
+		}
 	} else {
-		queries.UnbanUser(r.Context(), user.ID)
+		if err := queries.UnbanUser(ctx, user.ID); err != nil {
+			return "", fmt.Errorf("failed to unban user: %w", err)
+		}
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "User updated successfully",
-	})
+	after := userSnapshot(dbUser)
+	after["banned"] = user.Banned
+	if err := logActivity(ctx, queries, activityEntry{
+		UserID:     user.ID,
+		ActorID:    activityActorClerk,
+		Action:     activityUserUpdated,
+		EntityType: activityUser,
+		EntityID:   user.ID,
+		Before:     before,
+		After:      after,
+	}); err != nil {
+		return "", fmt.Errorf("failed to record activity: %w", err)
+	}
+
+	return "User updated successfully", nil
 }
 
 // handleUserDeleted processes user.deleted webhook events
-func (app *Application) handleUserDeleted(w http.ResponseWriter, r *http.Request, data json.RawMessage) {
+func (app *Application) handleUserDeleted(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
 	var user ClerkUser
 	if err := json.Unmarshal(data, &user); err != nil {
-		app.writeJSONError(w, http.StatusBadRequest, "Invalid user data")
-		return
+		return "", fmt.Errorf("%w: user data: %v", errInvalidClerkPayload, err)
 	}
 
-	// Delete user from database
-	queries := store.New(app.DB)
-	err := queries.DeleteUserByClerkID(r.Context(), user.ID)
-	if err != nil {
-		app.writeJSONError(w, http.StatusInternalServerError, "Failed to delete user")
-		return
+	if user.ID == "" {
+		return "", fmt.Errorf("%w: missing user id", errInvalidClerkPayload)
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "User deleted successfully",
-	})
+	if err := app.eraseUserData(ctx, queries, user.ID, "clerk.user.deleted"); err != nil {
+		return "", err
+	}
+
+	return "User deleted successfully", nil
 }
 
 // handleSessionCreated processes session.created webhook events
-func (app *Application) handleSessionCreated(w http.ResponseWriter, r *http.Request, data json.RawMessage) {
-	// Parse session data if needed
-	var sessionData map[string]interface{}
-	if err := json.Unmarshal(data, &sessionData); err != nil {
-		app.writeJSONError(w, http.StatusBadRequest, "Invalid session data")
-		return
+func (app *Application) handleSessionCreated(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
+	session, err := parseClerkSession(data)
+	if err != nil {
+		return "", err
 	}
 
-	// Extract user_id from session data
-	userID, ok := sessionData["user_id"].(string)
-	if !ok {
-		app.writeJSONError(w, http.StatusBadRequest, "Missing user_id in session data")
-		return
+	lastActive := app.convertTimestamp(session.LastActiveAt)
+	if err := queries.UpsertUserSession(ctx, store.UpsertUserSessionParams{
+		ID:           session.ID,
+		UserClerkID:  session.UserID,
+		Status:       session.Status,
+		LastActiveAt: timeToNullTime(lastActive),
+	}); err != nil {
+		return "", fmt.Errorf("failed to save session: %w", err)
 	}
 
-	// Update last sign in time
-	queries := store.New(app.DB)
-	now := time.Now()
-	params := store.UpdateUserParams{
-		ClerkID:      userID,
-		Email:        "", // Required field, will be handled by COALESCE in SQL
-		FirstName:    sql.NullString{Valid: false},
-		LastName:     sql.NullString{Valid: false},
-		Name:         sql.NullString{Valid: false},
-		ImageUrl:     sql.NullString{Valid: false},
-		EmailVerified: sql.NullBool{Valid: false},
-		LastSignInAt: timeToNullTime(&now),
+	// Update last sign in time. The session may belong to a user we have
+	// not seen yet, in which case there is nothing to update.
+	rows, err := queries.UpdateUserLastSignIn(ctx, store.UpdateUserLastSignInParams{
+		ClerkID:      session.UserID,
+		LastSignInAt: time.Now(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to update last sign in time: %w", err)
+	}
+	if rows == 0 {
+		fmt.Printf("⚠️ Session created for unknown user %s\n", session.UserID)
 	}
 
-	_, err := queries.UpdateUser(r.Context(), params)
+	return "Session created successfully", nil
+}
+
+// handleSessionEnded processes session.ended, session.revoked and
+// session.removed webhook events
+func (app *Application) handleSessionEnded(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
+	session, err := parseClerkSession(data)
 	if err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Failed to update last sign in time for user %s: %v\n", userID, err)
+		return "", err
+	}
+
+	if err := queries.EndUserSession(ctx, store.EndUserSessionParams{
+		ID:          session.ID,
+		UserClerkID: session.UserID,
+		Status:      session.Status,
+	}); err != nil {
+		return "", fmt.Errorf("failed to end session: %w", err)
+	}
+
+	return "Session ended successfully", nil
+}
+
+func parseClerkSession(data json.RawMessage) (ClerkSession, error) {
+	var session ClerkSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return session, fmt.Errorf("%w: session data: %v", errInvalidClerkPayload, err)
+	}
+	if session.ID == "" || session.UserID == "" {
+		return session, fmt.Errorf("%w: missing session or user id", errInvalidClerkPayload)
+	}
+	return session, nil
+}
+
+// handleEmailCreated processes email.created webhook events. Clerk still
+// delivers these emails itself; address changes arrive as user.updated, so
+// there is nothing to store here.
+func (app *Application) handleEmailCreated(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
+	var email ClerkEmail
+	if err := json.Unmarshal(data, &email); err != nil {
+		return "", fmt.Errorf("%w: email data: %v", errInvalidClerkPayload, err)
+	}
+
+	fmt.Printf("📧 Clerk email %s (%s) for user %s: %s\n", email.ID, email.Slug, email.UserID, email.Status)
+	return "Email event received", nil
+}
+
+// handleOrganizationUpserted processes organization.created and
+// organization.updated webhook events
+func (app *Application) handleOrganizationUpserted(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
+	var org ClerkOrganization
+	if err := json.Unmarshal(data, &org); err != nil {
+		return "", fmt.Errorf("%w: organization data: %v", errInvalidClerkPayload, err)
 	}
 
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Session created successfully",
+	if err := upsertOrganization(ctx, queries, org); err != nil {
+		return "", err
+	}
+
+	return "Organization saved successfully", nil
+}
+
+// handleOrganizationDeleted processes organization.deleted webhook events.
+// Memberships are removed along with the organization.
+func (app *Application) handleOrganizationDeleted(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
+	var org ClerkOrganization
+	if err := json.Unmarshal(data, &org); err != nil {
+		return "", fmt.Errorf("%w: organization data: %v", errInvalidClerkPayload, err)
+	}
+
+	if err := queries.DeleteOrganizationByClerkID(ctx, org.ID); err != nil {
+		return "", fmt.Errorf("failed to delete organization: %w", err)
+	}
+
+	return "Organization deleted successfully", nil
+}
+
+// handleOrganizationMembershipUpserted processes organizationMembership.created
+// and organizationMembership.updated webhook events
+func (app *Application) handleOrganizationMembershipUpserted(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
+	var membership ClerkOrganizationMembership
+	if err := json.Unmarshal(data, &membership); err != nil {
+		return "", fmt.Errorf("%w: membership data: %v", errInvalidClerkPayload, err)
+	}
+	if membership.PublicUserData.UserID == "" {
+		return "", fmt.Errorf("%w: missing user_id in membership data", errInvalidClerkPayload)
+	}
+
+	// The membership carries its organization, which may not have been
+	// delivered yet
+	if err := upsertOrganization(ctx, queries, membership.Organization); err != nil {
+		return "", err
+	}
+
+	if _, err := queries.UpsertOrganizationMembership(ctx, store.UpsertOrganizationMembershipParams{
+		ClerkID:             membership.ID,
+		OrganizationClerkID: membership.Organization.ID,
+		UserClerkID:         membership.PublicUserData.UserID,
+		Role:                membership.Role,
+	}); err != nil {
+		return "", fmt.Errorf("failed to save membership: %w", err)
+	}
+
+	return "Membership saved successfully", nil
+}
+
+// handleOrganizationMembershipDeleted processes organizationMembership.deleted webhook events
+func (app *Application) handleOrganizationMembershipDeleted(ctx context.Context, queries *store.Queries, data json.RawMessage) (string, error) {
+	var membership ClerkOrganizationMembership
+	if err := json.Unmarshal(data, &membership); err != nil {
+		return "", fmt.Errorf("%w: membership data: %v", errInvalidClerkPayload, err)
+	}
+
+	if err := queries.DeleteOrganizationMembershipByClerkID(ctx, membership.ID); err != nil {
+		return "", fmt.Errorf("failed to delete membership: %w", err)
+	}
+
+	return "Membership deleted successfully", nil
+}
+
+func upsertOrganization(ctx context.Context, queries *store.Queries, org ClerkOrganization) error {
+	if org.ID == "" || org.Name == "" {
+		return fmt.Errorf("%w: missing organization id or name", errInvalidClerkPayload)
+	}
+
+	_, err := queries.UpsertOrganization(ctx, store.UpsertOrganizationParams{
+		ClerkID:   org.ID,
+		Name:      org.Name,
+		Slug:      stringValueToNullString(org.Slug),
+		ImageUrl:  stringValueToNullString(org.ImageURL),
+		CreatedBy: stringValueToNullString(org.CreatedBy),
 	})
+	if err != nil {
+		return fmt.Errorf("failed to save organization: %w", err)
+	}
+	return nil
+}
+
+// syncExternalAccounts stores the user's linked accounts and removes any
+// that were unlinked in Clerk
+func (app *Application) syncExternalAccounts(ctx context.Context, queries *store.Queries, user ClerkUser) error {
+	keep := make([]string, 0, len(user.ExternalAccounts))
+	for _, account := range user.ExternalAccounts {
+		if err := queries.UpsertUserExternalAccount(ctx, store.UpsertUserExternalAccountParams{
+			ID:             account.ID,
+			UserClerkID:    user.ID,
+			Provider:       account.Provider,
+			ProviderUserID: stringValueToNullString(account.ProviderUserID),
+			EmailAddress:   stringValueToNullString(account.EmailAddress),
+			FirstName:      stringValueToNullString(account.FirstName),
+			LastName:       stringValueToNullString(account.LastName),
+			ImageUrl:       stringValueToNullString(account.ImageURL),
+		}); err != nil {
+			return fmt.Errorf("failed to save external account: %w", err)
+		}
+		keep = append(keep, account.ID)
+	}
+
+	if err := queries.DeleteStaleUserExternalAccounts(ctx, store.DeleteStaleUserExternalAccountsParams{
+		UserClerkID: user.ID,
+		KeepIds:     keep,
+	}); err != nil {
+		return fmt.Errorf("failed to remove external accounts: %w", err)
+	}
+	return nil
 }
 
 // Helper functions
 
-func (app *Application) getPrimaryEmail(emails []ClerkEmailAddress) string {
-	for _, email := range emails {
-		if email.EmailAddress != "" {
-			return email.EmailAddress
+// getPrimaryEmailAddress returns the address Clerk marks as primary. Older
+// payloads without primary_email_address_id fall back to the first address.
+func (app *Application) getPrimaryEmailAddress(user ClerkUser) *ClerkEmailAddress {
+	if user.PrimaryEmailAddressID != nil {
+		for i := range user.EmailAddresses {
+			if user.EmailAddresses[i].ID == *user.PrimaryEmailAddressID {
+				return &user.EmailAddresses[i]
+			}
 		}
 	}
-	return ""
+	for i := range user.EmailAddresses {
+		if user.EmailAddresses[i].EmailAddress != "" {
+			return &user.EmailAddresses[i]
+		}
+	}
+	return nil
 }
 
-func (app *Application) isEmailVerified(emails []ClerkEmailAddress) bool {
-	for _, email := range emails {
-		if email.Verification.Status == "verified" {
-			return true
-		}
+func (app *Application) getPrimaryEmail(user ClerkUser) string {
+	if email := app.getPrimaryEmailAddress(user); email != nil {
+		return email.EmailAddress
 	}
-	return false
+	return ""
+}
+
+func (app *Application) isEmailVerified(user ClerkUser) bool {
+	email := app.getPrimaryEmailAddress(user)
+	return email != nil && email.Verification.Status == "verified"
 }
 
 func (app *Application) getFullName(firstName, lastName *string) *string {
 	if firstName == nil && lastName == nil {
 		return nil
 	}
-	
+
 	var parts []string
 	if firstName != nil && *firstName != "" {
 		parts = append(parts, *firstName)
@@ -460,11 +749,11 @@ func (app *Application) getFullName(firstName, lastName *string) *string {
 	if lastName != nil && *lastName != "" {
 		parts = append(parts, *lastName)
 	}
-	
+
 	if len(parts) == 0 {
 		return nil
 	}
-	
+
 	fullName := strings.Join(parts, " ")
 	return &fullName
 }
@@ -475,4 +764,4 @@ func (app *Application) convertTimestamp(ts *int64) *time.Time {
 	}
 	t := time.Unix(*ts/1000, 0) // Clerk timestamps are in milliseconds
 	return &t
-}
\ No newline at end of file
+}
@@ -0,0 +1,208 @@
+// Package clerk verifies the session tokens Clerk issues to signed-in users.
+//
+// Session tokens are RS256 JWTs signed with the instance's keys, published
+// as a JWKS at <issuer>/.well-known/jwks.json. A token is accepted when its
+// signature is valid, it has not expired, it was issued by the configured
+// Clerk instance and, if it names one, for one of the authorized parties
+// (the origins of the apps allowed to call the API).
+package clerk
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultLeeway is the clock skew allowed on exp and nbf
+const DefaultLeeway = 5 * time.Second
+
+var (
+	ErrNoIssuer             = errors.New("clerk: no issuer configured")
+	ErrInvalidPublicKey     = errors.New("clerk: invalid public key")
+	ErrMalformedToken       = errors.New("clerk: malformed token")
+	ErrUnsupportedAlgorithm = errors.New("clerk: unsupported signing algorithm")
+	ErrUnknownKey           = errors.New("clerk: token signed with an unknown key")
+	ErrInvalidSignature     = errors.New("clerk: invalid signature")
+	ErrTokenExpired         = errors.New("clerk: token has expired")
+	ErrTokenNotYetValid     = errors.New("clerk: token is not valid yet")
+	ErrWrongIssuer          = errors.New("clerk: token issued by another instance")
+	ErrWrongAuthorizedParty = errors.New("clerk: token issued for another party")
+	ErrMissingSubject       = errors.New("clerk: token has no subject")
+)
+
+// Claims are the session token claims the API uses
+type Claims struct {
+	Subject         string `json:"sub"`
+	Email           string `json:"email"`
+	Issuer          string `json:"iss"`
+	AuthorizedParty string `json:"azp"`
+	ExpiresAt       int64  `json:"exp"`
+	NotBefore       int64  `json:"nbf"`
+	IssuedAt        int64  `json:"iat"`
+}
+
+// Options configure a Verifier
+type Options struct {
+	// Issuer is the Frontend API URL of the Clerk instance, e.g.
+	// https://clerk.example.com
+	Issuer string
+	// AuthorizedParties are the origins tokens may be issued for. Tokens
+	// without an azp claim are accepted.
+	AuthorizedParties []string
+	// PublicKey is the instance's PEM encoded public key. When set, tokens
+	// are verified without fetching the JWKS.
+	PublicKey string
+	// JWKSURL overrides <Issuer>/.well-known/jwks.json
+	JWKSURL string
+	// Leeway is the clock skew allowed, DefaultLeeway when zero
+	Leeway time.Duration
+}
+
+// keySource finds the public key a token was signed with
+type keySource interface {
+	key(ctx context.Context, kid string) (*rsa.PublicKey, error)
+}
+
+// Verifier verifies session tokens of one Clerk instance
+type Verifier struct {
+	issuer  string
+	parties []string
+	keys    keySource
+	leeway  time.Duration
+}
+
+// NewVerifier creates a Verifier from opts
+func NewVerifier(opts Options) (*Verifier, error) {
+	issuer := strings.TrimSuffix(opts.Issuer, "/")
+	if issuer == "" {
+		return nil, ErrNoIssuer
+	}
+
+	v := &Verifier{
+		issuer:  issuer,
+		parties: opts.AuthorizedParties,
+		leeway:  opts.Leeway,
+	}
+	if v.leeway == 0 {
+		v.leeway = DefaultLeeway
+	}
+
+	if opts.PublicKey != "" {
+		key, err := parsePublicKey(opts.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		v.keys = staticKey{key}
+	} else {
+		url := opts.JWKSURL
+		if url == "" {
+			url = issuer + "/.well-known/jwks.json"
+		}
+		v.keys = newJWKS(url)
+	}
+	return v, nil
+}
+
+// Verify checks a session token and returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (Claims, error) {
+	return v.VerifyAt(ctx, token, time.Now())
+}
+
+// VerifyAt checks a session token as of now
+func (v *Verifier) VerifyAt(ctx context.Context, token string, now time.Time) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrMalformedToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Claims{}, err
+	}
+	if header.Alg != "RS256" {
+		return Claims{}, ErrUnsupportedAlgorithm
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Claims{}, ErrMalformedToken
+	}
+	key, err := v.keys.key(ctx, header.Kid)
+	if err != nil {
+		return Claims{}, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return Claims{}, ErrInvalidSignature
+	}
+
+	// Only trusted once the signature has been checked
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Claims{}, err
+	}
+	switch {
+	case claims.ExpiresAt == 0 || !now.Before(time.Unix(claims.ExpiresAt, 0).Add(v.leeway)):
+		return Claims{}, ErrTokenExpired
+	case claims.NotBefore != 0 && now.Add(v.leeway).Before(time.Unix(claims.NotBefore, 0)):
+		return Claims{}, ErrTokenNotYetValid
+	case strings.TrimSuffix(claims.Issuer, "/") != v.issuer:
+		return Claims{}, ErrWrongIssuer
+	case claims.AuthorizedParty != "" && len(v.parties) > 0 && !slices.Contains(v.parties, claims.AuthorizedParty):
+		return Claims{}, ErrWrongAuthorizedParty
+	case claims.Subject == "":
+		return Claims{}, ErrMissingSubject
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
+
+// staticKey verifies every token with the configured public key
+type staticKey struct {
+	public *rsa.PublicKey
+}
+
+func (s staticKey) key(context.Context, string) (*rsa.PublicKey, error) {
+	return s.public, nil
+}
+
+// parsePublicKey reads a PEM encoded RSA public key. Environment variables
+// often lose their newlines, so escaped ones are accepted.
+func parsePublicKey(data string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(strings.ReplaceAll(data, `\n`, "\n")))
+	if block == nil {
+		return nil, ErrInvalidPublicKey
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	key, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, ErrInvalidPublicKey
+	}
+	return key, nil
+}
@@ -0,0 +1,261 @@
+package clerk
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testIssuer = "https://clerk.example.com"
+
+var testNow = time.Unix(1_700_000_000, 0)
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	return key
+}
+
+func encodeSegment(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// sign makes a token with the given header and claims
+func sign(t *testing.T, key *rsa.PrivateKey, header, claims map[string]any) string {
+	t.Helper()
+	unsigned := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatalf("SignPKCS1v15: %v", err)
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub": "user_2abc",
+		"iss": testIssuer,
+		"azp": "https://app.example.com",
+		"exp": testNow.Add(time.Minute).Unix(),
+		"nbf": testNow.Add(-time.Minute).Unix(),
+		"iat": testNow.Add(-time.Minute).Unix(),
+	}
+}
+
+func with(claims map[string]any, key string, value any) map[string]any {
+	if value == nil {
+		delete(claims, key)
+	} else {
+		claims[key] = value
+	}
+	return claims
+}
+
+func publicPEM(t *testing.T, key *rsa.PrivateKey) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func TestVerify(t *testing.T) {
+	key := newKey(t)
+	other := newKey(t)
+	header := map[string]any{"alg": "RS256", "typ": "JWT", "kid": "ins_1"}
+
+	verifier, err := NewVerifier(Options{
+		Issuer:            testIssuer + "/",
+		AuthorizedParties: []string{"https://app.example.com"},
+		PublicKey:         publicPEM(t, key),
+	})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{name: "valid", token: sign(t, key, header, validClaims())},
+		{name: "without azp", token: sign(t, key, header, with(validClaims(), "azp", nil))},
+		{name: "within leeway", token: sign(t, key, header, with(validClaims(), "exp", testNow.Unix()))},
+		{name: "signed with another key", token: sign(t, other, header, validClaims()), want: ErrInvalidSignature},
+		{name: "expired", token: sign(t, key, header, with(validClaims(), "exp", testNow.Add(-time.Minute).Unix())), want: ErrTokenExpired},
+		{name: "no expiry", token: sign(t, key, header, with(validClaims(), "exp", nil)), want: ErrTokenExpired},
+		{name: "not yet valid", token: sign(t, key, header, with(validClaims(), "nbf", testNow.Add(time.Minute).Unix())), want: ErrTokenNotYetValid},
+		{name: "another issuer", token: sign(t, key, header, with(validClaims(), "iss", "https://clerk.attacker.com")), want: ErrWrongIssuer},
+		{name: "another party", token: sign(t, key, header, with(validClaims(), "azp", "https://attacker.com")), want: ErrWrongAuthorizedParty},
+		{name: "no subject", token: sign(t, key, header, with(validClaims(), "sub", nil)), want: ErrMissingSubject},
+		{name: "hs256", token: sign(t, key, map[string]any{"alg": "HS256"}, validClaims()), want: ErrUnsupportedAlgorithm},
+		{name: "none", token: encodeSegment(t, map[string]any{"alg": "none"}) + "." + encodeSegment(t, validClaims()) + ".", want: ErrUnsupportedAlgorithm},
+		{name: "two parts", token: "a.b", want: ErrMalformedToken},
+		{name: "not base64", token: "!!.!!.!!", want: ErrMalformedToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.VerifyAt(context.Background(), tt.token, testNow)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("VerifyAt = %v, want %v", err, tt.want)
+			}
+			if err == nil && claims.Subject != "user_2abc" {
+				t.Errorf("Subject = %q, want %q", claims.Subject, "user_2abc")
+			}
+		})
+	}
+}
+
+func TestVerifyTamperedClaims(t *testing.T) {
+	key := newKey(t)
+	verifier, err := NewVerifier(Options{Issuer: testIssuer, PublicKey: publicPEM(t, key)})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	token := sign(t, key, map[string]any{"alg": "RS256"}, validClaims())
+	parts := strings.Split(token, ".")
+	parts[1] = encodeSegment(t, with(validClaims(), "sub", "user_admin"))
+
+	if _, err := verifier.VerifyAt(context.Background(), strings.Join(parts, "."), testNow); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("VerifyAt = %v, want %v", err, ErrInvalidSignature)
+	}
+}
+
+func TestVerifyWithJWKS(t *testing.T) {
+	key := newKey(t)
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"kid": "ins_1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+
+	verifier, err := NewVerifier(Options{Issuer: testIssuer, JWKSURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewVerifier: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		kid  string
+		want error
+	}{
+		{name: "known key", kid: "ins_1"},
+		{name: "cached key", kid: "ins_1"},
+		{name: "unknown key", kid: "ins_2", want: ErrUnknownKey},
+		{name: "unknown key again", kid: "ins_3", want: ErrUnknownKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := sign(t, key, map[string]any{"alg": "RS256", "kid": tt.kid}, validClaims())
+			if _, err := verifier.VerifyAt(context.Background(), token, testNow); !errors.Is(err, tt.want) {
+				t.Errorf("VerifyAt = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Unknown keys are only looked up once a minute
+	if fetches != 1 {
+		t.Errorf("fetched the JWKS %d times, want 1", fetches)
+	}
+}
+
+func TestJWKSSharesFetches(t *testing.T) {
+	key := newKey(t)
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "ins_1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	defer server.Close()
+	j := newJWKS(server.URL)
+
+	// A caller that gives up does not cancel the fetch for the others
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := j.key(ctx, "ins_1"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("key = %v, want %v", err, context.Canceled)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := j.key(context.Background(), "ins_1")
+			errs <- err
+		}()
+	}
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("key = %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("fetched the JWKS %d times, want 1", got)
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want error
+	}{
+		{name: "no issuer", opts: Options{}, want: ErrNoIssuer},
+		{name: "bad public key", opts: Options{Issuer: testIssuer, PublicKey: "not a key"}, want: ErrInvalidPublicKey},
+		{name: "jwks", opts: Options{Issuer: testIssuer}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewVerifier(tt.opts); !errors.Is(err, tt.want) {
+				t.Errorf("NewVerifier = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package clerk
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jwksRefreshInterval is the least time between two fetches of the JWKS, so
+// tokens with made up key IDs cannot make the API hammer Clerk
+const jwksRefreshInterval = time.Minute
+
+// jwks fetches the instance's keys and caches them. They are fetched again
+// when a token names a key that is not cached, which happens after Clerk
+// rotates them.
+type jwks struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+	// pending is the fetch in progress, which other requests wait for
+	// instead of starting their own
+	pending *jwksFetch
+}
+
+// jwksFetch is closed once a fetch is over, with err set if it failed
+type jwksFetch struct {
+	done chan struct{}
+	err  error
+}
+
+func newJWKS(url string) *jwks {
+	return &jwks{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// key returns the key named kid. The lock is not held while fetching, so a
+// slow JWKS endpoint only holds up the requests that need a new key.
+func (j *jwks) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	j.mu.Lock()
+	if key, ok := j.keys[kid]; ok {
+		j.mu.Unlock()
+		return key, nil
+	}
+	pending := j.pending
+	if pending == nil {
+		if time.Since(j.fetched) < jwksRefreshInterval {
+			j.mu.Unlock()
+			return nil, ErrUnknownKey
+		}
+		pending = &jwksFetch{done: make(chan struct{})}
+		j.pending = pending
+		// Not tied to this request, others may be waiting for the result
+		go j.refresh(context.WithoutCancel(ctx), pending)
+	}
+	j.mu.Unlock()
+
+	select {
+	case <-pending.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if pending.err != nil {
+		return nil, pending.err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if key, ok := j.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// refresh fetches the keys and swaps them into the cache
+func (j *jwks) refresh(ctx context.Context, pending *jwksFetch) {
+	keys, err := j.fetch(ctx)
+
+	j.mu.Lock()
+	// A failed fetch counts too, so an outage is not retried on every request
+	j.fetched = time.Now()
+	if err == nil {
+		j.keys = keys
+	}
+	j.pending = nil
+	j.mu.Unlock()
+
+	pending.err = err
+	close(pending.done)
+}
+
+func (j *jwks) fetch(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := j.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("clerk: failed to fetch the JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("clerk: failed to fetch the JWKS: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&set); err != nil {
+		return nil, fmt.Errorf("clerk: failed to read the JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	return keys, nil
+}
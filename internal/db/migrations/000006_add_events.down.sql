@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_events_created_at;
+
+DROP INDEX IF EXISTS idx_events_user_id_id;
+
+DROP TABLE IF EXISTS events;
@@ -0,0 +1,13 @@
+-- Persistent event log backing the real-time stream. Rows are kept for a
+-- short window so clients can resume with Last-Event-ID after a reconnect.
+CREATE TABLE events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_events_user_id_id ON events (user_id, id);
+
+CREATE INDEX idx_events_created_at ON events (created_at);
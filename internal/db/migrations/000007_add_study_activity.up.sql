@@ -0,0 +1,8 @@
+-- Daily study activity per user, used to compute streaks
+CREATE TABLE study_activity (
+    user_id TEXT NOT NULL,
+    activity_date DATE NOT NULL,
+    tasks_completed INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, activity_date)
+);
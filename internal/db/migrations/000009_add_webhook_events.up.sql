@@ -0,0 +1,14 @@
+-- Inbound Clerk webhook events, keyed by svix-id so retries are idempotent
+CREATE TABLE webhook_events (
+    svix_id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL DEFAULT 'processing', -- processing, processed, failed
+    attempts INT NOT NULL DEFAULT 1,
+    last_error TEXT,
+    received_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_events_status ON webhook_events (status, received_at);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_organization_memberships_user;
+DROP INDEX IF EXISTS idx_organization_memberships_org;
+DROP TABLE IF EXISTS organization_memberships;
+
+DROP TABLE IF EXISTS organizations;
+
+DROP INDEX IF EXISTS idx_user_sessions_user;
+DROP TABLE IF EXISTS user_sessions;
+
+DROP INDEX IF EXISTS idx_user_external_accounts_user;
+DROP TABLE IF EXISTS user_external_accounts;
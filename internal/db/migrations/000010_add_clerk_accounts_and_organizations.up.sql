@@ -0,0 +1,53 @@
+-- Social and enterprise accounts linked to a user in Clerk
+CREATE TABLE user_external_accounts (
+    id TEXT PRIMARY KEY, -- Clerk external account ID
+    user_clerk_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    provider_user_id TEXT,
+    email_address TEXT,
+    first_name TEXT,
+    last_name TEXT,
+    image_url TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_external_accounts_user ON user_external_accounts (user_clerk_id);
+
+-- Clerk sessions. Not tied to users by a foreign key because session events
+-- can arrive before the user.created event.
+CREATE TABLE user_sessions (
+    id TEXT PRIMARY KEY, -- Clerk session ID
+    user_clerk_id TEXT NOT NULL,
+    status TEXT NOT NULL, -- active, ended, revoked, ...
+    last_active_at TIMESTAMP,
+    ended_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_sessions_user ON user_sessions (user_clerk_id);
+
+CREATE TABLE organizations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    clerk_id TEXT UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    slug TEXT,
+    image_url TEXT,
+    created_by TEXT, -- Clerk user ID
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE organization_memberships (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    clerk_id TEXT UNIQUE NOT NULL,
+    organization_clerk_id TEXT NOT NULL REFERENCES organizations (clerk_id) ON DELETE CASCADE,
+    user_clerk_id TEXT NOT NULL,
+    role TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_organization_memberships_org ON organization_memberships (organization_clerk_id);
+CREATE INDEX idx_organization_memberships_user ON organization_memberships (user_clerk_id);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_data_exports_pending;
+DROP INDEX IF EXISTS idx_data_exports_user;
+DROP TABLE IF EXISTS data_exports;
+
+DROP TABLE IF EXISTS user_tombstones;
+
+ALTER TABLE webhook_endpoints DROP CONSTRAINT IF EXISTS fk_webhook_endpoints_user;
+ALTER TABLE study_activity DROP CONSTRAINT IF EXISTS fk_study_activity_user;
+ALTER TABLE study_plans DROP CONSTRAINT IF EXISTS fk_study_plans_user;
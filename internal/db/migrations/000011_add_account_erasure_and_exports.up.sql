@@ -0,0 +1,47 @@
+-- Remove rows left behind by users deleted before study plans were tied to
+-- the users table. Tasks go with their plans.
+DELETE FROM study_plans
+WHERE user_id NOT IN (SELECT clerk_id FROM users);
+
+DELETE FROM study_activity
+WHERE user_id NOT IN (SELECT clerk_id FROM users);
+
+DELETE FROM webhook_endpoints
+WHERE user_id NOT IN (SELECT clerk_id FROM users);
+
+ALTER TABLE study_plans
+ADD CONSTRAINT fk_study_plans_user
+FOREIGN KEY (user_id) REFERENCES users (clerk_id) ON DELETE CASCADE;
+
+ALTER TABLE study_activity
+ADD CONSTRAINT fk_study_activity_user
+FOREIGN KEY (user_id) REFERENCES users (clerk_id) ON DELETE CASCADE;
+
+ALTER TABLE webhook_endpoints
+ADD CONSTRAINT fk_webhook_endpoints_user
+FOREIGN KEY (user_id) REFERENCES users (clerk_id) ON DELETE CASCADE;
+
+-- Record of an erased account. Holds no personal data beyond the Clerk ID.
+CREATE TABLE user_tombstones (
+    clerk_id TEXT PRIMARY KEY,
+    source TEXT NOT NULL, -- what triggered the erasure, e.g. clerk.user.deleted
+    summary JSONB NOT NULL, -- rows removed per table
+    deleted_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Self-service data exports, built in the background
+CREATE TABLE data_exports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending', -- pending, processing, completed, failed
+    archive BYTEA,
+    size_bytes BIGINT,
+    error TEXT,
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_data_exports_user ON data_exports (user_id, created_at DESC);
+CREATE INDEX idx_data_exports_pending ON data_exports (created_at) WHERE status IN ('pending', 'processing');
@@ -0,0 +1,9 @@
+-- Anything still in the trash is gone for good
+DELETE FROM study_tasks WHERE deleted_at IS NOT NULL;
+DELETE FROM study_plans WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_study_tasks_deleted_at;
+DROP INDEX IF EXISTS idx_study_plans_deleted_at;
+
+ALTER TABLE study_tasks DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE study_plans DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,6 @@
+-- Deleted plans and tasks stay in the trash until restored or purged
+ALTER TABLE study_plans ADD COLUMN deleted_at TIMESTAMP;
+ALTER TABLE study_tasks ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX idx_study_plans_deleted_at ON study_plans (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_study_tasks_deleted_at ON study_tasks (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS activity_log_no_update ON activity_log;
+DROP FUNCTION IF EXISTS activity_log_prevent_update();
+
+DROP INDEX IF EXISTS idx_activity_log_entity;
+DROP INDEX IF EXISTS idx_activity_log_user;
+DROP TABLE IF EXISTS activity_log;
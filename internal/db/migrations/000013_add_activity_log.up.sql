@@ -0,0 +1,26 @@
+-- Append-only record of changes to a user's data, with before/after diffs
+CREATE TABLE activity_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE, -- whose data changed
+    actor_id TEXT NOT NULL, -- who changed it: a Clerk user ID, or "clerk" for webhooks
+    action TEXT NOT NULL, -- e.g. plan.updated, task.deleted
+    entity_type TEXT NOT NULL, -- study_plan, study_task, user
+    entity_id TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_activity_log_user ON activity_log (user_id, created_at DESC);
+CREATE INDEX idx_activity_log_entity ON activity_log (entity_type, entity_id);
+
+-- Entries are never edited. Rows only go away with their user.
+CREATE FUNCTION activity_log_prevent_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'activity_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER activity_log_no_update
+BEFORE UPDATE ON activity_log
+FOR EACH ROW EXECUTE FUNCTION activity_log_prevent_update();
@@ -0,0 +1,2 @@
+ALTER TABLE study_tasks DROP COLUMN IF EXISTS version;
+ALTER TABLE study_plans DROP COLUMN IF EXISTS version;
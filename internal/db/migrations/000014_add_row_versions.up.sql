@@ -0,0 +1,3 @@
+-- Incremented on every change, used for ETags and If-Match checks
+ALTER TABLE study_plans ADD COLUMN version INT NOT NULL DEFAULT 1;
+ALTER TABLE study_tasks ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1,16 @@
+-- Long-lived tokens for scripts and CI. Only a SHA-256 hash of the token is
+-- stored; the prefix is kept so users can tell their tokens apart.
+CREATE TABLE personal_access_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    token_prefix TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL, -- e.g. plans:read, tasks:write
+    expires_at TIMESTAMP, -- NULL for tokens that never expire
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_personal_access_tokens_user ON personal_access_tokens (user_id, created_at DESC);
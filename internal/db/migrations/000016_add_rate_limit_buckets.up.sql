@@ -0,0 +1,9 @@
+-- Token buckets of the Postgres rate limit store, shared by every replica.
+-- Keys combine the route group with the user or client IP.
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_rate_limit_buckets_updated_at ON rate_limit_buckets (updated_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_impersonation_sessions_created_at;
+DROP TABLE IF EXISTS impersonation_sessions;
+
+DROP INDEX IF EXISTS idx_users_role;
+ALTER TABLE users DROP COLUMN IF EXISTS role;
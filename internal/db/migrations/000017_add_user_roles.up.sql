@@ -0,0 +1,19 @@
+-- Roles gate the admin API: support staff can look, admins can also act
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user'
+    CHECK (role IN ('user', 'support', 'admin'));
+
+CREATE INDEX idx_users_role ON users (role) WHERE role <> 'user';
+
+-- Every time an admin acts as a user. Changes made during the session are
+-- recorded in the activity log with the admin as the actor.
+CREATE TABLE impersonation_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_id TEXT NOT NULL, -- kept when the admin account is deleted
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_impersonation_sessions_created_at ON impersonation_sessions (created_at DESC);
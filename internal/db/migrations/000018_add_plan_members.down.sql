@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_task_completions_user;
+DROP TABLE IF EXISTS task_completions;
+
+ALTER TABLE study_tasks DROP COLUMN IF EXISTS created_by;
+
+DROP INDEX IF EXISTS idx_plan_invites_email;
+DROP INDEX IF EXISTS idx_plan_invites_plan;
+DROP TABLE IF EXISTS plan_invites;
+
+DROP INDEX IF EXISTS idx_plan_members_user;
+DROP TABLE IF EXISTS plan_members;
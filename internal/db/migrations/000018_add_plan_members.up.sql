@@ -0,0 +1,56 @@
+-- Who can see and change a study plan. The user who created the plan is
+-- always one of its owners.
+CREATE TABLE plan_members (
+    plan_id UUID NOT NULL REFERENCES study_plans (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor', 'owner')),
+    invited_by TEXT, -- NULL for the plan's creator
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (plan_id, user_id)
+);
+
+CREATE INDEX idx_plan_members_user ON plan_members (user_id);
+
+INSERT INTO plan_members (plan_id, user_id, role)
+SELECT id, user_id, 'owner' FROM study_plans;
+
+-- Invitations to join a plan. Email invites are accepted by the user with
+-- that address and can be used once; link invites carry a token, of which
+-- only a SHA-256 hash is stored, and can be used until they expire.
+CREATE TABLE plan_invites (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    plan_id UUID NOT NULL REFERENCES study_plans (id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('viewer', 'editor')),
+    email TEXT,
+    token_prefix TEXT,
+    token_hash TEXT UNIQUE,
+    invited_by TEXT NOT NULL,
+    expires_at TIMESTAMP, -- NULL for invites that never expire
+    accepted_by TEXT,
+    accepted_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((email IS NULL) <> (token_hash IS NULL))
+);
+
+CREATE INDEX idx_plan_invites_plan ON plan_invites (plan_id, created_at DESC);
+CREATE INDEX idx_plan_invites_email ON plan_invites (lower(email)) WHERE email IS NOT NULL;
+
+-- Who created a task, so tasks without a plan still have an owner
+ALTER TABLE study_tasks ADD COLUMN created_by TEXT REFERENCES users (clerk_id) ON DELETE SET NULL;
+
+UPDATE study_tasks st
+SET created_by = sp.user_id
+FROM study_plans sp
+WHERE st.plan_id = sp.id;
+
+-- Progress of plan members other than its creator, whose progress stays in
+-- study_tasks.is_completed
+CREATE TABLE task_completions (
+    task_id UUID NOT NULL REFERENCES study_tasks (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    completed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (task_id, user_id)
+);
+
+CREATE INDEX idx_task_completions_user ON task_completions (user_id);
@@ -0,0 +1,18 @@
+DROP INDEX IF EXISTS idx_study_tasks_source;
+DROP INDEX IF EXISTS idx_study_plans_classroom_user;
+
+ALTER TABLE study_tasks
+    DROP COLUMN IF EXISTS synced_hash,
+    DROP COLUMN IF EXISTS source_task_id;
+
+ALTER TABLE study_plans
+    DROP COLUMN IF EXISTS synced_hash,
+    DROP COLUMN IF EXISTS source_plan_id,
+    DROP COLUMN IF EXISTS classroom_id;
+
+DROP INDEX IF EXISTS idx_classroom_students_user;
+DROP TABLE IF EXISTS classroom_students;
+
+DROP INDEX IF EXISTS idx_classrooms_organization;
+DROP INDEX IF EXISTS idx_classrooms_teacher;
+DROP TABLE IF EXISTS classrooms;
@@ -0,0 +1,42 @@
+-- A teacher's class. Students join with the code, or without one when they
+-- belong to the Clerk organization the classroom is tied to.
+CREATE TABLE classrooms (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    teacher_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    organization_id TEXT REFERENCES organizations (clerk_id) ON DELETE SET NULL,
+    template_plan_id UUID NOT NULL REFERENCES study_plans (id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    join_code TEXT NOT NULL UNIQUE,
+    published_at TIMESTAMP, -- last time the template was sent to students
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_classrooms_teacher ON classrooms (teacher_id);
+CREATE INDEX idx_classrooms_organization ON classrooms (organization_id) WHERE organization_id IS NOT NULL;
+
+CREATE TABLE classroom_students (
+    classroom_id UUID NOT NULL REFERENCES classrooms (id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (classroom_id, user_id)
+);
+
+CREATE INDEX idx_classroom_students_user ON classroom_students (user_id);
+
+-- Students get their own copy of the template. Copies remember their source
+-- and a hash of the content last synced from it, so later publishes only
+-- update what the student has not changed.
+ALTER TABLE study_plans
+    ADD COLUMN classroom_id UUID REFERENCES classrooms (id) ON DELETE SET NULL,
+    ADD COLUMN source_plan_id UUID REFERENCES study_plans (id) ON DELETE SET NULL,
+    ADD COLUMN synced_hash TEXT;
+
+ALTER TABLE study_tasks
+    ADD COLUMN source_task_id UUID REFERENCES study_tasks (id) ON DELETE SET NULL,
+    ADD COLUMN synced_hash TEXT;
+
+CREATE UNIQUE INDEX idx_study_plans_classroom_user ON study_plans (classroom_id, user_id)
+    WHERE classroom_id IS NOT NULL;
+CREATE INDEX idx_study_tasks_source ON study_tasks (source_task_id) WHERE source_task_id IS NOT NULL;
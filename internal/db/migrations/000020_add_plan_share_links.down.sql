@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_plan_share_links_plan;
+DROP TABLE IF EXISTS plan_share_links;
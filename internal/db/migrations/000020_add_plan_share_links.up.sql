@@ -0,0 +1,16 @@
+-- Public read-only links to a study plan. Only a SHA-256 hash of the token
+-- in the link is stored.
+CREATE TABLE plan_share_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    plan_id UUID NOT NULL REFERENCES study_plans (id) ON DELETE CASCADE,
+    created_by TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    token_prefix TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMP, -- NULL for links that never expire
+    revoked_at TIMESTAMP,
+    view_count BIGINT NOT NULL DEFAULT 0,
+    last_viewed_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_plan_share_links_plan ON plan_share_links (plan_id, created_at DESC);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_plan_template_tasks_template;
+DROP TABLE IF EXISTS plan_template_tasks;
+
+DROP INDEX IF EXISTS idx_plan_templates_tags;
+DROP INDEX IF EXISTS idx_plan_templates_published;
+DROP INDEX IF EXISTS idx_plan_templates_author;
+DROP TABLE IF EXISTS plan_templates;
@@ -0,0 +1,35 @@
+-- Reusable outlines of a study plan. Task due dates are offsets in days from
+-- the exam date, so one template fits any exam. Official templates are seeded
+-- from YAML files and have no author.
+CREATE TABLE plan_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    slug TEXT UNIQUE, -- identifies official templates when they are reseeded
+    author_id TEXT REFERENCES users (clerk_id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    description TEXT,
+    duration_days INTEGER NOT NULL CHECK (duration_days > 0), -- default days of study before the exam
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    is_official BOOLEAN NOT NULL DEFAULT FALSE,
+    published_at TIMESTAMP, -- listed in the catalogue when set
+    usage_count BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (is_official OR author_id IS NOT NULL)
+);
+
+CREATE INDEX idx_plan_templates_author ON plan_templates (author_id) WHERE author_id IS NOT NULL;
+CREATE INDEX idx_plan_templates_published ON plan_templates (usage_count DESC) WHERE published_at IS NOT NULL;
+CREATE INDEX idx_plan_templates_tags ON plan_templates USING GIN (tags);
+
+CREATE TABLE plan_template_tasks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    template_id UUID NOT NULL REFERENCES plan_templates (id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    title TEXT NOT NULL,
+    day_offset INTEGER NOT NULL CHECK (day_offset <= 0), -- 0 is the exam day, -7 a week before
+    priority INTEGER,
+    notes TEXT
+);
+
+CREATE INDEX idx_plan_template_tasks_template ON plan_template_tasks (template_id, position);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS idx_study_tasks_subject;
+DROP INDEX IF EXISTS idx_study_plans_subject;
+
+ALTER TABLE study_tasks
+    DROP COLUMN IF EXISTS minutes_spent,
+    DROP COLUMN IF EXISTS subject_id;
+
+ALTER TABLE study_plans
+    DROP COLUMN IF EXISTS subject_id;
+
+DROP INDEX IF EXISTS idx_subjects_child_name;
+DROP INDEX IF EXISTS idx_subjects_root_name;
+DROP TABLE IF EXISTS subjects;
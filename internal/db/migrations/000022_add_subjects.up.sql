@@ -0,0 +1,44 @@
+-- A user's subjects, optionally split into units and those into topics
+CREATE TABLE subjects (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES subjects (id) ON DELETE CASCADE,
+    kind TEXT NOT NULL DEFAULT 'subject' CHECK (kind IN ('subject', 'unit', 'topic')),
+    name TEXT NOT NULL,
+    color TEXT, -- #rrggbb
+    icon TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((kind = 'subject') = (parent_id IS NULL))
+);
+
+-- Names are unique among siblings, ignoring case
+CREATE UNIQUE INDEX idx_subjects_root_name ON subjects (user_id, lower(name)) WHERE parent_id IS NULL;
+CREATE UNIQUE INDEX idx_subjects_child_name ON subjects (parent_id, lower(name)) WHERE parent_id IS NOT NULL;
+
+-- A plan belongs to a subject; a task can narrow it down to a unit or topic.
+-- minutes_spent is the time the user reports spending on a task.
+ALTER TABLE study_plans
+    ADD COLUMN subject_id UUID REFERENCES subjects (id) ON DELETE SET NULL;
+
+ALTER TABLE study_tasks
+    ADD COLUMN subject_id UUID REFERENCES subjects (id) ON DELETE SET NULL,
+    ADD COLUMN minutes_spent INTEGER CHECK (minutes_spent >= 0);
+
+CREATE INDEX idx_study_plans_subject ON study_plans (subject_id) WHERE subject_id IS NOT NULL;
+CREATE INDEX idx_study_tasks_subject ON study_tasks (subject_id) WHERE subject_id IS NOT NULL;
+
+-- Turn the free text subjects of existing plans into subjects
+INSERT INTO subjects (user_id, name)
+SELECT DISTINCT ON (sp.user_id, lower(btrim(sp.subject))) sp.user_id, btrim(sp.subject)
+FROM study_plans sp
+JOIN users u ON u.clerk_id = sp.user_id
+WHERE btrim(sp.subject) <> ''
+ORDER BY sp.user_id, lower(btrim(sp.subject)), sp.created_at
+ON CONFLICT DO NOTHING;
+
+UPDATE study_plans sp
+SET subject_id = s.id
+FROM subjects s
+WHERE s.user_id = sp.user_id AND s.parent_id IS NULL
+  AND lower(s.name) = lower(btrim(sp.subject));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_plan_exams_exam;
+DROP TABLE IF EXISTS plan_exams;
+
+DROP INDEX IF EXISTS idx_exams_user_starts_at;
+DROP TABLE IF EXISTS exams;
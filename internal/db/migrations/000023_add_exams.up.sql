@@ -0,0 +1,50 @@
+-- Exams a user sits, with what they aimed for and, once taken, what they got.
+-- Scores are out of max_score; weight is the exam's share of the final grade
+-- in percent.
+CREATE TABLE exams (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    subject_id UUID REFERENCES subjects (id) ON DELETE SET NULL,
+    title TEXT NOT NULL,
+    starts_at TIMESTAMP NOT NULL,
+    duration_minutes INTEGER CHECK (duration_minutes > 0),
+    location TEXT,
+    format TEXT NOT NULL DEFAULT 'written' CHECK (format IN ('written', 'oral', 'practical', 'online', 'other')),
+    weight DOUBLE PRECISION CHECK (weight > 0 AND weight <= 100),
+    max_score DOUBLE PRECISION NOT NULL DEFAULT 100 CHECK (max_score > 0),
+    target_score DOUBLE PRECISION,
+    actual_score DOUBLE PRECISION,
+    notes TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (target_score BETWEEN 0 AND max_score),
+    CHECK (actual_score BETWEEN 0 AND max_score)
+);
+
+CREATE INDEX idx_exams_user_starts_at ON exams (user_id, starts_at);
+
+-- The plans that prepare for an exam. A plan may prepare for several exams.
+CREATE TABLE plan_exams (
+    plan_id UUID NOT NULL REFERENCES study_plans (id) ON DELETE CASCADE,
+    exam_id UUID NOT NULL REFERENCES exams (id) ON DELETE CASCADE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (plan_id, exam_id)
+);
+
+CREATE INDEX idx_plan_exams_exam ON plan_exams (exam_id);
+
+-- Give every existing plan an exam on its exam date
+WITH plans AS (
+    SELECT gen_random_uuid() AS exam_id, sp.id AS plan_id, sp.user_id, sp.subject_id, sp.title, sp.exam_date
+    FROM study_plans sp
+    JOIN users u ON u.clerk_id = sp.user_id
+    WHERE sp.deleted_at IS NULL
+), created AS (
+    INSERT INTO exams (id, user_id, subject_id, title, starts_at)
+    SELECT exam_id, user_id, subject_id, title, exam_date FROM plans
+    RETURNING id
+)
+INSERT INTO plan_exams (plan_id, exam_id)
+SELECT plans.plan_id, created.id
+FROM plans
+JOIN created ON created.id = plans.exam_id;
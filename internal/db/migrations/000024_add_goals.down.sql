@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS goal_milestones;
+DROP TABLE IF EXISTS goal_periods;
+
+DROP INDEX IF EXISTS idx_goals_user;
+DROP TABLE IF EXISTS goals;
+
+DROP TRIGGER IF EXISTS study_tasks_completed_at ON study_tasks;
+DROP FUNCTION IF EXISTS study_tasks_set_completed_at();
+
+DROP INDEX IF EXISTS idx_study_tasks_completed_at;
+ALTER TABLE study_tasks DROP COLUMN IF EXISTS completed_at;
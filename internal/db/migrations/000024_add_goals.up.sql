@@ -0,0 +1,72 @@
+-- When a task was completed, for goals that count work done over a period.
+-- A trigger keeps it in step with is_completed. Tasks completed before it
+-- existed are dated by their last update.
+ALTER TABLE study_tasks ADD COLUMN completed_at TIMESTAMP;
+
+UPDATE study_tasks
+SET completed_at = COALESCE(updated_at, created_at, NOW())
+WHERE is_completed;
+
+CREATE INDEX idx_study_tasks_completed_at ON study_tasks (completed_at) WHERE completed_at IS NOT NULL;
+
+CREATE FUNCTION study_tasks_set_completed_at() RETURNS trigger AS $$
+BEGIN
+    IF NEW.is_completed IS NOT TRUE THEN
+        NEW.completed_at := NULL;
+    ELSIF TG_OP = 'INSERT' OR OLD.is_completed IS NOT TRUE THEN
+        NEW.completed_at := NOW();
+    ELSE
+        NEW.completed_at := OLD.completed_at;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER study_tasks_completed_at
+BEFORE INSERT OR UPDATE ON study_tasks
+FOR EACH ROW EXECUTE FUNCTION study_tasks_set_completed_at();
+
+-- A target to reach every period, or once by ends_on. study_minutes and
+-- tasks_completed count the work completed in the period, narrowed down to
+-- a plan or subject when set; plan_completion is the percent of a plan's
+-- tasks completed.
+CREATE TABLE goals (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('study_minutes', 'tasks_completed', 'plan_completion')),
+    target INTEGER NOT NULL CHECK (target > 0),
+    period TEXT NOT NULL CHECK (period IN ('daily', 'weekly', 'monthly', 'once')),
+    plan_id UUID REFERENCES study_plans (id) ON DELETE CASCADE,
+    subject_id UUID REFERENCES subjects (id) ON DELETE SET NULL,
+    starts_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    ends_on DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (ends_on >= starts_on),
+    CHECK (period <> 'once' OR ends_on IS NOT NULL),
+    CHECK (kind <> 'plan_completion' OR (plan_id IS NOT NULL AND period = 'once' AND target <= 100))
+);
+
+CREATE INDEX idx_goals_user ON goals (user_id);
+
+-- Periods that have ended, with whether the goal was met
+CREATE TABLE goal_periods (
+    goal_id UUID NOT NULL REFERENCES goals (id) ON DELETE CASCADE,
+    starts_on DATE NOT NULL,
+    ends_on DATE NOT NULL,
+    progress BIGINT NOT NULL,
+    target INTEGER NOT NULL, -- the goal's target when the period closed
+    met BOOLEAN NOT NULL,
+    closed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (goal_id, starts_on)
+);
+
+-- The first time a period's progress reached a share of the target
+CREATE TABLE goal_milestones (
+    goal_id UUID NOT NULL REFERENCES goals (id) ON DELETE CASCADE,
+    period_starts_on DATE NOT NULL,
+    percent INTEGER NOT NULL CHECK (percent > 0 AND percent <= 100),
+    reached_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (goal_id, period_starts_on, percent)
+);
@@ -0,0 +1,10 @@
+-- Badges a user has earned. Badges are defined in YAML files shipped with
+-- the API, so only the badge slug is stored. awarded_at is when the badge's
+-- threshold was reached, which is in the past for awards made by a backfill.
+CREATE TABLE user_achievements (
+    user_id TEXT NOT NULL REFERENCES users (clerk_id) ON DELETE CASCADE,
+    badge TEXT NOT NULL,
+    awarded_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, badge)
+);
@@ -0,0 +1,3 @@
+-- Endpoint responses are no longer kept: a URL pointing at another service
+-- would otherwise let users read its replies. Only status codes remain.
+ALTER TABLE webhook_delivery_attempts DROP COLUMN IF EXISTS response_body;
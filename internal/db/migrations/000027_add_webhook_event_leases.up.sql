@@ -0,0 +1,5 @@
+-- When an event was last claimed for processing. A claim is a lease: an
+-- event left in processing past it, say after a crash, can be claimed again.
+ALTER TABLE webhook_events ADD COLUMN claimed_at TIMESTAMP;
+
+UPDATE webhook_events SET claimed_at = updated_at WHERE status = 'processing';
@@ -0,0 +1,3 @@
+-- The creators filled in cannot be told apart from those recorded since, and
+-- are correct either way, so there is nothing to undo.
+SELECT 1;
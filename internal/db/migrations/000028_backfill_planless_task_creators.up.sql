@@ -0,0 +1,14 @@
+-- 000018 only backfilled created_by for tasks in a plan, which left tasks
+-- without one unreachable. Their creator is whoever the activity log first
+-- recorded a change to the task for.
+UPDATE study_tasks st
+SET created_by = first_entry.user_id
+FROM (
+    SELECT DISTINCT ON (entity_id) entity_id, user_id
+    FROM activity_log
+    WHERE entity_type = 'study_task'
+    ORDER BY entity_id, created_at, id
+) first_entry
+WHERE st.plan_id IS NULL
+  AND st.created_by IS NULL
+  AND first_entry.entity_id = st.id::text;
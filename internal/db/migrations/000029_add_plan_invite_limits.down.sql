@@ -0,0 +1,2 @@
+ALTER TABLE plan_invites DROP COLUMN IF EXISTS uses;
+ALTER TABLE plan_invites DROP COLUMN IF EXISTS max_uses;
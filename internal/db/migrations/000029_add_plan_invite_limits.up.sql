@@ -0,0 +1,11 @@
+-- Link invites can be limited to a number of uses. NULL max_uses means any
+-- number; uses counts the people who joined with the link.
+ALTER TABLE plan_invites ADD COLUMN max_uses INT CHECK (max_uses > 0);
+ALTER TABLE plan_invites ADD COLUMN uses INT NOT NULL DEFAULT 0;
+
+-- Link invites now expire after a week unless asked otherwise. Existing
+-- ones that never expired get the same week from now.
+UPDATE plan_invites
+SET expires_at = NOW() + INTERVAL '7 days'
+WHERE token_hash IS NOT NULL AND expires_at IS NULL
+  AND revoked_at IS NULL;
@@ -0,0 +1,5 @@
+-- When the actual score was recorded. Exams scored before this only have
+-- their last update to go by.
+ALTER TABLE exams ADD COLUMN scored_at TIMESTAMP;
+
+UPDATE exams SET scored_at = updated_at WHERE actual_score IS NOT NULL;
@@ -0,0 +1,3 @@
+-- IANA name of the user's time zone. Days, weeks and months are counted in
+-- it; existing users keep counting in UTC until they set one.
+ALTER TABLE users ADD COLUMN time_zone TEXT NOT NULL DEFAULT 'UTC';
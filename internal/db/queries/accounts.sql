@@ -0,0 +1,54 @@
+-- name: DeleteUserEvents :execrows
+DELETE FROM events WHERE user_id = $1;
+
+-- name: DeleteUserStudyPlans :execrows
+DELETE FROM study_plans WHERE user_id = $1;
+
+-- name: CountUserStudyTasks :one
+SELECT COUNT(*) FROM study_tasks st
+JOIN study_plans sp ON st.plan_id = sp.id
+WHERE sp.user_id = $1;
+
+-- name: DeleteUserPlanlessTasks :execrows
+DELETE FROM study_tasks WHERE plan_id IS NULL AND created_by = $1;
+
+-- name: DeleteUserStudyActivity :execrows
+DELETE FROM study_activity WHERE user_id = $1;
+
+-- name: GetUserStudyActivity :many
+SELECT * FROM study_activity
+WHERE user_id = $1
+ORDER BY activity_date;
+
+-- name: DeleteUserWebhookEndpoints :execrows
+DELETE FROM webhook_endpoints WHERE user_id = $1;
+
+-- name: DeleteUserExternalAccounts :execrows
+DELETE FROM user_external_accounts WHERE user_clerk_id = $1;
+
+-- name: GetUserSessions :many
+SELECT * FROM user_sessions
+WHERE user_clerk_id = $1
+ORDER BY created_at;
+
+-- name: RedactUserWebhookEvents :execrows
+UPDATE webhook_events
+SET payload = jsonb_build_object('type', type, 'redacted', true),
+    updated_at = NOW()
+WHERE status <> 'processing'
+  AND (payload->'data'->>'id' = sqlc.arg(clerk_id)::text
+    OR payload->'data'->>'user_id' = sqlc.arg(clerk_id)::text
+    OR payload->'data'->'public_user_data'->>'user_id' = sqlc.arg(clerk_id)::text);
+
+-- name: CreateUserTombstone :exec
+INSERT INTO user_tombstones (clerk_id, source, summary)
+VALUES ($1, $2, $3)
+ON CONFLICT (clerk_id)
+DO UPDATE SET
+    source = EXCLUDED.source,
+    summary = EXCLUDED.summary,
+    deleted_at = NOW();
+
+-- name: GetUserTombstone :one
+SELECT * FROM user_tombstones
+WHERE clerk_id = $1;
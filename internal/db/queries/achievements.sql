@@ -0,0 +1,67 @@
+-- name: AwardAchievement :execrows
+INSERT INTO user_achievements (user_id, badge, awarded_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, badge) DO NOTHING;
+
+-- name: ListUserAchievements :many
+SELECT * FROM user_achievements
+WHERE user_id = $1
+ORDER BY awarded_at ASC, badge ASC;
+
+-- name: ListAchievementUserIDs :many
+SELECT clerk_id FROM users ORDER BY created_at ASC;
+
+-- name: ListAchievementCompletions :many
+SELECT st.completed_at::timestamp AS completed_at, COALESCE(st.minutes_spent, 0)::bigint AS minutes_spent
+FROM study_tasks st
+LEFT JOIN study_plans sp ON sp.id = st.plan_id
+WHERE st.deleted_at IS NULL AND st.completed_at IS NOT NULL
+  AND (sp.user_id = sqlc.arg(user_id) OR (st.plan_id IS NULL AND st.created_by = sqlc.arg(user_id)))
+  AND (sp.id IS NULL OR sp.deleted_at IS NULL)
+UNION ALL
+SELECT tc.completed_at, COALESCE(st.minutes_spent, 0)::bigint
+FROM task_completions tc
+JOIN study_tasks st ON st.id = tc.task_id
+JOIN study_plans sp ON sp.id = st.plan_id
+WHERE tc.user_id = sqlc.arg(user_id) AND sp.user_id <> sqlc.arg(user_id)
+  AND st.deleted_at IS NULL AND sp.deleted_at IS NULL
+ORDER BY completed_at ASC;
+
+-- name: ListAchievementStudyDays :many
+SELECT activity_date FROM study_activity
+WHERE user_id = $1 AND tasks_completed > 0
+ORDER BY activity_date ASC;
+
+-- name: ListAchievementFinishedPlans :many
+WITH plan_tasks AS (
+    SELECT sp.id AS plan_id,
+           CASE WHEN sp.user_id = sqlc.arg(user_id) THEN st.completed_at ELSE tc.completed_at END AS completed_at
+    FROM study_plans sp
+    JOIN study_tasks st ON st.plan_id = sp.id AND st.deleted_at IS NULL
+    LEFT JOIN task_completions tc ON tc.task_id = st.id AND tc.user_id = sqlc.arg(user_id)
+    WHERE sp.deleted_at IS NULL
+      AND (sp.user_id = sqlc.arg(user_id) OR EXISTS (
+          SELECT 1 FROM plan_members pm WHERE pm.plan_id = sp.id AND pm.user_id = sqlc.arg(user_id)))
+), finished AS (
+    SELECT plan_id, MAX(completed_at) AS finished_at
+    FROM plan_tasks
+    GROUP BY plan_id
+    HAVING COUNT(*) = COUNT(completed_at)
+)
+SELECT f.finished_at::timestamp AS finished_at, MIN(e.starts_at)::timestamp AS exam_starts_at
+FROM finished f
+JOIN plan_exams pe ON pe.plan_id = f.plan_id
+JOIN exams e ON e.id = pe.exam_id
+GROUP BY f.plan_id, f.finished_at
+ORDER BY finished_at ASC;
+
+-- name: ListAchievementGoalsMet :many
+SELECT gp.ends_on FROM goal_periods gp
+JOIN goals g ON g.id = gp.goal_id
+WHERE g.user_id = $1 AND gp.met
+ORDER BY gp.ends_on ASC;
+
+-- name: ListAchievementExamTargetsMet :many
+SELECT scored_at FROM exams
+WHERE user_id = $1 AND actual_score >= target_score AND scored_at IS NOT NULL
+ORDER BY scored_at ASC;
@@ -0,0 +1,14 @@
+-- name: CreateActivityLogEntry :exec
+INSERT INTO activity_log (user_id, actor_id, action, entity_type, entity_id, before, after)
+VALUES ($1, $2, $3, $4, $5, NULLIF($6, 'null'::jsonb), NULLIF($7, 'null'::jsonb));
+
+-- name: ListActivity :many
+SELECT * FROM activity_log
+WHERE user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(entity_type)::text IS NULL OR entity_type = sqlc.narg(entity_type)::text)
+  AND (sqlc.narg(entity_id)::text IS NULL OR entity_id = sqlc.narg(entity_id)::text)
+  AND (sqlc.narg(action)::text IS NULL OR action = sqlc.narg(action)::text)
+  AND (sqlc.narg(since)::timestamp IS NULL OR created_at >= sqlc.narg(since)::timestamp)
+  AND (sqlc.narg(until)::timestamp IS NULL OR created_at < sqlc.narg(until)::timestamp)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
@@ -0,0 +1,167 @@
+-- name: CreateClassroom :one
+INSERT INTO classrooms (teacher_id, organization_id, template_plan_id, name, description, join_code)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetClassroom :one
+SELECT * FROM classrooms WHERE id = $1;
+
+-- name: GetClassroomByJoinCode :one
+SELECT * FROM classrooms WHERE join_code = $1;
+
+-- name: ListClassroomsForUser :many
+SELECT c.*,
+       (SELECT COUNT(*) FROM classroom_students s WHERE s.classroom_id = c.id) AS student_count
+FROM classrooms c
+WHERE c.teacher_id = $1
+   OR EXISTS (SELECT 1 FROM classroom_students s WHERE s.classroom_id = c.id AND s.user_id = $1)
+ORDER BY c.created_at DESC;
+
+-- name: UpdateClassroom :one
+UPDATE classrooms
+SET name = $2, description = $3, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RotateClassroomJoinCode :one
+UPDATE classrooms
+SET join_code = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkClassroomPublished :one
+UPDATE classrooms
+SET published_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteClassroom :execrows
+DELETE FROM classrooms WHERE id = $1;
+
+-- name: AddClassroomStudent :execrows
+INSERT INTO classroom_students (classroom_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (classroom_id, user_id) DO NOTHING;
+
+-- name: IsClassroomStudent :one
+SELECT EXISTS (
+    SELECT 1 FROM classroom_students WHERE classroom_id = $1 AND user_id = $2
+) AS enrolled;
+
+-- name: RemoveClassroomStudent :execrows
+DELETE FROM classroom_students
+WHERE classroom_id = $1 AND user_id = $2;
+
+-- name: UnlinkClassroomPlan :exec
+UPDATE study_plans
+SET classroom_id = NULL
+WHERE classroom_id = $1 AND user_id = $2;
+
+-- name: CopyClassroomPlans :execrows
+-- Subjects belong to the teacher, so the copy gets the student's subject of
+-- the same name, or none
+WITH copies AS (
+    INSERT INTO study_plans (user_id, title, subject, subject_id, description, exam_date, start_date, end_date,
+                             classroom_id, source_plan_id, synced_hash)
+    SELECT s.user_id, t.title, t.subject, ms.id, t.description, t.exam_date, t.start_date, t.end_date,
+           c.id, t.id, md5(concat_ws('|', t.title, t.subject, coalesce(t.description, ''), t.exam_date::text, t.start_date::text, t.end_date::text))
+    FROM classrooms c
+    JOIN study_plans t ON t.id = c.template_plan_id AND t.deleted_at IS NULL
+    JOIN classroom_students s ON s.classroom_id = c.id
+    LEFT JOIN subjects ts ON ts.id = t.subject_id
+    LEFT JOIN subjects tp ON tp.id = ts.parent_id
+    LEFT JOIN subjects tg ON tg.id = tp.parent_id
+    LEFT JOIN LATERAL (
+        SELECT ss.id FROM subjects ss
+        LEFT JOIN subjects sp1 ON sp1.id = ss.parent_id
+        LEFT JOIN subjects sp2 ON sp2.id = sp1.parent_id
+        WHERE ss.user_id = s.user_id AND ss.kind = ts.kind AND lower(ss.name) = lower(ts.name)
+          AND lower(sp1.name) IS NOT DISTINCT FROM lower(tp.name)
+          AND lower(sp2.name) IS NOT DISTINCT FROM lower(tg.name)
+    ) ms ON TRUE
+    WHERE c.id = sqlc.arg(classroom_id)
+      AND (sqlc.narg(user_id)::text IS NULL OR s.user_id = sqlc.narg(user_id)::text)
+    ON CONFLICT (classroom_id, user_id) WHERE classroom_id IS NOT NULL DO NOTHING
+    RETURNING id, user_id
+)
+INSERT INTO plan_members (plan_id, user_id, role)
+SELECT id, user_id, 'owner' FROM copies;
+
+-- name: SyncClassroomPlans :execrows
+UPDATE study_plans sp
+SET title = t.title,
+    subject = t.subject,
+    description = t.description,
+    exam_date = t.exam_date,
+    start_date = t.start_date,
+    end_date = t.end_date,
+    synced_hash = md5(concat_ws('|', t.title, t.subject, coalesce(t.description, ''), t.exam_date::text, t.start_date::text, t.end_date::text)),
+    version = sp.version + 1,
+    updated_at = NOW()
+FROM classrooms c
+JOIN study_plans t ON t.id = c.template_plan_id AND t.deleted_at IS NULL
+WHERE c.id = $1 AND sp.classroom_id = c.id AND sp.source_plan_id = t.id AND sp.deleted_at IS NULL
+  AND sp.synced_hash = md5(concat_ws('|', sp.title, sp.subject, coalesce(sp.description, ''), sp.exam_date::text, sp.start_date::text, sp.end_date::text))
+  AND sp.synced_hash <> md5(concat_ws('|', t.title, t.subject, coalesce(t.description, ''), t.exam_date::text, t.start_date::text, t.end_date::text));
+
+-- name: CopyClassroomTasks :execrows
+-- Like plans, tasks get the student's unit or topic at the same path, or none
+INSERT INTO study_tasks (plan_id, title, due_date, is_completed, priority, notes, subject_id, created_by,
+                         source_task_id, synced_hash)
+SELECT sp.id, t.title, t.due_date, FALSE, t.priority, t.notes, ms.id, sp.user_id, t.id, md5(concat_ws('|', t.title, t.due_date::text, coalesce(t.priority::text, ''), coalesce(t.notes, '')))
+FROM classrooms c
+JOIN study_plans sp ON sp.classroom_id = c.id AND sp.deleted_at IS NULL
+JOIN study_tasks t ON t.plan_id = c.template_plan_id AND t.deleted_at IS NULL
+LEFT JOIN subjects ts ON ts.id = t.subject_id
+LEFT JOIN subjects tp ON tp.id = ts.parent_id
+LEFT JOIN subjects tg ON tg.id = tp.parent_id
+LEFT JOIN LATERAL (
+    SELECT ss.id FROM subjects ss
+    LEFT JOIN subjects sp1 ON sp1.id = ss.parent_id
+    LEFT JOIN subjects sp2 ON sp2.id = sp1.parent_id
+    WHERE ss.user_id = sp.user_id AND ss.kind = ts.kind AND lower(ss.name) = lower(ts.name)
+      AND lower(sp1.name) IS NOT DISTINCT FROM lower(tp.name)
+      AND lower(sp2.name) IS NOT DISTINCT FROM lower(tg.name)
+) ms ON TRUE
+WHERE c.id = sqlc.arg(classroom_id)
+  AND (sqlc.narg(user_id)::text IS NULL OR sp.user_id = sqlc.narg(user_id)::text)
+  AND NOT EXISTS (
+      SELECT 1 FROM study_tasks x WHERE x.plan_id = sp.id AND x.source_task_id = t.id
+  );
+
+-- name: SyncClassroomTasks :execrows
+UPDATE study_tasks x
+SET title = t.title,
+    due_date = t.due_date,
+    priority = t.priority,
+    notes = t.notes,
+    synced_hash = md5(concat_ws('|', t.title, t.due_date::text, coalesce(t.priority::text, ''), coalesce(t.notes, ''))),
+    version = x.version + 1,
+    updated_at = NOW()
+FROM study_plans sp, classrooms c, study_tasks t
+WHERE c.id = $1 AND sp.classroom_id = c.id AND sp.deleted_at IS NULL
+  AND x.plan_id = sp.id AND x.deleted_at IS NULL
+  AND t.id = x.source_task_id AND t.plan_id = c.template_plan_id AND t.deleted_at IS NULL
+  AND x.synced_hash = md5(concat_ws('|', x.title, x.due_date::text, coalesce(x.priority::text, ''), coalesce(x.notes, '')))
+  AND x.synced_hash <> md5(concat_ws('|', t.title, t.due_date::text, coalesce(t.priority::text, ''), coalesce(t.notes, '')));
+
+-- name: ListClassroomStudents :many
+SELECT s.user_id, s.joined_at, u.email, u.first_name, u.last_name, u.image_url
+FROM classroom_students s
+JOIN users u ON u.clerk_id = s.user_id
+WHERE s.classroom_id = $1
+ORDER BY s.joined_at ASC;
+
+-- name: GetClassroomProgress :many
+SELECT s.user_id, u.email, u.first_name, u.last_name, sp.id AS plan_id,
+       COUNT(st.id) AS total_tasks,
+       COUNT(st.id) FILTER (WHERE st.is_completed) AS completed_tasks,
+       COUNT(st.id) FILTER (WHERE NOT COALESCE(st.is_completed, FALSE) AND st.due_date < CURRENT_DATE) AS overdue_tasks,
+       (SELECT MAX(a.activity_date) FROM study_activity a WHERE a.user_id = s.user_id) AS last_active_on
+FROM classroom_students s
+JOIN users u ON u.clerk_id = s.user_id
+LEFT JOIN study_plans sp ON sp.classroom_id = s.classroom_id AND sp.user_id = s.user_id AND sp.deleted_at IS NULL
+LEFT JOIN study_tasks st ON st.plan_id = sp.id AND st.deleted_at IS NULL
+WHERE s.classroom_id = $1
+GROUP BY s.user_id, s.joined_at, u.email, u.first_name, u.last_name, sp.id
+ORDER BY s.joined_at ASC;
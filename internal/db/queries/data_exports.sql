@@ -0,0 +1,59 @@
+-- name: CreateDataExport :one
+INSERT INTO data_exports (user_id)
+VALUES ($1)
+RETURNING id, user_id, status, size_bytes, error, started_at, completed_at, expires_at, created_at;
+
+-- name: GetActiveDataExport :one
+SELECT id, user_id, status, size_bytes, error, started_at, completed_at, expires_at, created_at FROM data_exports
+WHERE user_id = $1 AND status IN ('pending', 'processing')
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: GetDataExport :one
+SELECT id, user_id, status, size_bytes, error, started_at, completed_at, expires_at, created_at FROM data_exports
+WHERE id = $1 AND user_id = $2;
+
+-- name: GetDataExportsByUser :many
+SELECT id, user_id, status, size_bytes, error, started_at, completed_at, expires_at, created_at FROM data_exports
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetDataExportArchive :one
+SELECT archive FROM data_exports
+WHERE id = $1 AND user_id = $2 AND status = 'completed' AND expires_at > NOW();
+
+-- name: ClaimDataExport :one
+UPDATE data_exports
+SET status = 'processing',
+    started_at = NOW()
+WHERE id = (
+    SELECT id FROM data_exports
+    WHERE status = 'pending'
+       OR (status = 'processing' AND started_at < NOW() - INTERVAL '15 minutes')
+    ORDER BY created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id;
+
+-- name: CompleteDataExport :exec
+UPDATE data_exports
+SET status = 'completed',
+    archive = sqlc.arg(archive),
+    size_bytes = length(sqlc.arg(archive)),
+    error = NULL,
+    completed_at = NOW(),
+    expires_at = sqlc.arg(expires_at)
+WHERE id = sqlc.arg(id);
+
+-- name: FailDataExport :exec
+UPDATE data_exports
+SET status = 'failed',
+    error = $2,
+    completed_at = NOW()
+WHERE id = $1;
+
+-- name: DeleteExpiredDataExports :execrows
+DELETE FROM data_exports
+WHERE expires_at < NOW()
+   OR (status = 'failed' AND completed_at < NOW() - INTERVAL '7 days');
@@ -0,0 +1,25 @@
+-- name: CreateEvent :one
+WITH serialized AS (
+    -- Held until commit, so a user's events are numbered in commit order
+    SELECT pg_advisory_xact_lock(hashtext(sqlc.arg(user_id)::text))
+)
+INSERT INTO events (user_id, type, data)
+SELECT sqlc.arg(user_id)::text, sqlc.arg(type)::text, sqlc.arg(data)::jsonb FROM serialized
+RETURNING *;
+
+-- name: GetEventByID :one
+SELECT * FROM events
+WHERE id = $1;
+
+-- name: GetEventsAfter :many
+SELECT * FROM events
+WHERE user_id = $1 AND id > $2
+ORDER BY id ASC
+LIMIT $3;
+
+-- name: DeleteEventsBefore :exec
+DELETE FROM events
+WHERE created_at < $1;
+
+-- name: NotifyEvent :exec
+SELECT pg_notify(sqlc.arg(channel)::text, sqlc.arg(payload)::text);
@@ -0,0 +1,76 @@
+-- name: CreateExam :one
+INSERT INTO exams (user_id, subject_id, title, starts_at, duration_minutes, location, format, weight,
+                   max_score, target_score, actual_score, notes, scored_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12,
+        CASE WHEN $11::double precision IS NULL THEN NULL ELSE NOW() END)
+RETURNING *;
+
+-- name: GetExam :one
+SELECT * FROM exams WHERE id = $1;
+
+-- name: ListExams :many
+SELECT * FROM exams
+WHERE user_id = sqlc.arg(user_id)
+  AND (sqlc.narg(subject_id)::uuid IS NULL OR subject_id = sqlc.narg(subject_id)::uuid)
+  AND (sqlc.narg(plan_id)::uuid IS NULL OR EXISTS (
+        SELECT 1 FROM plan_exams pe WHERE pe.exam_id = exams.id AND pe.plan_id = sqlc.narg(plan_id)::uuid))
+  AND (sqlc.narg(upcoming)::boolean IS NULL OR (starts_at >= NOW()) = sqlc.narg(upcoming)::boolean)
+ORDER BY starts_at ASC, id ASC;
+
+-- name: UpdateExam :one
+UPDATE exams
+SET subject_id = $2, title = $3, starts_at = $4, duration_minutes = $5, location = $6, format = $7,
+    weight = $8, max_score = $9, target_score = $10, actual_score = $11, notes = $12, updated_at = NOW(),
+    scored_at = CASE WHEN $11::double precision IS NULL THEN NULL
+                     WHEN actual_score IS NOT DISTINCT FROM $11::double precision THEN scored_at
+                     ELSE NOW() END
+WHERE id = $1
+RETURNING *;
+
+-- name: GetExamsStartingIn :many
+SELECT e.id, e.user_id, e.title, e.starts_at, e.location, s.name AS subject,
+       COALESCE(array_agg(sp.id ORDER BY pe.created_at) FILTER (WHERE sp.id IS NOT NULL), '{}')::uuid[] AS plan_ids
+FROM exams e
+LEFT JOIN subjects s ON s.id = e.subject_id
+LEFT JOIN plan_exams pe ON pe.exam_id = e.id
+LEFT JOIN study_plans sp ON sp.id = pe.plan_id AND sp.deleted_at IS NULL
+WHERE e.starts_at::date = CURRENT_DATE + sqlc.arg(days)::int
+GROUP BY e.id, s.name
+ORDER BY e.user_id;
+
+-- name: DeleteExam :exec
+DELETE FROM exams WHERE id = $1;
+
+-- name: ListExamPlans :many
+SELECT pe.exam_id, pe.plan_id
+FROM plan_exams pe
+JOIN exams e ON e.id = pe.exam_id
+WHERE e.user_id = sqlc.arg(user_id) AND e.id = ANY(sqlc.arg(exam_ids)::uuid[])
+ORDER BY pe.created_at ASC;
+
+-- name: DeleteExamPlans :exec
+DELETE FROM plan_exams WHERE exam_id = $1;
+
+-- name: AddExamPlans :execrows
+INSERT INTO plan_exams (exam_id, plan_id)
+SELECT sqlc.arg(exam_id), sp.id
+FROM study_plans sp
+JOIN plan_members pm ON pm.plan_id = sp.id AND pm.user_id = sqlc.arg(user_id)
+WHERE sp.id = ANY(sqlc.arg(plan_ids)::uuid[]) AND sp.deleted_at IS NULL
+ON CONFLICT DO NOTHING;
+
+-- name: GetExamPreparation :many
+SELECT e.id AS exam_id,
+       COUNT(DISTINCT sp.id) AS plan_count,
+       COUNT(st.id) AS total_tasks,
+       COUNT(st.id) FILTER (WHERE CASE WHEN sp.user_id = e.user_id THEN COALESCE(st.is_completed, FALSE)
+                                       ELSE EXISTS (SELECT 1 FROM task_completions tc
+                                                    WHERE tc.task_id = st.id AND tc.user_id = e.user_id) END) AS completed_tasks,
+       COALESCE(SUM(st.minutes_spent), 0)::bigint AS minutes_studied
+FROM exams e
+LEFT JOIN plan_exams pe ON pe.exam_id = e.id
+LEFT JOIN study_plans sp ON sp.id = pe.plan_id AND sp.deleted_at IS NULL
+     AND EXISTS (SELECT 1 FROM plan_members pm WHERE pm.plan_id = sp.id AND pm.user_id = e.user_id)
+LEFT JOIN study_tasks st ON st.plan_id = sp.id AND st.deleted_at IS NULL AND st.due_date <= e.starts_at
+WHERE e.user_id = sqlc.arg(user_id) AND e.id = ANY(sqlc.arg(exam_ids)::uuid[])
+GROUP BY e.id;
@@ -0,0 +1,97 @@
+-- name: CreateGoal :one
+INSERT INTO goals (user_id, title, kind, target, period, plan_id, subject_id, starts_on, ends_on)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetGoal :one
+SELECT * FROM goals WHERE id = $1;
+
+-- name: ListGoals :many
+SELECT * FROM goals
+WHERE user_id = $1
+ORDER BY created_at ASC, id ASC;
+
+-- name: UpdateGoal :one
+UPDATE goals
+SET title = $2, target = $3, plan_id = $4, subject_id = $5, ends_on = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteGoal :exec
+DELETE FROM goals WHERE id = $1;
+
+-- name: ListGoalsToClose :many
+SELECT g.*, closed.closed_through, u.time_zone
+FROM goals g
+JOIN users u ON u.clerk_id = g.user_id
+LEFT JOIN LATERAL (
+    SELECT MAX(gp.ends_on) AS closed_through FROM goal_periods gp WHERE gp.goal_id = g.id
+) closed ON TRUE
+-- Days end at midnight where the user is
+WHERE g.starts_on < (NOW() AT TIME ZONE u.time_zone)::date
+  AND (closed.closed_through IS NULL OR closed.closed_through < (NOW() AT TIME ZONE u.time_zone)::date - 1)
+  AND (g.ends_on IS NULL OR closed.closed_through IS NULL OR closed.closed_through < g.ends_on);
+
+-- name: CloseGoalPeriod :exec
+INSERT INTO goal_periods (goal_id, starts_on, ends_on, progress, target, met)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (goal_id, starts_on) DO NOTHING;
+
+-- name: ListGoalPeriods :many
+SELECT * FROM goal_periods
+WHERE goal_id = sqlc.arg(goal_id)
+ORDER BY starts_on DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: ListGoalPeriodsByUser :many
+SELECT gp.* FROM goal_periods gp
+JOIN goals g ON g.id = gp.goal_id
+WHERE g.user_id = $1
+ORDER BY gp.goal_id ASC, gp.starts_on ASC;
+
+-- name: AddGoalMilestone :execrows
+INSERT INTO goal_milestones (goal_id, period_starts_on, percent)
+VALUES ($1, $2, $3)
+ON CONFLICT DO NOTHING;
+
+-- name: ListGoalMilestones :many
+SELECT * FROM goal_milestones
+WHERE goal_id = $1
+ORDER BY period_starts_on DESC, percent ASC;
+
+-- name: GetGoalProgress :one
+WITH RECURSIVE tree AS (
+    SELECT id FROM subjects WHERE id = sqlc.narg(subject_id)::uuid
+    UNION ALL
+    SELECT s.id FROM subjects s JOIN tree ON s.parent_id = tree.id
+), zone AS (
+    -- Completions are counted on the day they happened for the user
+    SELECT COALESCE((SELECT time_zone FROM users WHERE clerk_id = sqlc.arg(user_id)), 'UTC') AS name
+), completions AS (
+    SELECT st.plan_id, COALESCE(st.subject_id, sp.subject_id) AS subject_id, st.minutes_spent,
+           (st.completed_at AT TIME ZONE 'UTC' AT TIME ZONE (SELECT name FROM zone))::date AS completed_on
+    FROM study_tasks st
+    LEFT JOIN study_plans sp ON sp.id = st.plan_id
+    WHERE st.deleted_at IS NULL AND st.completed_at IS NOT NULL
+      AND (sp.user_id = sqlc.arg(user_id) OR (st.plan_id IS NULL AND st.created_by = sqlc.arg(user_id)))
+      AND (sp.id IS NULL OR sp.deleted_at IS NULL)
+    UNION ALL
+    SELECT st.plan_id, COALESCE(st.subject_id, sp.subject_id), st.minutes_spent,
+           (tc.completed_at AT TIME ZONE 'UTC' AT TIME ZONE (SELECT name FROM zone))::date
+    FROM task_completions tc
+    JOIN study_tasks st ON st.id = tc.task_id
+    JOIN study_plans sp ON sp.id = st.plan_id
+    WHERE tc.user_id = sqlc.arg(user_id) AND sp.user_id <> sqlc.arg(user_id)
+      AND st.deleted_at IS NULL AND sp.deleted_at IS NULL
+), matching AS (
+    SELECT * FROM completions c
+    WHERE (sqlc.narg(plan_id)::uuid IS NULL OR c.plan_id = sqlc.narg(plan_id)::uuid)
+      AND (sqlc.narg(subject_id)::uuid IS NULL OR c.subject_id IN (SELECT id FROM tree))
+)
+SELECT (SELECT COUNT(*) FROM matching
+        WHERE completed_on BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date) AS tasks_completed,
+       (SELECT COALESCE(SUM(minutes_spent), 0) FROM matching
+        WHERE completed_on BETWEEN sqlc.arg(from_date)::date AND sqlc.arg(to_date)::date)::bigint AS minutes_studied,
+       (SELECT COUNT(*) FROM matching WHERE completed_on <= sqlc.arg(to_date)::date) AS completed_by_end,
+       (SELECT COUNT(*) FROM study_tasks st
+        WHERE st.plan_id = sqlc.narg(plan_id)::uuid AND st.deleted_at IS NULL) AS plan_tasks;
@@ -0,0 +1,19 @@
+-- name: CreateImpersonationSession :one
+INSERT INTO impersonation_sessions (admin_id, user_id, reason, expires_at)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetImpersonationSession :one
+SELECT * FROM impersonation_sessions WHERE id = $1;
+
+-- name: ListImpersonationSessions :many
+SELECT * FROM impersonation_sessions
+WHERE (sqlc.narg(user_id)::text IS NULL OR user_id = sqlc.narg(user_id)::text)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: EndImpersonationSession :one
+UPDATE impersonation_sessions
+SET ended_at = NOW()
+WHERE id = $1 AND admin_id = $2 AND ended_at IS NULL
+RETURNING *;
@@ -0,0 +1,41 @@
+-- name: UpsertOrganization :one
+INSERT INTO organizations (clerk_id, name, slug, image_url, created_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (clerk_id)
+DO UPDATE SET
+    name = EXCLUDED.name,
+    slug = EXCLUDED.slug,
+    image_url = EXCLUDED.image_url,
+    created_by = COALESCE(organizations.created_by, EXCLUDED.created_by),
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteOrganizationByClerkID :exec
+DELETE FROM organizations WHERE clerk_id = $1;
+
+-- name: GetOrganizationsByUser :many
+SELECT o.* FROM organizations o
+JOIN organization_memberships m ON m.organization_clerk_id = o.clerk_id
+WHERE m.user_clerk_id = $1
+ORDER BY o.name;
+
+-- name: IsOrganizationMember :one
+SELECT EXISTS (
+    SELECT 1 FROM organization_memberships
+    WHERE organization_clerk_id = $1 AND user_clerk_id = $2
+) AS member;
+
+-- name: UpsertOrganizationMembership :one
+INSERT INTO organization_memberships (clerk_id, organization_clerk_id, user_clerk_id, role)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (clerk_id)
+DO UPDATE SET
+    role = EXCLUDED.role,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteOrganizationMembershipByClerkID :exec
+DELETE FROM organization_memberships WHERE clerk_id = $1;
+
+-- name: DeleteOrganizationMembershipsByUser :exec
+DELETE FROM organization_memberships WHERE user_clerk_id = $1;
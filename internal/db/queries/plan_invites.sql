@@ -0,0 +1,51 @@
+-- name: CreatePlanInvite :one
+INSERT INTO plan_invites (plan_id, role, email, token_prefix, token_hash, invited_by, expires_at, max_uses)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ListPlanInvites :many
+SELECT * FROM plan_invites
+WHERE plan_id = $1 AND accepted_at IS NULL AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: GetPlanInviteByTokenHash :one
+SELECT pi.* FROM plan_invites pi
+JOIN study_plans sp ON sp.id = pi.plan_id
+WHERE pi.token_hash = $1 AND pi.revoked_at IS NULL
+  AND (pi.expires_at IS NULL OR pi.expires_at > NOW())
+  AND (pi.max_uses IS NULL OR pi.uses < pi.max_uses)
+  AND sp.deleted_at IS NULL;
+
+-- name: ListPlanInvitesForEmail :many
+SELECT pi.*, sp.title AS plan_title FROM plan_invites pi
+JOIN study_plans sp ON sp.id = pi.plan_id
+WHERE lower(pi.email) = lower(sqlc.arg(email))
+  AND pi.accepted_at IS NULL AND pi.revoked_at IS NULL
+  AND (pi.expires_at IS NULL OR pi.expires_at > NOW())
+  AND sp.deleted_at IS NULL
+ORDER BY pi.created_at DESC;
+
+-- name: GetPlanInviteForEmail :one
+SELECT pi.* FROM plan_invites pi
+JOIN study_plans sp ON sp.id = pi.plan_id
+WHERE pi.id = sqlc.arg(id) AND lower(pi.email) = lower(sqlc.arg(email))
+  AND pi.accepted_at IS NULL AND pi.revoked_at IS NULL
+  AND (pi.expires_at IS NULL OR pi.expires_at > NOW())
+  AND sp.deleted_at IS NULL;
+
+-- name: AcceptPlanInvite :execrows
+UPDATE plan_invites
+SET accepted_by = $2, accepted_at = NOW()
+WHERE id = $1 AND accepted_at IS NULL AND revoked_at IS NULL;
+
+-- name: UsePlanInvite :execrows
+UPDATE plan_invites
+SET uses = uses + 1
+WHERE id = $1 AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > NOW())
+  AND (max_uses IS NULL OR uses < max_uses);
+
+-- name: RevokePlanInvite :execrows
+UPDATE plan_invites
+SET revoked_at = NOW()
+WHERE id = $1 AND plan_id = $2 AND accepted_at IS NULL AND revoked_at IS NULL;
@@ -0,0 +1,32 @@
+-- name: AddPlanMember :one
+INSERT INTO plan_members (plan_id, user_id, role, invited_by)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetPlanMember :one
+SELECT * FROM plan_members
+WHERE plan_id = $1 AND user_id = $2;
+
+-- name: ListPlanMembers :many
+SELECT pm.plan_id, pm.user_id, pm.role, pm.invited_by, pm.created_at,
+       u.email, u.first_name, u.last_name, u.image_url
+FROM plan_members pm
+JOIN users u ON u.clerk_id = pm.user_id
+WHERE pm.plan_id = $1
+ORDER BY pm.created_at ASC;
+
+-- name: ListPlanMemberIDs :many
+SELECT user_id FROM plan_members WHERE plan_id = $1;
+
+-- name: GetPlanMembershipsByUser :many
+SELECT * FROM plan_members WHERE user_id = $1;
+
+-- name: UpdatePlanMemberRole :one
+UPDATE plan_members
+SET role = $3
+WHERE plan_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: RemovePlanMember :execrows
+DELETE FROM plan_members
+WHERE plan_id = $1 AND user_id = $2;
@@ -0,0 +1,33 @@
+-- name: CreatePlanShareLink :one
+INSERT INTO plan_share_links (plan_id, created_by, token_prefix, token_hash, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListPlanShareLinks :many
+SELECT * FROM plan_share_links
+WHERE plan_id = $1
+ORDER BY created_at DESC;
+
+-- name: RevokePlanShareLink :one
+UPDATE plan_share_links
+SET revoked_at = NOW()
+WHERE id = $1 AND plan_id = $2 AND revoked_at IS NULL
+RETURNING *;
+
+-- name: ViewPlanShareLink :one
+UPDATE plan_share_links l
+SET view_count = l.view_count + 1, last_viewed_at = NOW()
+FROM study_plans sp
+WHERE l.token_hash = $1 AND sp.id = l.plan_id
+  AND l.revoked_at IS NULL
+  AND (l.expires_at IS NULL OR l.expires_at > NOW())
+  AND sp.deleted_at IS NULL
+RETURNING l.*;
+
+-- name: GetActivePlanShareLink :one
+SELECT l.* FROM plan_share_links l
+JOIN study_plans sp ON sp.id = l.plan_id
+WHERE l.token_hash = $1
+  AND l.revoked_at IS NULL
+  AND (l.expires_at IS NULL OR l.expires_at > NOW())
+  AND sp.deleted_at IS NULL;
@@ -0,0 +1,82 @@
+-- name: CreatePlanTemplate :one
+INSERT INTO plan_templates (author_id, title, subject, description, duration_days, tags)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: UpsertOfficialPlanTemplate :one
+INSERT INTO plan_templates (slug, title, subject, description, duration_days, tags, is_official, published_at)
+VALUES ($1, $2, $3, $4, $5, $6, TRUE, NOW())
+ON CONFLICT (slug) DO UPDATE
+SET title = EXCLUDED.title,
+    subject = EXCLUDED.subject,
+    description = EXCLUDED.description,
+    duration_days = EXCLUDED.duration_days,
+    tags = EXCLUDED.tags,
+    is_official = TRUE,
+    published_at = COALESCE(plan_templates.published_at, NOW()),
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetPlanTemplate :one
+SELECT * FROM plan_templates WHERE id = $1;
+
+-- name: SearchPlanTemplates :many
+SELECT * FROM plan_templates
+WHERE (published_at IS NOT NULL OR author_id = sqlc.arg(user_id))
+  AND (sqlc.narg(author_id)::text IS NULL OR author_id = sqlc.narg(author_id)::text)
+  AND (sqlc.narg(query)::text IS NULL
+       OR title ILIKE '%' || sqlc.narg(query)::text || '%'
+       OR subject ILIKE '%' || sqlc.narg(query)::text || '%'
+       OR description ILIKE '%' || sqlc.narg(query)::text || '%')
+  AND (sqlc.narg(tag)::text IS NULL OR sqlc.narg(tag)::text = ANY(tags))
+  AND (sqlc.narg(official)::boolean IS NULL OR is_official = sqlc.narg(official)::boolean)
+ORDER BY CASE WHEN sqlc.arg(popular)::boolean THEN usage_count ELSE 0 END DESC,
+         COALESCE(published_at, created_at) DESC, id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: ListPlanTemplateTags :many
+SELECT tag, COUNT(*) AS template_count
+FROM plan_templates, unnest(tags) AS tag
+WHERE published_at IS NOT NULL
+GROUP BY tag
+ORDER BY template_count DESC, tag ASC;
+
+-- name: UpdatePlanTemplate :one
+UPDATE plan_templates
+SET title = $2, subject = $3, description = $4, duration_days = $5, tags = $6, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SetPlanTemplatePublished :one
+UPDATE plan_templates
+SET published_at = CASE WHEN sqlc.arg(published)::boolean THEN COALESCE(published_at, NOW()) END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: IncrementPlanTemplateUsage :exec
+UPDATE plan_templates SET usage_count = usage_count + 1 WHERE id = $1;
+
+-- name: DeletePlanTemplate :exec
+DELETE FROM plan_templates WHERE id = $1;
+
+-- name: AddPlanTemplateTask :exec
+INSERT INTO plan_template_tasks (template_id, position, title, day_offset, priority, notes)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ListPlanTemplateTasks :many
+SELECT * FROM plan_template_tasks
+WHERE template_id = $1
+ORDER BY position ASC;
+
+-- name: DeletePlanTemplateTasks :exec
+DELETE FROM plan_template_tasks WHERE template_id = $1;
+
+-- name: InstantiatePlanTemplateTasks :execrows
+INSERT INTO study_tasks (plan_id, title, due_date, is_completed, priority, notes, created_by)
+SELECT sqlc.arg(plan_id), title,
+       GREATEST(sqlc.arg(exam_date)::timestamp + day_offset * INTERVAL '1 day', sqlc.arg(start_date)::timestamp),
+       FALSE, priority, notes, sqlc.arg(created_by)
+FROM plan_template_tasks
+WHERE template_id = sqlc.arg(template_id)
+ORDER BY position ASC;
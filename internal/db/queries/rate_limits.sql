@@ -0,0 +1,21 @@
+-- name: TakeRateLimitToken :one
+WITH bucket AS (
+    SELECT LEAST(sqlc.arg(capacity)::float8,
+                 tokens + EXTRACT(EPOCH FROM NOW() - updated_at)::float8 * sqlc.arg(refill_rate)::float8) AS tokens
+    FROM rate_limit_buckets
+    WHERE key = sqlc.arg(key)
+    FOR UPDATE
+), available AS (
+    SELECT COALESCE((SELECT tokens FROM bucket), sqlc.arg(capacity)::float8) AS tokens
+), saved AS (
+    INSERT INTO rate_limit_buckets (key, tokens, updated_at)
+    SELECT sqlc.arg(key), CASE WHEN tokens >= 1 THEN tokens - 1 ELSE tokens END, NOW()
+    FROM available
+    ON CONFLICT (key) DO UPDATE SET tokens = EXCLUDED.tokens, updated_at = EXCLUDED.updated_at
+    RETURNING tokens
+)
+SELECT (available.tokens >= 1)::boolean AS allowed, saved.tokens AS remaining
+FROM available, saved;
+
+-- name: DeleteStaleRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets WHERE updated_at < $1;
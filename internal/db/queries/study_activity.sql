@@ -0,0 +1,26 @@
+-- name: RecordTaskCompleted :exec
+INSERT INTO study_activity (user_id, activity_date, tasks_completed)
+VALUES ($1, CURRENT_DATE, 1)
+ON CONFLICT (user_id, activity_date)
+DO UPDATE SET
+    tasks_completed = study_activity.tasks_completed + 1,
+    updated_at = NOW();
+
+-- name: GetBrokenStreaks :many
+WITH days AS (
+    SELECT user_id,
+           activity_date,
+           activity_date - (ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY activity_date))::int AS streak_group
+    FROM study_activity
+    WHERE tasks_completed > 0
+), streaks AS (
+    SELECT user_id,
+           MIN(activity_date)::date AS started_on,
+           MAX(activity_date)::date AS ended_on,
+           COUNT(*)::bigint AS length
+    FROM days
+    GROUP BY user_id, streak_group
+)
+SELECT user_id, started_on, ended_on, length
+FROM streaks
+WHERE ended_on = CURRENT_DATE - 2 AND length >= sqlc.arg(min_length)::bigint;
@@ -1,29 +1,109 @@
 -- name: CreateStudyPlan :one
-INSERT INTO study_plans (user_id, title, subject, description, exam_date, start_date, end_date)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
+INSERT INTO study_plans (user_id, title, subject, description, exam_date, start_date, end_date, subject_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 RETURNING *;
 
 -- name: GetStudyPlansByUserId :many
 SELECT * FROM study_plans
-WHERE user_id = $1
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC;
 
+-- name: GetStudyPlansForMember :many
+SELECT sp.* FROM study_plans sp
+JOIN plan_members pm ON pm.plan_id = sp.id
+WHERE pm.user_id = $1 AND sp.deleted_at IS NULL
+ORDER BY sp.created_at DESC;
+
 -- name: GetStudyPlanByID :one
 SELECT * FROM study_plans
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: UpdateStudyPlan :one
 UPDATE study_plans
-SET title = $2,
-    subject = $3,
-    description = $4,
-    exam_date = $5,
-    start_date = $6,
-    end_date = $7,
+SET title = sqlc.arg(title),
+    subject = sqlc.arg(subject),
+    description = sqlc.arg(description),
+    exam_date = sqlc.arg(exam_date),
+    start_date = sqlc.arg(start_date),
+    end_date = sqlc.arg(end_date),
+    subject_id = sqlc.arg(subject_id),
+    version = version + 1,
     updated_at = now()
-WHERE id = $1
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version)::int)
 RETURNING *;
 
--- name: DeleteStudyPlan :exec
+-- name: PatchStudyPlan :one
+UPDATE study_plans
+SET title = COALESCE(sqlc.narg(title), title),
+    subject = COALESCE(sqlc.narg(subject), subject),
+    description = CASE WHEN sqlc.arg(set_description)::bool THEN sqlc.narg(description) ELSE description END,
+    exam_date = COALESCE(sqlc.narg(exam_date), exam_date),
+    start_date = COALESCE(sqlc.narg(start_date), start_date),
+    end_date = COALESCE(sqlc.narg(end_date), end_date),
+    subject_id = COALESCE(sqlc.narg(subject_id), subject_id),
+    version = version + 1,
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version)::int)
+RETURNING *;
+
+-- name: DeleteStudyPlan :one
+WITH plan AS (
+    UPDATE study_plans
+    SET deleted_at = now(), version = version + 1
+    WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+      AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version)::int)
+    RETURNING id, deleted_at
+), tasks AS (
+    UPDATE study_tasks st
+    SET deleted_at = plan.deleted_at, version = st.version + 1
+    FROM plan
+    WHERE st.plan_id = plan.id AND st.deleted_at IS NULL
+)
+SELECT id FROM plan;
+
+-- name: GetStudyPlansWithExamIn :many
+SELECT * FROM study_plans
+WHERE exam_date = CURRENT_DATE + sqlc.arg(days)::int AND deleted_at IS NULL
+  -- Plans linked to exams are reminded of through them
+  AND NOT EXISTS (SELECT 1 FROM plan_exams pe WHERE pe.plan_id = study_plans.id)
+ORDER BY user_id;
+
+-- name: GetDeletedStudyPlans :many
+SELECT sp.id, sp.user_id, sp.title, sp.subject, sp.description, sp.exam_date, sp.start_date,
+       sp.end_date, sp.created_at, sp.updated_at, sp.deleted_at, sp.version,
+       (SELECT COUNT(*) FROM study_tasks st
+        WHERE st.plan_id = sp.id AND st.deleted_at = sp.deleted_at) AS task_count
+FROM study_plans sp
+JOIN plan_members pm ON pm.plan_id = sp.id AND pm.user_id = $1 AND pm.role = 'owner'
+WHERE sp.deleted_at IS NOT NULL
+ORDER BY sp.deleted_at DESC;
+
+-- name: GetDeletedStudyPlanByID :one
+SELECT * FROM study_plans
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: RestoreStudyPlan :one
+WITH plan AS (
+    SELECT id, deleted_at FROM study_plans
+    WHERE study_plans.id = $1 AND deleted_at IS NOT NULL
+), tasks AS (
+    UPDATE study_tasks st
+    SET deleted_at = NULL, version = st.version + 1
+    FROM plan
+    WHERE st.plan_id = plan.id AND st.deleted_at = plan.deleted_at
+)
+UPDATE study_plans sp
+SET deleted_at = NULL, version = sp.version + 1, updated_at = now()
+FROM plan
+WHERE sp.id = plan.id
+RETURNING sp.*;
+
+-- name: PurgeStudyPlan :execrows
+DELETE FROM study_plans
+WHERE id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeDeletedStudyPlans :execrows
 DELETE FROM study_plans
-WHERE id = $1;
+WHERE deleted_at < $1;
@@ -1,54 +1,138 @@
 -- name: CreateTask :one
-INSERT INTO study_tasks (plan_id, title, due_date, is_completed, priority, notes)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO study_tasks (plan_id, title, due_date, is_completed, priority, notes, created_by, subject_id, minutes_spent)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 RETURNING *;
 
 -- name: GetTaskByID :one
 SELECT * FROM study_tasks
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
 
 -- name: GetTasksByPlan :many
 SELECT * FROM study_tasks
-WHERE plan_id = $1
+WHERE plan_id = $1 AND deleted_at IS NULL
 ORDER BY due_date ASC;
 
--- name: UpdateTaskStatus :exec
+-- name: UpdateTaskStatus :one
 UPDATE study_tasks
-SET is_completed = $2, updated_at = now()
-WHERE id = $1;
+SET is_completed = sqlc.arg(is_completed), version = version + 1, updated_at = now()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version)::int)
+RETURNING *;
 
 -- name: UpdateTask :one
 UPDATE study_tasks
-SET title = $2,
-    due_date = $3,
-    is_completed = $4,
-    priority = $5,
-    notes = $6,
+SET title = sqlc.arg(title),
+    due_date = sqlc.arg(due_date),
+    is_completed = COALESCE(sqlc.narg(is_completed), is_completed),
+    priority = sqlc.arg(priority),
+    notes = sqlc.arg(notes),
+    subject_id = sqlc.arg(subject_id),
+    minutes_spent = sqlc.arg(minutes_spent),
+    version = version + 1,
     updated_at = now()
-WHERE id = $1
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version)::int)
 RETURNING *;
 
--- name: DeleteTask :exec
-DELETE FROM study_tasks
-WHERE id = $1;
-
--- name: GetOverdueTasks :many
-SELECT * FROM study_tasks
-WHERE plan_id = $1 AND due_date < CURRENT_DATE AND is_completed = FALSE
-ORDER BY due_date ASC;
+-- name: PatchTask :one
+UPDATE study_tasks
+SET title = COALESCE(sqlc.narg(title), title),
+    due_date = COALESCE(sqlc.narg(due_date), due_date),
+    is_completed = COALESCE(sqlc.narg(is_completed), is_completed),
+    priority = CASE WHEN sqlc.arg(set_priority)::bool THEN sqlc.narg(priority) ELSE priority END,
+    notes = CASE WHEN sqlc.arg(set_notes)::bool THEN sqlc.narg(notes) ELSE notes END,
+    subject_id = CASE WHEN sqlc.arg(set_subject_id)::bool THEN sqlc.narg(subject_id) ELSE subject_id END,
+    minutes_spent = CASE WHEN sqlc.arg(set_minutes_spent)::bool THEN sqlc.narg(minutes_spent) ELSE minutes_spent END,
+    version = version + 1,
+    updated_at = now()
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version)::int)
+RETURNING *;
 
--- name: GetTasksByStatus :many
-SELECT * FROM study_tasks
-WHERE plan_id = $1 AND is_completed = $2
-ORDER BY due_date ASC;
+-- name: DeleteTask :execrows
+UPDATE study_tasks
+SET deleted_at = now(), version = version + 1
+WHERE id = sqlc.arg(id) AND deleted_at IS NULL
+  AND (sqlc.narg(expected_version)::int IS NULL OR version = sqlc.narg(expected_version)::int);
 
--- name: GetTasksByPriority :many
+-- name: GetOverdueTasks :many
 SELECT * FROM study_tasks
-WHERE plan_id = $1 AND priority = $2
+WHERE plan_id = $1 AND due_date < CURRENT_DATE AND is_completed = FALSE AND deleted_at IS NULL
 ORDER BY due_date ASC;
 
 -- name: GetTasksByUser :many
 SELECT st.* FROM study_tasks st
+LEFT JOIN study_plans sp ON st.plan_id = sp.id
+WHERE st.deleted_at IS NULL
+  AND ((st.plan_id IS NULL AND st.created_by = $1) OR (sp.user_id = $1 AND sp.deleted_at IS NULL))
+ORDER BY st.due_date ASC;
+
+-- name: GetTasksByPlanForMember :many
+SELECT st.id, st.plan_id, st.title, st.due_date,
+       CASE WHEN sp.user_id = sqlc.arg(user_id) THEN st.is_completed
+            ELSE EXISTS (SELECT 1 FROM task_completions tc
+                         WHERE tc.task_id = st.id AND tc.user_id = sqlc.arg(user_id)) END AS is_completed,
+       st.priority, st.notes, st.created_at, st.updated_at, st.deleted_at, st.version, st.created_by,
+       st.source_task_id, st.synced_hash, st.subject_id, st.minutes_spent,
+       CASE WHEN sp.user_id = sqlc.arg(user_id) THEN st.completed_at
+            ELSE (SELECT tc.completed_at FROM task_completions tc
+                  WHERE tc.task_id = st.id AND tc.user_id = sqlc.arg(user_id)) END AS completed_at
+FROM study_tasks st
 JOIN study_plans sp ON st.plan_id = sp.id
-WHERE sp.user_id = $1
+WHERE st.plan_id = sqlc.arg(plan_id) AND st.deleted_at IS NULL
 ORDER BY st.due_date ASC;
+
+-- name: GetTasksForMember :many
+SELECT st.id, st.plan_id, st.title, st.due_date,
+       CASE WHEN st.plan_id IS NULL OR sp.user_id = sqlc.arg(user_id) THEN st.is_completed
+            ELSE EXISTS (SELECT 1 FROM task_completions tc
+                         WHERE tc.task_id = st.id AND tc.user_id = sqlc.arg(user_id)) END AS is_completed,
+       st.priority, st.notes, st.created_at, st.updated_at, st.deleted_at, st.version, st.created_by,
+       st.source_task_id, st.synced_hash, st.subject_id, st.minutes_spent,
+       CASE WHEN st.plan_id IS NULL OR sp.user_id = sqlc.arg(user_id) THEN st.completed_at
+            ELSE (SELECT tc.completed_at FROM task_completions tc
+                  WHERE tc.task_id = st.id AND tc.user_id = sqlc.arg(user_id)) END AS completed_at
+FROM study_tasks st
+LEFT JOIN study_plans sp ON st.plan_id = sp.id
+LEFT JOIN plan_members pm ON pm.plan_id = sp.id AND pm.user_id = sqlc.arg(user_id)
+WHERE st.deleted_at IS NULL
+  AND ((st.plan_id IS NULL AND st.created_by = sqlc.arg(user_id))
+       OR (sp.deleted_at IS NULL AND pm.user_id IS NOT NULL))
+ORDER BY st.due_date ASC;
+
+-- name: GetDeletedTasksByUser :many
+SELECT st.* FROM study_tasks st
+LEFT JOIN study_plans sp ON st.plan_id = sp.id
+LEFT JOIN plan_members pm ON pm.plan_id = sp.id AND pm.user_id = $1
+WHERE st.deleted_at IS NOT NULL
+  AND ((st.plan_id IS NULL AND st.created_by = $1)
+       OR (sp.deleted_at IS NULL AND pm.role IN ('editor', 'owner')))
+ORDER BY st.deleted_at DESC;
+
+-- name: GetDeletedTaskByID :one
+SELECT st.* FROM study_tasks st
+LEFT JOIN study_plans sp ON st.plan_id = sp.id
+WHERE st.id = $1 AND st.deleted_at IS NOT NULL
+  AND (st.plan_id IS NULL OR sp.deleted_at IS NULL);
+
+-- name: RestoreTask :one
+UPDATE study_tasks
+SET deleted_at = NULL, version = version + 1, updated_at = now()
+WHERE id = $1 AND deleted_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM study_plans sp WHERE sp.id = study_tasks.plan_id AND sp.deleted_at IS NOT NULL)
+RETURNING *;
+
+-- name: PurgeTask :execrows
+DELETE FROM study_tasks
+WHERE id = $1 AND deleted_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM study_plans sp WHERE sp.id = study_tasks.plan_id AND sp.deleted_at IS NOT NULL);
+
+-- name: PurgeDeletedTasks :execrows
+DELETE FROM study_tasks
+WHERE deleted_at < $1;
+
+-- name: CopyPlanTasks :execrows
+INSERT INTO study_tasks (plan_id, title, due_date, is_completed, priority, notes, created_by)
+SELECT sqlc.arg(plan_id), title, due_date, FALSE, priority, NULL, sqlc.arg(created_by)
+FROM study_tasks
+WHERE plan_id = sqlc.arg(source_plan_id) AND deleted_at IS NULL;
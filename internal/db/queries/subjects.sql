@@ -0,0 +1,60 @@
+-- name: CreateSubject :one
+INSERT INTO subjects (user_id, parent_id, kind, name, color, icon)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: EnsureSubject :one
+INSERT INTO subjects (user_id, name)
+VALUES ($1, $2)
+ON CONFLICT (user_id, lower(name)) WHERE parent_id IS NULL
+DO UPDATE SET name = subjects.name
+RETURNING *;
+
+-- name: GetSubject :one
+SELECT * FROM subjects WHERE id = $1;
+
+-- name: ListSubjects :many
+SELECT * FROM subjects
+WHERE user_id = $1
+ORDER BY lower(name) ASC, id ASC;
+
+-- name: UpdateSubject :one
+UPDATE subjects
+SET parent_id = $2, name = $3, color = $4, icon = $5, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: RenamePlanSubjects :exec
+UPDATE study_plans
+SET subject = $2, version = version + 1, updated_at = NOW()
+WHERE subject_id = $1 AND subject <> $2;
+
+-- name: DeleteSubject :exec
+DELETE FROM subjects WHERE id = $1;
+
+-- name: GetSubjectStats :many
+WITH RECURSIVE tree AS (
+    SELECT id AS root_id, id FROM subjects WHERE user_id = sqlc.arg(user_id)
+    UNION ALL
+    SELECT tree.root_id, s.id FROM subjects s JOIN tree ON s.parent_id = tree.id
+), tasks AS (
+    SELECT COALESCE(st.subject_id, sp.subject_id) AS subject_id, st.due_date, st.is_completed, st.minutes_spent
+    FROM study_tasks st
+    JOIN study_plans sp ON sp.id = st.plan_id
+    WHERE sp.user_id = sqlc.arg(user_id) AND st.deleted_at IS NULL AND sp.deleted_at IS NULL
+    UNION ALL
+    SELECT st.subject_id, st.due_date, st.is_completed, st.minutes_spent
+    FROM study_tasks st
+    WHERE st.plan_id IS NULL AND st.created_by = sqlc.arg(user_id) AND st.deleted_at IS NULL
+)
+SELECT tree.root_id AS subject_id,
+       (SELECT COUNT(*) FROM study_plans sp
+        WHERE sp.deleted_at IS NULL
+          AND sp.subject_id IN (SELECT t2.id FROM tree t2 WHERE t2.root_id = tree.root_id)) AS plan_count,
+       COUNT(t.subject_id) AS total_tasks,
+       COUNT(t.subject_id) FILTER (WHERE t.is_completed) AS completed_tasks,
+       COUNT(t.subject_id) FILTER (WHERE NOT COALESCE(t.is_completed, FALSE) AND t.due_date < CURRENT_DATE) AS overdue_tasks,
+       COALESCE(SUM(t.minutes_spent), 0)::bigint AS minutes_studied
+FROM tree
+LEFT JOIN tasks t ON t.subject_id = tree.id
+GROUP BY tree.root_id;
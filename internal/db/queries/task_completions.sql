@@ -0,0 +1,18 @@
+-- name: CompleteTaskForMember :exec
+INSERT INTO task_completions (task_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (task_id, user_id) DO NOTHING;
+
+-- name: ReopenTaskForMember :exec
+DELETE FROM task_completions
+WHERE task_id = $1 AND user_id = $2;
+
+-- name: IsTaskCompletedBy :one
+SELECT EXISTS (
+    SELECT 1 FROM task_completions WHERE task_id = $1 AND user_id = $2
+) AS completed;
+
+-- name: ListTaskCompletionsByUser :many
+SELECT * FROM task_completions
+WHERE user_id = $1
+ORDER BY completed_at ASC;
@@ -0,0 +1,43 @@
+-- name: UpsertUserExternalAccount :exec
+INSERT INTO user_external_accounts (id, user_clerk_id, provider, provider_user_id, email_address, first_name, last_name, image_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (id)
+DO UPDATE SET
+    provider = EXCLUDED.provider,
+    provider_user_id = EXCLUDED.provider_user_id,
+    email_address = EXCLUDED.email_address,
+    first_name = EXCLUDED.first_name,
+    last_name = EXCLUDED.last_name,
+    image_url = EXCLUDED.image_url,
+    updated_at = NOW();
+
+-- name: DeleteStaleUserExternalAccounts :exec
+DELETE FROM user_external_accounts
+WHERE user_clerk_id = sqlc.arg(user_clerk_id)
+  AND NOT (id = ANY(sqlc.arg(keep_ids)::text[]));
+
+-- name: GetUserExternalAccounts :many
+SELECT * FROM user_external_accounts
+WHERE user_clerk_id = $1
+ORDER BY created_at;
+
+-- name: UpsertUserSession :exec
+INSERT INTO user_sessions (id, user_clerk_id, status, last_active_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (id)
+DO UPDATE SET
+    status = EXCLUDED.status,
+    last_active_at = COALESCE(EXCLUDED.last_active_at, user_sessions.last_active_at),
+    updated_at = NOW();
+
+-- name: EndUserSession :exec
+INSERT INTO user_sessions (id, user_clerk_id, status, ended_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (id)
+DO UPDATE SET
+    status = EXCLUDED.status,
+    ended_at = COALESCE(user_sessions.ended_at, NOW()),
+    updated_at = NOW();
+
+-- name: DeleteUserSessions :exec
+DELETE FROM user_sessions WHERE user_clerk_id = $1;
@@ -49,3 +49,25 @@ UPDATE users SET banned = false, updated_at = NOW() WHERE clerk_id = $1;
 
 -- name: DeleteUserByClerkID :exec
 DELETE FROM users WHERE clerk_id = $1;
+
+-- name: UpdateUserLastSignIn :execrows
+UPDATE users SET last_sign_in_at = $2, updated_at = NOW() WHERE clerk_id = $1;
+
+-- name: SearchUsers :many
+SELECT * FROM users
+WHERE (sqlc.narg(query)::text IS NULL
+       OR email ILIKE '%' || sqlc.narg(query)::text || '%'
+       OR name ILIKE '%' || sqlc.narg(query)::text || '%'
+       OR clerk_id = sqlc.narg(query)::text)
+  AND (sqlc.narg(role)::text IS NULL OR role = sqlc.narg(role)::text)
+  AND (sqlc.narg(banned)::boolean IS NULL OR COALESCE(banned, false) = sqlc.narg(banned)::boolean)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: SetUserRole :one
+UPDATE users SET role = $2, updated_at = NOW() WHERE clerk_id = $1
+RETURNING *;
+
+-- name: SetUserTimeZone :one
+UPDATE users SET time_zone = $2, updated_at = NOW() WHERE clerk_id = $1
+RETURNING *;
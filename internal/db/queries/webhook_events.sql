@@ -0,0 +1,47 @@
+-- name: ClaimWebhookEvent :one
+INSERT INTO webhook_events (svix_id, type, payload, claimed_at)
+VALUES ($1, $2, $3, NOW())
+ON CONFLICT (svix_id) DO UPDATE
+SET status = 'processing',
+    attempts = webhook_events.attempts + 1,
+    claimed_at = NOW(),
+    updated_at = NOW()
+WHERE webhook_events.status = 'failed'
+   OR (webhook_events.status = 'processing'
+       AND webhook_events.claimed_at < NOW() - $4::int * INTERVAL '1 second')
+RETURNING *;
+
+-- name: StartWebhookEventReplay :one
+UPDATE webhook_events
+SET status = 'processing',
+    attempts = attempts + 1,
+    claimed_at = NOW(),
+    updated_at = NOW()
+WHERE svix_id = $1
+  AND (status = 'failed' OR (status = 'processing' AND claimed_at < NOW() - $2::int * INTERVAL '1 second'))
+RETURNING *;
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET status = 'processed',
+    last_error = NULL,
+    processed_at = NOW(),
+    updated_at = NOW()
+WHERE svix_id = $1;
+
+-- name: MarkWebhookEventFailed :exec
+UPDATE webhook_events
+SET status = 'failed',
+    last_error = $2,
+    updated_at = NOW()
+WHERE svix_id = $1;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE svix_id = $1;
+
+-- name: ListWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status)::text
+ORDER BY received_at DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
@@ -0,0 +1,121 @@
+-- name: CreateWebhookEndpoint :one
+INSERT INTO webhook_endpoints (user_id, url, description, secret, event_types)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetWebhookEndpointsByUser :many
+SELECT * FROM webhook_endpoints
+WHERE user_id = $1
+ORDER BY created_at DESC;
+
+-- name: GetWebhookEndpointByID :one
+SELECT * FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: UpdateWebhookEndpoint :one
+UPDATE webhook_endpoints
+SET url = sqlc.arg(url),
+    description = sqlc.arg(description),
+    event_types = sqlc.arg(event_types),
+    enabled = sqlc.arg(enabled),
+    consecutive_failures = CASE WHEN sqlc.arg(enabled) THEN 0 ELSE consecutive_failures END,
+    disabled_at = CASE WHEN sqlc.arg(enabled) THEN NULL ELSE COALESCE(disabled_at, NOW()) END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: RotateWebhookEndpointSecret :one
+UPDATE webhook_endpoints
+SET secret = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhookEndpoint :exec
+DELETE FROM webhook_endpoints
+WHERE id = $1;
+
+-- name: RecordWebhookEndpointSuccess :exec
+UPDATE webhook_endpoints
+SET consecutive_failures = 0, updated_at = NOW()
+WHERE id = $1;
+
+-- name: RecordWebhookEndpointFailure :one
+UPDATE webhook_endpoints
+SET consecutive_failures = consecutive_failures + 1,
+    enabled = enabled AND consecutive_failures + 1 < sqlc.arg(max_failures)::int,
+    disabled_at = CASE
+        WHEN enabled AND consecutive_failures + 1 >= sqlc.arg(max_failures)::int THEN NOW()
+        ELSE disabled_at
+    END,
+    updated_at = NOW()
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: EnqueueWebhookDeliveries :execrows
+INSERT INTO webhook_deliveries (endpoint_id, message_id, event_type, payload, dedupe_key)
+SELECT e.id,
+       'msg_' || replace(gen_random_uuid()::text, '-', ''),
+       sqlc.arg(event_type)::text,
+       sqlc.arg(payload)::jsonb,
+       sqlc.narg(dedupe_key)::text
+FROM webhook_endpoints e
+WHERE e.user_id = sqlc.arg(user_id)
+  AND e.enabled
+  AND sqlc.arg(event_type)::text = ANY(e.event_types)
+ON CONFLICT (endpoint_id, dedupe_key) DO NOTHING;
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET next_attempt_at = NOW() + INTERVAL '5 minutes', updated_at = NOW()
+WHERE id IN (
+    SELECT d.id FROM webhook_deliveries d
+    JOIN webhook_endpoints e ON e.id = d.endpoint_id
+    WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND e.enabled
+    ORDER BY d.next_attempt_at
+    LIMIT $1
+    FOR UPDATE OF d SKIP LOCKED
+)
+RETURNING *;
+
+-- name: CreateWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (delivery_id, response_code, error, duration_ms)
+VALUES ($1, $2, $3, $4);
+
+-- name: MarkWebhookDeliverySucceeded :exec
+UPDATE webhook_deliveries
+SET status = 'succeeded',
+    attempts = attempts + 1,
+    last_response_code = $2,
+    delivered_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookDeliveryFailed :exec
+UPDATE webhook_deliveries
+SET status = CASE WHEN sqlc.narg(next_attempt_at)::timestamp IS NULL THEN 'failed' ELSE 'pending' END,
+    attempts = attempts + 1,
+    last_response_code = sqlc.narg(last_response_code),
+    next_attempt_at = COALESCE(sqlc.narg(next_attempt_at)::timestamp, next_attempt_at),
+    updated_at = NOW()
+WHERE id = sqlc.arg(id);
+
+-- name: RedeliverWebhookDelivery :one
+UPDATE webhook_deliveries
+SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetWebhookDeliveryByID :one
+SELECT * FROM webhook_deliveries
+WHERE id = $1;
+
+-- name: GetWebhookDeliveriesByEndpoint :many
+SELECT * FROM webhook_deliveries
+WHERE endpoint_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: GetWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,300 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Event types pushed to clients over the stream
+const (
+	TaskCreated  = "task.created"
+	TaskUpdated  = "task.updated"
+	TaskDeleted  = "task.deleted"
+	TaskRestored = "task.restored"
+	PlanCreated  = "plan.created"
+	PlanUpdated  = "plan.updated"
+	PlanDeleted  = "plan.deleted"
+	PlanRestored = "plan.restored"
+	TimerTick    = "timer.tick"
+
+	GoalMilestone      = "goal.milestone"
+	AchievementAwarded = "achievement.awarded"
+)
+
+const (
+	// channel is the Postgres NOTIFY channel shared by every API replica
+	channel = "prepilot_events"
+
+	// maxNotifyPayload keeps us under Postgres' 8000 byte NOTIFY limit.
+	// Larger events are announced by ID and fetched by the listener.
+	maxNotifyPayload = 7900
+
+	// subscriberBuffer is how many events a slow client may fall behind
+	// before it is disconnected and has to resume with Last-Event-ID.
+	subscriberBuffer = 64
+
+	// retention is how long persisted events are kept for resuming
+	retention = 24 * time.Hour
+)
+
+// Subscription is a single open stream for a user
+type Subscription struct {
+	C      <-chan store.Event
+	userID string
+	ch     chan store.Event
+	once   sync.Once
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() { close(s.ch) })
+}
+
+// Broker fans events out to subscribers. Publishing goes through Postgres
+// LISTEN/NOTIFY so that subscribers connected to any replica receive it.
+type Broker struct {
+	db      *sql.DB
+	queries *store.Queries
+	dsn     string
+
+	mu   sync.RWMutex
+	subs map[string]map[*Subscription]struct{}
+
+	listener *pq.Listener
+	done     chan struct{}
+}
+
+// NewBroker creates a new Broker. Start must be called before events are
+// delivered to subscribers.
+func NewBroker(db *sql.DB, dsn string) *Broker {
+	return &Broker{
+		db:      db,
+		queries: store.New(db),
+		dsn:     dsn,
+		subs:    make(map[string]map[*Subscription]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start connects the LISTEN side and begins dispatching notifications
+func (b *Broker) Start() error {
+	b.listener = pq.NewListener(b.dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("events: listener error: %v", err)
+		}
+	})
+	if err := b.listener.Listen(channel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+
+	go b.run()
+	return nil
+}
+
+// Close stops the listener and disconnects every subscriber
+func (b *Broker) Close() error {
+	close(b.done)
+	b.disconnectAll()
+	if b.listener == nil {
+		return nil
+	}
+	return b.listener.Close()
+}
+
+// Subscribe registers a new stream for the given user
+func (b *Broker) Subscribe(userID string) *Subscription {
+	ch := make(chan store.Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, userID: userID}
+
+	b.mu.Lock()
+	if b.subs[userID] == nil {
+		b.subs[userID] = make(map[*Subscription]struct{})
+	}
+	b.subs[userID][sub] = struct{}{}
+	b.mu.Unlock()
+
+	return sub
+}
+
+// Unsubscribe removes a stream and closes its channel
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	if userSubs, ok := b.subs[sub.userID]; ok {
+		delete(userSubs, sub)
+		if len(userSubs) == 0 {
+			delete(b.subs, sub.userID)
+		}
+	}
+	b.mu.Unlock()
+	sub.close()
+}
+
+// Publish stores an event for the user and notifies every replica
+func (b *Broker) Publish(ctx context.Context, userID, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event data: %w", err)
+	}
+
+	// Stored and announced in one transaction: a user's events are numbered
+	// in commit order and notifications go out on commit, in the same order,
+	// so a stream never sees an event after one with a higher ID
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := b.queries.WithTx(tx)
+	event, err := queries.CreateEvent(ctx, store.CreateEventParams{
+		UserID: userID,
+		Type:   eventType,
+		Data:   payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store event: %w", err)
+	}
+	if err := b.notify(ctx, queries, event); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit event: %w", err)
+	}
+	return nil
+}
+
+// PublishEphemeral notifies every replica without storing the event.
+// Ephemeral events have no ID and cannot be resumed.
+func (b *Broker) PublishEphemeral(ctx context.Context, userID, eventType string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event data: %w", err)
+	}
+
+	return b.notify(ctx, b.queries, store.Event{
+		UserID:    userID,
+		Type:      eventType,
+		Data:      payload,
+		CreatedAt: time.Now(),
+	})
+}
+
+// Replay returns stored events for the user newer than lastID
+func (b *Broker) Replay(ctx context.Context, userID string, lastID int64, limit int32) ([]store.Event, error) {
+	return b.queries.GetEventsAfter(ctx, store.GetEventsAfterParams{
+		UserID: userID,
+		ID:     lastID,
+		Limit:  limit,
+	})
+}
+
+func (b *Broker) notify(ctx context.Context, queries *store.Queries, event store.Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	// Too large for NOTIFY: send a reference and let the listener load it
+	if len(payload) > maxNotifyPayload {
+		if event.ID == 0 {
+			return fmt.Errorf("ephemeral event %s exceeds %d bytes", event.Type, maxNotifyPayload)
+		}
+		payload, err = json.Marshal(store.Event{ID: event.ID, UserID: event.UserID})
+		if err != nil {
+			return fmt.Errorf("failed to encode notification: %w", err)
+		}
+	}
+
+	return queries.NotifyEvent(ctx, store.NotifyEventParams{
+		Channel: channel,
+		Payload: string(payload),
+	})
+}
+
+// run dispatches notifications until Close is called
+func (b *Broker) run() {
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+
+	for {
+		select {
+		case <-b.done:
+			return
+		case n := <-b.listener.Notify:
+			if n == nil {
+				// The connection was re-established and notifications may
+				// have been lost. Drop every stream so clients resume from
+				// their Last-Event-ID.
+				b.disconnectAll()
+				continue
+			}
+			b.dispatch(n.Extra)
+		case <-ping.C:
+			go b.listener.Ping()
+		case <-purge.C:
+			if err := b.queries.DeleteEventsBefore(context.Background(), time.Now().Add(-retention)); err != nil {
+				log.Printf("events: failed to purge old events: %v", err)
+			}
+		}
+	}
+}
+
+func (b *Broker) dispatch(payload string) {
+	var event store.Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		log.Printf("events: invalid notification payload: %v", err)
+		return
+	}
+
+	if event.Data == nil && event.ID != 0 {
+		stored, err := b.queries.GetEventByID(context.Background(), event.ID)
+		if err != nil {
+			log.Printf("events: failed to load event %d: %v", event.ID, err)
+			return
+		}
+		event = stored
+	}
+
+	b.mu.RLock()
+	var lagging []*Subscription
+	for sub := range b.subs[event.UserID] {
+		select {
+		case sub.ch <- event:
+		default:
+			// Dropping a tick is harmless; dropping a stored event is not,
+			// so disconnect the client and let it catch up via replay.
+			if event.ID != 0 {
+				lagging = append(lagging, sub)
+			}
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range lagging {
+		b.Unsubscribe(sub)
+	}
+}
+
+func (b *Broker) disconnectAll() {
+	b.mu.Lock()
+	subs := b.subs
+	b.subs = make(map[string]map[*Subscription]struct{})
+	b.mu.Unlock()
+
+	for _, userSubs := range subs {
+		for sub := range userSubs {
+			sub.close()
+		}
+	}
+}
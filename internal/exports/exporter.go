@@ -0,0 +1,278 @@
+// Package exports builds downloadable archives of everything stored about a
+// user. Exports are requested through the API and built in the background.
+package exports
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Export statuses
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusFailed     = "failed"
+)
+
+// Retention is how long a finished archive can be downloaded
+const Retention = 7 * 24 * time.Hour
+
+const (
+	pollInterval  = 5 * time.Second
+	purgeInterval = time.Hour
+)
+
+// Exporter builds requested exports in the background
+type Exporter struct {
+	queries *store.Queries
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewExporter creates a new Exporter. Start must be called before any
+// requested exports are built.
+func NewExporter(db *sql.DB) *Exporter {
+	return &Exporter{
+		queries: store.New(db),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start begins building requested exports and purging expired ones
+func (e *Exporter) Start() {
+	e.wg.Add(1)
+	go e.loop()
+}
+
+// Close stops the background worker and waits for the export in progress
+func (e *Exporter) Close() {
+	close(e.done)
+	e.wg.Wait()
+}
+
+func (e *Exporter) loop() {
+	defer e.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	lastPurge := time.Time{}
+
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			e.buildPending()
+
+			if time.Since(lastPurge) >= purgeInterval {
+				e.purgeExpired()
+				lastPurge = time.Now()
+			}
+		}
+	}
+}
+
+// buildPending builds exports until none are left waiting
+func (e *Exporter) buildPending() {
+	ctx := context.Background()
+
+	for {
+		select {
+		case <-e.done:
+			return
+		default:
+		}
+
+		export, err := e.queries.ClaimDataExport(ctx)
+		if err == sql.ErrNoRows {
+			return
+		}
+		if err != nil {
+			log.Printf("exports: failed to claim export: %v", err)
+			return
+		}
+
+		archive, err := e.build(ctx, export.UserID)
+		if err != nil {
+			log.Printf("exports: export %s failed: %v", export.ID, err)
+			if err := e.queries.FailDataExport(ctx, store.FailDataExportParams{
+				ID:    export.ID,
+				Error: sql.NullString{String: err.Error(), Valid: true},
+			}); err != nil {
+				log.Printf("exports: failed to record failure of %s: %v", export.ID, err)
+			}
+			continue
+		}
+
+		if err := e.queries.CompleteDataExport(ctx, store.CompleteDataExportParams{
+			Archive:   archive,
+			ExpiresAt: sql.NullTime{Time: time.Now().Add(Retention), Valid: true},
+			ID:        export.ID,
+		}); err != nil {
+			log.Printf("exports: failed to save export %s: %v", export.ID, err)
+		}
+	}
+}
+
+func (e *Exporter) purgeExpired() {
+	removed, err := e.queries.DeleteExpiredDataExports(context.Background())
+	if err != nil {
+		log.Printf("exports: failed to purge expired exports: %v", err)
+		return
+	}
+	if removed > 0 {
+		log.Printf("exports: purged %d expired exports", removed)
+	}
+}
+
+// build collects the user's data and writes it as a zip of JSON files
+func (e *Exporter) build(ctx context.Context, userID string) ([]byte, error) {
+	files, err := e.collect(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, file := range files {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s: %w", file.name, err)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+type file struct {
+	name string
+	data any
+}
+
+func (e *Exporter) collect(ctx context.Context, userID string) ([]file, error) {
+	user, err := e.queries.GetUserByClerkID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+
+	plans, err := e.queries.GetStudyPlansByUserId(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load study plans: %w", err)
+	}
+
+	tasks, err := e.queries.GetTasksByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load study tasks: %w", err)
+	}
+
+	activity, err := e.queries.GetUserStudyActivity(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load study activity: %w", err)
+	}
+
+	endpoints, err := e.queries.GetWebhookEndpointsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook endpoints: %w", err)
+	}
+
+	accounts, err := e.queries.GetUserExternalAccounts(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load external accounts: %w", err)
+	}
+
+	sessions, err := e.queries.GetUserSessions(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sessions: %w", err)
+	}
+
+	organizations, err := e.queries.GetOrganizationsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load organizations: %w", err)
+	}
+
+	completions, err := e.queries.ListTaskCompletionsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load task completions: %w", err)
+	}
+
+	subjects, err := e.queries.ListSubjects(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load subjects: %w", err)
+	}
+
+	exams, err := e.queries.ListExams(ctx, store.ListExamsParams{UserID: userID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exams: %w", err)
+	}
+	examIDs := make([]uuid.UUID, len(exams))
+	for i, exam := range exams {
+		examIDs[i] = exam.ID
+	}
+	links, err := e.queries.ListExamPlans(ctx, store.ListExamPlansParams{UserID: userID, ExamIds: examIDs})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load exam plans: %w", err)
+	}
+	planIDs := make(map[uuid.UUID][]uuid.UUID)
+	for _, link := range links {
+		planIDs[link.ExamID] = append(planIDs[link.ExamID], link.PlanID)
+	}
+	examRecords := make([]record, len(exams))
+	for i, exam := range exams {
+		examRecords[i] = exportExam(exam, planIDs[exam.ID])
+	}
+
+	goals, err := e.queries.ListGoals(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load goals: %w", err)
+	}
+
+	periods, err := e.queries.ListGoalPeriodsByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load goal periods: %w", err)
+	}
+
+	achievements, err := e.queries.ListUserAchievements(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load achievements: %w", err)
+	}
+
+	return []file{
+		{"manifest.json", map[string]any{"user_id": userID, "generated_at": time.Now().UTC()}},
+		{"profile.json", exportUser(user)},
+		{"study_plans.json", mapSlice(plans, exportStudyPlan)},
+		{"study_tasks.json", mapSlice(tasks, exportStudyTask)},
+		{"task_completions.json", mapSlice(completions, exportTaskCompletion)},
+		{"subjects.json", mapSlice(subjects, exportSubject)},
+		{"exams.json", examRecords},
+		{"goals.json", mapSlice(goals, exportGoal)},
+		{"goal_periods.json", mapSlice(periods, exportGoalPeriod)},
+		{"achievements.json", mapSlice(achievements, exportAchievement)},
+		{"study_activity.json", mapSlice(activity, exportStudyActivity)},
+		{"webhook_endpoints.json", mapSlice(endpoints, exportWebhookEndpoint)},
+		{"external_accounts.json", mapSlice(accounts, exportExternalAccount)},
+		{"sessions.json", mapSlice(sessions, exportSession)},
+		{"organizations.json", mapSlice(organizations, exportOrganization)},
+	}, nil
+}
@@ -0,0 +1,245 @@
+package exports
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// The records below flatten the store models into plain JSON, so null
+// columns come out as null instead of {"String": "", "Valid": false}.
+// Secrets, such as webhook signing keys, are left out.
+
+type record map[string]any
+
+func exportUser(u store.User) record {
+	return record{
+		"id":              u.ID,
+		"clerk_id":        u.ClerkID,
+		"email":           u.Email,
+		"name":            nullString(u.Name),
+		"first_name":      nullString(u.FirstName),
+		"last_name":       nullString(u.LastName),
+		"image_url":       nullString(u.ImageUrl),
+		"email_verified":  u.EmailVerified.Valid && u.EmailVerified.Bool,
+		"banned":          u.Banned.Valid && u.Banned.Bool,
+		"role":            u.Role,
+		"time_zone":       u.TimeZone,
+		"last_sign_in_at": nullTime(u.LastSignInAt),
+		"created_at":      u.CreatedAt,
+		"updated_at":      u.UpdatedAt,
+	}
+}
+
+func exportStudyPlan(p store.StudyPlan) record {
+	return record{
+		"id":          p.ID,
+		"title":       p.Title,
+		"subject":     p.Subject,
+		"description": nullString(p.Description),
+		"exam_date":   p.ExamDate.Format(time.DateOnly),
+		"start_date":  p.StartDate.Format(time.DateOnly),
+		"end_date":    p.EndDate.Format(time.DateOnly),
+		"created_at":  nullTime(p.CreatedAt),
+		"updated_at":  nullTime(p.UpdatedAt),
+	}
+}
+
+func exportStudyTask(t store.StudyTask) record {
+	r := record{
+		"id":            t.ID,
+		"plan_id":       nil,
+		"title":         t.Title,
+		"due_date":      t.DueDate.Format(time.DateOnly),
+		"is_completed":  t.IsCompleted.Valid && t.IsCompleted.Bool,
+		"completed_at":  nullTime(t.CompletedAt),
+		"priority":      t.Priority.Int32,
+		"notes":         nullString(t.Notes),
+		"subject_id":    nullUUID(t.SubjectID),
+		"minutes_spent": nullInt32(t.MinutesSpent),
+		"created_at":    nullTime(t.CreatedAt),
+		"updated_at":    nullTime(t.UpdatedAt),
+	}
+	if t.PlanID.Valid {
+		r["plan_id"] = t.PlanID.UUID
+	}
+	return r
+}
+
+func exportTaskCompletion(c store.TaskCompletion) record {
+	return record{
+		"task_id":      c.TaskID,
+		"completed_at": c.CompletedAt,
+	}
+}
+
+func exportSubject(s store.Subject) record {
+	return record{
+		"id":         s.ID,
+		"parent_id":  nullUUID(s.ParentID),
+		"kind":       s.Kind,
+		"name":       s.Name,
+		"color":      nullString(s.Color),
+		"icon":       nullString(s.Icon),
+		"created_at": s.CreatedAt,
+		"updated_at": s.UpdatedAt,
+	}
+}
+
+func exportExam(e store.Exam, planIDs []uuid.UUID) record {
+	if planIDs == nil {
+		planIDs = []uuid.UUID{}
+	}
+	return record{
+		"id":               e.ID,
+		"subject_id":       nullUUID(e.SubjectID),
+		"plan_ids":         planIDs,
+		"title":            e.Title,
+		"starts_at":        e.StartsAt,
+		"duration_minutes": nullInt32(e.DurationMinutes),
+		"location":         nullString(e.Location),
+		"format":           e.Format,
+		"weight":           nullFloat64(e.Weight),
+		"max_score":        e.MaxScore,
+		"target_score":     nullFloat64(e.TargetScore),
+		"actual_score":     nullFloat64(e.ActualScore),
+		"scored_at":        nullTime(e.ScoredAt),
+		"notes":            nullString(e.Notes),
+		"created_at":       e.CreatedAt,
+		"updated_at":       e.UpdatedAt,
+	}
+}
+
+func exportGoal(g store.Goal) record {
+	r := record{
+		"id":         g.ID,
+		"title":      g.Title,
+		"kind":       g.Kind,
+		"target":     g.Target,
+		"period":     g.Period,
+		"plan_id":    nullUUID(g.PlanID),
+		"subject_id": nullUUID(g.SubjectID),
+		"starts_on":  g.StartsOn.Format(time.DateOnly),
+		"ends_on":    nil,
+		"created_at": g.CreatedAt,
+		"updated_at": g.UpdatedAt,
+	}
+	if g.EndsOn.Valid {
+		r["ends_on"] = g.EndsOn.Time.Format(time.DateOnly)
+	}
+	return r
+}
+
+func exportGoalPeriod(p store.GoalPeriod) record {
+	return record{
+		"goal_id":   p.GoalID,
+		"starts_on": p.StartsOn.Format(time.DateOnly),
+		"ends_on":   p.EndsOn.Format(time.DateOnly),
+		"progress":  p.Progress,
+		"target":    p.Target,
+		"met":       p.Met,
+		"closed_at": p.ClosedAt,
+	}
+}
+
+func exportAchievement(a store.UserAchievement) record {
+	return record{
+		"badge":      a.Badge,
+		"awarded_at": a.AwardedAt,
+	}
+}
+
+func exportStudyActivity(a store.StudyActivity) record {
+	return record{
+		"date":            a.ActivityDate.Format(time.DateOnly),
+		"tasks_completed": a.TasksCompleted,
+	}
+}
+
+func exportWebhookEndpoint(e store.WebhookEndpoint) record {
+	return record{
+		"id":          e.ID,
+		"url":         e.Url,
+		"description": nullString(e.Description),
+		"event_types": e.EventTypes,
+		"enabled":     e.Enabled,
+		"created_at":  e.CreatedAt,
+		"updated_at":  e.UpdatedAt,
+	}
+}
+
+func exportExternalAccount(a store.UserExternalAccount) record {
+	return record{
+		"id":               a.ID,
+		"provider":         a.Provider,
+		"provider_user_id": nullString(a.ProviderUserID),
+		"email_address":    nullString(a.EmailAddress),
+		"first_name":       nullString(a.FirstName),
+		"last_name":        nullString(a.LastName),
+		"image_url":        nullString(a.ImageUrl),
+		"created_at":       a.CreatedAt,
+	}
+}
+
+func exportSession(s store.UserSession) record {
+	return record{
+		"id":             s.ID,
+		"status":         s.Status,
+		"last_active_at": nullTime(s.LastActiveAt),
+		"ended_at":       nullTime(s.EndedAt),
+		"created_at":     s.CreatedAt,
+	}
+}
+
+func exportOrganization(o store.Organization) record {
+	return record{
+		"id":   o.ClerkID,
+		"name": o.Name,
+		"slug": nullString(o.Slug),
+	}
+}
+
+func mapSlice[T any](items []T, fn func(T) record) []record {
+	records := make([]record, len(items))
+	for i, item := range items {
+		records[i] = fn(item)
+	}
+	return records
+}
+
+func nullString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullUUID(u uuid.NullUUID) *uuid.UUID {
+	if !u.Valid {
+		return nil
+	}
+	return &u.UUID
+}
+
+func nullInt32(n sql.NullInt32) *int32 {
+	if !n.Valid {
+		return nil
+	}
+	return &n.Int32
+}
+
+func nullFloat64(f sql.NullFloat64) *float64 {
+	if !f.Valid {
+		return nil
+	}
+	return &f.Float64
+}
@@ -0,0 +1,166 @@
+// Package goals measures progress on study goals and keeps their history.
+//
+// A goal has a target to reach every day, week or month, or once by a
+// deadline. Progress is computed from the tasks the user completed in the
+// period, so nothing has to be logged against the goal itself. When a
+// period ends, the Tracker records whether the goal was met.
+package goals
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Goal kinds: what the target counts
+const (
+	// KindStudyMinutes counts the minutes spent on tasks completed in the
+	// period
+	KindStudyMinutes = "study_minutes"
+	// KindTasksCompleted counts the tasks completed in the period
+	KindTasksCompleted = "tasks_completed"
+	// KindPlanCompletion is the percent of a plan's tasks completed by the
+	// end of the goal
+	KindPlanCompletion = "plan_completion"
+)
+
+// Goal periods. Weeks start on Monday; once goals run from their start to
+// their end date.
+const (
+	PeriodDaily   = "daily"
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+	PeriodOnce    = "once"
+)
+
+// Milestones are the shares of a period's target, in percent, that are
+// recorded the first time progress reaches them
+var Milestones = []int32{25, 50, 75, 100}
+
+// Window is one period of a goal, from StartsOn to EndsOn inclusive
+type Window struct {
+	StartsOn time.Time
+	EndsOn   time.Time
+}
+
+// Progress is how far a goal got in a window
+type Progress struct {
+	Window
+	Value   int64
+	Target  int32
+	Percent int32
+	Met     bool
+}
+
+// Date returns the UTC day of t at midnight, as DATE columns are read
+func Date(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Today returns the day it is at now in the user's time zone, at midnight
+// UTC like Date. Periods are counted in the user's days.
+func Today(now time.Time, loc *time.Location) time.Time {
+	year, month, day := now.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Location loads the time zone of a user, falling back to UTC
+func Location(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil || name == "" {
+		return time.UTC
+	}
+	return loc
+}
+
+// WindowAt returns the period of the goal that contains day, trimmed to the
+// goal's start and end dates. ok is false when the goal does not run on
+// that day.
+func WindowAt(goal store.Goal, day time.Time) (window Window, ok bool) {
+	day = Date(day)
+	startsOn := Date(goal.StartsOn)
+	if day.Before(startsOn) || (goal.EndsOn.Valid && day.After(Date(goal.EndsOn.Time))) {
+		return Window{}, false
+	}
+
+	switch goal.Period {
+	case PeriodDaily:
+		window = Window{StartsOn: day, EndsOn: day}
+	case PeriodWeekly:
+		monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		window = Window{StartsOn: monday, EndsOn: monday.AddDate(0, 0, 6)}
+	case PeriodMonthly:
+		first := day.AddDate(0, 0, 1-day.Day())
+		window = Window{StartsOn: first, EndsOn: first.AddDate(0, 1, -1)}
+	default:
+		window = Window{StartsOn: startsOn, EndsOn: Date(goal.EndsOn.Time)}
+	}
+
+	if window.StartsOn.Before(startsOn) {
+		window.StartsOn = startsOn
+	}
+	if goal.EndsOn.Valid && window.EndsOn.After(Date(goal.EndsOn.Time)) {
+		window.EndsOn = Date(goal.EndsOn.Time)
+	}
+	return window, true
+}
+
+// Measure computes the progress of the goal over the window
+func Measure(ctx context.Context, queries *store.Queries, goal store.Goal, window Window) (Progress, error) {
+	params := store.GetGoalProgressParams{
+		SubjectID: goal.SubjectID,
+		UserID:    goal.UserID,
+		PlanID:    goal.PlanID,
+		FromDate:  window.StartsOn,
+		ToDate:    window.EndsOn,
+	}
+	if goal.Kind == KindPlanCompletion {
+		params.SubjectID = uuid.NullUUID{}
+	}
+
+	row, err := queries.GetGoalProgress(ctx, params)
+	if err != nil {
+		return Progress{}, err
+	}
+
+	progress := Progress{Window: window, Target: goal.Target}
+	switch goal.Kind {
+	case KindStudyMinutes:
+		progress.Value = row.MinutesStudied
+	case KindTasksCompleted:
+		progress.Value = row.TasksCompleted
+	case KindPlanCompletion:
+		if row.PlanTasks > 0 {
+			progress.Value = 100 * row.CompletedByEnd / row.PlanTasks
+		}
+	}
+	progress.Percent = int32(100 * progress.Value / int64(goal.Target))
+	progress.Met = progress.Value >= int64(goal.Target)
+	return progress, nil
+}
+
+// RecordMilestones stores the milestones the progress has reached and
+// returns the ones reached for the first time in its window
+func RecordMilestones(ctx context.Context, queries *store.Queries, goal store.Goal, progress Progress) ([]int32, error) {
+	var reached []int32
+	for _, percent := range Milestones {
+		if progress.Percent < percent {
+			break
+		}
+		added, err := queries.AddGoalMilestone(ctx, store.AddGoalMilestoneParams{
+			GoalID:         goal.ID,
+			PeriodStartsOn: progress.StartsOn,
+			Percent:        percent,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if added > 0 {
+			reached = append(reached, percent)
+		}
+	}
+	return reached, nil
+}
@@ -0,0 +1,182 @@
+package goals
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+func day(s string) time.Time {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestWindowAt(t *testing.T) {
+	goal := func(period, startsOn, endsOn string) store.Goal {
+		g := store.Goal{Period: period, StartsOn: day(startsOn)}
+		if endsOn != "" {
+			g.EndsOn = sql.NullTime{Time: day(endsOn), Valid: true}
+		}
+		return g
+	}
+
+	tests := []struct {
+		name   string
+		goal   store.Goal
+		at     time.Time
+		want   Window
+		wantOK bool
+	}{
+		{
+			name:   "daily",
+			goal:   goal(PeriodDaily, "2026-01-01", ""),
+			at:     day("2026-03-04").Add(23 * time.Hour),
+			want:   Window{StartsOn: day("2026-03-04"), EndsOn: day("2026-03-04")},
+			wantOK: true,
+		},
+		{
+			name:   "time zones are read as UTC",
+			goal:   goal(PeriodDaily, "2026-01-01", ""),
+			at:     time.Date(2026, time.March, 4, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60)),
+			want:   Window{StartsOn: day("2026-03-05"), EndsOn: day("2026-03-05")},
+			wantOK: true,
+		},
+		{
+			name:   "week from a Wednesday",
+			goal:   goal(PeriodWeekly, "2026-01-01", ""),
+			at:     day("2026-03-04"),
+			want:   Window{StartsOn: day("2026-03-02"), EndsOn: day("2026-03-08")},
+			wantOK: true,
+		},
+		{
+			name:   "week from a Sunday",
+			goal:   goal(PeriodWeekly, "2026-01-01", ""),
+			at:     day("2026-03-08"),
+			want:   Window{StartsOn: day("2026-03-02"), EndsOn: day("2026-03-08")},
+			wantOK: true,
+		},
+		{
+			name:   "week from a Monday",
+			goal:   goal(PeriodWeekly, "2026-01-01", ""),
+			at:     day("2026-03-09"),
+			want:   Window{StartsOn: day("2026-03-09"), EndsOn: day("2026-03-15")},
+			wantOK: true,
+		},
+		{
+			name:   "week across a year end",
+			goal:   goal(PeriodWeekly, "2026-01-01", ""),
+			at:     day("2027-01-01"),
+			want:   Window{StartsOn: day("2026-12-28"), EndsOn: day("2027-01-03")},
+			wantOK: true,
+		},
+		{
+			name:   "month of 31 days",
+			goal:   goal(PeriodMonthly, "2026-01-01", ""),
+			at:     day("2026-01-31"),
+			want:   Window{StartsOn: day("2026-01-01"), EndsOn: day("2026-01-31")},
+			wantOK: true,
+		},
+		{
+			name:   "February",
+			goal:   goal(PeriodMonthly, "2026-01-01", ""),
+			at:     day("2026-02-15"),
+			want:   Window{StartsOn: day("2026-02-01"), EndsOn: day("2026-02-28")},
+			wantOK: true,
+		},
+		{
+			name:   "February of a leap year",
+			goal:   goal(PeriodMonthly, "2026-01-01", ""),
+			at:     day("2028-02-29"),
+			want:   Window{StartsOn: day("2028-02-01"), EndsOn: day("2028-02-29")},
+			wantOK: true,
+		},
+		{
+			name:   "once",
+			goal:   goal(PeriodOnce, "2026-03-04", "2026-04-10"),
+			at:     day("2026-03-20"),
+			want:   Window{StartsOn: day("2026-03-04"), EndsOn: day("2026-04-10")},
+			wantOK: true,
+		},
+		{
+			name:   "first week clipped to the start",
+			goal:   goal(PeriodWeekly, "2026-03-04", ""),
+			at:     day("2026-03-06"),
+			want:   Window{StartsOn: day("2026-03-04"), EndsOn: day("2026-03-08")},
+			wantOK: true,
+		},
+		{
+			name:   "last month clipped to the end",
+			goal:   goal(PeriodMonthly, "2026-01-01", "2026-03-10"),
+			at:     day("2026-03-10"),
+			want:   Window{StartsOn: day("2026-03-01"), EndsOn: day("2026-03-10")},
+			wantOK: true,
+		},
+		{
+			name:   "clipped at both ends",
+			goal:   goal(PeriodMonthly, "2026-03-04", "2026-03-20"),
+			at:     day("2026-03-10"),
+			want:   Window{StartsOn: day("2026-03-04"), EndsOn: day("2026-03-20")},
+			wantOK: true,
+		},
+		{
+			name: "before the start",
+			goal: goal(PeriodDaily, "2026-03-04", ""),
+			at:   day("2026-03-03"),
+		},
+		{
+			name: "after the end",
+			goal: goal(PeriodWeekly, "2026-03-04", "2026-03-20"),
+			at:   day("2026-03-21"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := WindowAt(tt.goal, tt.at)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !got.StartsOn.Equal(tt.want.StartsOn) || !got.EndsOn.Equal(tt.want.EndsOn) {
+				t.Errorf("WindowAt = %s to %s, want %s to %s",
+					got.StartsOn.Format(time.DateOnly), got.EndsOn.Format(time.DateOnly),
+					tt.want.StartsOn.Format(time.DateOnly), tt.want.EndsOn.Format(time.DateOnly))
+			}
+		})
+	}
+}
+
+func TestToday(t *testing.T) {
+	// 23:30 on a Sunday in UTC
+	now := time.Date(2025, 3, 2, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		zone string
+		want time.Time
+	}{
+		{zone: "UTC", want: day("2025-03-02")},
+		{zone: "Europe/Paris", want: day("2025-03-03")},
+		{zone: "Pacific/Kiritimati", want: day("2025-03-03")},
+		{zone: "America/Los_Angeles", want: day("2025-03-02")},
+		{zone: "", want: day("2025-03-02")},
+		{zone: "Nowhere/Special", want: day("2025-03-02")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			got := Today(now, Location(tt.zone))
+			if !got.Equal(tt.want) {
+				t.Errorf("Today = %s, want %s", got.Format(time.DateOnly), tt.want.Format(time.DateOnly))
+			}
+		})
+	}
+
+	// A weekly goal rolls over to the next week on Paris's Monday
+	goal := store.Goal{Period: PeriodWeekly, StartsOn: day("2025-01-01")}
+	window, _ := WindowAt(goal, Today(now, Location("Europe/Paris")))
+	if !window.StartsOn.Equal(day("2025-03-03")) {
+		t.Errorf("week starts on %s, want 2025-03-03", window.StartsOn.Format(time.DateOnly))
+	}
+}
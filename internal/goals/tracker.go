@@ -0,0 +1,143 @@
+package goals
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+const trackInterval = time.Hour
+
+// maxPeriodsPerRun bounds how many periods of one goal are closed in a run,
+// so a daily goal left behind for long catches up over several runs
+const maxPeriodsPerRun = 400
+
+// Tracker records the outcome of goal periods once they have ended
+type Tracker struct {
+	queries *store.Queries
+
+	// OnPeriodMet, when set, is called after a period that met its target
+	// is recorded
+	OnPeriodMet func(ctx context.Context, goal store.Goal)
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewTracker creates a new Tracker
+func NewTracker(db *sql.DB) *Tracker {
+	return &Tracker{
+		queries: store.New(db),
+		done:    make(chan struct{}),
+	}
+}
+
+// Start begins closing ended periods
+func (t *Tracker) Start() {
+	t.wg.Add(1)
+	go t.loop()
+}
+
+// Close stops the background worker
+func (t *Tracker) Close() {
+	close(t.done)
+	t.wg.Wait()
+}
+
+func (t *Tracker) loop() {
+	defer t.wg.Done()
+
+	t.closePeriods()
+
+	ticker := time.NewTicker(trackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.done:
+			return
+		case <-ticker.C:
+			t.closePeriods()
+		}
+	}
+}
+
+// closePeriods records every period that ended before the user's today and
+// has not been recorded yet. A period is only recorded once, so running on several
+// replicas is safe.
+func (t *Tracker) closePeriods() {
+	ctx := context.Background()
+
+	rows, err := t.queries.ListGoalsToClose(ctx)
+	if err != nil {
+		log.Printf("goals: failed to list goals: %v", err)
+		return
+	}
+
+	now := time.Now()
+	closed := 0
+	for _, row := range rows {
+		today := Today(now, Location(row.TimeZone))
+		goal := store.Goal{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			Title:     row.Title,
+			Kind:      row.Kind,
+			Target:    row.Target,
+			Period:    row.Period,
+			PlanID:    row.PlanID,
+			SubjectID: row.SubjectID,
+			StartsOn:  row.StartsOn,
+			EndsOn:    row.EndsOn,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+		}
+
+		day := goal.StartsOn
+		if row.ClosedThrough.Valid {
+			day = row.ClosedThrough.Time.AddDate(0, 0, 1)
+		}
+
+		for i := 0; i < maxPeriodsPerRun; i++ {
+			window, ok := WindowAt(goal, day)
+			if !ok || !window.EndsOn.Before(today) {
+				break
+			}
+
+			progress, err := Measure(ctx, t.queries, goal, window)
+			if err != nil {
+				log.Printf("goals: failed to measure goal %s: %v", goal.ID, err)
+				break
+			}
+			if err := t.queries.CloseGoalPeriod(ctx, store.CloseGoalPeriodParams{
+				GoalID:   goal.ID,
+				StartsOn: window.StartsOn,
+				EndsOn:   window.EndsOn,
+				Progress: progress.Value,
+				Target:   progress.Target,
+				Met:      progress.Met,
+			}); err != nil {
+				log.Printf("goals: failed to close a period of goal %s: %v", goal.ID, err)
+				break
+			}
+			// Milestones missed while the period ran are recorded, unannounced
+			if _, err := RecordMilestones(ctx, t.queries, goal, progress); err != nil {
+				log.Printf("goals: failed to record the milestones of goal %s: %v", goal.ID, err)
+			}
+
+			closed++
+			if progress.Met && t.OnPeriodMet != nil {
+				t.OnPeriodMet(ctx, goal)
+			}
+			day = window.EndsOn.AddDate(0, 0, 1)
+		}
+	}
+
+	if closed > 0 {
+		log.Printf("goals: closed %d goal periods", closed)
+	}
+}
@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often a MemoryStore drops full buckets. A
+// bucket that has refilled holds nothing a new one would not, so they can
+// go as soon as that happens.
+const memorySweepInterval = time.Minute
+
+// maxMemoryKeys caps the buckets a MemoryStore holds, so that requests from
+// many addresses cannot grow it without bound
+const maxMemoryKeys = 100_000
+
+// MemoryStore keeps buckets in the process. Each replica enforces its own
+// limits, so use PostgresStore when running more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	maxKeys   int
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket will have refilled
+	full time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*bucket),
+		maxKeys:   maxMemoryKeys,
+		lastSweep: time.Now(),
+	}
+}
+
+// Take takes a token from the bucket of key if one is left
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	return s.take(key, limit, time.Now()), nil
+}
+
+func (s *MemoryStore) take(key string, limit Limit, now time.Time) Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > memorySweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		s.makeRoom(now)
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = refill(limit, b.tokens, b.updated, now)
+	b.updated = now
+
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	result := newResult(limit, allowed, b.tokens)
+	b.full = now.Add(result.Reset)
+	return result
+}
+
+// sweep drops the buckets that have refilled. The caller holds the lock.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
+
+// makeRoom keeps the store under maxKeys before a bucket is added. When no
+// bucket has refilled, an arbitrary one is dropped, which at worst gives its
+// key a full budget early. The caller holds the lock.
+func (s *MemoryStore) makeRoom(now time.Time) {
+	if len(s.buckets) < s.maxKeys {
+		return
+	}
+	s.sweep(now)
+	for key := range s.buckets {
+		if len(s.buckets) < s.maxKeys {
+			break
+		}
+		delete(s.buckets, key)
+	}
+}
@@ -0,0 +1,65 @@
+package ratelimit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	s := NewMemoryStore()
+	limit := PerMinute(2)
+	start := time.Now()
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  bool
+	}{
+		{name: "first", want: true},
+		{name: "second", want: true},
+		{name: "spent", want: false},
+		{name: "one token back", after: 30 * time.Second, want: true},
+		{name: "spent again", after: 30 * time.Second, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.take("key", limit, start.Add(tt.after)).Allowed; got != tt.want {
+				t.Errorf("Allowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	s := NewMemoryStore()
+	now := s.lastSweep
+
+	s.take("quick", PerMinute(60), now)
+	s.take("slow", PerHour(5), now)
+
+	// One token of the per-minute bucket comes back within the second
+	s.take("other", PerMinute(60), now.Add(2*memorySweepInterval))
+	if _, ok := s.buckets["quick"]; ok {
+		t.Error("the refilled bucket was kept")
+	}
+	if _, ok := s.buckets["slow"]; !ok {
+		t.Error("the bucket still refilling was dropped")
+	}
+}
+
+func TestMemoryStoreMaxKeys(t *testing.T) {
+	s := NewMemoryStore()
+	s.maxKeys = 10
+	now := s.lastSweep
+
+	for i := range 25 {
+		s.take(fmt.Sprintf("ip:%d", i), PerHour(5), now)
+		if len(s.buckets) > s.maxKeys {
+			t.Fatalf("%d buckets after %d keys, want at most %d", len(s.buckets), i+1, s.maxKeys)
+		}
+	}
+	if _, ok := s.buckets["ip:24"]; !ok {
+		t.Error("the newest bucket was not added")
+	}
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every
+// replica shares the same limits. Each request takes one round trip.
+type PostgresStore struct {
+	queries *store.Queries
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewPostgresStore creates a PostgresStore
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{
+		queries:   store.New(db),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take takes a token from the bucket of key if one is left. The bucket row
+// is locked while it is refilled, so concurrent requests are counted once.
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	s.maybeSweep()
+
+	row, err := s.queries.TakeRateLimitToken(ctx, store.TakeRateLimitTokenParams{
+		Capacity:   float64(limit.Burst),
+		RefillRate: limit.rate(),
+		Key:        key,
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	return newResult(limit, row.Allowed, row.Remaining), nil
+}
+
+// maybeSweep deletes stale buckets in the background, at most once per
+// sweepInterval on each replica
+func (s *PostgresStore) maybeSweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = time.Now()
+
+	go func() {
+		n, err := s.queries.DeleteStaleRateLimitBuckets(context.Background(), time.Now().Add(-staleAfter))
+		if err != nil {
+			log.Printf("ratelimit: failed to delete stale buckets: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("ratelimit: deleted %d stale buckets", n)
+		}
+	}()
+}
@@ -0,0 +1,84 @@
+// Package ratelimit implements token bucket rate limits with stores that
+// keep the buckets in memory or in Postgres.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// staleAfter is how long PostgresStore keeps an untouched bucket. It must
+// be longer than the period of every limit, after which a bucket is full
+// anyway.
+const staleAfter = 24 * time.Hour
+
+// sweepInterval is how often PostgresStore drops stale buckets
+const sweepInterval = 10 * time.Minute
+
+// Limit allows bursts of up to Burst requests and refills at Burst requests
+// per Period
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// PerMinute allows n requests a minute
+func PerMinute(n int) Limit {
+	return Limit{Burst: n, Period: time.Minute}
+}
+
+// PerHour allows n requests an hour
+func PerHour(n int) Limit {
+	return Limit{Burst: n, Period: time.Hour}
+}
+
+// rate is the refill rate in tokens per second
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Policy describes the limit for the RateLimit-Policy header, e.g. "100;w=60"
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Burst, int(l.Period.Seconds()))
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store takes tokens from the bucket of a key
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// newResult describes a bucket left with tokens after a request
+func newResult(limit Limit, allowed bool, tokens float64) Result {
+	result := Result{
+		Allowed:   allowed,
+		Limit:     limit.Burst,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     secondsToDuration((float64(limit.Burst) - tokens) / limit.rate()),
+	}
+	if !allowed {
+		result.RetryAfter = secondsToDuration((1 - tokens) / limit.rate())
+	}
+	return result
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(math.Max(seconds, 0) * float64(time.Second))
+}
+
+// refill is the tokens in a bucket that had tokens at updated
+func refill(limit Limit, tokens float64, updated, now time.Time) float64 {
+	return math.Min(float64(limit.Burst), tokens+now.Sub(updated).Seconds()*limit.rate())
+}
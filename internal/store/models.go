@@ -6,34 +6,297 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-type StudyPlan struct {
+type ActivityLog struct {
+	ID         int64           `json:"id"`
+	UserID     string          `json:"user_id"`
+	ActorID    string          `json:"actor_id"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type Classroom struct {
+	ID             uuid.UUID      `json:"id"`
+	TeacherID      string         `json:"teacher_id"`
+	OrganizationID sql.NullString `json:"organization_id"`
+	TemplatePlanID uuid.UUID      `json:"template_plan_id"`
+	Name           string         `json:"name"`
+	Description    sql.NullString `json:"description"`
+	JoinCode       string         `json:"join_code"`
+	PublishedAt    sql.NullTime   `json:"published_at"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+type ClassroomStudent struct {
+	ClassroomID uuid.UUID `json:"classroom_id"`
+	UserID      string    `json:"user_id"`
+	JoinedAt    time.Time `json:"joined_at"`
+}
+
+type DataExport struct {
 	ID          uuid.UUID      `json:"id"`
 	UserID      string         `json:"user_id"`
-	Title       string         `json:"title"`
-	Subject     string         `json:"subject"`
-	Description sql.NullString `json:"description"`
-	ExamDate    time.Time      `json:"exam_date"`
-	StartDate   time.Time      `json:"start_date"`
-	EndDate     time.Time      `json:"end_date"`
-	CreatedAt   sql.NullTime   `json:"created_at"`
-	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	Status      string         `json:"status"`
+	Archive     []byte         `json:"archive"`
+	SizeBytes   sql.NullInt64  `json:"size_bytes"`
+	Error       sql.NullString `json:"error"`
+	StartedAt   sql.NullTime   `json:"started_at"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+	ExpiresAt   sql.NullTime   `json:"expires_at"`
+	CreatedAt   time.Time      `json:"created_at"`
 }
 
-type StudyTask struct {
+type Event struct {
+	ID        int64           `json:"id"`
+	UserID    string          `json:"user_id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type Exam struct {
+	ID              uuid.UUID       `json:"id"`
+	UserID          string          `json:"user_id"`
+	SubjectID       uuid.NullUUID   `json:"subject_id"`
+	Title           string          `json:"title"`
+	StartsAt        time.Time       `json:"starts_at"`
+	DurationMinutes sql.NullInt32   `json:"duration_minutes"`
+	Location        sql.NullString  `json:"location"`
+	Format          string          `json:"format"`
+	Weight          sql.NullFloat64 `json:"weight"`
+	MaxScore        float64         `json:"max_score"`
+	TargetScore     sql.NullFloat64 `json:"target_score"`
+	ActualScore     sql.NullFloat64 `json:"actual_score"`
+	Notes           sql.NullString  `json:"notes"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+	ScoredAt        sql.NullTime    `json:"scored_at"`
+}
+
+type Goal struct {
+	ID        uuid.UUID     `json:"id"`
+	UserID    string        `json:"user_id"`
+	Title     string        `json:"title"`
+	Kind      string        `json:"kind"`
+	Target    int32         `json:"target"`
+	Period    string        `json:"period"`
+	PlanID    uuid.NullUUID `json:"plan_id"`
+	SubjectID uuid.NullUUID `json:"subject_id"`
+	StartsOn  time.Time     `json:"starts_on"`
+	EndsOn    sql.NullTime  `json:"ends_on"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+type GoalMilestone struct {
+	GoalID         uuid.UUID `json:"goal_id"`
+	PeriodStartsOn time.Time `json:"period_starts_on"`
+	Percent        int32     `json:"percent"`
+	ReachedAt      time.Time `json:"reached_at"`
+}
+
+type GoalPeriod struct {
+	GoalID   uuid.UUID `json:"goal_id"`
+	StartsOn time.Time `json:"starts_on"`
+	EndsOn   time.Time `json:"ends_on"`
+	Progress int64     `json:"progress"`
+	Target   int32     `json:"target"`
+	Met      bool      `json:"met"`
+	ClosedAt time.Time `json:"closed_at"`
+}
+
+type ImpersonationSession struct {
+	ID        uuid.UUID    `json:"id"`
+	AdminID   string       `json:"admin_id"`
+	UserID    string       `json:"user_id"`
+	Reason    string       `json:"reason"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	EndedAt   sql.NullTime `json:"ended_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type Organization struct {
+	ID        uuid.UUID      `json:"id"`
+	ClerkID   string         `json:"clerk_id"`
+	Name      string         `json:"name"`
+	Slug      sql.NullString `json:"slug"`
+	ImageUrl  sql.NullString `json:"image_url"`
+	CreatedBy sql.NullString `json:"created_by"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type OrganizationMembership struct {
+	ID                  uuid.UUID `json:"id"`
+	ClerkID             string    `json:"clerk_id"`
+	OrganizationClerkID string    `json:"organization_clerk_id"`
+	UserClerkID         string    `json:"user_clerk_id"`
+	Role                string    `json:"role"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type PersonalAccessToken struct {
+	ID          uuid.UUID    `json:"id"`
+	UserID      string       `json:"user_id"`
+	Name        string       `json:"name"`
+	TokenPrefix string       `json:"token_prefix"`
+	TokenHash   string       `json:"token_hash"`
+	Scopes      []string     `json:"scopes"`
+	ExpiresAt   sql.NullTime `json:"expires_at"`
+	LastUsedAt  sql.NullTime `json:"last_used_at"`
+	RevokedAt   sql.NullTime `json:"revoked_at"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type PlanExam struct {
+	PlanID    uuid.UUID `json:"plan_id"`
+	ExamID    uuid.UUID `json:"exam_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type PlanInvite struct {
 	ID          uuid.UUID      `json:"id"`
-	PlanID      uuid.NullUUID  `json:"plan_id"`
-	Title       string         `json:"title"`
-	DueDate     time.Time      `json:"due_date"`
-	IsCompleted sql.NullBool   `json:"is_completed"`
-	Priority    sql.NullInt32  `json:"priority"`
-	Notes       sql.NullString `json:"notes"`
-	CreatedAt   sql.NullTime   `json:"created_at"`
-	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	PlanID      uuid.UUID      `json:"plan_id"`
+	Role        string         `json:"role"`
+	Email       sql.NullString `json:"email"`
+	TokenPrefix sql.NullString `json:"token_prefix"`
+	TokenHash   sql.NullString `json:"token_hash"`
+	InvitedBy   string         `json:"invited_by"`
+	ExpiresAt   sql.NullTime   `json:"expires_at"`
+	AcceptedBy  sql.NullString `json:"accepted_by"`
+	AcceptedAt  sql.NullTime   `json:"accepted_at"`
+	RevokedAt   sql.NullTime   `json:"revoked_at"`
+	CreatedAt   time.Time      `json:"created_at"`
+	MaxUses     sql.NullInt32  `json:"max_uses"`
+	Uses        int32          `json:"uses"`
+}
+
+type PlanMember struct {
+	PlanID    uuid.UUID      `json:"plan_id"`
+	UserID    string         `json:"user_id"`
+	Role      string         `json:"role"`
+	InvitedBy sql.NullString `json:"invited_by"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type PlanShareLink struct {
+	ID           uuid.UUID    `json:"id"`
+	PlanID       uuid.UUID    `json:"plan_id"`
+	CreatedBy    string       `json:"created_by"`
+	TokenPrefix  string       `json:"token_prefix"`
+	TokenHash    string       `json:"token_hash"`
+	ExpiresAt    sql.NullTime `json:"expires_at"`
+	RevokedAt    sql.NullTime `json:"revoked_at"`
+	ViewCount    int64        `json:"view_count"`
+	LastViewedAt sql.NullTime `json:"last_viewed_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+type PlanTemplate struct {
+	ID           uuid.UUID      `json:"id"`
+	Slug         sql.NullString `json:"slug"`
+	AuthorID     sql.NullString `json:"author_id"`
+	Title        string         `json:"title"`
+	Subject      string         `json:"subject"`
+	Description  sql.NullString `json:"description"`
+	DurationDays int32          `json:"duration_days"`
+	Tags         []string       `json:"tags"`
+	IsOfficial   bool           `json:"is_official"`
+	PublishedAt  sql.NullTime   `json:"published_at"`
+	UsageCount   int64          `json:"usage_count"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+type PlanTemplateTask struct {
+	ID         uuid.UUID      `json:"id"`
+	TemplateID uuid.UUID      `json:"template_id"`
+	Position   int32          `json:"position"`
+	Title      string         `json:"title"`
+	DayOffset  int32          `json:"day_offset"`
+	Priority   sql.NullInt32  `json:"priority"`
+	Notes      sql.NullString `json:"notes"`
+}
+
+type RateLimitBucket struct {
+	Key       string    `json:"key"`
+	Tokens    float64   `json:"tokens"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type StudyActivity struct {
+	UserID         string    `json:"user_id"`
+	ActivityDate   time.Time `json:"activity_date"`
+	TasksCompleted int32     `json:"tasks_completed"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type StudyPlan struct {
+	ID           uuid.UUID      `json:"id"`
+	UserID       string         `json:"user_id"`
+	Title        string         `json:"title"`
+	Subject      string         `json:"subject"`
+	Description  sql.NullString `json:"description"`
+	ExamDate     time.Time      `json:"exam_date"`
+	StartDate    time.Time      `json:"start_date"`
+	EndDate      time.Time      `json:"end_date"`
+	CreatedAt    sql.NullTime   `json:"created_at"`
+	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	DeletedAt    sql.NullTime   `json:"deleted_at"`
+	Version      int32          `json:"version"`
+	ClassroomID  uuid.NullUUID  `json:"classroom_id"`
+	SourcePlanID uuid.NullUUID  `json:"source_plan_id"`
+	SyncedHash   sql.NullString `json:"synced_hash"`
+	SubjectID    uuid.NullUUID  `json:"subject_id"`
+}
+
+type StudyTask struct {
+	ID           uuid.UUID      `json:"id"`
+	PlanID       uuid.NullUUID  `json:"plan_id"`
+	Title        string         `json:"title"`
+	DueDate      time.Time      `json:"due_date"`
+	IsCompleted  sql.NullBool   `json:"is_completed"`
+	Priority     sql.NullInt32  `json:"priority"`
+	Notes        sql.NullString `json:"notes"`
+	CreatedAt    sql.NullTime   `json:"created_at"`
+	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	DeletedAt    sql.NullTime   `json:"deleted_at"`
+	Version      int32          `json:"version"`
+	CreatedBy    sql.NullString `json:"created_by"`
+	SourceTaskID uuid.NullUUID  `json:"source_task_id"`
+	SyncedHash   sql.NullString `json:"synced_hash"`
+	SubjectID    uuid.NullUUID  `json:"subject_id"`
+	MinutesSpent sql.NullInt32  `json:"minutes_spent"`
+	CompletedAt  sql.NullTime   `json:"completed_at"`
+}
+
+type Subject struct {
+	ID        uuid.UUID      `json:"id"`
+	UserID    string         `json:"user_id"`
+	ParentID  uuid.NullUUID  `json:"parent_id"`
+	Kind      string         `json:"kind"`
+	Name      string         `json:"name"`
+	Color     sql.NullString `json:"color"`
+	Icon      sql.NullString `json:"icon"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+type TaskCompletion struct {
+	TaskID      uuid.UUID `json:"task_id"`
+	UserID      string    `json:"user_id"`
+	CompletedAt time.Time `json:"completed_at"`
 }
 
 type User struct {
@@ -49,4 +312,95 @@ type User struct {
 	EmailVerified sql.NullBool   `json:"email_verified"`
 	LastSignInAt  sql.NullTime   `json:"last_sign_in_at"`
 	Banned        sql.NullBool   `json:"banned"`
+	Role          string         `json:"role"`
+	TimeZone      string         `json:"time_zone"`
+}
+
+type UserAchievement struct {
+	UserID    string    `json:"user_id"`
+	Badge     string    `json:"badge"`
+	AwardedAt time.Time `json:"awarded_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserExternalAccount struct {
+	ID             string         `json:"id"`
+	UserClerkID    string         `json:"user_clerk_id"`
+	Provider       string         `json:"provider"`
+	ProviderUserID sql.NullString `json:"provider_user_id"`
+	EmailAddress   sql.NullString `json:"email_address"`
+	FirstName      sql.NullString `json:"first_name"`
+	LastName       sql.NullString `json:"last_name"`
+	ImageUrl       sql.NullString `json:"image_url"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+type UserSession struct {
+	ID           string       `json:"id"`
+	UserClerkID  string       `json:"user_clerk_id"`
+	Status       string       `json:"status"`
+	LastActiveAt sql.NullTime `json:"last_active_at"`
+	EndedAt      sql.NullTime `json:"ended_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type UserTombstone struct {
+	ClerkID   string          `json:"clerk_id"`
+	Source    string          `json:"source"`
+	Summary   json.RawMessage `json:"summary"`
+	DeletedAt time.Time       `json:"deleted_at"`
+}
+
+type WebhookDelivery struct {
+	ID               uuid.UUID       `json:"id"`
+	EndpointID       uuid.UUID       `json:"endpoint_id"`
+	MessageID        string          `json:"message_id"`
+	EventType        string          `json:"event_type"`
+	Payload          json.RawMessage `json:"payload"`
+	DedupeKey        sql.NullString  `json:"dedupe_key"`
+	Status           string          `json:"status"`
+	Attempts         int32           `json:"attempts"`
+	LastResponseCode sql.NullInt32   `json:"last_response_code"`
+	NextAttemptAt    time.Time       `json:"next_attempt_at"`
+	DeliveredAt      sql.NullTime    `json:"delivered_at"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+}
+
+type WebhookDeliveryAttempt struct {
+	ID           uuid.UUID      `json:"id"`
+	DeliveryID   uuid.UUID      `json:"delivery_id"`
+	ResponseCode sql.NullInt32  `json:"response_code"`
+	Error        sql.NullString `json:"error"`
+	DurationMs   int32          `json:"duration_ms"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+type WebhookEndpoint struct {
+	ID                  uuid.UUID      `json:"id"`
+	UserID              string         `json:"user_id"`
+	Url                 string         `json:"url"`
+	Description         sql.NullString `json:"description"`
+	Secret              string         `json:"secret"`
+	EventTypes          []string       `json:"event_types"`
+	Enabled             bool           `json:"enabled"`
+	ConsecutiveFailures int32          `json:"consecutive_failures"`
+	DisabledAt          sql.NullTime   `json:"disabled_at"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+}
+
+type WebhookEvent struct {
+	SvixID      string          `json:"svix_id"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	LastError   sql.NullString  `json:"last_error"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt sql.NullTime    `json:"processed_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	ClaimedAt   sql.NullTime    `json:"claimed_at"`
 }
@@ -14,19 +14,20 @@ import (
 )
 
 const createStudyPlan = `-- name: CreateStudyPlan :one
-INSERT INTO study_plans (user_id, title, subject, description, exam_date, start_date, end_date)
-VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at
+INSERT INTO study_plans (user_id, title, subject, description, exam_date, start_date, end_date, subject_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at, deleted_at, version, classroom_id, source_plan_id, synced_hash, subject_id
 `
 
 type CreateStudyPlanParams struct {
-	UserID      string         `json:"user_id"`
-	Title       string         `json:"title"`
-	Subject     string         `json:"subject"`
-	Description string `json:"description"`
-	ExamDate    time.Time      `json:"exam_date"`
-	StartDate   time.Time      `json:"start_date"`
-	EndDate     time.Time      `json:"end_date"`
+	UserID      string        `json:"user_id"`
+	Title       string        `json:"title"`
+	Subject     string        `json:"subject"`
+	Description string        `json:"description"`
+	ExamDate    time.Time     `json:"exam_date"`
+	StartDate   time.Time     `json:"start_date"`
+	EndDate     time.Time     `json:"end_date"`
+	SubjectID   uuid.NullUUID `json:"subject_id"`
 }
 
 func (q *Queries) CreateStudyPlan(ctx context.Context, arg CreateStudyPlanParams) (StudyPlan, error) {
@@ -38,6 +39,7 @@ func (q *Queries) CreateStudyPlan(ctx context.Context, arg CreateStudyPlanParams
 		arg.ExamDate,
 		arg.StartDate,
 		arg.EndDate,
+		arg.SubjectID,
 	)
 	var i StudyPlan
 	err := row.Scan(
@@ -51,23 +53,140 @@ func (q *Queries) CreateStudyPlan(ctx context.Context, arg CreateStudyPlanParams
 		&i.EndDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ClassroomID,
+		&i.SourcePlanID,
+		&i.SyncedHash,
+		&i.SubjectID,
 	)
 	return i, err
 }
 
-const deleteStudyPlan = `-- name: DeleteStudyPlan :exec
-DELETE FROM study_plans
-WHERE id = $1
+const deleteStudyPlan = `-- name: DeleteStudyPlan :one
+WITH plan AS (
+    UPDATE study_plans
+    SET deleted_at = now(), version = version + 1
+    WHERE id = $1 AND deleted_at IS NULL
+      AND ($2::int IS NULL OR version = $2::int)
+    RETURNING id, deleted_at
+), tasks AS (
+    UPDATE study_tasks st
+    SET deleted_at = plan.deleted_at, version = st.version + 1
+    FROM plan
+    WHERE st.plan_id = plan.id AND st.deleted_at IS NULL
+)
+SELECT id FROM plan
 `
 
-func (q *Queries) DeleteStudyPlan(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteStudyPlan, id)
-	return err
+type DeleteStudyPlanParams struct {
+	ID              uuid.UUID     `json:"id"`
+	ExpectedVersion sql.NullInt32 `json:"expected_version"`
+}
+
+func (q *Queries) DeleteStudyPlan(ctx context.Context, arg DeleteStudyPlanParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, deleteStudyPlan, arg.ID, arg.ExpectedVersion)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getDeletedStudyPlanByID = `-- name: GetDeletedStudyPlanByID :one
+SELECT id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at, deleted_at, version, classroom_id, source_plan_id, synced_hash, subject_id FROM study_plans
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) GetDeletedStudyPlanByID(ctx context.Context, id uuid.UUID) (StudyPlan, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedStudyPlanByID, id)
+	var i StudyPlan
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Subject,
+		&i.Description,
+		&i.ExamDate,
+		&i.StartDate,
+		&i.EndDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ClassroomID,
+		&i.SourcePlanID,
+		&i.SyncedHash,
+		&i.SubjectID,
+	)
+	return i, err
+}
+
+const getDeletedStudyPlans = `-- name: GetDeletedStudyPlans :many
+SELECT sp.id, sp.user_id, sp.title, sp.subject, sp.description, sp.exam_date, sp.start_date,
+       sp.end_date, sp.created_at, sp.updated_at, sp.deleted_at, sp.version,
+       (SELECT COUNT(*) FROM study_tasks st
+        WHERE st.plan_id = sp.id AND st.deleted_at = sp.deleted_at) AS task_count
+FROM study_plans sp
+JOIN plan_members pm ON pm.plan_id = sp.id AND pm.user_id = $1 AND pm.role = 'owner'
+WHERE sp.deleted_at IS NOT NULL
+ORDER BY sp.deleted_at DESC
+`
+
+type GetDeletedStudyPlansRow struct {
+	ID          uuid.UUID      `json:"id"`
+	UserID      string         `json:"user_id"`
+	Title       string         `json:"title"`
+	Subject     string         `json:"subject"`
+	Description sql.NullString `json:"description"`
+	ExamDate    time.Time      `json:"exam_date"`
+	StartDate   time.Time      `json:"start_date"`
+	EndDate     time.Time      `json:"end_date"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	DeletedAt   sql.NullTime   `json:"deleted_at"`
+	Version     int32          `json:"version"`
+	TaskCount   int64          `json:"task_count"`
+}
+
+func (q *Queries) GetDeletedStudyPlans(ctx context.Context, userID string) ([]GetDeletedStudyPlansRow, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedStudyPlans, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetDeletedStudyPlansRow
+	for rows.Next() {
+		var i GetDeletedStudyPlansRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Subject,
+			&i.Description,
+			&i.ExamDate,
+			&i.StartDate,
+			&i.EndDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.TaskCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getStudyPlanByID = `-- name: GetStudyPlanByID :one
-SELECT id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at FROM study_plans
-WHERE id = $1
+SELECT id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at, deleted_at, version, classroom_id, source_plan_id, synced_hash, subject_id FROM study_plans
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetStudyPlanByID(ctx context.Context, id uuid.UUID) (StudyPlan, error) {
@@ -84,13 +203,19 @@ func (q *Queries) GetStudyPlanByID(ctx context.Context, id uuid.UUID) (StudyPlan
 		&i.EndDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ClassroomID,
+		&i.SourcePlanID,
+		&i.SyncedHash,
+		&i.SubjectID,
 	)
 	return i, err
 }
 
 const getStudyPlansByUserId = `-- name: GetStudyPlansByUserId :many
-SELECT id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at FROM study_plans
-WHERE user_id = $1
+SELECT id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at, deleted_at, version, classroom_id, source_plan_id, synced_hash, subject_id FROM study_plans
+WHERE user_id = $1 AND deleted_at IS NULL
 ORDER BY created_at DESC
 `
 
@@ -114,6 +239,107 @@ func (q *Queries) GetStudyPlansByUserId(ctx context.Context, userID string) ([]S
 			&i.EndDate,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ClassroomID,
+			&i.SourcePlanID,
+			&i.SyncedHash,
+			&i.SubjectID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStudyPlansForMember = `-- name: GetStudyPlansForMember :many
+SELECT sp.id, sp.user_id, sp.title, sp.subject, sp.description, sp.exam_date, sp.start_date, sp.end_date, sp.created_at, sp.updated_at, sp.deleted_at, sp.version, sp.classroom_id, sp.source_plan_id, sp.synced_hash, sp.subject_id FROM study_plans sp
+JOIN plan_members pm ON pm.plan_id = sp.id
+WHERE pm.user_id = $1 AND sp.deleted_at IS NULL
+ORDER BY sp.created_at DESC
+`
+
+func (q *Queries) GetStudyPlansForMember(ctx context.Context, userID string) ([]StudyPlan, error) {
+	rows, err := q.db.QueryContext(ctx, getStudyPlansForMember, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StudyPlan
+	for rows.Next() {
+		var i StudyPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Subject,
+			&i.Description,
+			&i.ExamDate,
+			&i.StartDate,
+			&i.EndDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ClassroomID,
+			&i.SourcePlanID,
+			&i.SyncedHash,
+			&i.SubjectID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getStudyPlansWithExamIn = `-- name: GetStudyPlansWithExamIn :many
+SELECT id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at, deleted_at, version, classroom_id, source_plan_id, synced_hash, subject_id FROM study_plans
+WHERE exam_date = CURRENT_DATE + $1::int AND deleted_at IS NULL
+  -- Plans linked to exams are reminded of through them
+  AND NOT EXISTS (SELECT 1 FROM plan_exams pe WHERE pe.plan_id = study_plans.id)
+ORDER BY user_id
+`
+
+func (q *Queries) GetStudyPlansWithExamIn(ctx context.Context, days int32) ([]StudyPlan, error) {
+	rows, err := q.db.QueryContext(ctx, getStudyPlansWithExamIn, days)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StudyPlan
+	for rows.Next() {
+		var i StudyPlan
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Subject,
+			&i.Description,
+			&i.ExamDate,
+			&i.StartDate,
+			&i.EndDate,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.ClassroomID,
+			&i.SourcePlanID,
+			&i.SyncedHash,
+			&i.SubjectID,
 		); err != nil {
 			return nil, err
 		}
@@ -128,38 +354,176 @@ func (q *Queries) GetStudyPlansByUserId(ctx context.Context, userID string) ([]S
 	return items, nil
 }
 
+const patchStudyPlan = `-- name: PatchStudyPlan :one
+UPDATE study_plans
+SET title = COALESCE($1, title),
+    subject = COALESCE($2, subject),
+    description = CASE WHEN $3::bool THEN $4 ELSE description END,
+    exam_date = COALESCE($5, exam_date),
+    start_date = COALESCE($6, start_date),
+    end_date = COALESCE($7, end_date),
+    subject_id = COALESCE($8, subject_id),
+    version = version + 1,
+    updated_at = now()
+WHERE id = $9 AND deleted_at IS NULL
+  AND ($10::int IS NULL OR version = $10::int)
+RETURNING id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at, deleted_at, version, classroom_id, source_plan_id, synced_hash, subject_id
+`
+
+type PatchStudyPlanParams struct {
+	Title           sql.NullString `json:"title"`
+	Subject         sql.NullString `json:"subject"`
+	SetDescription  bool           `json:"set_description"`
+	Description     sql.NullString `json:"description"`
+	ExamDate        sql.NullTime   `json:"exam_date"`
+	StartDate       sql.NullTime   `json:"start_date"`
+	EndDate         sql.NullTime   `json:"end_date"`
+	SubjectID       uuid.NullUUID  `json:"subject_id"`
+	ID              uuid.UUID      `json:"id"`
+	ExpectedVersion sql.NullInt32  `json:"expected_version"`
+}
+
+func (q *Queries) PatchStudyPlan(ctx context.Context, arg PatchStudyPlanParams) (StudyPlan, error) {
+	row := q.db.QueryRowContext(ctx, patchStudyPlan,
+		arg.Title,
+		arg.Subject,
+		arg.SetDescription,
+		arg.Description,
+		arg.ExamDate,
+		arg.StartDate,
+		arg.EndDate,
+		arg.SubjectID,
+		arg.ID,
+		arg.ExpectedVersion,
+	)
+	var i StudyPlan
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Subject,
+		&i.Description,
+		&i.ExamDate,
+		&i.StartDate,
+		&i.EndDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ClassroomID,
+		&i.SourcePlanID,
+		&i.SyncedHash,
+		&i.SubjectID,
+	)
+	return i, err
+}
+
+const purgeDeletedStudyPlans = `-- name: PurgeDeletedStudyPlans :execrows
+DELETE FROM study_plans
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedStudyPlans(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedStudyPlans, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeStudyPlan = `-- name: PurgeStudyPlan :execrows
+DELETE FROM study_plans
+WHERE id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) PurgeStudyPlan(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeStudyPlan, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreStudyPlan = `-- name: RestoreStudyPlan :one
+WITH plan AS (
+    SELECT id, deleted_at FROM study_plans
+    WHERE study_plans.id = $1 AND deleted_at IS NOT NULL
+), tasks AS (
+    UPDATE study_tasks st
+    SET deleted_at = NULL, version = st.version + 1
+    FROM plan
+    WHERE st.plan_id = plan.id AND st.deleted_at = plan.deleted_at
+)
+UPDATE study_plans sp
+SET deleted_at = NULL, version = sp.version + 1, updated_at = now()
+FROM plan
+WHERE sp.id = plan.id
+RETURNING sp.id, sp.user_id, sp.title, sp.subject, sp.description, sp.exam_date, sp.start_date, sp.end_date, sp.created_at, sp.updated_at, sp.deleted_at, sp.version, sp.classroom_id, sp.source_plan_id, sp.synced_hash, sp.subject_id
+`
+
+func (q *Queries) RestoreStudyPlan(ctx context.Context, id uuid.UUID) (StudyPlan, error) {
+	row := q.db.QueryRowContext(ctx, restoreStudyPlan, id)
+	var i StudyPlan
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Subject,
+		&i.Description,
+		&i.ExamDate,
+		&i.StartDate,
+		&i.EndDate,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ClassroomID,
+		&i.SourcePlanID,
+		&i.SyncedHash,
+		&i.SubjectID,
+	)
+	return i, err
+}
+
 const updateStudyPlan = `-- name: UpdateStudyPlan :one
 UPDATE study_plans
-SET title = $2,
-    subject = $3,
-    description = $4,
-    exam_date = $5,
-    start_date = $6,
-    end_date = $7,
+SET title = $1,
+    subject = $2,
+    description = $3,
+    exam_date = $4,
+    start_date = $5,
+    end_date = $6,
+    subject_id = $7,
+    version = version + 1,
     updated_at = now()
-WHERE id = $1
-RETURNING id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at
+WHERE id = $8 AND deleted_at IS NULL
+  AND ($9::int IS NULL OR version = $9::int)
+RETURNING id, user_id, title, subject, description, exam_date, start_date, end_date, created_at, updated_at, deleted_at, version, classroom_id, source_plan_id, synced_hash, subject_id
 `
 
 type UpdateStudyPlanParams struct {
-	ID          uuid.UUID      `json:"id"`
-	Title       string         `json:"title"`
-	Subject     string         `json:"subject"`
-	Description sql.NullString `json:"description"`
-	ExamDate    time.Time      `json:"exam_date"`
-	StartDate   time.Time      `json:"start_date"`
-	EndDate     time.Time      `json:"end_date"`
+	Title           string         `json:"title"`
+	Subject         string         `json:"subject"`
+	Description     sql.NullString `json:"description"`
+	ExamDate        time.Time      `json:"exam_date"`
+	StartDate       time.Time      `json:"start_date"`
+	EndDate         time.Time      `json:"end_date"`
+	SubjectID       uuid.NullUUID  `json:"subject_id"`
+	ID              uuid.UUID      `json:"id"`
+	ExpectedVersion sql.NullInt32  `json:"expected_version"`
 }
 
 func (q *Queries) UpdateStudyPlan(ctx context.Context, arg UpdateStudyPlanParams) (StudyPlan, error) {
 	row := q.db.QueryRowContext(ctx, updateStudyPlan,
-		arg.ID,
 		arg.Title,
 		arg.Subject,
 		arg.Description,
 		arg.ExamDate,
 		arg.StartDate,
 		arg.EndDate,
+		arg.SubjectID,
+		arg.ID,
+		arg.ExpectedVersion,
 	)
 	var i StudyPlan
 	err := row.Scan(
@@ -173,6 +537,12 @@ func (q *Queries) UpdateStudyPlan(ctx context.Context, arg UpdateStudyPlanParams
 		&i.EndDate,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.ClassroomID,
+		&i.SourcePlanID,
+		&i.SyncedHash,
+		&i.SubjectID,
 	)
 	return i, err
 }
@@ -13,19 +13,43 @@ import (
 	"github.com/google/uuid"
 )
 
+const copyPlanTasks = `-- name: CopyPlanTasks :execrows
+INSERT INTO study_tasks (plan_id, title, due_date, is_completed, priority, notes, created_by)
+SELECT $1, title, due_date, FALSE, priority, NULL, $2
+FROM study_tasks
+WHERE plan_id = $3 AND deleted_at IS NULL
+`
+
+type CopyPlanTasksParams struct {
+	PlanID       uuid.UUID      `json:"plan_id"`
+	CreatedBy    sql.NullString `json:"created_by"`
+	SourcePlanID uuid.UUID      `json:"source_plan_id"`
+}
+
+func (q *Queries) CopyPlanTasks(ctx context.Context, arg CopyPlanTasksParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, copyPlanTasks, arg.PlanID, arg.CreatedBy, arg.SourcePlanID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const createTask = `-- name: CreateTask :one
-INSERT INTO study_tasks (plan_id, title, due_date, is_completed, priority, notes)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at
+INSERT INTO study_tasks (plan_id, title, due_date, is_completed, priority, notes, created_by, subject_id, minutes_spent)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at, deleted_at, version, created_by, source_task_id, synced_hash, subject_id, minutes_spent, completed_at
 `
 
 type CreateTaskParams struct {
-	PlanID      uuid.NullUUID  `json:"plan_id"`
-	Title       string         `json:"title"`
-	DueDate     time.Time      `json:"due_date"`
-	IsCompleted sql.NullBool   `json:"is_completed"`
-	Priority    sql.NullInt32  `json:"priority"`
-	Notes       sql.NullString `json:"notes"`
+	PlanID       uuid.NullUUID  `json:"plan_id"`
+	Title        string         `json:"title"`
+	DueDate      time.Time      `json:"due_date"`
+	IsCompleted  sql.NullBool   `json:"is_completed"`
+	Priority     sql.NullInt32  `json:"priority"`
+	Notes        sql.NullString `json:"notes"`
+	CreatedBy    sql.NullString `json:"created_by"`
+	SubjectID    uuid.NullUUID  `json:"subject_id"`
+	MinutesSpent sql.NullInt32  `json:"minutes_spent"`
 }
 
 func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (StudyTask, error) {
@@ -36,6 +60,9 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (StudyTa
 		arg.IsCompleted,
 		arg.Priority,
 		arg.Notes,
+		arg.CreatedBy,
+		arg.SubjectID,
+		arg.MinutesSpent,
 	)
 	var i StudyTask
 	err := row.Scan(
@@ -48,23 +75,124 @@ func (q *Queries) CreateTask(ctx context.Context, arg CreateTaskParams) (StudyTa
 		&i.Notes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.CreatedBy,
+		&i.SourceTaskID,
+		&i.SyncedHash,
+		&i.SubjectID,
+		&i.MinutesSpent,
+		&i.CompletedAt,
 	)
 	return i, err
 }
 
-const deleteTask = `-- name: DeleteTask :exec
-DELETE FROM study_tasks
-WHERE id = $1
+const deleteTask = `-- name: DeleteTask :execrows
+UPDATE study_tasks
+SET deleted_at = now(), version = version + 1
+WHERE id = $1 AND deleted_at IS NULL
+  AND ($2::int IS NULL OR version = $2::int)
 `
 
-func (q *Queries) DeleteTask(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, deleteTask, id)
-	return err
+type DeleteTaskParams struct {
+	ID              uuid.UUID     `json:"id"`
+	ExpectedVersion sql.NullInt32 `json:"expected_version"`
+}
+
+func (q *Queries) DeleteTask(ctx context.Context, arg DeleteTaskParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteTask, arg.ID, arg.ExpectedVersion)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getDeletedTaskByID = `-- name: GetDeletedTaskByID :one
+SELECT st.id, st.plan_id, st.title, st.due_date, st.is_completed, st.priority, st.notes, st.created_at, st.updated_at, st.deleted_at, st.version, st.created_by, st.source_task_id, st.synced_hash, st.subject_id, st.minutes_spent, st.completed_at FROM study_tasks st
+LEFT JOIN study_plans sp ON st.plan_id = sp.id
+WHERE st.id = $1 AND st.deleted_at IS NOT NULL
+  AND (st.plan_id IS NULL OR sp.deleted_at IS NULL)
+`
+
+func (q *Queries) GetDeletedTaskByID(ctx context.Context, id uuid.UUID) (StudyTask, error) {
+	row := q.db.QueryRowContext(ctx, getDeletedTaskByID, id)
+	var i StudyTask
+	err := row.Scan(
+		&i.ID,
+		&i.PlanID,
+		&i.Title,
+		&i.DueDate,
+		&i.IsCompleted,
+		&i.Priority,
+		&i.Notes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.CreatedBy,
+		&i.SourceTaskID,
+		&i.SyncedHash,
+		&i.SubjectID,
+		&i.MinutesSpent,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const getDeletedTasksByUser = `-- name: GetDeletedTasksByUser :many
+SELECT st.id, st.plan_id, st.title, st.due_date, st.is_completed, st.priority, st.notes, st.created_at, st.updated_at, st.deleted_at, st.version, st.created_by, st.source_task_id, st.synced_hash, st.subject_id, st.minutes_spent, st.completed_at FROM study_tasks st
+LEFT JOIN study_plans sp ON st.plan_id = sp.id
+LEFT JOIN plan_members pm ON pm.plan_id = sp.id AND pm.user_id = $1
+WHERE st.deleted_at IS NOT NULL
+  AND ((st.plan_id IS NULL AND st.created_by = $1)
+       OR (sp.deleted_at IS NULL AND pm.role IN ('editor', 'owner')))
+ORDER BY st.deleted_at DESC
+`
+
+func (q *Queries) GetDeletedTasksByUser(ctx context.Context, userID string) ([]StudyTask, error) {
+	rows, err := q.db.QueryContext(ctx, getDeletedTasksByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []StudyTask
+	for rows.Next() {
+		var i StudyTask
+		if err := rows.Scan(
+			&i.ID,
+			&i.PlanID,
+			&i.Title,
+			&i.DueDate,
+			&i.IsCompleted,
+			&i.Priority,
+			&i.Notes,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.CreatedBy,
+			&i.SourceTaskID,
+			&i.SyncedHash,
+			&i.SubjectID,
+			&i.MinutesSpent,
+			&i.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getOverdueTasks = `-- name: GetOverdueTasks :many
-SELECT id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at FROM study_tasks
-WHERE plan_id = $1 AND due_date < CURRENT_DATE AND is_completed = FALSE
+SELECT id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at, deleted_at, version, created_by, source_task_id, synced_hash, subject_id, minutes_spent, completed_at FROM study_tasks
+WHERE plan_id = $1 AND due_date < CURRENT_DATE AND is_completed = FALSE AND deleted_at IS NULL
 ORDER BY due_date ASC
 `
 
@@ -87,6 +215,14 @@ func (q *Queries) GetOverdueTasks(ctx context.Context, planID uuid.NullUUID) ([]
 			&i.Notes,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.CreatedBy,
+			&i.SourceTaskID,
+			&i.SyncedHash,
+			&i.SubjectID,
+			&i.MinutesSpent,
+			&i.CompletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -102,8 +238,8 @@ func (q *Queries) GetOverdueTasks(ctx context.Context, planID uuid.NullUUID) ([]
 }
 
 const getTaskByID = `-- name: GetTaskByID :one
-SELECT id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at FROM study_tasks
-WHERE id = $1
+SELECT id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at, deleted_at, version, created_by, source_task_id, synced_hash, subject_id, minutes_spent, completed_at FROM study_tasks
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 func (q *Queries) GetTaskByID(ctx context.Context, id uuid.UUID) (StudyTask, error) {
@@ -119,13 +255,21 @@ func (q *Queries) GetTaskByID(ctx context.Context, id uuid.UUID) (StudyTask, err
 		&i.Notes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.CreatedBy,
+		&i.SourceTaskID,
+		&i.SyncedHash,
+		&i.SubjectID,
+		&i.MinutesSpent,
+		&i.CompletedAt,
 	)
 	return i, err
 }
 
 const getTasksByPlan = `-- name: GetTasksByPlan :many
-SELECT id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at FROM study_tasks
-WHERE plan_id = $1
+SELECT id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at, deleted_at, version, created_by, source_task_id, synced_hash, subject_id, minutes_spent, completed_at FROM study_tasks
+WHERE plan_id = $1 AND deleted_at IS NULL
 ORDER BY due_date ASC
 `
 
@@ -148,6 +292,14 @@ func (q *Queries) GetTasksByPlan(ctx context.Context, planID uuid.NullUUID) ([]S
 			&i.Notes,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.CreatedBy,
+			&i.SourceTaskID,
+			&i.SyncedHash,
+			&i.SubjectID,
+			&i.MinutesSpent,
+			&i.CompletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -162,19 +314,29 @@ func (q *Queries) GetTasksByPlan(ctx context.Context, planID uuid.NullUUID) ([]S
 	return items, nil
 }
 
-const getTasksByPriority = `-- name: GetTasksByPriority :many
-SELECT id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at FROM study_tasks
-WHERE plan_id = $1 AND priority = $2
-ORDER BY due_date ASC
+const getTasksByPlanForMember = `-- name: GetTasksByPlanForMember :many
+SELECT st.id, st.plan_id, st.title, st.due_date,
+       CASE WHEN sp.user_id = $1 THEN st.is_completed
+            ELSE EXISTS (SELECT 1 FROM task_completions tc
+                         WHERE tc.task_id = st.id AND tc.user_id = $1) END AS is_completed,
+       st.priority, st.notes, st.created_at, st.updated_at, st.deleted_at, st.version, st.created_by,
+       st.source_task_id, st.synced_hash, st.subject_id, st.minutes_spent,
+       CASE WHEN sp.user_id = $1 THEN st.completed_at
+            ELSE (SELECT tc.completed_at FROM task_completions tc
+                  WHERE tc.task_id = st.id AND tc.user_id = $1) END AS completed_at
+FROM study_tasks st
+JOIN study_plans sp ON st.plan_id = sp.id
+WHERE st.plan_id = $2 AND st.deleted_at IS NULL
+ORDER BY st.due_date ASC
 `
 
-type GetTasksByPriorityParams struct {
-	PlanID   uuid.NullUUID `json:"plan_id"`
-	Priority sql.NullInt32 `json:"priority"`
+type GetTasksByPlanForMemberParams struct {
+	UserID string    `json:"user_id"`
+	PlanID uuid.UUID `json:"plan_id"`
 }
 
-func (q *Queries) GetTasksByPriority(ctx context.Context, arg GetTasksByPriorityParams) ([]StudyTask, error) {
-	rows, err := q.db.QueryContext(ctx, getTasksByPriority, arg.PlanID, arg.Priority)
+func (q *Queries) GetTasksByPlanForMember(ctx context.Context, arg GetTasksByPlanForMemberParams) ([]StudyTask, error) {
+	rows, err := q.db.QueryContext(ctx, getTasksByPlanForMember, arg.UserID, arg.PlanID)
 	if err != nil {
 		return nil, err
 	}
@@ -192,6 +354,14 @@ func (q *Queries) GetTasksByPriority(ctx context.Context, arg GetTasksByPriority
 			&i.Notes,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.CreatedBy,
+			&i.SourceTaskID,
+			&i.SyncedHash,
+			&i.SubjectID,
+			&i.MinutesSpent,
+			&i.CompletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -206,19 +376,16 @@ func (q *Queries) GetTasksByPriority(ctx context.Context, arg GetTasksByPriority
 	return items, nil
 }
 
-const getTasksByStatus = `-- name: GetTasksByStatus :many
-SELECT id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at FROM study_tasks
-WHERE plan_id = $1 AND is_completed = $2
-ORDER BY due_date ASC
+const getTasksByUser = `-- name: GetTasksByUser :many
+SELECT st.id, st.plan_id, st.title, st.due_date, st.is_completed, st.priority, st.notes, st.created_at, st.updated_at, st.deleted_at, st.version, st.created_by, st.source_task_id, st.synced_hash, st.subject_id, st.minutes_spent, st.completed_at FROM study_tasks st
+LEFT JOIN study_plans sp ON st.plan_id = sp.id
+WHERE st.deleted_at IS NULL
+  AND ((st.plan_id IS NULL AND st.created_by = $1) OR (sp.user_id = $1 AND sp.deleted_at IS NULL))
+ORDER BY st.due_date ASC
 `
 
-type GetTasksByStatusParams struct {
-	PlanID      uuid.NullUUID `json:"plan_id"`
-	IsCompleted sql.NullBool  `json:"is_completed"`
-}
-
-func (q *Queries) GetTasksByStatus(ctx context.Context, arg GetTasksByStatusParams) ([]StudyTask, error) {
-	rows, err := q.db.QueryContext(ctx, getTasksByStatus, arg.PlanID, arg.IsCompleted)
+func (q *Queries) GetTasksByUser(ctx context.Context, userID string) ([]StudyTask, error) {
+	rows, err := q.db.QueryContext(ctx, getTasksByUser, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -236,6 +403,14 @@ func (q *Queries) GetTasksByStatus(ctx context.Context, arg GetTasksByStatusPara
 			&i.Notes,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.CreatedBy,
+			&i.SourceTaskID,
+			&i.SyncedHash,
+			&i.SubjectID,
+			&i.MinutesSpent,
+			&i.CompletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -250,15 +425,27 @@ func (q *Queries) GetTasksByStatus(ctx context.Context, arg GetTasksByStatusPara
 	return items, nil
 }
 
-const getTasksByUser = `-- name: GetTasksByUser :many
-SELECT st.id, st.plan_id, st.title, st.due_date, st.is_completed, st.priority, st.notes, st.created_at, st.updated_at FROM study_tasks st
-JOIN study_plans sp ON st.plan_id = sp.id
-WHERE sp.user_id = $1
+const getTasksForMember = `-- name: GetTasksForMember :many
+SELECT st.id, st.plan_id, st.title, st.due_date,
+       CASE WHEN st.plan_id IS NULL OR sp.user_id = $1 THEN st.is_completed
+            ELSE EXISTS (SELECT 1 FROM task_completions tc
+                         WHERE tc.task_id = st.id AND tc.user_id = $1) END AS is_completed,
+       st.priority, st.notes, st.created_at, st.updated_at, st.deleted_at, st.version, st.created_by,
+       st.source_task_id, st.synced_hash, st.subject_id, st.minutes_spent,
+       CASE WHEN st.plan_id IS NULL OR sp.user_id = $1 THEN st.completed_at
+            ELSE (SELECT tc.completed_at FROM task_completions tc
+                  WHERE tc.task_id = st.id AND tc.user_id = $1) END AS completed_at
+FROM study_tasks st
+LEFT JOIN study_plans sp ON st.plan_id = sp.id
+LEFT JOIN plan_members pm ON pm.plan_id = sp.id AND pm.user_id = $1
+WHERE st.deleted_at IS NULL
+  AND ((st.plan_id IS NULL AND st.created_by = $1)
+       OR (sp.deleted_at IS NULL AND pm.user_id IS NOT NULL))
 ORDER BY st.due_date ASC
 `
 
-func (q *Queries) GetTasksByUser(ctx context.Context, userID string) ([]StudyTask, error) {
-	rows, err := q.db.QueryContext(ctx, getTasksByUser, userID)
+func (q *Queries) GetTasksForMember(ctx context.Context, userID string) ([]StudyTask, error) {
+	rows, err := q.db.QueryContext(ctx, getTasksForMember, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -276,6 +463,14 @@ func (q *Queries) GetTasksByUser(ctx context.Context, userID string) ([]StudyTas
 			&i.Notes,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Version,
+			&i.CreatedBy,
+			&i.SourceTaskID,
+			&i.SyncedHash,
+			&i.SubjectID,
+			&i.MinutesSpent,
+			&i.CompletedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -290,35 +485,176 @@ func (q *Queries) GetTasksByUser(ctx context.Context, userID string) ([]StudyTas
 	return items, nil
 }
 
+const patchTask = `-- name: PatchTask :one
+UPDATE study_tasks
+SET title = COALESCE($1, title),
+    due_date = COALESCE($2, due_date),
+    is_completed = COALESCE($3, is_completed),
+    priority = CASE WHEN $4::bool THEN $5 ELSE priority END,
+    notes = CASE WHEN $6::bool THEN $7 ELSE notes END,
+    subject_id = CASE WHEN $8::bool THEN $9 ELSE subject_id END,
+    minutes_spent = CASE WHEN $10::bool THEN $11 ELSE minutes_spent END,
+    version = version + 1,
+    updated_at = now()
+WHERE id = $12 AND deleted_at IS NULL
+  AND ($13::int IS NULL OR version = $13::int)
+RETURNING id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at, deleted_at, version, created_by, source_task_id, synced_hash, subject_id, minutes_spent, completed_at
+`
+
+type PatchTaskParams struct {
+	Title           sql.NullString `json:"title"`
+	DueDate         sql.NullTime   `json:"due_date"`
+	IsCompleted     sql.NullBool   `json:"is_completed"`
+	SetPriority     bool           `json:"set_priority"`
+	Priority        sql.NullInt32  `json:"priority"`
+	SetNotes        bool           `json:"set_notes"`
+	Notes           sql.NullString `json:"notes"`
+	SetSubjectID    bool           `json:"set_subject_id"`
+	SubjectID       uuid.NullUUID  `json:"subject_id"`
+	SetMinutesSpent bool           `json:"set_minutes_spent"`
+	MinutesSpent    sql.NullInt32  `json:"minutes_spent"`
+	ID              uuid.UUID      `json:"id"`
+	ExpectedVersion sql.NullInt32  `json:"expected_version"`
+}
+
+func (q *Queries) PatchTask(ctx context.Context, arg PatchTaskParams) (StudyTask, error) {
+	row := q.db.QueryRowContext(ctx, patchTask,
+		arg.Title,
+		arg.DueDate,
+		arg.IsCompleted,
+		arg.SetPriority,
+		arg.Priority,
+		arg.SetNotes,
+		arg.Notes,
+		arg.SetSubjectID,
+		arg.SubjectID,
+		arg.SetMinutesSpent,
+		arg.MinutesSpent,
+		arg.ID,
+		arg.ExpectedVersion,
+	)
+	var i StudyTask
+	err := row.Scan(
+		&i.ID,
+		&i.PlanID,
+		&i.Title,
+		&i.DueDate,
+		&i.IsCompleted,
+		&i.Priority,
+		&i.Notes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.CreatedBy,
+		&i.SourceTaskID,
+		&i.SyncedHash,
+		&i.SubjectID,
+		&i.MinutesSpent,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
+const purgeDeletedTasks = `-- name: PurgeDeletedTasks :execrows
+DELETE FROM study_tasks
+WHERE deleted_at < $1
+`
+
+func (q *Queries) PurgeDeletedTasks(ctx context.Context, deletedAt sql.NullTime) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeDeletedTasks, deletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const purgeTask = `-- name: PurgeTask :execrows
+DELETE FROM study_tasks
+WHERE id = $1 AND deleted_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM study_plans sp WHERE sp.id = study_tasks.plan_id AND sp.deleted_at IS NOT NULL)
+`
+
+func (q *Queries) PurgeTask(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, purgeTask, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const restoreTask = `-- name: RestoreTask :one
+UPDATE study_tasks
+SET deleted_at = NULL, version = version + 1, updated_at = now()
+WHERE id = $1 AND deleted_at IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM study_plans sp WHERE sp.id = study_tasks.plan_id AND sp.deleted_at IS NOT NULL)
+RETURNING id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at, deleted_at, version, created_by, source_task_id, synced_hash, subject_id, minutes_spent, completed_at
+`
+
+func (q *Queries) RestoreTask(ctx context.Context, id uuid.UUID) (StudyTask, error) {
+	row := q.db.QueryRowContext(ctx, restoreTask, id)
+	var i StudyTask
+	err := row.Scan(
+		&i.ID,
+		&i.PlanID,
+		&i.Title,
+		&i.DueDate,
+		&i.IsCompleted,
+		&i.Priority,
+		&i.Notes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.CreatedBy,
+		&i.SourceTaskID,
+		&i.SyncedHash,
+		&i.SubjectID,
+		&i.MinutesSpent,
+		&i.CompletedAt,
+	)
+	return i, err
+}
+
 const updateTask = `-- name: UpdateTask :one
 UPDATE study_tasks
-SET title = $2,
-    due_date = $3,
-    is_completed = $4,
-    priority = $5,
-    notes = $6,
+SET title = $1,
+    due_date = $2,
+    is_completed = COALESCE($3, is_completed),
+    priority = $4,
+    notes = $5,
+    subject_id = $6,
+    minutes_spent = $7,
+    version = version + 1,
     updated_at = now()
-WHERE id = $1
-RETURNING id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at
+WHERE id = $8 AND deleted_at IS NULL
+  AND ($9::int IS NULL OR version = $9::int)
+RETURNING id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at, deleted_at, version, created_by, source_task_id, synced_hash, subject_id, minutes_spent, completed_at
 `
 
 type UpdateTaskParams struct {
-	ID          uuid.UUID      `json:"id"`
-	Title       string         `json:"title"`
-	DueDate     time.Time      `json:"due_date"`
-	IsCompleted sql.NullBool   `json:"is_completed"`
-	Priority    sql.NullInt32  `json:"priority"`
-	Notes       sql.NullString `json:"notes"`
+	Title           string         `json:"title"`
+	DueDate         time.Time      `json:"due_date"`
+	IsCompleted     sql.NullBool   `json:"is_completed"`
+	Priority        sql.NullInt32  `json:"priority"`
+	Notes           sql.NullString `json:"notes"`
+	SubjectID       uuid.NullUUID  `json:"subject_id"`
+	MinutesSpent    sql.NullInt32  `json:"minutes_spent"`
+	ID              uuid.UUID      `json:"id"`
+	ExpectedVersion sql.NullInt32  `json:"expected_version"`
 }
 
 func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (StudyTask, error) {
 	row := q.db.QueryRowContext(ctx, updateTask,
-		arg.ID,
 		arg.Title,
 		arg.DueDate,
 		arg.IsCompleted,
 		arg.Priority,
 		arg.Notes,
+		arg.SubjectID,
+		arg.MinutesSpent,
+		arg.ID,
+		arg.ExpectedVersion,
 	)
 	var i StudyTask
 	err := row.Scan(
@@ -331,22 +667,53 @@ func (q *Queries) UpdateTask(ctx context.Context, arg UpdateTaskParams) (StudyTa
 		&i.Notes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.CreatedBy,
+		&i.SourceTaskID,
+		&i.SyncedHash,
+		&i.SubjectID,
+		&i.MinutesSpent,
+		&i.CompletedAt,
 	)
 	return i, err
 }
 
-const updateTaskStatus = `-- name: UpdateTaskStatus :exec
+const updateTaskStatus = `-- name: UpdateTaskStatus :one
 UPDATE study_tasks
-SET is_completed = $2, updated_at = now()
-WHERE id = $1
+SET is_completed = $1, version = version + 1, updated_at = now()
+WHERE id = $2 AND deleted_at IS NULL
+  AND ($3::int IS NULL OR version = $3::int)
+RETURNING id, plan_id, title, due_date, is_completed, priority, notes, created_at, updated_at, deleted_at, version, created_by, source_task_id, synced_hash, subject_id, minutes_spent, completed_at
 `
 
 type UpdateTaskStatusParams struct {
-	ID          uuid.UUID    `json:"id"`
-	IsCompleted sql.NullBool `json:"is_completed"`
+	IsCompleted     sql.NullBool  `json:"is_completed"`
+	ID              uuid.UUID     `json:"id"`
+	ExpectedVersion sql.NullInt32 `json:"expected_version"`
 }
 
-func (q *Queries) UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusParams) error {
-	_, err := q.db.ExecContext(ctx, updateTaskStatus, arg.ID, arg.IsCompleted)
-	return err
+func (q *Queries) UpdateTaskStatus(ctx context.Context, arg UpdateTaskStatusParams) (StudyTask, error) {
+	row := q.db.QueryRowContext(ctx, updateTaskStatus, arg.IsCompleted, arg.ID, arg.ExpectedVersion)
+	var i StudyTask
+	err := row.Scan(
+		&i.ID,
+		&i.PlanID,
+		&i.Title,
+		&i.DueDate,
+		&i.IsCompleted,
+		&i.Priority,
+		&i.Notes,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Version,
+		&i.CreatedBy,
+		&i.SourceTaskID,
+		&i.SyncedHash,
+		&i.SubjectID,
+		&i.MinutesSpent,
+		&i.CompletedAt,
+	)
+	return i, err
 }
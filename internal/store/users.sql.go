@@ -8,6 +8,7 @@ package store
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -24,7 +25,7 @@ func (q *Queries) BanUser(ctx context.Context, clerkID string) error {
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (clerk_id, email, first_name, last_name, name, image_url, email_verified, last_sign_in_at)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned
+RETURNING id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone
 `
 
 type CreateUserParams struct {
@@ -63,6 +64,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.EmailVerified,
 		&i.LastSignInAt,
 		&i.Banned,
+		&i.Role,
+		&i.TimeZone,
 	)
 	return i, err
 }
@@ -77,7 +80,7 @@ func (q *Queries) DeleteUserByClerkID(ctx context.Context, clerkID string) error
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned FROM users WHERE id = $1 LIMIT 1
+SELECT id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone FROM users WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
@@ -96,12 +99,14 @@ func (q *Queries) GetUser(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.EmailVerified,
 		&i.LastSignInAt,
 		&i.Banned,
+		&i.Role,
+		&i.TimeZone,
 	)
 	return i, err
 }
 
 const getUserByClerkID = `-- name: GetUserByClerkID :one
-SELECT id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned FROM users WHERE clerk_id = $1 LIMIT 1
+SELECT id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone FROM users WHERE clerk_id = $1 LIMIT 1
 `
 
 func (q *Queries) GetUserByClerkID(ctx context.Context, clerkID string) (User, error) {
@@ -120,12 +125,14 @@ func (q *Queries) GetUserByClerkID(ctx context.Context, clerkID string) (User, e
 		&i.EmailVerified,
 		&i.LastSignInAt,
 		&i.Banned,
+		&i.Role,
+		&i.TimeZone,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned FROM users WHERE email = $1 LIMIT 1
+SELECT id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone FROM users WHERE email = $1 LIMIT 1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -144,6 +151,136 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.EmailVerified,
 		&i.LastSignInAt,
 		&i.Banned,
+		&i.Role,
+		&i.TimeZone,
+	)
+	return i, err
+}
+
+const searchUsers = `-- name: SearchUsers :many
+SELECT id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone FROM users
+WHERE ($1::text IS NULL
+       OR email ILIKE '%' || $1::text || '%'
+       OR name ILIKE '%' || $1::text || '%'
+       OR clerk_id = $1::text)
+  AND ($2::text IS NULL OR role = $2::text)
+  AND ($3::boolean IS NULL OR COALESCE(banned, false) = $3::boolean)
+ORDER BY created_at DESC, id DESC
+LIMIT $4 OFFSET $5
+`
+
+type SearchUsersParams struct {
+	Query     sql.NullString `json:"query"`
+	Role      sql.NullString `json:"role"`
+	Banned    sql.NullBool   `json:"banned"`
+	RowLimit  int32          `json:"row_limit"`
+	RowOffset int32          `json:"row_offset"`
+}
+
+func (q *Queries) SearchUsers(ctx context.Context, arg SearchUsersParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, searchUsers,
+		arg.Query,
+		arg.Role,
+		arg.Banned,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []User
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ClerkID,
+			&i.FirstName,
+			&i.LastName,
+			&i.ImageUrl,
+			&i.EmailVerified,
+			&i.LastSignInAt,
+			&i.Banned,
+			&i.Role,
+			&i.TimeZone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users SET role = $2, updated_at = NOW() WHERE clerk_id = $1
+RETURNING id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone
+`
+
+type SetUserRoleParams struct {
+	ClerkID string `json:"clerk_id"`
+	Role    string `json:"role"`
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ClerkID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ClerkID,
+		&i.FirstName,
+		&i.LastName,
+		&i.ImageUrl,
+		&i.EmailVerified,
+		&i.LastSignInAt,
+		&i.Banned,
+		&i.Role,
+		&i.TimeZone,
+	)
+	return i, err
+}
+
+const setUserTimeZone = `-- name: SetUserTimeZone :one
+UPDATE users SET time_zone = $2, updated_at = NOW() WHERE clerk_id = $1
+RETURNING id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone
+`
+
+type SetUserTimeZoneParams struct {
+	ClerkID  string `json:"clerk_id"`
+	TimeZone string `json:"time_zone"`
+}
+
+func (q *Queries) SetUserTimeZone(ctx context.Context, arg SetUserTimeZoneParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserTimeZone, arg.ClerkID, arg.TimeZone)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ClerkID,
+		&i.FirstName,
+		&i.LastName,
+		&i.ImageUrl,
+		&i.EmailVerified,
+		&i.LastSignInAt,
+		&i.Banned,
+		&i.Role,
+		&i.TimeZone,
 	)
 	return i, err
 }
@@ -169,7 +306,7 @@ SET
     last_sign_in_at = COALESCE($8, last_sign_in_at),
     updated_at = NOW()
 WHERE clerk_id = $1
-RETURNING id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned
+RETURNING id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone
 `
 
 type UpdateUserParams struct {
@@ -208,10 +345,29 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.EmailVerified,
 		&i.LastSignInAt,
 		&i.Banned,
+		&i.Role,
+		&i.TimeZone,
 	)
 	return i, err
 }
 
+const updateUserLastSignIn = `-- name: UpdateUserLastSignIn :execrows
+UPDATE users SET last_sign_in_at = $2, updated_at = NOW() WHERE clerk_id = $1
+`
+
+type UpdateUserLastSignInParams struct {
+	ClerkID      string    `json:"clerk_id"`
+	LastSignInAt time.Time `json:"last_sign_in_at"`
+}
+
+func (q *Queries) UpdateUserLastSignIn(ctx context.Context, arg UpdateUserLastSignInParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, updateUserLastSignIn, arg.ClerkID, arg.LastSignInAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const upsertUserByClerkID = `-- name: UpsertUserByClerkID :one
 INSERT INTO users (clerk_id, email, first_name, last_name, name, image_url, email_verified, last_sign_in_at)
 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
@@ -225,7 +381,7 @@ DO UPDATE SET
     email_verified = EXCLUDED.email_verified,
     last_sign_in_at = EXCLUDED.last_sign_in_at,
     updated_at = NOW()
-RETURNING id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned
+RETURNING id, name, email, created_at, updated_at, clerk_id, first_name, last_name, image_url, email_verified, last_sign_in_at, banned, role, time_zone
 `
 
 type UpsertUserByClerkIDParams struct {
@@ -264,6 +420,8 @@ func (q *Queries) UpsertUserByClerkID(ctx context.Context, arg UpsertUserByClerk
 		&i.EmailVerified,
 		&i.LastSignInAt,
 		&i.Banned,
+		&i.Role,
+		&i.TimeZone,
 	)
 	return i, err
 }
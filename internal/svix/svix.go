@@ -0,0 +1,153 @@
+// Package svix signs and verifies webhooks using the Svix scheme that Clerk
+// uses: an HMAC-SHA256 over "<msg id>.<unix timestamp>.<body>", keyed with a
+// base64 "whsec_" secret and sent as space-separated "v1,<base64>" entries.
+package svix
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how far a message timestamp may drift from now, in
+// either direction, before it is rejected
+const DefaultTolerance = 5 * time.Minute
+
+const (
+	secretPrefix     = "whsec_"
+	signatureVersion = "v1"
+)
+
+// Errors returned by Verify. Use errors.Is to check for them.
+var (
+	ErrNoSecrets           = errors.New("svix: no secrets configured")
+	ErrInvalidSecret       = errors.New("svix: secret is not valid base64")
+	ErrMissingHeaders      = errors.New("svix: missing required headers")
+	ErrInvalidTimestamp    = errors.New("svix: invalid timestamp")
+	ErrTimestampTooOld     = errors.New("svix: message timestamp too old")
+	ErrTimestampTooNew     = errors.New("svix: message timestamp too new")
+	ErrNoMatchingSignature = errors.New("svix: no matching signature found")
+)
+
+// Verifier checks webhook signatures against one or more active secrets.
+// Accepting several secrets allows rotating them without dropping messages.
+type Verifier struct {
+	keys      [][]byte
+	tolerance time.Duration
+}
+
+// NewVerifier creates a Verifier for the given secrets. A tolerance of zero
+// uses DefaultTolerance.
+func NewVerifier(secrets []string, tolerance time.Duration) (*Verifier, error) {
+	if len(secrets) == 0 {
+		return nil, ErrNoSecrets
+	}
+
+	keys := make([][]byte, 0, len(secrets))
+	for i, secret := range secrets {
+		key, err := decodeSecret(secret)
+		if err != nil {
+			return nil, fmt.Errorf("secret %d: %w", i, err)
+		}
+		keys = append(keys, key)
+	}
+
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+
+	return &Verifier{keys: keys, tolerance: tolerance}, nil
+}
+
+// Verify checks the headers of an incoming request against its raw body.
+// Both the "svix-" and the unbranded "webhook-" header names are accepted.
+func (v *Verifier) Verify(header http.Header, payload []byte) error {
+	return v.VerifyAt(header, payload, time.Now())
+}
+
+// VerifyAt is Verify with an explicit current time
+func (v *Verifier) VerifyAt(header http.Header, payload []byte, now time.Time) error {
+	msgID := headerValue(header, "id")
+	timestamp := headerValue(header, "timestamp")
+	signatures := headerValue(header, "signature")
+
+	if msgID == "" || timestamp == "" || signatures == "" {
+		return ErrMissingHeaders
+	}
+
+	ts, err := parseTimestamp(timestamp)
+	if err != nil {
+		return err
+	}
+
+	if now.Sub(ts) > v.tolerance {
+		return ErrTimestampTooOld
+	}
+	if ts.Sub(now) > v.tolerance {
+		return ErrTimestampTooNew
+	}
+
+	for _, key := range v.keys {
+		expected := []byte(sign(key, msgID, timestamp, payload))
+		for _, candidate := range strings.Fields(signatures) {
+			version, signature, found := strings.Cut(candidate, ",")
+			if !found || version != signatureVersion {
+				continue
+			}
+			if hmac.Equal(expected, []byte(signature)) {
+				return nil
+			}
+		}
+	}
+
+	return ErrNoMatchingSignature
+}
+
+// Sign returns the "v1,<base64>" signature for a message
+func Sign(secret, msgID string, timestamp time.Time, payload []byte) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return signatureVersion + "," + sign(key, msgID, ts, payload), nil
+}
+
+func sign(key []byte, msgID, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(msgID))
+	mac.Write([]byte("."))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, secretPrefix))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+	return key, nil
+}
+
+func parseTimestamp(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidTimestamp
+	}
+	return time.Unix(seconds, 0), nil
+}
+
+func headerValue(header http.Header, name string) string {
+	if value := header.Get("svix-" + name); value != "" {
+		return value
+	}
+	return header.Get("webhook-" + name)
+}
@@ -0,0 +1,195 @@
+package svix
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// The example message from the Svix documentation on verifying webhooks
+// manually
+const (
+	vectorSecret    = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	vectorMsgID     = "msg_p5jXN8AQM9LWM0D4loKWxJek"
+	vectorTimestamp = "1614265330"
+	vectorPayload   = `{"test": 2432232314}`
+	vectorSignature = "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="
+)
+
+var vectorTime = time.Unix(1614265330, 0)
+
+func headers(prefix, msgID, timestamp, signature string) http.Header {
+	header := http.Header{}
+	header.Set(prefix+"-id", msgID)
+	header.Set(prefix+"-timestamp", timestamp)
+	header.Set(prefix+"-signature", signature)
+	return header
+}
+
+func TestSignMatchesVector(t *testing.T) {
+	signature, err := Sign(vectorSecret, vectorMsgID, vectorTime, []byte(vectorPayload))
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	if signature != vectorSignature {
+		t.Errorf("Sign = %q, want %q", signature, vectorSignature)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	const otherSecret = "whsec_c2VjcmV0LWtleS10aGF0LWlzLW5vdC11c2Vk"
+
+	tests := []struct {
+		name    string
+		secrets []string
+		header  http.Header
+		payload string
+		now     time.Time
+		want    error
+	}{
+		{
+			name:    "svix headers",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime,
+		},
+		{
+			name:    "unbranded headers",
+			secrets: []string{vectorSecret},
+			header:  headers("webhook", vectorMsgID, vectorTimestamp, vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime,
+		},
+		{
+			name:    "several signatures, one valid",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, "v1,Ceo5qEr07ixe2NLpvHk3FH9bwy/WavXrAFQ/9tdO6mc= v2,abc "+vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime,
+		},
+		{
+			name:    "rotated secrets, second matches",
+			secrets: []string{otherSecret, vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime,
+		},
+		{
+			name:    "within tolerance",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime.Add(DefaultTolerance),
+		},
+		{
+			name:    "wrong secret",
+			secrets: []string{otherSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime,
+			want:    ErrNoMatchingSignature,
+		},
+		{
+			name:    "tampered payload",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, vectorSignature),
+			payload: `{"test": 2432232315}`,
+			now:     vectorTime,
+			want:    ErrNoMatchingSignature,
+		},
+		{
+			name:    "tampered message id",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", "msg_other", vectorTimestamp, vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime,
+			want:    ErrNoMatchingSignature,
+		},
+		{
+			name:    "unknown signature version",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, "v2,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="),
+			payload: vectorPayload,
+			now:     vectorTime,
+			want:    ErrNoMatchingSignature,
+		},
+		{
+			name:    "signature without version",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, "g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="),
+			payload: vectorPayload,
+			now:     vectorTime,
+			want:    ErrNoMatchingSignature,
+		},
+		{
+			name:    "expired timestamp",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime.Add(DefaultTolerance + time.Second),
+			want:    ErrTimestampTooOld,
+		},
+		{
+			name:    "timestamp in the future",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime.Add(-DefaultTolerance - time.Second),
+			want:    ErrTimestampTooNew,
+		},
+		{
+			name:    "invalid timestamp",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, "yesterday", vectorSignature),
+			payload: vectorPayload,
+			now:     vectorTime,
+			want:    ErrInvalidTimestamp,
+		},
+		{
+			name:    "missing signature",
+			secrets: []string{vectorSecret},
+			header:  headers("svix", vectorMsgID, vectorTimestamp, ""),
+			payload: vectorPayload,
+			now:     vectorTime,
+			want:    ErrMissingHeaders,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier, err := NewVerifier(tt.secrets, 0)
+			if err != nil {
+				t.Fatalf("NewVerifier: %v", err)
+			}
+			err = verifier.VerifyAt(tt.header, []byte(tt.payload), tt.now)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("VerifyAt = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewVerifier(t *testing.T) {
+	tests := []struct {
+		name    string
+		secrets []string
+		want    error
+	}{
+		{name: "no secrets", secrets: nil, want: ErrNoSecrets},
+		{name: "not base64", secrets: []string{"whsec_!!!"}, want: ErrInvalidSecret},
+		{name: "empty key", secrets: []string{"whsec_"}, want: ErrInvalidSecret},
+		{name: "one bad secret among good ones", secrets: []string{vectorSecret, "whsec_!!!"}, want: ErrInvalidSecret},
+		{name: "without prefix", secrets: []string{"MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(tt.secrets, 0)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("NewVerifier = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+// Package templates reads study plan templates from YAML files and seeds the
+// official ones into the database.
+//
+// A template file looks like this:
+//
+//	slug: calculus-1-final
+//	title: Calculus I final
+//	subject: Mathematics
+//	description: >
+//	  Limits, derivatives and integrals
+//	  in four weeks.
+//	duration_days: 28
+//	tags: [math, calculus]
+//	tasks:
+//	  - day: -28        # days before the exam, 0 is the exam day
+//	    title: Limits and continuity
+//	    priority: 1     # 0 low, 1 medium, 2 high
+//	    notes: Optional
+package templates
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+	"gopkg.in/yaml.v3"
+)
+
+// Limits on what a template may hold
+const (
+	MaxTags      = 10
+	MaxTagLength = 30
+	MaxTasks     = 200
+	MaxDuration  = 366
+)
+
+// Official holds the official templates shipped with the API
+//
+//go:embed official/*.yaml
+var Official embed.FS
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Template is a study plan outline
+type Template struct {
+	Slug         string   `yaml:"slug"`
+	Title        string   `yaml:"title"`
+	Subject      string   `yaml:"subject"`
+	Description  string   `yaml:"description"`
+	DurationDays int32    `yaml:"duration_days"`
+	Tags         []string `yaml:"tags"`
+	Tasks        []Task   `yaml:"tasks"`
+}
+
+// Task is a task of a template, due Day days from the exam date
+type Task struct {
+	Day      int32  `yaml:"day"`
+	Title    string `yaml:"title"`
+	Priority *int32 `yaml:"priority"`
+	Notes    string `yaml:"notes"`
+}
+
+// NormalizeTags lowercases and trims tags and drops empty and repeated ones
+func NormalizeTags(tags []string) []string {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// Parse reads a template from YAML. Unknown fields are an error, so a typo
+// is not silently ignored.
+func Parse(data []byte) (Template, error) {
+	var t Template
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&t); err != nil && !errors.Is(err, io.EOF) {
+		return Template{}, err
+	}
+
+	t.Tags = NormalizeTags(t.Tags)
+	return t, t.Validate()
+}
+
+// Validate checks a template before it is stored
+func (t Template) Validate() error {
+	switch {
+	case !slugPattern.MatchString(t.Slug):
+		return fmt.Errorf("slug must be lowercase letters, digits and dashes")
+	case strings.TrimSpace(t.Title) == "":
+		return fmt.Errorf("title is required")
+	case strings.TrimSpace(t.Subject) == "":
+		return fmt.Errorf("subject is required")
+	case t.DurationDays < 1 || t.DurationDays > MaxDuration:
+		return fmt.Errorf("duration_days must be between 1 and %d", MaxDuration)
+	case len(t.Tags) > MaxTags:
+		return fmt.Errorf("at most %d tags are allowed", MaxTags)
+	case len(t.Tasks) > MaxTasks:
+		return fmt.Errorf("at most %d tasks are allowed", MaxTasks)
+	}
+	for _, tag := range t.Tags {
+		if len(tag) > MaxTagLength {
+			return fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+	}
+	for i, task := range t.Tasks {
+		switch {
+		case strings.TrimSpace(task.Title) == "":
+			return fmt.Errorf("tasks[%d]: title is required", i)
+		case task.Day > 0 || task.Day < -MaxDuration:
+			return fmt.Errorf("tasks[%d]: day must be between -%d and 0", i, MaxDuration)
+		case task.Priority != nil && (*task.Priority < 0 || *task.Priority > 2):
+			return fmt.Errorf("tasks[%d]: priority must be 0, 1 or 2", i)
+		}
+	}
+	return nil
+}
+
+// Load reads every .yaml and .yml file in the root of fsys, sorted by name
+func Load(fsys fs.FS) ([]Template, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		ext := path.Ext(entry.Name())
+		if !entry.IsDir() && (ext == ".yaml" || ext == ".yml") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+
+	templates := make([]Template, 0, len(names))
+	slugs := make(map[string]string, len(names))
+	for _, name := range names {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		t, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if other, ok := slugs[t.Slug]; ok {
+			return nil, fmt.Errorf("%s: slug %q is already used by %s", name, t.Slug, other)
+		}
+		slugs[t.Slug] = name
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// LoadOfficial reads the official templates shipped with the API
+func LoadOfficial() ([]Template, error) {
+	fsys, err := fs.Sub(Official, "official")
+	if err != nil {
+		return nil, err
+	}
+	return Load(fsys)
+}
+
+// Seed creates or updates official templates, matched by slug, and
+// publishes them. The tasks of a template are replaced by those in the file.
+func Seed(ctx context.Context, db *sql.DB, templates []Template) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	queries := store.New(tx)
+	for _, t := range templates {
+		template, err := queries.UpsertOfficialPlanTemplate(ctx, store.UpsertOfficialPlanTemplateParams{
+			Slug:         sql.NullString{String: t.Slug, Valid: true},
+			Title:        t.Title,
+			Subject:      t.Subject,
+			Description:  sql.NullString{String: t.Description, Valid: t.Description != ""},
+			DurationDays: t.DurationDays,
+			Tags:         t.Tags,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to seed template %s: %w", t.Slug, err)
+		}
+
+		if err := queries.DeletePlanTemplateTasks(ctx, template.ID); err != nil {
+			return fmt.Errorf("failed to seed template %s: %w", t.Slug, err)
+		}
+		for i, task := range t.Tasks {
+			params := store.AddPlanTemplateTaskParams{
+				TemplateID: template.ID,
+				Position:   int32(i),
+				Title:      task.Title,
+				DayOffset:  task.Day,
+				Notes:      sql.NullString{String: task.Notes, Valid: task.Notes != ""},
+			}
+			if task.Priority != nil {
+				params.Priority = sql.NullInt32{Int32: *task.Priority, Valid: true}
+			}
+			if err := queries.AddPlanTemplateTask(ctx, params); err != nil {
+				return fmt.Errorf("failed to seed template %s: %w", t.Slug, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit templates: %w", err)
+	}
+	return nil
+}
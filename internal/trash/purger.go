@@ -0,0 +1,96 @@
+// Package trash permanently removes soft-deleted plans and tasks once they
+// have been in the trash for longer than the retention period.
+package trash
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// DefaultRetention is how long deleted items can be restored
+const DefaultRetention = 30 * 24 * time.Hour
+
+const purgeInterval = time.Hour
+
+// Purger removes expired trash in the background
+type Purger struct {
+	queries   *store.Queries
+	retention time.Duration
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewPurger creates a new Purger. A retention of zero uses DefaultRetention.
+func NewPurger(db *sql.DB, retention time.Duration) *Purger {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	return &Purger{
+		queries:   store.New(db),
+		retention: retention,
+		done:      make(chan struct{}),
+	}
+}
+
+// Retention returns how long deleted items are kept
+func (p *Purger) Retention() time.Duration {
+	return p.retention
+}
+
+// Start begins purging expired trash
+func (p *Purger) Start() {
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Close stops the background worker
+func (p *Purger) Close() {
+	close(p.done)
+	p.wg.Wait()
+}
+
+func (p *Purger) loop() {
+	defer p.wg.Done()
+
+	p.purge()
+
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-ticker.C:
+			p.purge()
+		}
+	}
+}
+
+func (p *Purger) purge() {
+	ctx := context.Background()
+	cutoff := sql.NullTime{Time: time.Now().Add(-p.retention), Valid: true}
+
+	// Plans first, so their tasks go with them through the foreign key
+	plans, err := p.queries.PurgeDeletedStudyPlans(ctx, cutoff)
+	if err != nil {
+		log.Printf("trash: failed to purge study plans: %v", err)
+		return
+	}
+
+	tasks, err := p.queries.PurgeDeletedTasks(ctx, cutoff)
+	if err != nil {
+		log.Printf("trash: failed to purge study tasks: %v", err)
+		return
+	}
+
+	if plans > 0 || tasks > 0 {
+		log.Printf("trash: purged %d plans and %d tasks", plans, tasks)
+	}
+}
@@ -0,0 +1,117 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "::ffff:93.184.216.34", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "10.0.0.1"},
+		{addr: "172.16.5.4"},
+		{addr: "192.168.1.1"},
+		{addr: "::ffff:192.168.1.1"},
+		{addr: "fd00::1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "0.0.0.0"},
+		{addr: "::"},
+		{addr: "0.1.2.3"},
+		{addr: "100.64.0.1"},
+		{addr: "192.0.2.1"},
+		{addr: "198.18.0.1"},
+		{addr: "203.0.113.9"},
+		{addr: "224.0.0.1"},
+		{addr: "255.255.255.255"},
+		{addr: "64:ff9b::a00:1"},
+		{addr: "2001:db8::1"},
+		{addr: "2002:a00:1::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := isPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+				t.Errorf("isPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{url: "https://hooks.example.com/prepilot"},
+		{url: "https://93.184.216.34/hook"},
+		{url: "http://localhost:8080/hook", want: ErrForbiddenAddress},
+		{url: "http://LOCALHOST./hook", want: ErrForbiddenAddress},
+		{url: "http://api.localhost/hook", want: ErrForbiddenAddress},
+		{url: "http://127.0.0.1/hook", want: ErrForbiddenAddress},
+		{url: "http://[::1]:8080/hook", want: ErrForbiddenAddress},
+		{url: "http://169.254.169.254/latest/meta-data", want: ErrForbiddenAddress},
+		{url: "http://[::ffff:10.0.0.1]/hook", want: ErrForbiddenAddress},
+		// Resolved and checked when delivering
+		{url: "https://internal.example.com/hook"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := ValidateURL(tt.url); !errors.Is(err, tt.want) {
+				t.Errorf("ValidateURL = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDialAddress(t *testing.T) {
+	tests := []struct {
+		address string
+		want    error
+	}{
+		{address: "93.184.216.34:443"},
+		{address: "[2606:2800:220:1:248:1893:25c8:1946]:443"},
+		{address: "127.0.0.1:80", want: ErrForbiddenAddress},
+		{address: "[::1]:80", want: ErrForbiddenAddress},
+		{address: "10.1.2.3:443", want: ErrForbiddenAddress},
+		{address: "[fe80::1%eth0]:443", want: ErrForbiddenAddress},
+		{address: "example.com:443", want: ErrForbiddenAddress},
+	}
+	for _, tt := range tests {
+		t.Run(tt.address, func(t *testing.T) {
+			if err := checkDialAddress("tcp", tt.address, nil); !errors.Is(err, tt.want) {
+				t.Errorf("checkDialAddress = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransportRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := &http.Client{Transport: newTransport()}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, server.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("delivered to a loopback address")
+	}
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Errorf("Do = %v, want %v", err, ErrForbiddenAddress)
+	}
+}
@@ -0,0 +1,264 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/store"
+)
+
+// Event types users can subscribe an endpoint to
+const (
+	TaskCompleted   = "task.completed"
+	PlanCreated     = "plan.created"
+	ExamApproaching = "exam.approaching"
+	StreakBroken    = "streak.broken"
+)
+
+// EventTypes lists every event type that can be delivered
+var EventTypes = []string{TaskCompleted, PlanCreated, ExamApproaching, StreakBroken}
+
+const (
+	// Delivery statuses
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+
+	// MaxConsecutiveFailures is how many failed attempts in a row disable an endpoint
+	MaxConsecutiveFailures = 15
+
+	pollInterval     = 5 * time.Second
+	batchSize        = 10
+	requestTimeout   = 10 * time.Second
+	maxResponseBytes = 1024
+)
+
+// retrySchedule is the wait before each retry. A delivery is attempted once
+// plus once per entry before it is marked as failed.
+var retrySchedule = []time.Duration{
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+}
+
+// Payload is the JSON body sent to endpoints
+type Payload struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// Dispatcher queues outbound webhooks and delivers them in the background
+type Dispatcher struct {
+	queries *store.Queries
+	client  *http.Client
+	version string
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewDispatcher creates a new Dispatcher. Start must be called before any
+// queued deliveries are sent.
+func NewDispatcher(db *sql.DB, version string) *Dispatcher {
+	return &Dispatcher{
+		queries: store.New(db),
+		client: &http.Client{
+			Timeout:   requestTimeout,
+			Transport: newTransport(),
+			// A redirect is treated as a failed delivery
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		version: version,
+		done:    make(chan struct{}),
+	}
+}
+
+// Start begins delivering queued webhooks and running scheduled events
+func (d *Dispatcher) Start() {
+	d.wg.Add(2)
+	go d.deliverLoop()
+	go d.scheduleLoop()
+}
+
+// Close stops the background workers and waits for in-flight deliveries
+func (d *Dispatcher) Close() {
+	close(d.done)
+	d.wg.Wait()
+}
+
+// Enqueue queues an event for every enabled endpoint of the user subscribed
+// to it. A non-empty dedupeKey makes the call idempotent per endpoint.
+func (d *Dispatcher) Enqueue(ctx context.Context, userID, eventType, dedupeKey string, data any) error {
+	payload, err := json.Marshal(Payload{
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	_, err = d.queries.EnqueueWebhookDeliveries(ctx, store.EnqueueWebhookDeliveriesParams{
+		EventType: eventType,
+		Payload:   payload,
+		DedupeKey: sql.NullString{String: dedupeKey, Valid: dedupeKey != ""},
+		UserID:    userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enqueue webhook: %w", err)
+	}
+	return nil
+}
+
+func (d *Dispatcher) deliverLoop() {
+	defer d.wg.Done()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			d.deliverDue()
+		}
+	}
+}
+
+// deliverDue claims a batch of due deliveries and sends them concurrently.
+// Claiming pushes next_attempt_at forward, so a crashed worker's deliveries
+// are picked up again once the lease expires.
+func (d *Dispatcher) deliverDue() {
+	ctx := context.Background()
+
+	deliveries, err := d.queries.ClaimDueWebhookDeliveries(ctx, batchSize)
+	if err != nil {
+		log.Printf("webhooks: failed to claim deliveries: %v", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery store.WebhookDelivery) {
+			defer wg.Done()
+			if err := d.deliver(ctx, delivery); err != nil {
+				log.Printf("webhooks: delivery %s failed: %v", delivery.ID, err)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+}
+
+// deliver makes a single attempt and records its outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery store.WebhookDelivery) error {
+	endpoint, err := d.queries.GetWebhookEndpointByID(ctx, delivery.EndpointID)
+	if err != nil {
+		return fmt.Errorf("failed to load endpoint: %w", err)
+	}
+
+	started := time.Now()
+	code, sendErr := d.send(ctx, endpoint, delivery)
+	duration := time.Since(started)
+
+	attempt := store.CreateWebhookDeliveryAttemptParams{
+		DeliveryID:   delivery.ID,
+		ResponseCode: sql.NullInt32{Int32: int32(code), Valid: code != 0},
+		DurationMs:   int32(duration.Milliseconds()),
+	}
+	if sendErr != nil {
+		attempt.Error = sql.NullString{String: sendErr.Error(), Valid: true}
+	}
+	if err := d.queries.CreateWebhookDeliveryAttempt(ctx, attempt); err != nil {
+		return fmt.Errorf("failed to record attempt: %w", err)
+	}
+
+	if sendErr == nil && code >= 200 && code < 300 {
+		if err := d.queries.MarkWebhookDeliverySucceeded(ctx, store.MarkWebhookDeliverySucceededParams{
+			ID:               delivery.ID,
+			LastResponseCode: attempt.ResponseCode,
+		}); err != nil {
+			return fmt.Errorf("failed to mark delivery succeeded: %w", err)
+		}
+		return d.queries.RecordWebhookEndpointSuccess(ctx, endpoint.ID)
+	}
+
+	// Schedule a retry, or give up once the schedule is exhausted
+	var next sql.NullTime
+	if int(delivery.Attempts) < len(retrySchedule) {
+		next = sql.NullTime{Time: time.Now().Add(retrySchedule[delivery.Attempts]), Valid: true}
+	}
+	if err := d.queries.MarkWebhookDeliveryFailed(ctx, store.MarkWebhookDeliveryFailedParams{
+		NextAttemptAt:    next,
+		LastResponseCode: attempt.ResponseCode,
+		ID:               delivery.ID,
+	}); err != nil {
+		return fmt.Errorf("failed to mark delivery failed: %w", err)
+	}
+
+	updated, err := d.queries.RecordWebhookEndpointFailure(ctx, store.RecordWebhookEndpointFailureParams{
+		MaxFailures: MaxConsecutiveFailures,
+		ID:          endpoint.ID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record endpoint failure: %w", err)
+	}
+	if endpoint.Enabled && !updated.Enabled {
+		log.Printf("webhooks: endpoint %s disabled after %d consecutive failures", endpoint.ID, updated.ConsecutiveFailures)
+	}
+
+	return nil
+}
+
+// send posts the payload and returns the status code. The response body is
+// discarded so endpoints cannot be used to read other services' replies.
+func (d *Dispatcher) send(ctx context.Context, endpoint store.WebhookEndpoint, delivery store.WebhookDelivery) (int, error) {
+	now := time.Now()
+	signature, err := Sign(endpoint.Secret, delivery.MessageID, now, delivery.Payload)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Prepilot-Webhooks/"+d.version)
+	req.Header.Set("webhook-id", delivery.MessageID)
+	req.Header.Set("webhook-timestamp", strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set("webhook-signature", signature)
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		var urlErr interface{ Timeout() bool }
+		if errors.Is(err, ErrForbiddenAddress) {
+			return 0, ErrForbiddenAddress
+		}
+		if errors.As(err, &urlErr) && urlErr.Timeout() {
+			return 0, fmt.Errorf("request timed out after %s", requestTimeout)
+		}
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,124 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+)
+
+const scheduleInterval = time.Hour
+
+// examReminderDays are the days before an exam that exam.approaching fires
+var examReminderDays = []int32{7, 3, 1}
+
+// minStreakLength is the shortest streak worth reporting as broken
+const minStreakLength = 2
+
+// scheduleLoop emits time-based events. Every event carries a dedupe key,
+// so running the scan on several replicas or several times a day is safe.
+func (d *Dispatcher) scheduleLoop() {
+	defer d.wg.Done()
+
+	d.runSchedules()
+
+	ticker := time.NewTicker(scheduleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+			d.runSchedules()
+		}
+	}
+}
+
+func (d *Dispatcher) runSchedules() {
+	ctx := context.Background()
+
+	if err := d.enqueueExamReminders(ctx); err != nil {
+		log.Printf("webhooks: exam reminders failed: %v", err)
+	}
+	if err := d.enqueueBrokenStreaks(ctx); err != nil {
+		log.Printf("webhooks: broken streaks failed: %v", err)
+	}
+}
+
+// enqueueExamReminders fires exam.approaching for exams, and for the
+// exam_date of plans that are not linked to any exam
+func (d *Dispatcher) enqueueExamReminders(ctx context.Context) error {
+	for _, days := range examReminderDays {
+		exams, err := d.queries.GetExamsStartingIn(ctx, days)
+		if err != nil {
+			return fmt.Errorf("failed to load exams: %w", err)
+		}
+
+		for _, exam := range exams {
+			data := map[string]any{
+				"exam_id":        exam.ID,
+				"plan_ids":       exam.PlanIds,
+				"title":          exam.Title,
+				"subject":        nullableString(exam.Subject),
+				"location":       nullableString(exam.Location),
+				"starts_at":      exam.StartsAt,
+				"exam_date":      exam.StartsAt.Format(time.DateOnly),
+				"days_remaining": days,
+			}
+			key := fmt.Sprintf("%s:exam:%s:%d", ExamApproaching, exam.ID, days)
+			if err := d.Enqueue(ctx, exam.UserID, ExamApproaching, key, data); err != nil {
+				return err
+			}
+		}
+
+		plans, err := d.queries.GetStudyPlansWithExamIn(ctx, days)
+		if err != nil {
+			return fmt.Errorf("failed to load plans: %w", err)
+		}
+
+		for _, plan := range plans {
+			data := map[string]any{
+				"plan_id":        plan.ID,
+				"title":          plan.Title,
+				"subject":        plan.Subject,
+				"exam_date":      plan.ExamDate.Format(time.DateOnly),
+				"days_remaining": days,
+			}
+			key := fmt.Sprintf("%s:%s:%d", ExamApproaching, plan.ID, days)
+			if err := d.Enqueue(ctx, plan.UserID, ExamApproaching, key, data); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *Dispatcher) enqueueBrokenStreaks(ctx context.Context) error {
+	streaks, err := d.queries.GetBrokenStreaks(ctx, minStreakLength)
+	if err != nil {
+		return fmt.Errorf("failed to load streaks: %w", err)
+	}
+
+	for _, streak := range streaks {
+		data := map[string]any{
+			"started_on": streak.StartedOn.Format(time.DateOnly),
+			"ended_on":   streak.EndedOn.Format(time.DateOnly),
+			"length":     streak.Length,
+		}
+		key := fmt.Sprintf("%s:%s", StreakBroken, streak.EndedOn.Format(time.DateOnly))
+		if err := d.Enqueue(ctx, streak.UserID, StreakBroken, key, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// nullableString is the value of s, or nil so it is sent as null
+func nullableString(s sql.NullString) any {
+	if !s.Valid {
+		return nil
+	}
+	return s.String
+}
@@ -0,0 +1,27 @@
+package webhooks
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/mustaphalimar/prepilot/internal/svix"
+)
+
+// NewSecret generates a signing secret in the same "whsec_" format Clerk
+// uses for the webhooks we receive
+func NewSecret() (string, error) {
+	key := make([]byte, 24)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return "whsec_" + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Sign returns the signature header value for a message. Outbound webhooks
+// use the same Svix scheme we verify for inbound Clerk webhooks, so
+// receivers can check them with any Svix library.
+func Sign(secret, msgID string, timestamp time.Time, body []byte) (string, error) {
+	return svix.Sign(secret, msgID, timestamp, body)
+}